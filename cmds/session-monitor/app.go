@@ -11,11 +11,14 @@ import (
 	"github.com/go-chi/render"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/internal/worker"
-	_ "go.uber.org/dig"
+	"go.uber.org/dig"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -61,6 +64,17 @@ func (r *CompositionRoot) startupModules() error {
 		// if err != nil {
 		// 	return err
 		// }
+		// background workers of the module, e.g. timeout trackers. a module without workers gets an empty group,
+		// the error is a worker whose dependencies are missing
+		err = container.Invoke(func(p struct {
+			dig.In
+			Workers []worker.Worker `group:"workers"`
+		}) {
+			r.workerSyncer.Add(p.Workers...)
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -126,16 +140,26 @@ type ModuleContext struct {
 	config          config.IConfig
 	kvRepository    repository.IKVRepository
 	eventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	sessionService  session.ISessionService
+	sessionMetrics  session.ISessionMetrics
+	gpuInventory    gpu.IInventory
+	scaleUpTracker  scaleup.ITracker
 }
 
 func newModuleContext(mux *chi.Mux, logger *zap.Logger, config config.IConfig,
-	kvRepository repository.IKVRepository, eventDispatcher ddd.IEventDispatcher[ddd.IEvent]) module.IModuleContext {
+	kvRepository repository.IKVRepository, eventDispatcher ddd.IEventDispatcher[ddd.IEvent],
+	sessionService session.ISessionService, sessionMetrics session.ISessionMetrics,
+	gpuInventory gpu.IInventory, scaleUpTracker scaleup.ITracker) module.IModuleContext {
 	return &ModuleContext{
 		mux,
 		logger,
 		config,
 		kvRepository,
 		eventDispatcher,
+		sessionService,
+		sessionMetrics,
+		gpuInventory,
+		scaleUpTracker,
 	}
 }
 
@@ -158,3 +182,19 @@ func (r *ModuleContext) KvRepository() repository.IKVRepository {
 func (r *ModuleContext) EventDispatcher() ddd.IEventDispatcher[ddd.IEvent] {
 	return r.eventDispatcher
 }
+
+func (r *ModuleContext) SessionService() session.ISessionService {
+	return r.sessionService
+}
+
+func (r *ModuleContext) SessionMetrics() session.ISessionMetrics {
+	return r.sessionMetrics
+}
+
+func (r *ModuleContext) GpuInventory() gpu.IInventory {
+	return r.gpuInventory
+}
+
+func (r *ModuleContext) ScaleUpTracker() scaleup.ITracker {
+	return r.scaleUpTracker
+}
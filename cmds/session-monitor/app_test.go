@@ -1 +1,40 @@
 package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/module"
+	"github.com/xcheng85/session-monitor-k8s/internal/worker"
+	"go.uber.org/dig"
+)
+
+type testModule func(container *dig.Container) error
+
+func (m testModule) Startup(ctx context.Context, mono module.IModuleContext) (*dig.Container, error) {
+	container := dig.New()
+	return container, m(container)
+}
+
+type missingDependency struct{}
+
+func TestStartupModules(t *testing.T) {
+	// no workers at all
+	noWorkers := testModule(func(container *dig.Container) error {
+		return nil
+	})
+	root := newCompositionRoot(chi.NewRouter(), module.NewMockIModuleContext(t), worker.NewWorkerSyncer(context.TODO()), noWorkers)
+	assert.Nil(t, root.startupModules())
+
+	brokenWorker := testModule(func(container *dig.Container) error {
+		return container.Provide(func(missingDependency) worker.Worker {
+			return func(ctx context.Context) error {
+				return nil
+			}
+		}, dig.Group("workers"))
+	})
+	root = newCompositionRoot(chi.NewRouter(), module.NewMockIModuleContext(t), worker.NewWorkerSyncer(context.TODO()), noWorkers, brokenWorker)
+	assert.NotNil(t, root.startupModules())
+}
@@ -5,15 +5,22 @@ import (
 	"os"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xcheng85/session-monitor-k8s/event"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/internal/worker"
 	"github.com/xcheng85/session-monitor-k8s/k8s"
 	"github.com/xcheng85/session-monitor-k8s/node"
 	"github.com/xcheng85/session-monitor-k8s/pod"
+	sessionmodule "github.com/xcheng85/session-monitor-k8s/session"
+	"github.com/xcheng85/session-monitor-k8s/workload"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
 )
@@ -41,21 +48,36 @@ func newIocContainer() (*IocContainer, error) {
 	err = container.Provide(k8s.NewK8sModule, dig.Name("k8s"))
 	err = container.Provide(pod.NewPodMonitoringModule, dig.Name("pod"))
 	err = container.Provide(node.NewNodeMonitoringModule, dig.Name("node"))
+	err = container.Provide(event.NewEventMonitoringModule, dig.Name("event"))
+	err = container.Provide(workload.NewWorkloadMonitoringModule, dig.Name("workload"))
+	err = container.Provide(sessionmodule.NewSessionModule, dig.Name("session"))
 	err = container.Provide(newMux)
 	err = container.Provide(newModuleContext)
 	err = container.Provide(worker.NewWorkerSyncer)
 	err = container.Provide(repository.NewRedisRepository)
 	err = container.Provide(ddd.NewEventDispatcher[ddd.IEvent])
+	err = container.Provide(session.NewTimestampStore)
+	err = container.Provide(session.NewLifecycleStore)
+	err = container.Provide(session.NewSessionService)
+	err = container.Provide(func() prometheus.Registerer {
+		return prometheus.DefaultRegisterer
+	})
+	err = container.Provide(session.NewSessionMetrics)
+	err = container.Provide(gpu.NewInventory)
+	err = container.Provide(scaleup.NewTracker)
 	err = container.Provide(func(p struct {
 		dig.In
 		ModuleContext module.IModuleContext
 		K8s           module.Module `name:"k8s"`
 		Pod           module.Module `name:"pod"`
 		Node          module.Module `name:"node"`
+		Event         module.Module `name:"event"`
+		Workload      module.Module `name:"workload"`
+		Session       module.Module `name:"session"`
 		Mux           *chi.Mux
 		WorkerSyncer  worker.IWorkerSyncer
 	}) (*CompositionRoot, error) {
-		root := newCompositionRoot(p.Mux, p.ModuleContext, p.WorkerSyncer, p.K8s, p.Pod, p.Node)
+		root := newCompositionRoot(p.Mux, p.ModuleContext, p.WorkerSyncer, p.K8s, p.Pod, p.Node, p.Event, p.Workload, p.Session)
 		err := root.startupModules()
 		if err == nil {
 			return root, nil
@@ -39,6 +39,16 @@ func Test_PublicEndpoint_Integration(t *testing.T) {
 			assert.Equal(t, 200, resp.StatusCode)
 		})
 
+		t.Run("it should return 200 for prometheus metrics", func(t *testing.T) {
+			resp, err := http.Get(fmt.Sprintf("%s/metrics", ts.URL))
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			assert.Equal(t, 200, resp.StatusCode)
+		})
+
 		return nil
 	})
 }
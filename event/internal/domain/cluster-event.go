@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+)
+
+// a core/v1 Event reported for the pod of a session
+type ClusterEvent struct {
+	Name         string `json:"name,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	InvolvedKind string `json:"involvedKind,omitempty"`
+	InvolvedName string `json:"involvedName,omitempty"`
+	SessionId    string `json:"sessionId,omitempty"`
+	// occurrences aggregated by the kubelet or scheduler into this event
+	Count     int32     `json:"count,omitempty"`
+	FirstSeen time.Time `json:"firstSeen,omitempty"`
+	LastSeen  time.Time `json:"lastSeen,omitempty"`
+}
+
+func (e *ClusterEvent) Warning() session.SessionWarning {
+	warning := session.SessionWarning{
+		Reason:   e.Reason,
+		Message:  e.Message,
+		Object:   fmt.Sprintf("%s/%s", e.InvolvedKind, e.InvolvedName),
+		Count:    e.Count,
+		LastSeen: e.LastSeen.Unix(),
+	}
+	if !e.FirstSeen.IsZero() {
+		warning.FirstSeen = e.FirstSeen.Unix()
+	}
+	return warning
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+)
+
+func TestClusterEvent_Warning(t *testing.T) {
+	event := &ClusterEvent{
+		Reason:       "BackOff",
+		Message:      "Back-off restarting failed container viz3d",
+		InvolvedKind: "Pod",
+		InvolvedName: "pod-1",
+		Count:        3,
+		FirstSeen:    time.Unix(100, 0),
+		LastSeen:     time.Unix(160, 0),
+	}
+	assert.Equal(t, session.SessionWarning{
+		Reason:    "BackOff",
+		Message:   "Back-off restarting failed container viz3d",
+		Object:    "Pod/pod-1",
+		Count:     3,
+		FirstSeen: 100,
+		LastSeen:  160,
+	}, event.Warning())
+
+	event.FirstSeen = time.Time{}
+	assert.Equal(t, int64(0), event.Warning().FirstSeen)
+}
@@ -0,0 +1,14 @@
+package domain
+
+const (
+	ClusterEventInformerErrorEvent = "ClusterEventInformerErrorEvent"
+	ClusterEventWarningEvent       = "ClusterEventWarningEvent"
+)
+
+type ClusterEventInformerErrorPayload struct {
+	Err error
+}
+
+type ClusterEventPayload struct {
+	Event *ClusterEvent
+}
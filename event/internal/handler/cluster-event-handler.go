@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/event/internal/domain"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// reasons of app.cluster_event_reasons by default, most session failures only show up in them
+var defaultClusterEventReasons = []string{
+	"FailedScheduling",
+	"NotTriggerScaleUp",
+	"FailedMount",
+	"FailedAttachVolume",
+	"FailedCreatePodSandBox",
+	"Failed",
+	"BackOff",
+	"Unhealthy",
+}
+
+type ClusterEventHandler struct {
+	ctx                   context.Context
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	sessionService        session.ISessionService
+	scaleUps              scaleup.ITracker
+	reasons               map[string]bool
+}
+
+func NewClusterEventHandler(ctx context.Context, logger *zap.Logger, config config.IConfig,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent],
+	domainEventHandler ddd.IEventHandler[ddd.IEvent],
+	sessionService session.ISessionService,
+	scaleUps scaleup.ITracker,
+) k8s.IK8sEventHandler {
+	reasons := defaultClusterEventReasons
+	if configured := config.Get("app.cluster_event_reasons"); configured != nil {
+		reasons = cast.ToStringSlice(configured)
+	}
+	handler := &ClusterEventHandler{
+		ctx:                   ctx,
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		sessionService:        sessionService,
+		scaleUps:              scaleUps,
+		reasons:               map[string]bool{},
+	}
+	for _, reason := range reasons {
+		handler.reasons[reason] = true
+	}
+	return handler
+}
+
+func (handler *ClusterEventHandler) CustomWatchErrorHandler(r *cache.Reflector, err error) {
+	handler.logger.Sugar().Errorw("Watch error", err)
+	handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
+		domain.ClusterEventInformerErrorEvent,
+		&domain.ClusterEventInformerErrorPayload{
+			Err: err,
+		},
+	))
+}
+
+func (handler *ClusterEventHandler) OnAddObject(obj interface{}) {
+	handler.onEvent(obj)
+}
+
+// the count and last timestamp of a repeated event are updated in place
+func (handler *ClusterEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
+	handler.onEvent(newObj)
+}
+
+// events expire after the event ttl of the api server, the warnings of the session are kept
+func (handler *ClusterEventHandler) OnDeleteObject(obj interface{}) {
+}
+
+func (handler *ClusterEventHandler) onEvent(obj interface{}) {
+	event, err := parseEvent(obj.(*unstructured.Unstructured))
+	if err != nil {
+		handler.logger.Sugar().Error("onEvent:", err)
+		return
+	}
+	involved := event.InvolvedObject
+	if involved.Kind != "Pod" {
+		return
+	}
+	namespace := involved.Namespace
+	if namespace == "" {
+		namespace = event.Namespace
+	}
+	// the pod module reports the pods waiting for a node, the autoscaler reports the ones it adds a node for
+	if event.Reason == scaleup.TriggeredScaleUpReason {
+		handler.scaleUps.ScaleUpTriggered(namespace + "/" + involved.Name)
+	}
+	if !handler.reasons[event.Reason] {
+		return
+	}
+	// pods are known to the session service once the pod module has seen them
+	sessionId, exist := handler.sessionService.FindSessionByPod(namespace, involved.Name)
+	if !exist {
+		handler.logger.Sugar().Debugw("Event of a pod without session", "Reason", event.Reason,
+			"Namespace", namespace, "PodName", involved.Name)
+		return
+	}
+	firstSeen, lastSeen, count := eventOccurrences(&event)
+	handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
+		domain.ClusterEventWarningEvent,
+		&domain.ClusterEventPayload{
+			Event: &domain.ClusterEvent{
+				Name:         event.Name,
+				Namespace:    namespace,
+				Type:         event.Type,
+				Reason:       event.Reason,
+				Message:      event.Message,
+				InvolvedKind: involved.Kind,
+				InvolvedName: involved.Name,
+				SessionId:    sessionId,
+				Count:        count,
+				FirstSeen:    firstSeen,
+				LastSeen:     lastSeen,
+			},
+		},
+	))
+}
+
+// events of the events.k8s.io api only set the event time and the series, core/v1 ones the timestamps
+func eventOccurrences(event *v1.Event) (firstSeen time.Time, lastSeen time.Time, count int32) {
+	firstSeen, lastSeen, count = event.FirstTimestamp.Time, event.LastTimestamp.Time, event.Count
+	if firstSeen.IsZero() {
+		firstSeen = event.EventTime.Time
+	}
+	if event.Series != nil {
+		lastSeen, count = event.Series.LastObservedTime.Time, event.Series.Count
+	}
+	if lastSeen.IsZero() {
+		lastSeen = firstSeen
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.CreationTimestamp.Time
+	}
+	if count == 0 {
+		count = 1
+	}
+	return firstSeen, lastSeen, count
+}
+
+func parseEvent(u *unstructured.Unstructured) (event v1.Event, err error) {
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &event)
+	return event, err
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/event/internal/domain"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func clusterEvent(kind string, reason string, count int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Event",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "pod-1.17a0c3b2c1d2e3f4",
+				"namespace": "viz",
+			},
+			"involvedObject": map[string]interface{}{
+				"kind":      kind,
+				"name":      "pod-1",
+				"namespace": "viz",
+			},
+			"type":           "Warning",
+			"reason":         reason,
+			"message":        "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+			"count":          count,
+			"firstTimestamp": "2026-10-19T10:00:00Z",
+			"lastTimestamp":  "2026-10-19T10:05:00Z",
+		},
+	}
+}
+
+func TestCustomWatchErrorHandler(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.cluster_event_reasons").Return(nil)
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+
+	h := NewClusterEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}, &session.MockISessionService{}, &scaleup.MockITracker{}).(*ClusterEventHandler)
+	h.CustomWatchErrorHandler(nil, errors.New("errors from k8s api server"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestOnAddObject_Warning(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.cluster_event_reasons").Return(nil)
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+	sessionService := &session.MockISessionService{}
+	sessionService.On("FindSessionByPod", "viz", "pod-1").Return("session-1", true).Once()
+
+	h := NewClusterEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}, sessionService, &scaleup.MockITracker{}).(*ClusterEventHandler)
+	h.OnAddObject(clusterEvent("Pod", "FailedScheduling", 4))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.ClusterEventWarningEvent, event.EventName())
+	clusterEvent := event.Payload().(*domain.ClusterEventPayload).Event
+	assert.Equal(t, "session-1", clusterEvent.SessionId)
+	assert.Equal(t, "FailedScheduling", clusterEvent.Reason)
+	assert.Equal(t, int32(4), clusterEvent.Count)
+	assert.True(t, clusterEvent.LastSeen.Equal(time.Date(2026, 10, 19, 10, 5, 0, 0, time.UTC)))
+}
+
+func TestOnUpdateObject_Ignored(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.cluster_event_reasons").Return([]interface{}{"BackOff"})
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	sessionService := &session.MockISessionService{}
+	sessionService.On("FindSessionByPod", "viz", "pod-1").Return("", false).Once()
+
+	h := NewClusterEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}, sessionService, &scaleup.MockITracker{}).(*ClusterEventHandler)
+	// not configured
+	h.OnUpdateObject(nil, clusterEvent("Pod", "FailedScheduling", 1))
+	// not a pod
+	h.OnUpdateObject(nil, clusterEvent("Node", "BackOff", 1))
+	// pod without session
+	h.OnUpdateObject(nil, clusterEvent("Pod", "BackOff", 1))
+	eventDispatcher.AssertNotCalled(t, "Publish")
+	sessionService.AssertNumberOfCalls(t, "FindSessionByPod", 1)
+}
+
+// scale ups are tracked for every pod, the event is no warning
+func TestOnAddObject_TriggeredScaleUp(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.cluster_event_reasons").Return(nil)
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("ScaleUpTriggered", "viz/pod-1").Return().Once()
+
+	h := NewClusterEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}, &session.MockISessionService{}, scaleUps).(*ClusterEventHandler)
+	h.OnAddObject(clusterEvent("Pod", scaleup.TriggeredScaleUpReason, 1))
+	eventDispatcher.AssertNotCalled(t, "Publish")
+}
+
+func TestEventOccurrences(t *testing.T) {
+	created := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	observed := created.Add(time.Minute)
+	firstSeen, lastSeen, count := eventOccurrences(&v1.Event{
+		EventTime: metav1.NewMicroTime(created),
+		Series:    &v1.EventSeries{Count: 6, LastObservedTime: metav1.NewMicroTime(observed)},
+	})
+	assert.Equal(t, created, firstSeen)
+	assert.Equal(t, observed, lastSeen)
+	assert.Equal(t, int32(6), count)
+
+	firstSeen, lastSeen, count = eventOccurrences(&v1.Event{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+	})
+	assert.True(t, firstSeen.IsZero())
+	assert.Equal(t, created, lastSeen)
+	assert.Equal(t, int32(1), count)
+}
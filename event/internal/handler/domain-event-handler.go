@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/event/internal/domain"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/zap"
+)
+
+type domainEventHandlers[T ddd.IEvent] struct {
+	logger         *zap.Logger
+	sessionService session.ISessionService
+}
+
+var _ ddd.IEventHandler[ddd.IEvent] = (*domainEventHandlers[ddd.IEvent])(nil)
+
+func NewDomainEventHandlers(
+	logger *zap.Logger,
+	subscriber ddd.IEventDispatcher[ddd.IEvent],
+	sessionService session.ISessionService,
+) ddd.IEventHandler[ddd.IEvent] {
+	handler := &domainEventHandlers[ddd.IEvent]{
+		logger,
+		sessionService,
+	}
+	subscriber.Subscribe(handler,
+		domain.ClusterEventWarningEvent,
+		domain.ClusterEventInformerErrorEvent,
+	)
+	return handler
+}
+
+func (d domainEventHandlers[T]) HandleEvent(ctx context.Context, event T) (err error) {
+	switch event.EventName() {
+	case domain.ClusterEventWarningEvent:
+		return d.onClusterEventWarning(ctx, event)
+	case domain.ClusterEventInformerErrorEvent:
+		d.logger.Sugar().Errorf("Event informer has error: %s", event.Payload().(*domain.ClusterEventInformerErrorPayload).Err)
+	}
+	return nil
+}
+
+// the warning shows up in the session view and in the failure payloads of the session
+func (d domainEventHandlers[T]) onClusterEventWarning(ctx context.Context, event ddd.IEvent) error {
+	clusterEvent := event.Payload().(*domain.ClusterEventPayload).Event
+	d.logger.Sugar().Infow("Session pod event", "SessionId", clusterEvent.SessionId, "Type", clusterEvent.Type,
+		"Reason", clusterEvent.Reason, "Message", clusterEvent.Message, "Count", clusterEvent.Count)
+	err := d.sessionService.AddSessionWarning(&session.AddSessionWarningActionPayload{
+		SessionId: clusterEvent.SessionId,
+		Warning:   clusterEvent.Warning(),
+	})
+	if err != nil {
+		d.logger.Sugar().Errorf("AddSessionWarning of session %s has error: %s", clusterEvent.SessionId, err.Error())
+	}
+	return err
+}
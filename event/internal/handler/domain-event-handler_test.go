@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/event/internal/domain"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+)
+
+func TestHandleEvent_ClusterEventWarning(t *testing.T) {
+	ctx := context.TODO()
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	sessionService := session.NewMockISessionService(t)
+	sessionService.On("AddSessionWarning", &session.AddSessionWarningActionPayload{
+		SessionId: "session-1",
+		Warning: session.SessionWarning{
+			Reason:    "Unhealthy",
+			Message:   "Readiness probe failed",
+			Object:    "Pod/pod-1",
+			Count:     2,
+			FirstSeen: 100,
+			LastSeen:  130,
+		},
+	}).Return(nil).Once()
+
+	h := NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), eventDispatcher, sessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.ClusterEventWarningEvent, &domain.ClusterEventPayload{
+		Event: &domain.ClusterEvent{
+			Type:         "Warning",
+			Reason:       "Unhealthy",
+			Message:      "Readiness probe failed",
+			InvolvedKind: "Pod",
+			InvolvedName: "pod-1",
+			SessionId:    "session-1",
+			Count:        2,
+			FirstSeen:    time.Unix(100, 0),
+			LastSeen:     time.Unix(130, 0),
+		},
+	}))
+	assert.Nil(t, err)
+}
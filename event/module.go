@@ -0,0 +1,73 @@
+package event
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/event/internal/handler"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/module"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/dig"
+	"go.uber.org/zap"
+)
+
+// watches the core/v1 events of the session pods
+type EventMonitoringModule struct{}
+
+func (m EventMonitoringModule) Startup(ctx context.Context, mono module.IModuleContext) (*dig.Container, error) {
+	container := dig.New()
+	err := container.Provide(func() context.Context {
+		return ctx
+	})
+	err = container.Provide(func() *zap.Logger {
+		return mono.Logger()
+	})
+	err = container.Provide(func() config.IConfig {
+		return mono.Config()
+	})
+	err = container.Provide(func() ddd.IEventDispatcher[ddd.IEvent] {
+		return mono.EventDispatcher()
+	})
+	err = container.Provide(func() session.ISessionService {
+		return mono.SessionService()
+	})
+	err = container.Provide(func() scaleup.ITracker {
+		return mono.ScaleUpTracker()
+	})
+	err = container.Provide(k8s.NewK8sDynamicInformer)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewDomainEventHandlers)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewClusterEventHandler)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() string {
+		return "events"
+	}, dig.Name("k8s_resource"))
+	if err != nil {
+		return nil, err
+	}
+	// the events of the session pods are in the namespace of the pods
+	err = container.Provide(func(config config.IConfig) string {
+		return config.Get("app.pod_namespace").(string)
+	}, dig.Name("k8s_resource_namespace"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Invoke(func(informer k8s.IK8sInformer) error {
+		return nil
+	})
+	return container, err
+}
+
+func NewEventMonitoringModule() module.Module {
+	return &EventMonitoringModule{}
+}
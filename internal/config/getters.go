@@ -0,0 +1,54 @@
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// typed accessors for optional settings
+// viper returns int from yaml but string from env, cast covers both
+func GetString(cfg IConfig, key string, defaultValue string) string {
+	value, err := cast.ToStringE(cfg.Get(key))
+	if err != nil || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func GetInt(cfg IConfig, key string, defaultValue int) int {
+	raw := cfg.Get(key)
+	if raw == nil {
+		return defaultValue
+	}
+	value, err := cast.ToIntE(raw)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func GetBool(cfg IConfig, key string, defaultValue bool) bool {
+	raw := cfg.Get(key)
+	if raw == nil {
+		return defaultValue
+	}
+	value, err := cast.ToBoolE(raw)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// durations are configured in seconds across config.yaml
+func GetSeconds(cfg IConfig, key string, defaultValue time.Duration) time.Duration {
+	raw := cfg.Get(key)
+	if raw == nil {
+		return defaultValue
+	}
+	value, err := cast.ToIntE(raw)
+	if err != nil {
+		return defaultValue
+	}
+	return time.Duration(value) * time.Second
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetters(t *testing.T) {
+	mockConfig := &MockIConfig{}
+	mockConfig.On("Get", "app.string").Return("value")
+	mockConfig.On("Get", "app.int").Return(42)
+	mockConfig.On("Get", "app.int_from_env").Return("42")
+	mockConfig.On("Get", "app.bool").Return(true)
+	mockConfig.On("Get", "app.bool_from_env").Return("true")
+	mockConfig.On("Get", "app.bogus").Return("not-a-number")
+	mockConfig.On("Get", "app.missing").Return(nil)
+
+	assert.Equal(t, "value", GetString(mockConfig, "app.string", "default"))
+	assert.Equal(t, "default", GetString(mockConfig, "app.missing", "default"))
+	assert.Equal(t, 42, GetInt(mockConfig, "app.int", 1))
+	assert.Equal(t, 42, GetInt(mockConfig, "app.int_from_env", 1))
+	assert.Equal(t, 1, GetInt(mockConfig, "app.bogus", 1))
+	assert.Equal(t, 1, GetInt(mockConfig, "app.missing", 1))
+	assert.Equal(t, true, GetBool(mockConfig, "app.bool", false))
+	assert.Equal(t, true, GetBool(mockConfig, "app.bool_from_env", false))
+	assert.Equal(t, false, GetBool(mockConfig, "app.missing", false))
+	assert.Equal(t, 42*time.Second, GetSeconds(mockConfig, "app.int", time.Second))
+	assert.Equal(t, time.Second, GetSeconds(mockConfig, "app.missing", time.Second))
+}
@@ -0,0 +1,413 @@
+package gpu
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	GpuResource       = "nvidia.com/gpu"
+	MigResourcePrefix = "nvidia.com/mig-"
+)
+
+// whole gpus and mig slices, e.g. nvidia.com/mig-1g.10gb
+func IsGpuResource(name string) bool {
+	return name == GpuResource || strings.HasPrefix(name, MigResourcePrefix)
+}
+
+// count per gpu resource name
+type Resources map[string]int64
+
+// the gpu resources of a node status or container resources, others are dropped
+func ResourcesOf(list v1.ResourceList) Resources {
+	resources := Resources{}
+	for name, quantity := range list {
+		if IsGpuResource(string(name)) && !quantity.IsZero() {
+			resources[string(name)] = quantity.Value()
+		}
+	}
+	return resources
+}
+
+// the effective requests the scheduler reserves, per resource the larger of the sum of the app containers
+// and the largest init container, init containers run one at a time before them
+func PodRequests(pod *v1.Pod) Resources {
+	requests := Resources{}
+	for _, container := range pod.Spec.Containers {
+		add(requests, containerRequests(&container))
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for resource, count := range containerRequests(&container) {
+			if count > requests[resource] {
+				requests[resource] = count
+			}
+		}
+	}
+	return requests
+}
+
+// extended resources are not overcommitted, requests equal limits and either may be set
+func containerRequests(container *v1.Container) Resources {
+	if resources := ResourcesOf(container.Resources.Limits); len(resources) > 0 {
+		return resources
+	}
+	return ResourcesOf(container.Resources.Requests)
+}
+
+// used counts the gpus requested by the pods on the node, free is allocatable minus used
+type NodeInventory struct {
+	Name        string    `json:"name"`
+	AgentPool   string    `json:"agentPool"`
+	Capacity    Resources `json:"capacity"`
+	Allocatable Resources `json:"allocatable"`
+	Used        Resources `json:"used"`
+	Free        Resources `json:"free"`
+	Sessions    []string  `json:"sessions"`
+}
+
+type PoolInventory struct {
+	Name        string    `json:"name"`
+	Nodes       int       `json:"nodes"`
+	Capacity    Resources `json:"capacity"`
+	Allocatable Resources `json:"allocatable"`
+	Used        Resources `json:"used"`
+	Free        Resources `json:"free"`
+}
+
+// gpu capacity of the nodes joined with the gpu requests of the pods scheduled on them.
+// the node module sets the nodes, the pod module sets the pods
+//
+//go:generate mockery --name IInventory
+type IInventory interface {
+	SetNode(ctx context.Context, name string, agentPool string, capacity Resources, allocatable Resources) error
+	RemoveNode(ctx context.Context, name string) error
+	// key is namespace/name, pods without gpu requests or not scheduled yet are removed
+	SetPod(ctx context.Context, key string, sessionId string, nodeName string, requests Resources) error
+	RemovePod(ctx context.Context, key string) error
+	Node(name string) (NodeInventory, bool)
+	Nodes() []NodeInventory
+	Pool(name string) (PoolInventory, bool)
+	Pools() []PoolInventory
+	// listed maps the observed nodes of the synced node informer cache to their agent pool,
+	// the fields of nodes and pools deleted while the monitor was down are removed
+	Prune(ctx context.Context, listed map[string]string) error
+}
+
+type node struct {
+	agentPool   string
+	capacity    Resources
+	allocatable Resources
+}
+
+type pod struct {
+	sessionId string
+	nodeName  string
+	requests  Resources
+}
+
+// every change is written to one redis hash, fields are node.<name> and pool.<name> with json values.
+// the lock is held while writing so the hash never goes back to an older view
+type inventory struct {
+	logger  *zap.Logger
+	kvRepo  repository.IKVRepository
+	hashKey string
+	mutex   sync.Mutex
+	nodes   map[string]*node
+	pods    map[string]*pod
+}
+
+var _ IInventory = (*inventory)(nil)
+
+func NewInventory(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository) IInventory {
+	return &inventory{
+		logger:  logger,
+		kvRepo:  kvRepo,
+		hashKey: config.GetString(cfg, "app.gpu_inventory_key", "SessionMonitor.GpuInventory"),
+		nodes:   map[string]*node{},
+		pods:    map[string]*pod{},
+	}
+}
+
+func (i *inventory) SetNode(ctx context.Context, name string, agentPool string, capacity Resources, allocatable Resources) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	previous, exist := i.nodes[name]
+	// every status update of the node reports it, the hash is written only when its gpus or pool changed
+	if exist && previous.agentPool == agentPool && equal(previous.capacity, capacity) && equal(previous.allocatable, allocatable) {
+		return nil
+	}
+	i.nodes[name] = &node{
+		agentPool:   agentPool,
+		capacity:    capacity,
+		allocatable: allocatable,
+	}
+	// a relabelled node leaves its previous pool
+	if exist && previous.agentPool != agentPool {
+		if err := i.writePool(ctx, previous.agentPool); err != nil {
+			return err
+		}
+	}
+	return i.writeNode(ctx, name)
+}
+
+func (i *inventory) RemoveNode(ctx context.Context, name string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	previous, exist := i.nodes[name]
+	if !exist {
+		return nil
+	}
+	delete(i.nodes, name)
+	if _, err := i.kvRepo.RemoveFromHash(ctx, i.hashKey, nodeField(name)); err != nil {
+		return err
+	}
+	return i.writePool(ctx, previous.agentPool)
+}
+
+func (i *inventory) SetPod(ctx context.Context, key string, sessionId string, nodeName string, requests Resources) error {
+	if nodeName == "" || len(requests) == 0 {
+		return i.RemovePod(ctx, key)
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	previous, exist := i.pods[key]
+	if exist && previous.nodeName == nodeName && equal(previous.requests, requests) {
+		return nil
+	}
+	i.pods[key] = &pod{
+		sessionId: sessionId,
+		nodeName:  nodeName,
+		requests:  requests,
+	}
+	if exist && previous.nodeName != nodeName {
+		if err := i.writeNode(ctx, previous.nodeName); err != nil {
+			return err
+		}
+	}
+	return i.writeNode(ctx, nodeName)
+}
+
+func (i *inventory) RemovePod(ctx context.Context, key string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	previous, exist := i.pods[key]
+	if !exist {
+		return nil
+	}
+	delete(i.pods, key)
+	return i.writeNode(ctx, previous.nodeName)
+}
+
+func (i *inventory) Node(name string) (NodeInventory, bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if _, exist := i.nodes[name]; !exist {
+		return NodeInventory{}, false
+	}
+	return i.nodeInventory(name), true
+}
+
+func (i *inventory) Nodes() []NodeInventory {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	nodes := make([]NodeInventory, 0, len(i.nodes))
+	for name := range i.nodes {
+		nodes = append(nodes, i.nodeInventory(name))
+	}
+	sort.Slice(nodes, func(a, b int) bool {
+		return nodes[a].Name < nodes[b].Name
+	})
+	return nodes
+}
+
+func (i *inventory) Pool(name string) (PoolInventory, bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	pool := i.poolInventory(name)
+	return pool, pool.Nodes > 0
+}
+
+func (i *inventory) Pools() []PoolInventory {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	names := map[string]bool{}
+	for _, n := range i.nodes {
+		names[n.agentPool] = true
+	}
+	pools := make([]PoolInventory, 0, len(names))
+	for name := range names {
+		pools = append(pools, i.poolInventory(name))
+	}
+	sort.Slice(pools, func(a, b int) bool {
+		return pools[a].Name < pools[b].Name
+	})
+	return pools
+}
+
+func (i *inventory) Prune(ctx context.Context, listed map[string]string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	keep := map[string]bool{}
+	for name, agentPool := range listed {
+		keep[nodeField(name)] = true
+		keep[poolField(agentPool)] = true
+	}
+	// the delete events of nodes gone since the list may still be queued
+	changed := map[string]bool{}
+	for name, n := range i.nodes {
+		if _, exist := listed[name]; !exist {
+			delete(i.nodes, name)
+			changed[n.agentPool] = true
+			i.logger.Sugar().Infow("Pruned gpu inventory of node", "Name", name, "AgentPool", n.agentPool)
+		}
+	}
+	values, err := i.kvRepo.GetHash(ctx, i.hashKey)
+	if err != nil {
+		i.logger.Sugar().Errorf("Read gpu inventory has error: %s", err.Error())
+		return err
+	}
+	stale := []string{}
+	for field := range values {
+		if !keep[field] {
+			stale = append(stale, field)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		if _, err := i.kvRepo.RemoveFromHash(ctx, i.hashKey, stale...); err != nil {
+			i.logger.Sugar().Errorf("Prune gpu inventory has error: %s", err.Error())
+			return err
+		}
+		i.logger.Sugar().Infow("Pruned gpu inventory", "Fields", stale)
+	}
+	// the pools still listed drop the pruned nodes
+	for name := range changed {
+		if keep[poolField(name)] {
+			if err := i.writePool(ctx, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// caller holds the lock
+func (i *inventory) nodeInventory(name string) NodeInventory {
+	n := i.nodes[name]
+	used, sessions := Resources{}, []string{}
+	for _, p := range i.pods {
+		if p.nodeName != name {
+			continue
+		}
+		add(used, p.requests)
+		if p.sessionId != "" {
+			sessions = append(sessions, p.sessionId)
+		}
+	}
+	sort.Strings(sessions)
+	free := Resources{}
+	for resource, allocatable := range n.allocatable {
+		free[resource] = max(allocatable-used[resource], 0)
+	}
+	return NodeInventory{
+		Name:        name,
+		AgentPool:   n.agentPool,
+		Capacity:    n.capacity,
+		Allocatable: n.allocatable,
+		Used:        used,
+		Free:        free,
+		Sessions:    sessions,
+	}
+}
+
+// caller holds the lock
+func (i *inventory) poolInventory(name string) PoolInventory {
+	pool := PoolInventory{
+		Name:        name,
+		Capacity:    Resources{},
+		Allocatable: Resources{},
+		Used:        Resources{},
+		Free:        Resources{},
+	}
+	for nodeName, n := range i.nodes {
+		if n.agentPool != name {
+			continue
+		}
+		view := i.nodeInventory(nodeName)
+		pool.Nodes++
+		add(pool.Capacity, view.Capacity)
+		add(pool.Allocatable, view.Allocatable)
+		add(pool.Used, view.Used)
+		add(pool.Free, view.Free)
+	}
+	return pool
+}
+
+// pods scheduled before their node is known are written with the node
+func (i *inventory) writeNode(ctx context.Context, name string) error {
+	n, exist := i.nodes[name]
+	if !exist {
+		return nil
+	}
+	view, _ := json.Marshal(i.nodeInventory(name))
+	if _, err := i.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     i.hashKey,
+		Payload: map[string]interface{}{nodeField(name): string(view)},
+	}); err != nil {
+		i.logger.Sugar().Errorf("Write gpu inventory of node %s has error: %s", name, err.Error())
+		return err
+	}
+	return i.writePool(ctx, n.agentPool)
+}
+
+// the pool field is removed with the last node of the pool
+func (i *inventory) writePool(ctx context.Context, name string) error {
+	pool := i.poolInventory(name)
+	if pool.Nodes == 0 {
+		_, err := i.kvRepo.RemoveFromHash(ctx, i.hashKey, poolField(name))
+		return err
+	}
+	view, _ := json.Marshal(pool)
+	if _, err := i.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     i.hashKey,
+		Payload: map[string]interface{}{poolField(name): string(view)},
+	}); err != nil {
+		i.logger.Sugar().Errorf("Write gpu inventory of agent pool %s has error: %s", name, err.Error())
+		return err
+	}
+	return nil
+}
+
+func nodeField(name string) string {
+	return "node." + name
+}
+
+func poolField(name string) string {
+	return "pool." + name
+}
+
+func add(sum Resources, resources Resources) {
+	for resource, count := range resources {
+		sum[resource] += count
+	}
+}
+
+func equal(a Resources, b Resources) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for resource, count := range a {
+		if b[resource] != count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package gpu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func hashField(field string) interface{} {
+	return mock.MatchedBy(func(object *repository.Object) bool {
+		_, exist := object.Payload.(map[string]interface{})[field]
+		return object.Key == "SessionMonitor.GpuInventory" && exist
+	})
+}
+
+func TestResourcesOf(t *testing.T) {
+	resources := ResourcesOf(v1.ResourceList{
+		"nvidia.com/gpu":         resource.MustParse("4"),
+		"nvidia.com/mig-1g.10gb": resource.MustParse("7"),
+		"nvidia.com/mig-2g.20gb": resource.MustParse("0"),
+		v1.ResourceCPU:           resource.MustParse("16"),
+	})
+	assert.Equal(t, Resources{"nvidia.com/gpu": 4, "nvidia.com/mig-1g.10gb": 7}, resources)
+}
+
+func TestPodRequests(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{
+					Limits:   v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+					Requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+				}},
+				{Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+				}},
+				{Resources: v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+				}},
+			},
+		},
+	}
+	assert.Equal(t, Resources{"nvidia.com/gpu": 2}, PodRequests(pod))
+
+	// an init container asking for more than the app containers raises the effective request
+	pod.Spec.InitContainers = []v1.Container{
+		{Resources: v1.ResourceRequirements{
+			Limits: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("3")},
+		}},
+		{Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{"nvidia.com/mig-1g.10gb": resource.MustParse("1")},
+		}},
+		{Resources: v1.ResourceRequirements{
+			Limits: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")},
+		}},
+	}
+	assert.Equal(t, Resources{"nvidia.com/gpu": 3, "nvidia.com/mig-1g.10gb": 1}, PodRequests(pod))
+
+	// below the app containers, the sum is kept
+	pod.Spec.InitContainers = pod.Spec.InitContainers[2:]
+	assert.Equal(t, Resources{"nvidia.com/gpu": 2}, PodRequests(pod))
+}
+
+func TestInventory(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, hashField("node.node-1")).Return(int64(1), nil)
+	mockKVRepository.On("SetHash", ctx, hashField("node.node-2")).Return(int64(1), nil)
+	mockKVRepository.On("SetHash", ctx, hashField("pool.viz")).Return(int64(1), nil)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.gpu_inventory_key").Return(nil)
+	inventory := NewInventory(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	// scheduled before its node is known
+	assert.Nil(t, inventory.SetPod(ctx, "default/pod-1", "session-1", "node-1", Resources{GpuResource: 1}))
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 2}))
+	assert.Nil(t, inventory.SetNode(ctx, "node-2", "viz", Resources{GpuResource: 4, "nvidia.com/mig-1g.10gb": 7},
+		Resources{GpuResource: 4, "nvidia.com/mig-1g.10gb": 7}))
+	assert.Nil(t, inventory.SetPod(ctx, "default/pod-2", "session-2", "node-1", Resources{GpuResource: 2}))
+	assert.Nil(t, inventory.SetPod(ctx, "default/pod-3", "", "node-2", Resources{"nvidia.com/mig-1g.10gb": 3}))
+	// not scheduled yet, nothing to write
+	assert.Nil(t, inventory.SetPod(ctx, "default/pod-4", "session-4", "", Resources{GpuResource: 1}))
+
+	node, exist := inventory.Node("node-1")
+	assert.True(t, exist)
+	assert.Equal(t, Resources{GpuResource: 3}, node.Used)
+	assert.Equal(t, Resources{GpuResource: 0}, node.Free, "overcommitted node has nothing free")
+	assert.Equal(t, []string{"session-1", "session-2"}, node.Sessions)
+
+	pool, exist := inventory.Pool("viz")
+	assert.True(t, exist)
+	assert.Equal(t, 2, pool.Nodes)
+	assert.Equal(t, Resources{GpuResource: 6, "nvidia.com/mig-1g.10gb": 7}, pool.Allocatable)
+	assert.Equal(t, Resources{GpuResource: 3, "nvidia.com/mig-1g.10gb": 3}, pool.Used)
+	assert.Equal(t, Resources{GpuResource: 4, "nvidia.com/mig-1g.10gb": 4}, pool.Free)
+
+	assert.Nil(t, inventory.RemovePod(ctx, "default/pod-2"))
+	node, _ = inventory.Node("node-1")
+	assert.Equal(t, Resources{GpuResource: 1}, node.Free)
+	assert.Len(t, inventory.Nodes(), 2)
+	assert.Equal(t, "viz", inventory.Pools()[0].Name)
+
+	_, exist = inventory.Node("node-3")
+	assert.False(t, exist)
+	_, exist = inventory.Pool("cpu")
+	assert.False(t, exist)
+}
+
+func TestInventory_RemoveNode(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.Anything).Return(int64(1), nil)
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.GpuInventory", "node.node-1").Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.GpuInventory", "pool.viz").Return(int64(1), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.gpu_inventory_key").Return(nil)
+	inventory := NewInventory(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 2}))
+	assert.Nil(t, inventory.RemoveNode(ctx, "node-1"))
+	// unknown node
+	assert.Nil(t, inventory.RemoveNode(ctx, "node-1"))
+	assert.Len(t, inventory.Pools(), 0)
+}
+
+func TestInventory_SetNodeUnchanged(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.Anything).Return(int64(1), nil)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.gpu_inventory_key").Return(nil)
+	inventory := NewInventory(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 2}))
+	mockKVRepository.AssertNumberOfCalls(t, "SetHash", 2)
+	// same node reported again
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 2}))
+	mockKVRepository.AssertNumberOfCalls(t, "SetHash", 2)
+	// a gpu became unhealthy
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 1}))
+	mockKVRepository.AssertNumberOfCalls(t, "SetHash", 4)
+	node, _ := inventory.Node("node-1")
+	assert.Equal(t, Resources{GpuResource: 1}, node.Free)
+}
+
+func TestInventory_Prune(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.Anything).Return(int64(1), nil)
+	mockKVRepository.On("GetHash", ctx, "SessionMonitor.GpuInventory").Return(map[string]string{
+		"node.node-1": "{}",
+		"node.node-2": "{}",
+		"pool.viz":    "{}",
+		// deleted before the restart
+		"node.node-3": "{}",
+		"pool.cpu":    "{}",
+	}, nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.GpuInventory",
+		"node.node-2", "node.node-3", "pool.cpu").Return(int64(3), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.gpu_inventory_key").Return(nil)
+	inventory := NewInventory(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	assert.Nil(t, inventory.SetNode(ctx, "node-1", "viz", Resources{GpuResource: 2}, Resources{GpuResource: 2}))
+	// deleted since the informer listed it, the delete event is still queued
+	assert.Nil(t, inventory.SetNode(ctx, "node-2", "viz", Resources{GpuResource: 4}, Resources{GpuResource: 4}))
+
+	assert.Nil(t, inventory.Prune(ctx, map[string]string{"node-1": "viz"}))
+	_, exist := inventory.Node("node-2")
+	assert.False(t, exist)
+	pool, exist := inventory.Pool("viz")
+	assert.True(t, exist)
+	assert.Equal(t, 1, pool.Nodes)
+	mockKVRepository.AssertCalled(t, "SetHash", ctx, hashField("pool.viz"))
+}
@@ -3,4 +3,7 @@ package k8s
 //go:generate mockery --name IK8sInformer
 type IK8sInformer interface {
 	Run()
+	// objects in the informer cache, complete only once synced
+	List() []interface{}
+	HasSynced() bool
 }
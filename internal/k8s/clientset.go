@@ -0,0 +1,15 @@
+package k8s
+
+import (
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"k8s.io/client-go/kubernetes"
+)
+
+// typed client for writes, informers stay on the dynamic client
+func NewK8sClientset(config config.IConfig) (kubernetes.Interface, error) {
+	clusterConfig, err := newClusterConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(clusterConfig)
+}
@@ -6,6 +6,7 @@ import (
 	"go.uber.org/dig"
 	"go.uber.org/zap"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
@@ -32,10 +33,23 @@ func (informer *k8sDynamicInformer) Run() {
 	informer.informer.Run(informer.ctx.Done())
 }
 
+func (informer *k8sDynamicInformer) List() []interface{} {
+	return informer.informer.GetStore().List()
+}
+
+func (informer *k8sDynamicInformer) HasSynced() bool {
+	return informer.informer.HasSynced()
+}
+
+// the core group is watched unless the module provides another group and version,
+// e.g. apps/v1. the label selector is optional too
 type K8sInformerFilter struct {
 	dig.In
-	Resource  string `name:"k8s_resource"`
-	Namespace string `name:"k8s_resource_namespace"`
+	Resource      string `name:"k8s_resource"`
+	Namespace     string `name:"k8s_resource_namespace"`
+	Group         string `name:"k8s_resource_group" optional:"true"`
+	Version       string `name:"k8s_resource_version" optional:"true"`
+	LabelSelector string `name:"k8s_resource_label_selector" optional:"true"`
 }
 
 func NewK8sDynamicInformer(
@@ -45,7 +59,7 @@ func NewK8sDynamicInformer(
 	handler IK8sEventHandler,
 	filter K8sInformerFilter,
 ) (IK8sInformer, error) {
-	informer, err := newDynamicInformer(ctx, config, filter.Resource, filter.Namespace)
+	informer, err := newDynamicInformer(ctx, config, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -59,19 +73,8 @@ func NewK8sDynamicInformer(
 	}, nil
 }
 
-func newDynamicInformer(ctx context.Context, config config.IConfig, resource string, namespace string) (cache.SharedIndexInformer, error) {
-	var kubeConfig = ""
-	t := config.Get("app.kube_config")
-	if t != nil {
-		kubeConfig = t.(string)
-	}
-	var clusterConfig *rest.Config
-	var err error
-	if kubeConfig != "" {
-		clusterConfig, err = clientcmd.BuildConfigFromFlags("", kubeConfig)
-	} else {
-		clusterConfig, err = rest.InClusterConfig()
-	}
+func newDynamicInformer(ctx context.Context, config config.IConfig, filter K8sInformerFilter) (cache.SharedIndexInformer, error) {
+	clusterConfig, err := newClusterConfig(config)
 	if err != nil {
 		return nil, err
 	}
@@ -80,9 +83,32 @@ func newDynamicInformer(ctx context.Context, config config.IConfig, resource str
 		return nil, err
 	}
 
-	podResources := schema.GroupVersionResource{Group: "", Version: "v1", Resource: resource}
+	version := filter.Version
+	if version == "" {
+		version = "v1"
+	}
+	podResources := schema.GroupVersionResource{Group: filter.Group, Version: version, Resource: filter.Resource}
+	var tweakListOptions dynamicinformer.TweakListOptionsFunc
+	if filter.LabelSelector != "" {
+		tweakListOptions = func(options *metav1.ListOptions) {
+			options.LabelSelector = filter.LabelSelector
+		}
+	}
 	// node resource has empty namespace
-	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0, namespace, nil)
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0, filter.Namespace, tweakListOptions)
 	informer := factory.ForResource(podResources).Informer()
 	return informer, nil
 }
+
+// app.kube_config outside of the cluster, the service account otherwise
+func newClusterConfig(config config.IConfig) (*rest.Config, error) {
+	var kubeConfig = ""
+	t := config.Get("app.kube_config")
+	if t != nil {
+		kubeConfig = t.(string)
+	}
+	if kubeConfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeConfig)
+	}
+	return rest.InClusterConfig()
+}
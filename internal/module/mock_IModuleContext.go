@@ -7,10 +7,16 @@ import (
 	config "github.com/xcheng85/session-monitor-k8s/internal/config"
 	ddd "github.com/xcheng85/session-monitor-k8s/internal/ddd"
 
+	gpu "github.com/xcheng85/session-monitor-k8s/internal/gpu"
+
 	mock "github.com/stretchr/testify/mock"
 
 	repository "github.com/xcheng85/session-monitor-k8s/internal/repository"
 
+	scaleup "github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+
+	session "github.com/xcheng85/session-monitor-k8s/internal/session"
+
 	zap "go.uber.org/zap"
 )
 
@@ -51,6 +57,22 @@ func (_m *MockIModuleContext) EventDispatcher() ddd.IEventDispatcher[ddd.IEvent]
 	return r0
 }
 
+// GpuInventory provides a mock function with given fields:
+func (_m *MockIModuleContext) GpuInventory() gpu.IInventory {
+	ret := _m.Called()
+
+	var r0 gpu.IInventory
+	if rf, ok := ret.Get(0).(func() gpu.IInventory); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(gpu.IInventory)
+		}
+	}
+
+	return r0
+}
+
 // KvRepository provides a mock function with given fields:
 func (_m *MockIModuleContext) KvRepository() repository.IKVRepository {
 	ret := _m.Called()
@@ -99,6 +121,54 @@ func (_m *MockIModuleContext) Mux() *chi.Mux {
 	return r0
 }
 
+// ScaleUpTracker provides a mock function with given fields:
+func (_m *MockIModuleContext) ScaleUpTracker() scaleup.ITracker {
+	ret := _m.Called()
+
+	var r0 scaleup.ITracker
+	if rf, ok := ret.Get(0).(func() scaleup.ITracker); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(scaleup.ITracker)
+		}
+	}
+
+	return r0
+}
+
+// SessionMetrics provides a mock function with given fields:
+func (_m *MockIModuleContext) SessionMetrics() session.ISessionMetrics {
+	ret := _m.Called()
+
+	var r0 session.ISessionMetrics
+	if rf, ok := ret.Get(0).(func() session.ISessionMetrics); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(session.ISessionMetrics)
+		}
+	}
+
+	return r0
+}
+
+// SessionService provides a mock function with given fields:
+func (_m *MockIModuleContext) SessionService() session.ISessionService {
+	ret := _m.Called()
+
+	var r0 session.ISessionService
+	if rf, ok := ret.Get(0).(func() session.ISessionService); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(session.ISessionService)
+		}
+	}
+
+	return r0
+}
+
 // NewMockIModuleContext creates a new instance of MockIModuleContext. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockIModuleContext(t interface {
@@ -6,7 +6,10 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
 )
@@ -21,6 +24,10 @@ type IModuleContext interface {
 	Config() config.IConfig
 	KvRepository() repository.IKVRepository
 	EventDispatcher() ddd.IEventDispatcher[ddd.IEvent] // translate k8s event into domain event
+	SessionService() session.ISessionService           // session state shared by pod and node modules
+	SessionMetrics() session.ISessionMetrics           // prometheus collectors served on /metrics
+	GpuInventory() gpu.IInventory                      // gpu capacity of the nodes joined with the pods on them
+	ScaleUpTracker() scaleup.ITracker                  // pending pods joined with the nodes added for them
 }
 
 type Module interface {
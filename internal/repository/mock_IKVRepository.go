@@ -13,6 +13,37 @@ type MockIKVRepository struct {
 	mock.Mock
 }
 
+// AddMembersToUnsortedSet provides a mock function with given fields: ctx, UnsortedSetKey, members
+func (_m *MockIKVRepository) AddMembersToUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error) {
+	_va := make([]interface{}, len(members))
+	for _i := range members {
+		_va[_i] = members[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, UnsortedSetKey)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) (int64, error)); ok {
+		return rf(ctx, UnsortedSetKey, members...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) int64); ok {
+		r0 = rf(ctx, UnsortedSetKey, members...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...string) error); ok {
+		r1 = rf(ctx, UnsortedSetKey, members...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // AddStreamEvent provides a mock function with given fields: ctx, streamKey, streamId, payload
 func (_m *MockIKVRepository) AddStreamEvent(ctx context.Context, streamKey string, streamId string, payload interface{}) (string, error) {
 	ret := _m.Called(ctx, streamKey, streamId, payload)
@@ -68,6 +99,87 @@ func (_m *MockIKVRepository) AddToUnsortedSet(ctx context.Context, UnsortedSetKe
 	return r0, r1
 }
 
+// DeleteKeys provides a mock function with given fields: ctx, keys
+func (_m *MockIKVRepository) DeleteKeys(ctx context.Context, keys ...string) (int64, error) {
+	_va := make([]interface{}, len(keys))
+	for _i := range keys {
+		_va[_i] = keys[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) (int64, error)); ok {
+		return rf(ctx, keys...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) int64); ok {
+		r0 = rf(ctx, keys...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ...string) error); ok {
+		r1 = rf(ctx, keys...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *MockIKVRepository) Get(ctx context.Context, key string) (string, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHash provides a mock function with given fields: ctx, key
+func (_m *MockIKVRepository) GetHash(ctx context.Context, key string) (map[string]string, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 map[string]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (map[string]string, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) map[string]string); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetServerTimestamp provides a mock function with given fields: ctx
 func (_m *MockIKVRepository) GetServerTimestamp(ctx context.Context) (int64, error) {
 	ret := _m.Called(ctx)
@@ -92,6 +204,56 @@ func (_m *MockIKVRepository) GetServerTimestamp(ctx context.Context) (int64, err
 	return r0, r1
 }
 
+// GetUnsortedSetMembers provides a mock function with given fields: ctx, UnsortedSetKey
+func (_m *MockIKVRepository) GetUnsortedSetMembers(ctx context.Context, UnsortedSetKey string) ([]string, error) {
+	ret := _m.Called(ctx, UnsortedSetKey)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, UnsortedSetKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, UnsortedSetKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, UnsortedSetKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUnsortedSetSize provides a mock function with given fields: ctx, UnsortedSetKey
+func (_m *MockIKVRepository) GetUnsortedSetSize(ctx context.Context, UnsortedSetKey string) (int64, error) {
+	ret := _m.Called(ctx, UnsortedSetKey)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, UnsortedSetKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, UnsortedSetKey)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, UnsortedSetKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *MockIKVRepository) Ping(ctx context.Context) (string, error) {
 	ret := _m.Called(ctx)
@@ -116,6 +278,166 @@ func (_m *MockIKVRepository) Ping(ctx context.Context) (string, error) {
 	return r0, r1
 }
 
+// RemoveFromHash provides a mock function with given fields: ctx, key, fields
+func (_m *MockIKVRepository) RemoveFromHash(ctx context.Context, key string, fields ...string) (int64, error) {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) (int64, error)); ok {
+		return rf(ctx, key, fields...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) int64); ok {
+		r0 = rf(ctx, key, fields...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...string) error); ok {
+		r1 = rf(ctx, key, fields...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveFromUnsortedSet provides a mock function with given fields: ctx, UnsortedSetKey, members
+func (_m *MockIKVRepository) RemoveFromUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error) {
+	_va := make([]interface{}, len(members))
+	for _i := range members {
+		_va[_i] = members[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, UnsortedSetKey)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) (int64, error)); ok {
+		return rf(ctx, UnsortedSetKey, members...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) int64); ok {
+		r0 = rf(ctx, UnsortedSetKey, members...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...string) error); ok {
+		r1 = rf(ctx, UnsortedSetKey, members...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScanKeys provides a mock function with given fields: ctx, pattern
+func (_m *MockIKVRepository) ScanKeys(ctx context.Context, pattern string) ([]string, error) {
+	ret := _m.Called(ctx, pattern)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, pattern)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, pattern)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, pattern)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Set provides a mock function with given fields: ctx, object
+func (_m *MockIKVRepository) Set(ctx context.Context, object *Object) (string, error) {
+	ret := _m.Called(ctx, object)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) (string, error)); ok {
+		return rf(ctx, object)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) string); ok {
+		r0 = rf(ctx, object)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *Object) error); ok {
+		r1 = rf(ctx, object)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetHash provides a mock function with given fields: ctx, object
+func (_m *MockIKVRepository) SetHash(ctx context.Context, object *Object) (int64, error) {
+	ret := _m.Called(ctx, object)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) (int64, error)); ok {
+		return rf(ctx, object)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) int64); ok {
+		r0 = rf(ctx, object)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *Object) error); ok {
+		r1 = rf(ctx, object)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetIfNotExists provides a mock function with given fields: ctx, object
+func (_m *MockIKVRepository) SetIfNotExists(ctx context.Context, object *Object) (bool, error) {
+	ret := _m.Called(ctx, object)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) (bool, error)); ok {
+		return rf(ctx, object)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *Object) bool); ok {
+		r0 = rf(ctx, object)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *Object) error); ok {
+		r1 = rf(ctx, object)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewMockIKVRepository creates a new instance of MockIKVRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockIKVRepository(t interface {
@@ -80,3 +80,75 @@ func (s *redisClientV8) AddToUnsortedSet(ctx context.Context, UnsortedSetKey str
 	}
 	return 0, nil
 }
+
+// SET with expiration, overwrites the key if it exists
+func (s *redisClientV8) Set(ctx context.Context, object *Object) (string, error) {
+	return s.client.Set(ctx, object.Key, object.Payload, object.Expiration).Result()
+}
+
+// SETNX with expiration, false if the key already exists
+func (s *redisClientV8) SetIfNotExists(ctx context.Context, object *Object) (bool, error) {
+	return s.client.SetNX(ctx, object.Key, object.Payload, object.Expiration).Result()
+}
+
+func (s *redisClientV8) DeleteKeys(ctx context.Context, keys ...string) (int64, error) {
+	return s.client.Del(ctx, keys...).Result()
+}
+
+// SCAN instead of KEYS, redis keeps serving other clients while the keyspace is walked
+func (s *redisClientV8) ScanKeys(ctx context.Context, pattern string) ([]string, error) {
+	keys := []string{}
+	iter := s.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// HSET of the payload fields, the expiration applies to the whole hash
+func (s *redisClientV8) SetHash(ctx context.Context, object *Object) (int64, error) {
+	cmds, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, object.Key, object.Payload)
+		if object.Expiration > 0 {
+			pipe.Expire(ctx, object.Key, object.Expiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cmds[0].(*redis.IntCmd).Val(), nil
+}
+
+func (s *redisClientV8) GetHash(ctx context.Context, key string) (map[string]string, error) {
+	return s.client.HGetAll(ctx, key).Result()
+}
+
+func (s *redisClientV8) RemoveFromHash(ctx context.Context, key string, fields ...string) (int64, error) {
+	return s.client.HDel(ctx, key, fields...).Result()
+}
+
+// empty if the key does not exist
+func (s *redisClientV8) Get(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *redisClientV8) AddMembersToUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error) {
+	return s.client.SAdd(ctx, UnsortedSetKey, members).Result()
+}
+
+func (s *redisClientV8) RemoveFromUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error) {
+	return s.client.SRem(ctx, UnsortedSetKey, members).Result()
+}
+
+func (s *redisClientV8) GetUnsortedSetSize(ctx context.Context, UnsortedSetKey string) (int64, error) {
+	return s.client.SCard(ctx, UnsortedSetKey).Result()
+}
+
+func (s *redisClientV8) GetUnsortedSetMembers(ctx context.Context, UnsortedSetKey string) ([]string, error) {
+	return s.client.SMembers(ctx, UnsortedSetKey).Result()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/go-redis/redismock/v8"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestRedisClientV8_AddStreamEvent(t *testing.T) {
@@ -76,3 +77,206 @@ func TestRedisClientV8_AddToUnsortedSet(t *testing.T) {
 	assert.Equal(t, int64(1), res)
 	assert.Nil(t, err)
 }
+
+func TestRedisClientV8_Set(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSet("idempotency.sessionId", "1", 24*time.Hour).SetVal("OK")
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.Set(ctx, &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: 24 * time.Hour,
+	})
+	assert.Equal(t, "OK", res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_ScanKeys(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectScan(0, "Timestamps.Node.*", 0).SetVal([]string{"Timestamps.Node.node-1", "Timestamps.Node.node-2"}, 0)
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	keys, err := v8.ScanKeys(ctx, "Timestamps.Node.*")
+	assert.Equal(t, []string{"Timestamps.Node.node-1", "Timestamps.Node.node-2"}, keys)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_SetIfNotExists(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSetNX("idempotency.sessionId", "1", 24*time.Hour).SetVal(true)
+	mock.ExpectSetNX("idempotency.sessionId", "1", 24*time.Hour).SetVal(false)
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	object := &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: 24 * time.Hour,
+	}
+	created, err := v8.SetIfNotExists(ctx, object)
+	assert.True(t, created)
+	assert.Nil(t, err)
+	created, err = v8.SetIfNotExists(ctx, object)
+	assert.False(t, created)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_DeleteKeys(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectDel("key1", "key2").SetVal(2)
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.DeleteKeys(ctx, "key1", "key2")
+	assert.Equal(t, int64(2), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_SetHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	values := map[string]interface{}{
+		"PodScheduleTimeStamp": int64(88888888888),
+	}
+	mock.ExpectTxPipeline()
+	mock.ExpectHSet("timestamps.sessionId", values).SetVal(1)
+	mock.ExpectExpire("timestamps.sessionId", time.Hour).SetVal(true)
+	mock.ExpectTxPipelineExec()
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.SetHash(ctx, &Object{
+		Key:        "timestamps.sessionId",
+		Payload:    values,
+		Expiration: time.Hour,
+	})
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_GetHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectHGetAll("timestamps.sessionId").SetVal(map[string]string{
+		"PodScheduleTimeStamp": "88888888888",
+	})
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.GetHash(ctx, "timestamps.sessionId")
+	assert.Equal(t, map[string]string{"PodScheduleTimeStamp": "88888888888"}, res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_RemoveFromHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectHDel("SessionMonitor.GpuInventory", "node.node-1").SetVal(1)
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.RemoveFromHash(ctx, "SessionMonitor.GpuInventory", "node.node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_Get(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectGet("heartbeat.sessionId").SetVal("88888888888")
+	mock.ExpectGet("heartbeat.missing").RedisNil()
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.Get(ctx, "heartbeat.sessionId")
+	assert.Equal(t, "88888888888", res)
+	assert.Nil(t, err)
+	res, err = v8.Get(ctx, "heartbeat.missing")
+	assert.Equal(t, "", res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV8_UnsortedSetMembers(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSAdd("GpuNodePools.viz1.Nodes", "node-1", "node-2").SetVal(2)
+	mock.ExpectSRem("GpuNodePools.viz1.Nodes", "node-1").SetVal(1)
+	mock.ExpectSCard("GpuNodePools.viz1.Nodes").SetVal(1)
+	mock.ExpectSMembers("GpuNodePools.viz1.Nodes").SetVal([]string{"node-2"})
+
+	v8 := &redisClientV8{
+		db,
+		logger,
+	}
+	res, err := v8.AddMembersToUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1", "node-2")
+	assert.Equal(t, int64(2), res)
+	assert.Nil(t, err)
+	res, err = v8.RemoveFromUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	res, err = v8.GetUnsortedSetSize(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	members, err := v8.GetUnsortedSetMembers(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, []string{"node-2"}, members)
+	assert.Nil(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestRedisClientV9_AddStreamEvent(t *testing.T) {
@@ -77,3 +78,206 @@ func TestRedisClientV9_AddToUnsortedSet(t *testing.T) {
 	assert.Equal(t, int64(1), res)
 	assert.Nil(t, err)
 }
+
+func TestRedisClientV9_Set(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSet("idempotency.sessionId", "1", 24*time.Hour).SetVal("OK")
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.Set(ctx, &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: 24 * time.Hour,
+	})
+	assert.Equal(t, "OK", res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_ScanKeys(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectScan(0, "Timestamps.Node.*", 0).SetVal([]string{"Timestamps.Node.node-1", "Timestamps.Node.node-2"}, 0)
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	keys, err := v9.ScanKeys(ctx, "Timestamps.Node.*")
+	assert.Equal(t, []string{"Timestamps.Node.node-1", "Timestamps.Node.node-2"}, keys)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_SetIfNotExists(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSetNX("idempotency.sessionId", "1", 24*time.Hour).SetVal(true)
+	mock.ExpectSetNX("idempotency.sessionId", "1", 24*time.Hour).SetVal(false)
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	object := &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: 24 * time.Hour,
+	}
+	created, err := v9.SetIfNotExists(ctx, object)
+	assert.True(t, created)
+	assert.Nil(t, err)
+	created, err = v9.SetIfNotExists(ctx, object)
+	assert.False(t, created)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_DeleteKeys(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectDel("key1", "key2").SetVal(2)
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.DeleteKeys(ctx, "key1", "key2")
+	assert.Equal(t, int64(2), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_SetHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	values := map[string]interface{}{
+		"PodScheduleTimeStamp": int64(88888888888),
+	}
+	mock.ExpectTxPipeline()
+	mock.ExpectHSet("timestamps.sessionId", values).SetVal(1)
+	mock.ExpectExpire("timestamps.sessionId", time.Hour).SetVal(true)
+	mock.ExpectTxPipelineExec()
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.SetHash(ctx, &Object{
+		Key:        "timestamps.sessionId",
+		Payload:    values,
+		Expiration: time.Hour,
+	})
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_GetHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectHGetAll("timestamps.sessionId").SetVal(map[string]string{
+		"PodScheduleTimeStamp": "88888888888",
+	})
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.GetHash(ctx, "timestamps.sessionId")
+	assert.Equal(t, map[string]string{"PodScheduleTimeStamp": "88888888888"}, res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_RemoveFromHash(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectHDel("SessionMonitor.GpuInventory", "node.node-1").SetVal(1)
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.RemoveFromHash(ctx, "SessionMonitor.GpuInventory", "node.node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_Get(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectGet("heartbeat.sessionId").SetVal("88888888888")
+	mock.ExpectGet("heartbeat.missing").RedisNil()
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.Get(ctx, "heartbeat.sessionId")
+	assert.Equal(t, "88888888888", res)
+	assert.Nil(t, err)
+	res, err = v9.Get(ctx, "heartbeat.missing")
+	assert.Equal(t, "", res)
+	assert.Nil(t, err)
+}
+
+func TestRedisClientV9_UnsortedSetMembers(t *testing.T) {
+	ctx := context.TODO()
+	db, mock := redismock.NewClientMock()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	mock.ExpectSAdd("GpuNodePools.viz1.Nodes", "node-1", "node-2").SetVal(2)
+	mock.ExpectSRem("GpuNodePools.viz1.Nodes", "node-1").SetVal(1)
+	mock.ExpectSCard("GpuNodePools.viz1.Nodes").SetVal(1)
+	mock.ExpectSMembers("GpuNodePools.viz1.Nodes").SetVal([]string{"node-2"})
+
+	v9 := &redisClientV9{
+		db,
+		logger,
+	}
+	res, err := v9.AddMembersToUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1", "node-2")
+	assert.Equal(t, int64(2), res)
+	assert.Nil(t, err)
+	res, err = v9.RemoveFromUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	res, err = v9.GetUnsortedSetSize(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	members, err := v9.GetUnsortedSetMembers(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, []string{"node-2"}, members)
+	assert.Nil(t, err)
+}
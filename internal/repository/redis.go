@@ -80,3 +80,87 @@ func (s *redisRepository) AddToUnsortedSet(ctx context.Context, UnsortedSetKey s
 	}
 	return keyUpdated, err
 }
+
+func (s *redisRepository) Set(ctx context.Context, object *Object) (status string, err error) {
+	for _, client := range s.clients {
+		status, err = client.Set(ctx, object)
+	}
+	return status, err
+}
+
+func (s *redisRepository) SetIfNotExists(ctx context.Context, object *Object) (created bool, err error) {
+	for _, client := range s.clients {
+		created, err = client.SetIfNotExists(ctx, object)
+	}
+	return created, err
+}
+
+func (s *redisRepository) DeleteKeys(ctx context.Context, keys ...string) (numKeysDeleted int64, err error) {
+	for _, client := range s.clients {
+		numKeysDeleted, err = client.DeleteKeys(ctx, keys...)
+	}
+	return numKeysDeleted, err
+}
+
+func (s *redisRepository) ScanKeys(ctx context.Context, pattern string) (keys []string, err error) {
+	for _, client := range s.clients {
+		keys, err = client.ScanKeys(ctx, pattern)
+	}
+	return keys, err
+}
+
+func (s *redisRepository) SetHash(ctx context.Context, object *Object) (numFieldsAdded int64, err error) {
+	for _, client := range s.clients {
+		numFieldsAdded, err = client.SetHash(ctx, object)
+	}
+	return numFieldsAdded, err
+}
+
+func (s *redisRepository) GetHash(ctx context.Context, key string) (values map[string]string, err error) {
+	for _, client := range s.clients {
+		values, err = client.GetHash(ctx, key)
+	}
+	return values, err
+}
+
+func (s *redisRepository) RemoveFromHash(ctx context.Context, key string, fields ...string) (numFieldsRemoved int64, err error) {
+	for _, client := range s.clients {
+		numFieldsRemoved, err = client.RemoveFromHash(ctx, key, fields...)
+	}
+	return numFieldsRemoved, err
+}
+
+func (s *redisRepository) Get(ctx context.Context, key string) (value string, err error) {
+	for _, client := range s.clients {
+		value, err = client.Get(ctx, key)
+	}
+	return value, err
+}
+
+func (s *redisRepository) AddMembersToUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (numMembersAdded int64, err error) {
+	for _, client := range s.clients {
+		numMembersAdded, err = client.AddMembersToUnsortedSet(ctx, UnsortedSetKey, members...)
+	}
+	return numMembersAdded, err
+}
+
+func (s *redisRepository) RemoveFromUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (numMembersRemoved int64, err error) {
+	for _, client := range s.clients {
+		numMembersRemoved, err = client.RemoveFromUnsortedSet(ctx, UnsortedSetKey, members...)
+	}
+	return numMembersRemoved, err
+}
+
+func (s *redisRepository) GetUnsortedSetSize(ctx context.Context, UnsortedSetKey string) (size int64, err error) {
+	for _, client := range s.clients {
+		size, err = client.GetUnsortedSetSize(ctx, UnsortedSetKey)
+	}
+	return size, err
+}
+
+func (s *redisRepository) GetUnsortedSetMembers(ctx context.Context, UnsortedSetKey string) (members []string, err error) {
+	for _, client := range s.clients {
+		members, err = client.GetUnsortedSetMembers(ctx, UnsortedSetKey)
+	}
+	return members, err
+}
@@ -4,8 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
-	"github.com/go-redis/redis/v8"
-	"github.com/go-redis/redismock/v8"
+	"time"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
@@ -124,3 +123,198 @@ func TestAddToUnsortedSet(t *testing.T) {
 	assert.Equal(t, int64(1), res)
 	assert.Nil(t, err)
 }
+
+func TestSet(t *testing.T) {
+	ctx := context.TODO()
+	object := &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: time.Hour,
+	}
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("Set", ctx, object).Return("OK", nil).Once()
+	mockKVRepository2 := &MockIKVRepository{}
+	mockKVRepository2.On("Set", ctx, object).Return("OK", nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository, mockKVRepository2},
+		logger:  logger,
+	}
+	res, err := redisRepo.Set(ctx, object)
+	assert.Equal(t, "OK", res)
+	assert.Nil(t, err)
+	mockKVRepository.AssertNumberOfCalls(t, "Set", 1)
+	mockKVRepository2.AssertNumberOfCalls(t, "Set", 1)
+}
+
+func TestScanKeys(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("ScanKeys", ctx, "Timestamps.Node.*").Return([]string{"Timestamps.Node.node-1"}, nil).Once()
+	mockKVRepository2 := &MockIKVRepository{}
+	mockKVRepository2.On("ScanKeys", ctx, "Timestamps.Node.*").Return([]string{"Timestamps.Node.node-1"}, nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository, mockKVRepository2},
+		logger:  logger,
+	}
+	keys, err := redisRepo.ScanKeys(ctx, "Timestamps.Node.*")
+	assert.Equal(t, []string{"Timestamps.Node.node-1"}, keys)
+	assert.Nil(t, err)
+	mockKVRepository.AssertNumberOfCalls(t, "ScanKeys", 1)
+	mockKVRepository2.AssertNumberOfCalls(t, "ScanKeys", 1)
+}
+
+func TestSetIfNotExists(t *testing.T) {
+	ctx := context.TODO()
+	object := &Object{
+		Key:        "idempotency.sessionId",
+		Payload:    "1",
+		Expiration: time.Hour,
+	}
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("SetIfNotExists", ctx, object).Return(true, nil).Once()
+	mockKVRepository2 := &MockIKVRepository{}
+	mockKVRepository2.On("SetIfNotExists", ctx, object).Return(false, nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository, mockKVRepository2},
+		logger:  logger,
+	}
+	res, err := redisRepo.SetIfNotExists(ctx, object)
+	assert.False(t, res)
+	assert.Nil(t, err)
+	mockKVRepository.AssertNumberOfCalls(t, "SetIfNotExists", 1)
+}
+
+func TestDeleteKeys(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("DeleteKeys", ctx, "key1", "key2").Return(int64(2), nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.DeleteKeys(ctx, "key1", "key2")
+	assert.Equal(t, int64(2), res)
+	assert.Nil(t, err)
+}
+
+func TestSetHash(t *testing.T) {
+	ctx := context.TODO()
+	object := &Object{
+		Key: "timestamps.sessionId",
+		Payload: map[string]interface{}{
+			"PodScheduleTimeStamp": int64(88888888888),
+		},
+		Expiration: time.Hour,
+	}
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("SetHash", ctx, object).Return(int64(1), nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.SetHash(ctx, object)
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestGetHash(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("GetHash", ctx, "timestamps.sessionId").Return(map[string]string{
+		"PodScheduleTimeStamp": "88888888888",
+	}, nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.GetHash(ctx, "timestamps.sessionId")
+	assert.Equal(t, map[string]string{"PodScheduleTimeStamp": "88888888888"}, res)
+	assert.Nil(t, err)
+}
+
+func TestRemoveFromHash(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.GpuInventory", "node.node-1").Return(int64(1), nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.RemoveFromHash(ctx, "SessionMonitor.GpuInventory", "node.node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("Get", ctx, "heartbeat.sessionId").Return("88888888888", nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.Get(ctx, "heartbeat.sessionId")
+	assert.Equal(t, "88888888888", res)
+	assert.Nil(t, err)
+}
+
+func TestUnsortedSetMembers(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &MockIKVRepository{}
+	mockKVRepository.On("AddMembersToUnsortedSet", ctx, "GpuNodePools.viz1.Nodes", "node-1").Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromUnsortedSet", ctx, "GpuNodePools.viz1.Nodes", "node-1").Return(int64(1), nil).Once()
+	mockKVRepository.On("GetUnsortedSetSize", ctx, "GpuNodePools.viz1.Nodes").Return(int64(0), nil).Once()
+	mockKVRepository.On("GetUnsortedSetMembers", ctx, "GpuNodePools.viz1.Nodes").Return([]string{}, nil).Once()
+
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	redisRepo := &redisRepository{
+		clients: []IKVRepository{mockKVRepository},
+		logger:  logger,
+	}
+	res, err := redisRepo.AddMembersToUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	res, err = redisRepo.RemoveFromUnsortedSet(ctx, "GpuNodePools.viz1.Nodes", "node-1")
+	assert.Equal(t, int64(1), res)
+	assert.Nil(t, err)
+	res, err = redisRepo.GetUnsortedSetSize(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, int64(0), res)
+	assert.Nil(t, err)
+	members, err := redisRepo.GetUnsortedSetMembers(ctx, "GpuNodePools.viz1.Nodes")
+	assert.Equal(t, []string{}, members)
+	assert.Nil(t, err)
+}
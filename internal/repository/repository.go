@@ -19,4 +19,16 @@ type IKVRepository interface {
 	Ping(ctx context.Context) (string, error)
 	AddStreamEvent(ctx context.Context, streamKey string, streamId string, payload interface{}) (string, error)
 	AddToUnsortedSet(ctx context.Context, UnsortedSetKey string, objects ...*Object) (int64, error)
+	AddMembersToUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error)
+	RemoveFromUnsortedSet(ctx context.Context, UnsortedSetKey string, members ...string) (int64, error)
+	GetUnsortedSetSize(ctx context.Context, UnsortedSetKey string) (int64, error)
+	GetUnsortedSetMembers(ctx context.Context, UnsortedSetKey string) ([]string, error)
+	Set(ctx context.Context, object *Object) (string, error)
+	SetIfNotExists(ctx context.Context, object *Object) (bool, error)
+	DeleteKeys(ctx context.Context, keys ...string) (int64, error)
+	ScanKeys(ctx context.Context, pattern string) ([]string, error)
+	SetHash(ctx context.Context, object *Object) (int64, error)
+	GetHash(ctx context.Context, key string) (map[string]string, error)
+	RemoveFromHash(ctx context.Context, key string, fields ...string) (int64, error)
+	Get(ctx context.Context, key string) (string, error)
 }
@@ -0,0 +1,197 @@
+package scaleup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// reasons a session pod waits for a new node, FailedScheduling comes first, TriggeredScaleUp once the
+// cluster autoscaler picked a node group for the pod. pods the autoscaler scaled up for are matched first
+const (
+	FailedSchedulingReason = "FailedScheduling"
+	TriggeredScaleUpReason = "TriggeredScaleUp"
+)
+
+// unix seconds. Seconds runs from the first pod waiting for the node to the node being ready
+type ScaleUp struct {
+	AgentPool   string `json:"agentPool"`
+	NodeName    string `json:"nodeName"`
+	TriggeredAt int64  `json:"triggeredAt"`
+	NodeCreated int64  `json:"nodeCreated"`
+	NodeReady   int64  `json:"nodeReady"`
+	Seconds     int64  `json:"seconds"`
+}
+
+// correlates session pods waiting for a node with the nodes the cluster autoscaler adds to their agent pool.
+// the pod module reports the waiting pods, the node module the new nodes
+//
+//go:generate mockery --name ITracker
+type ITracker interface {
+	// key is namespace/name, the first time the pod waited is kept
+	PodPending(key string, agentPool string, since time.Time, reason string)
+	// the cluster autoscaler scaled up for the pod, pods which are not pending are skipped
+	ScaleUpTriggered(key string)
+	// the pod is bound to the node, it is matched with no other node
+	PodScheduled(key string, nodeName string)
+	ForgetPod(key string)
+	// the first ready of a node of the agent pool, false when no pod of the pool was waiting before the
+	// node was created or the node was ready before. a matched pod is not matched again
+	NodeReady(nodeName string, agentPool string, created time.Time, ready time.Time) (ScaleUp, bool)
+	// the node was ready when it was listed, its next ready is a recovery
+	NodeSeenReady(nodeName string)
+	RemoveNode(nodeName string)
+	// the scale up the pod waited for, measured from the pod. false when the pod did not wait for the node
+	PodScaleUp(key string, nodeName string) (ScaleUp, bool)
+}
+
+type pendingPod struct {
+	agentPool string
+	since     time.Time
+	reason    string
+	// empty until the pod is scheduled
+	nodeName string
+	matched  bool
+}
+
+type tracker struct {
+	logger  *zap.Logger
+	mutex   sync.Mutex
+	pods    map[string]*pendingPod
+	nodes   map[string]ScaleUp
+	ready   map[string]bool
+	latency *prometheus.HistogramVec
+}
+
+var _ ITracker = (*tracker)(nil)
+
+// gpu nodes take minutes to join the cluster, the autoscaler scans every 10 seconds
+var scaleUpBuckets = []float64{30, 60, 120, 180, 240, 300, 450, 600, 900, 1200, 1800}
+
+func NewTracker(logger *zap.Logger, registerer prometheus.Registerer) (ITracker, error) {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "session_monitor",
+		Name:      "node_scale_up_seconds",
+		Help:      "Seconds from the first session pod waiting for a node to the new node of the agent pool being ready.",
+		Buckets:   scaleUpBuckets,
+	}, []string{"agent_pool"})
+	if err := registerer.Register(latency); err != nil {
+		return nil, err
+	}
+	return &tracker{
+		logger:  logger,
+		pods:    map[string]*pendingPod{},
+		nodes:   map[string]ScaleUp{},
+		ready:   map[string]bool{},
+		latency: latency,
+	}, nil
+}
+
+func (t *tracker) PodPending(key string, agentPool string, since time.Time, reason string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if pod, exist := t.pods[key]; exist && !since.Before(pod.since) {
+		return
+	}
+	t.pods[key] = &pendingPod{
+		agentPool: agentPool,
+		since:     since,
+		reason:    reason,
+	}
+}
+
+func (t *tracker) ScaleUpTriggered(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if pod, exist := t.pods[key]; exist {
+		pod.reason = TriggeredScaleUpReason
+	}
+}
+
+func (t *tracker) PodScheduled(key string, nodeName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if pod, exist := t.pods[key]; exist {
+		pod.nodeName = nodeName
+	}
+}
+
+func (t *tracker) ForgetPod(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.pods, key)
+}
+
+// the earliest pod of the pool waiting before the node was created triggered the scale up,
+// pods the autoscaler scaled up for come before the ones only failing to schedule
+func (t *tracker) NodeReady(nodeName string, agentPool string, created time.Time, ready time.Time) (ScaleUp, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.ready[nodeName] {
+		return ScaleUp{}, false
+	}
+	t.ready[nodeName] = true
+	var triggered *pendingPod
+	for _, pod := range t.pods {
+		if pod.agentPool != agentPool || pod.since.After(created) || pod.matched || pod.nodeName != "" && pod.nodeName != nodeName {
+			continue
+		}
+		if triggered == nil || precedes(pod, triggered) {
+			triggered = pod
+		}
+	}
+	if triggered == nil {
+		return ScaleUp{}, false
+	}
+	triggered.matched = true
+	scaleUp := ScaleUp{
+		AgentPool:   agentPool,
+		NodeName:    nodeName,
+		TriggeredAt: triggered.since.Unix(),
+		NodeCreated: created.Unix(),
+		NodeReady:   ready.Unix(),
+		Seconds:     ready.Unix() - triggered.since.Unix(),
+	}
+	t.nodes[nodeName] = scaleUp
+	t.logger.Sugar().Infow("Node scale up", "AgentPool", agentPool, "NodeName", nodeName,
+		"Reason", triggered.reason, "Seconds", scaleUp.Seconds)
+	if scaleUp.Seconds >= 0 {
+		t.latency.WithLabelValues(agentPool).Observe(float64(scaleUp.Seconds))
+	}
+	return scaleUp, true
+}
+
+func precedes(pod *pendingPod, other *pendingPod) bool {
+	if scaledUp, otherScaledUp := pod.reason == TriggeredScaleUpReason, other.reason == TriggeredScaleUpReason; scaledUp != otherScaledUp {
+		return scaledUp
+	}
+	return pod.since.Before(other.since)
+}
+
+func (t *tracker) NodeSeenReady(nodeName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ready[nodeName] = true
+}
+
+func (t *tracker) RemoveNode(nodeName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.nodes, nodeName)
+	delete(t.ready, nodeName)
+}
+
+func (t *tracker) PodScaleUp(key string, nodeName string) (ScaleUp, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	pod, podExist := t.pods[key]
+	scaleUp, nodeExist := t.nodes[nodeName]
+	if !podExist || !nodeExist || pod.since.Unix() > scaleUp.NodeCreated {
+		return ScaleUp{}, false
+	}
+	scaleUp.TriggeredAt = pod.since.Unix()
+	scaleUp.Seconds = scaleUp.NodeReady - scaleUp.TriggeredAt
+	return scaleUp, true
+}
@@ -0,0 +1,105 @@
+package scaleup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+)
+
+func TestTracker(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tracker, err := NewTracker(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), registry)
+	assert.Nil(t, err)
+	start := time.Unix(1000, 0)
+
+	tracker.PodPending("viz/pod-1", "viz3d", start, FailedSchedulingReason)
+	// the later TriggeredScaleUp keeps the first time
+	tracker.PodPending("viz/pod-1", "viz3d", start.Add(10*time.Second), TriggeredScaleUpReason)
+	tracker.PodPending("viz/pod-2", "viz3d", start.Add(30*time.Second), FailedSchedulingReason)
+	tracker.PodPending("viz/pod-3", "viz2d", start, FailedSchedulingReason)
+	// waiting after the node was created
+	tracker.PodPending("viz/pod-4", "viz3d", start.Add(200*time.Second), FailedSchedulingReason)
+
+	scaleUp, triggered := tracker.NodeReady("node-1", "viz3d", start.Add(60*time.Second), start.Add(300*time.Second))
+	assert.True(t, triggered)
+	assert.Equal(t, ScaleUp{
+		AgentPool:   "viz3d",
+		NodeName:    "node-1",
+		TriggeredAt: 1000,
+		NodeCreated: 1060,
+		NodeReady:   1300,
+		Seconds:     300,
+	}, scaleUp)
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "session_monitor_node_scale_up_seconds"))
+
+	// measured from the pod
+	scaleUp, triggered = tracker.PodScaleUp("viz/pod-2", "node-1")
+	assert.True(t, triggered)
+	assert.Equal(t, int64(270), scaleUp.Seconds)
+	_, triggered = tracker.PodScaleUp("viz/pod-4", "node-1")
+	assert.False(t, triggered)
+	_, triggered = tracker.PodScaleUp("viz/pod-5", "node-1")
+	assert.False(t, triggered)
+
+	// no pod of the pool was waiting
+	_, triggered = tracker.NodeReady("node-2", "cpu", start, start.Add(time.Minute))
+	assert.False(t, triggered)
+	// a recovery is not a scale up
+	_, triggered = tracker.NodeReady("node-1", "viz3d", start.Add(60*time.Second), start.Add(900*time.Second))
+	assert.False(t, triggered)
+
+	tracker.ForgetPod("viz/pod-2")
+	_, triggered = tracker.PodScaleUp("viz/pod-2", "node-1")
+	assert.False(t, triggered)
+	tracker.RemoveNode("node-1")
+	_, triggered = tracker.PodScaleUp("viz/pod-1", "node-1")
+	assert.False(t, triggered)
+}
+
+func TestTracker_Matching(t *testing.T) {
+	tracker, err := NewTracker(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), prometheus.NewRegistry())
+	assert.Nil(t, err)
+	start := time.Unix(1000, 0)
+
+	tracker.PodPending("viz/pod-1", "viz3d", start, FailedSchedulingReason)
+	tracker.PodPending("viz/pod-2", "viz3d", start.Add(30*time.Second), FailedSchedulingReason)
+	tracker.PodPending("viz/pod-3", "viz3d", start.Add(40*time.Second), FailedSchedulingReason)
+	tracker.ScaleUpTriggered("viz/pod-2")
+	// unknown pods are skipped
+	tracker.ScaleUpTriggered("viz/pod-9")
+	// bound to a node which was ready already
+	tracker.PodScheduled("viz/pod-1", "node-0")
+
+	// the pod the autoscaler scaled up for comes first
+	scaleUp, triggered := tracker.NodeReady("node-1", "viz3d", start.Add(60*time.Second), start.Add(300*time.Second))
+	assert.True(t, triggered)
+	assert.Equal(t, int64(1030), scaleUp.TriggeredAt)
+	// a matched pod is not matched again
+	scaleUp, triggered = tracker.NodeReady("node-2", "viz3d", start.Add(60*time.Second), start.Add(310*time.Second))
+	assert.True(t, triggered)
+	assert.Equal(t, int64(1040), scaleUp.TriggeredAt)
+	_, triggered = tracker.NodeReady("node-3", "viz3d", start.Add(60*time.Second), start.Add(320*time.Second))
+	assert.False(t, triggered)
+
+	// ready when it was listed
+	tracker.PodPending("viz/pod-4", "viz3d", start.Add(400*time.Second), FailedSchedulingReason)
+	tracker.NodeSeenReady("node-4")
+	_, triggered = tracker.NodeReady("node-4", "viz3d", start.Add(500*time.Second), start.Add(600*time.Second))
+	assert.False(t, triggered)
+	// removed nodes are forgotten, the name may be reused
+	tracker.RemoveNode("node-4")
+	_, triggered = tracker.NodeReady("node-4", "viz3d", start.Add(700*time.Second), start.Add(800*time.Second))
+	assert.True(t, triggered)
+}
+
+func TestNewTracker_AlreadyRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := NewTracker(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), registry)
+	assert.Nil(t, err)
+	_, err = NewTracker(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), registry)
+	assert.NotNil(t, err)
+}
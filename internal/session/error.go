@@ -24,3 +24,26 @@ func (s *InvalidStoreKeyErr) Is(target error) bool {
 func NewInvalidStoreKeyErr(key string) *InvalidStoreKeyErr {
 	return &InvalidStoreKeyErr{key}
 }
+
+type InvalidLifecycleTransitionErr struct {
+	sessionId string
+	from      LifecycleState
+	to        LifecycleState
+}
+
+func (r *InvalidLifecycleTransitionErr) Error() string {
+	return fmt.Sprintf("session: %s cannot transition from %s to %s", r.sessionId, r.from, r.to)
+}
+
+// assert style in golang
+func (s *InvalidLifecycleTransitionErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidLifecycleTransitionErr)
+	if !ok {
+		return false
+	}
+	return s.sessionId == targetErr.sessionId && s.from == targetErr.from && s.to == targetErr.to
+}
+
+func NewInvalidLifecycleTransitionErr(sessionId string, from LifecycleState, to LifecycleState) *InvalidLifecycleTransitionErr {
+	return &InvalidLifecycleTransitionErr{sessionId, from, to}
+}
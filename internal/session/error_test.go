@@ -15,3 +15,10 @@ func TestInvalidStoreKeyErr(t *testing.T) {
 	anotherErr := NewInvalidStoreKeyErr(anotherinvalidStoreKey)
 	assert.Equal(t, false, err.Is(anotherErr), "should be equal error valuewise")
 }
+
+func TestInvalidLifecycleTransitionErr(t *testing.T) {
+	err := NewInvalidLifecycleTransitionErr("sessionId", Deleted, Ready)
+	assert.Equal(t, "session: sessionId cannot transition from Deleted to Ready", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewInvalidLifecycleTransitionErr("sessionId", Deleted, Ready)), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewInvalidLifecycleTransitionErr("sessionId", Terminating, Ready)), "should not be equal error valuewise")
+}
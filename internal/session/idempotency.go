@@ -0,0 +1,114 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+)
+
+const (
+	defaultIdempotencyKeyPrefix = "SessionMonitor.Idempotency"
+	defaultIdempotencyTTL       = 24 * time.Hour
+	defaultIdempotencyClaimTTL  = time.Minute
+	// value of a key claimed by a submission still in flight
+	claimedTask = "claimed"
+)
+
+func (svc *sessionService) idempotencyKey(taskType StreamTaskType, sessionId string) string {
+	prefix := config.GetString(svc.config, "app.session_idempotency_key_prefix", defaultIdempotencyKeyPrefix)
+	return fmt.Sprintf("%s.%s.%s", prefix, taskType, sessionId)
+}
+
+func (svc *sessionService) isTaskCached(key string) bool {
+	svc.tasksMutex.Lock()
+	defer svc.tasksMutex.Unlock()
+	now := svc.now()
+	for cached, expiresAt := range svc.recordedTasks {
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			delete(svc.recordedTasks, cached)
+		}
+	}
+	_, exist := svc.recordedTasks[key]
+	return exist
+}
+
+// true when the task was submitted or its submission is in flight, locally or on another replica.
+// local cache avoids a redis round trip for every status update of the same pod,
+// its entries expire with the redis key, sessions whose pod deletion was missed are not kept forever
+func (svc *sessionService) isRecorded(taskType StreamTaskType, sessionId string) (bool, error) {
+	key := svc.idempotencyKey(taskType, sessionId)
+	if svc.isTaskCached(key) {
+		return true, nil
+	}
+	value, err := svc.kvRepo.Get(svc.ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return false, nil
+	}
+	// a claim is released again when its submission fails
+	if value != claimedTask {
+		svc.cacheTask(key)
+	}
+	return true, nil
+}
+
+// SETNX of the key before the task is submitted, false when another replica or an earlier submission holds it.
+// the claim expires after a short ttl, a replica dying before it submitted does not block the task for a day
+func (svc *sessionService) claim(taskType StreamTaskType, sessionId string) (bool, error) {
+	key := svc.idempotencyKey(taskType, sessionId)
+	if svc.isTaskCached(key) {
+		return false, nil
+	}
+	ttl := config.GetSeconds(svc.config, "app.session_idempotency_claim_ttl_seconds", defaultIdempotencyClaimTTL)
+	return svc.kvRepo.SetIfNotExists(svc.ctx, &repository.Object{
+		Key:        key,
+		Payload:    claimedTask,
+		Expiration: ttl,
+	})
+}
+
+// the submission failed, the task can be claimed again right away. a claim left behind expires with its ttl
+func (svc *sessionService) release(taskType StreamTaskType, sessionId string) {
+	if _, err := svc.kvRepo.DeleteKeys(svc.ctx, svc.idempotencyKey(taskType, sessionId)); err != nil {
+		svc.logger.Sugar().Errorf("Release %s claim of session %s has error: %s", taskType, sessionId, err.Error())
+	}
+}
+
+// written once the task was submitted, a failed submission leaves nothing to skip the retry
+func (svc *sessionService) record(taskType StreamTaskType, sessionId string) error {
+	key := svc.idempotencyKey(taskType, sessionId)
+	ttl := config.GetSeconds(svc.config, "app.session_idempotency_ttl_seconds", defaultIdempotencyTTL)
+	if _, err := svc.kvRepo.Set(svc.ctx, &repository.Object{
+		Key:        key,
+		Payload:    svc.now().Unix(),
+		Expiration: ttl,
+	}); err != nil {
+		return err
+	}
+	svc.cacheTask(key)
+	return nil
+}
+
+func (svc *sessionService) cacheTask(key string) {
+	ttl := config.GetSeconds(svc.config, "app.session_idempotency_ttl_seconds", defaultIdempotencyTTL)
+	svc.tasksMutex.Lock()
+	defer svc.tasksMutex.Unlock()
+	// ttl 0 keeps the redis key until deleted
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = svc.now().Add(ttl)
+	}
+	svc.recordedTasks[key] = expiresAt
+}
+
+func (svc *sessionService) uncacheTasks(keys ...string) {
+	svc.tasksMutex.Lock()
+	defer svc.tasksMutex.Unlock()
+	for _, key := range keys {
+		delete(svc.recordedTasks, key)
+	}
+}
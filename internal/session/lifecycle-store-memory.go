@@ -0,0 +1,61 @@
+package session
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryLifecycleStore struct {
+	mutex    sync.Mutex
+	sessions map[string]RecordedSession
+}
+
+var _ ILifecycleStore = (*memoryLifecycleStore)(nil)
+
+func NewMemoryLifecycleStore() ILifecycleStore {
+	return &memoryLifecycleStore{
+		sessions: map[string]RecordedSession{},
+	}
+}
+
+func (s *memoryLifecycleStore) SetState(ctx context.Context, sessionId string, state LifecycleState, reason string, updatedAt int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	recorded := s.sessions[sessionId]
+	recorded.State, recorded.Reason, recorded.UpdatedAt = state, reason, updatedAt
+	s.sessions[sessionId] = recorded
+	return nil
+}
+
+func (s *memoryLifecycleStore) SetPod(ctx context.Context, sessionId string, podName string, namespace string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	recorded := s.sessions[sessionId]
+	recorded.PodName, recorded.Namespace = podName, namespace
+	s.sessions[sessionId] = recorded
+	return nil
+}
+
+func (s *memoryLifecycleStore) Get(ctx context.Context, sessionId string) (RecordedSession, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	recorded, exist := s.sessions[sessionId]
+	return recorded, exist, nil
+}
+
+func (s *memoryLifecycleStore) List(ctx context.Context) (map[string]RecordedSession, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sessions := make(map[string]RecordedSession, len(s.sessions))
+	for sessionId, recorded := range s.sessions {
+		sessions[sessionId] = recorded
+	}
+	return sessions, nil
+}
+
+func (s *memoryLifecycleStore) Delete(ctx context.Context, sessionId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, sessionId)
+	return nil
+}
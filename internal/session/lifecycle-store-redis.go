@@ -0,0 +1,99 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+)
+
+// one redis hash per session, a set indexes the recorded sessions.
+// entries have no ttl, they are deleted with the session
+type redisLifecycleStore struct {
+	kvRepo repository.IKVRepository
+	prefix string
+	setKey string
+}
+
+var _ ILifecycleStore = (*redisLifecycleStore)(nil)
+
+func NewRedisLifecycleStore(kvRepo repository.IKVRepository, prefix string, setKey string) ILifecycleStore {
+	return &redisLifecycleStore{
+		kvRepo: kvRepo,
+		prefix: prefix,
+		setKey: setKey,
+	}
+}
+
+func (s *redisLifecycleStore) SetState(ctx context.Context, sessionId string, state LifecycleState, reason string, updatedAt int64) error {
+	return s.set(ctx, sessionId, map[string]interface{}{
+		"State":     string(state),
+		"Reason":    reason,
+		"UpdatedAt": updatedAt,
+	})
+}
+
+func (s *redisLifecycleStore) SetPod(ctx context.Context, sessionId string, podName string, namespace string) error {
+	return s.set(ctx, sessionId, map[string]interface{}{
+		"PodName":   podName,
+		"Namespace": namespace,
+	})
+}
+
+func (s *redisLifecycleStore) Get(ctx context.Context, sessionId string) (RecordedSession, bool, error) {
+	values, err := s.kvRepo.GetHash(ctx, s.hashKey(sessionId))
+	if err != nil || len(values) == 0 {
+		return RecordedSession{}, false, err
+	}
+	updatedAt, _ := strconv.ParseInt(values["UpdatedAt"], 10, 64)
+	return RecordedSession{
+		State:     LifecycleState(values["State"]),
+		Reason:    values["Reason"],
+		UpdatedAt: updatedAt,
+		PodName:   values["PodName"],
+		Namespace: values["Namespace"],
+	}, true, nil
+}
+
+// indexed sessions whose hash is gone are skipped
+func (s *redisLifecycleStore) List(ctx context.Context) (map[string]RecordedSession, error) {
+	sessionIds, err := s.kvRepo.GetUnsortedSetMembers(ctx, s.setKey)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make(map[string]RecordedSession, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		recorded, exist, err := s.Get(ctx, sessionId)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			sessions[sessionId] = recorded
+		}
+	}
+	return sessions, nil
+}
+
+func (s *redisLifecycleStore) Delete(ctx context.Context, sessionId string) error {
+	if _, err := s.kvRepo.DeleteKeys(ctx, s.hashKey(sessionId)); err != nil {
+		return err
+	}
+	_, err := s.kvRepo.RemoveFromUnsortedSet(ctx, s.setKey, sessionId)
+	return err
+}
+
+func (s *redisLifecycleStore) set(ctx context.Context, sessionId string, fields map[string]interface{}) error {
+	if _, err := s.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     s.hashKey(sessionId),
+		Payload: fields,
+	}); err != nil {
+		return err
+	}
+	_, err := s.kvRepo.AddMembersToUnsortedSet(ctx, s.setKey, sessionId)
+	return err
+}
+
+func (s *redisLifecycleStore) hashKey(sessionId string) string {
+	return fmt.Sprintf("%s.%s", s.prefix, sessionId)
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLifecycleKeyPrefix = "SessionMonitor.Lifecycle"
+	defaultLifecycleSetKey    = "SessionMonitor.Sessions"
+)
+
+// last known lifecycle state of a session, empty fields are unknown
+type RecordedSession struct {
+	State     LifecycleState `json:"state,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+	UpdatedAt int64          `json:"updatedAt,omitempty"`
+	PodName   string         `json:"podName,omitempty"`
+	Namespace string         `json:"namespace,omitempty"`
+}
+
+// lifecycle states outlive the process, so a restarted monitor knows
+// which tasks were already submitted and the reconciler can find drift
+//
+//go:generate mockery --name ILifecycleStore
+type ILifecycleStore interface {
+	SetState(ctx context.Context, sessionId string, state LifecycleState, reason string, updatedAt int64) error
+	SetPod(ctx context.Context, sessionId string, podName string, namespace string) error
+	// false if the session is not recorded
+	Get(ctx context.Context, sessionId string) (RecordedSession, bool, error)
+	List(ctx context.Context) (map[string]RecordedSession, error)
+	Delete(ctx context.Context, sessionId string) error
+}
+
+// app.lifecycle_store: redis (default) survives restarts and is shared between replicas, memory is per process
+func NewLifecycleStore(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository) ILifecycleStore {
+	storeType := config.GetString(cfg, "app.lifecycle_store", "redis")
+	logger.Sugar().Infow("NewLifecycleStore", "Type", storeType)
+	if storeType == "memory" {
+		return NewMemoryLifecycleStore()
+	}
+	return NewRedisLifecycleStore(kvRepo,
+		config.GetString(cfg, "app.lifecycle_key_prefix", defaultLifecycleKeyPrefix),
+		config.GetString(cfg, "app.lifecycle_set_key", defaultLifecycleSetKey))
+}
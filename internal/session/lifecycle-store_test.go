@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+)
+
+func TestNewLifecycleStore(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.lifecycle_store").Return("memory").Once()
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	_, ok := NewLifecycleStore(logger, mockConfig, &repository.MockIKVRepository{}).(*memoryLifecycleStore)
+	assert.True(t, ok, "memory store is configured")
+
+	mockConfig = &config.MockIConfig{}
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	store, ok := NewLifecycleStore(logger, mockConfig, &repository.MockIKVRepository{}).(*redisLifecycleStore)
+	assert.True(t, ok, "redis store is the default")
+	assert.Equal(t, defaultLifecycleKeyPrefix, store.prefix)
+	assert.Equal(t, defaultLifecycleSetKey, store.setKey)
+}
+
+func TestMemoryLifecycleStore(t *testing.T) {
+	ctx := context.TODO()
+	store := NewMemoryLifecycleStore()
+
+	_, exist, err := store.Get(ctx, "sessionId")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+	assert.Nil(t, store.SetState(ctx, "sessionId", Ready, "", 100))
+	assert.Nil(t, store.SetPod(ctx, "sessionId", "pod-1", "viz"))
+	recorded, exist, err := store.Get(ctx, "sessionId")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+	assert.Equal(t, RecordedSession{State: Ready, UpdatedAt: 100, PodName: "pod-1", Namespace: "viz"}, recorded)
+	sessions, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]RecordedSession{"sessionId": recorded}, sessions)
+
+	assert.Nil(t, store.Delete(ctx, "sessionId"))
+	sessions, err = store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sessions))
+}
+
+func TestRedisLifecycleStore(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("SetHash", ctx, &repository.Object{
+		Key: "prefix.sessionId",
+		Payload: map[string]interface{}{
+			"State":     "Terminating",
+			"Reason":    "IdleTimeout",
+			"UpdatedAt": int64(100),
+		},
+	}).Return(int64(3), nil).Once()
+	mockKVRepository.On("AddMembersToUnsortedSet", ctx, "sessions", "sessionId").Return(int64(1), nil).Once()
+	mockKVRepository.On("GetUnsortedSetMembers", ctx, "sessions").Return([]string{"sessionId", "expired"}, nil).Once()
+	mockKVRepository.On("GetHash", ctx, "prefix.sessionId").Return(map[string]string{
+		"State":     "Terminating",
+		"Reason":    "IdleTimeout",
+		"UpdatedAt": "100",
+		"PodName":   "pod-1",
+		"Namespace": "viz",
+	}, nil)
+	mockKVRepository.On("GetHash", ctx, "prefix.expired").Return(map[string]string{}, nil)
+	mockKVRepository.On("DeleteKeys", ctx, "prefix.sessionId").Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromUnsortedSet", ctx, "sessions", "sessionId").Return(int64(1), nil).Once()
+
+	store := NewRedisLifecycleStore(mockKVRepository, "prefix", "sessions")
+	assert.Nil(t, store.SetState(ctx, "sessionId", Terminating, "IdleTimeout", 100))
+	sessions, err := store.List(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]RecordedSession{
+		"sessionId": {State: Terminating, Reason: "IdleTimeout", UpdatedAt: 100, PodName: "pod-1", Namespace: "viz"},
+	}, sessions)
+	assert.Nil(t, store.Delete(ctx, "sessionId"))
+	mockKVRepository.AssertExpectations(t)
+}
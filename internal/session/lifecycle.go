@@ -0,0 +1,178 @@
+package session
+
+import (
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+)
+
+const SessionLifecycleAggregate = "session.SessionLifecycle"
+
+type LifecycleState string
+
+const (
+	Requested    LifecycleState = "Requested"
+	Scheduled    LifecycleState = "Scheduled"
+	Initializing LifecycleState = "Initializing"
+	Ready        LifecycleState = "Ready"
+	Failing      LifecycleState = "Failing"
+	Terminating  LifecycleState = "Terminating"
+	Deleted      LifecycleState = "Deleted"
+)
+
+const (
+	SessionRequestedEvent    = "SessionRequestedEvent"
+	SessionScheduledEvent    = "SessionScheduledEvent"
+	SessionInitializingEvent = "SessionInitializingEvent"
+	SessionReadyEvent        = "SessionReadyEvent"
+	SessionFailingEvent      = "SessionFailingEvent"
+	SessionTerminatingEvent  = "SessionTerminatingEvent"
+	SessionDeletedEvent      = "SessionDeletedEvent"
+)
+
+// states may be skipped, e.g. the monitor restarted while the pod was starting,
+// but never revisited
+var lifecycleTransitions = map[LifecycleState][]LifecycleState{
+	Requested:    {Scheduled, Initializing, Ready, Failing, Terminating, Deleted},
+	Scheduled:    {Initializing, Ready, Failing, Terminating, Deleted},
+	Initializing: {Ready, Failing, Terminating, Deleted},
+	Ready:        {Failing, Terminating, Deleted},
+	Failing:      {Terminating, Deleted},
+	Terminating:  {Deleted},
+	Deleted:      {},
+}
+
+// reported by pods lagging behind the session, e.g. a readiness flap moves a ready pod back to initializing,
+// the monitor restarted and the pod is listed again, or a container fails while the pod is terminating.
+// those are expected and leave the session as it is
+var staleTransitions = map[LifecycleState][]LifecycleState{
+	Requested:    {},
+	Scheduled:    {Requested},
+	Initializing: {Requested, Scheduled},
+	Ready:        {Requested, Scheduled, Initializing},
+	Failing:      {Requested, Scheduled, Initializing},
+	Terminating:  {Requested, Scheduled, Initializing, Failing},
+	Deleted:      {Requested, Scheduled, Initializing, Failing, Terminating},
+}
+
+var lifecycleEvents = map[LifecycleState]string{
+	Requested:    SessionRequestedEvent,
+	Scheduled:    SessionScheduledEvent,
+	Initializing: SessionInitializingEvent,
+	Ready:        SessionReadyEvent,
+	Failing:      SessionFailingEvent,
+	Terminating:  SessionTerminatingEvent,
+	Deleted:      SessionDeletedEvent,
+}
+
+type LifecycleTransition struct {
+	From       LifecycleState `json:"from,omitempty"`
+	To         LifecycleState `json:"to"`
+	Reason     string         `json:"reason,omitempty"`
+	OccurredAt time.Time      `json:"occurredAt"`
+}
+
+type LifecycleTransitionPayload struct {
+	SessionId  string
+	Transition LifecycleTransition
+}
+
+type SessionLifecycle struct {
+	ddd.Entity
+	state   LifecycleState
+	history []LifecycleTransition
+	events  []ddd.IEvent
+}
+
+func NewSessionLifecycle(sessionId string, reason string, occurredAt time.Time) *SessionLifecycle {
+	lifecycle := &SessionLifecycle{
+		Entity: ddd.NewEntity(sessionId, SessionLifecycleAggregate),
+	}
+	lifecycle.apply(Requested, reason, occurredAt)
+	return lifecycle
+}
+
+// continues the lifecycle recorded before a restart, the restored state is not published again
+func RestoreSessionLifecycle(sessionId string, state LifecycleState, reason string, occurredAt time.Time) *SessionLifecycle {
+	return &SessionLifecycle{
+		Entity: ddd.NewEntity(sessionId, SessionLifecycleAggregate),
+		state:  state,
+		history: []LifecycleTransition{{
+			To:         state,
+			Reason:     reason,
+			OccurredAt: occurredAt,
+		}},
+	}
+}
+
+func (l *SessionLifecycle) State() LifecycleState {
+	return l.state
+}
+
+func (l *SessionLifecycle) History() []LifecycleTransition {
+	return append([]LifecycleTransition{}, l.history...)
+}
+
+func (l *SessionLifecycle) CanTransition(to LifecycleState) bool {
+	for _, allowed := range lifecycleTransitions[l.state] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *SessionLifecycle) IsStale(to LifecycleState) bool {
+	for _, stale := range staleTransitions[l.state] {
+		if stale == to {
+			return true
+		}
+	}
+	return false
+}
+
+// staying in the current state or going back to an earlier one is a no-op, pods report the same status many times.
+// no event is recorded for a no-op
+func (l *SessionLifecycle) Transition(to LifecycleState, reason string, occurredAt time.Time) error {
+	if to == l.state || l.IsStale(to) {
+		return nil
+	}
+	if !l.CanTransition(to) {
+		return NewInvalidLifecycleTransitionErr(l.ID(), l.state, to)
+	}
+	l.apply(to, reason, occurredAt)
+	return nil
+}
+
+// domain events recorded since the last call
+func (l *SessionLifecycle) Events() []ddd.IEvent {
+	return l.events
+}
+
+func (l *SessionLifecycle) ClearEvents() {
+	l.events = nil
+}
+
+// copy safe to hand out of the service lock, without pending events
+func (l *SessionLifecycle) Snapshot() *SessionLifecycle {
+	return &SessionLifecycle{
+		Entity:  l.Entity,
+		state:   l.state,
+		history: l.History(),
+	}
+}
+
+func (l *SessionLifecycle) apply(to LifecycleState, reason string, occurredAt time.Time) {
+	transition := LifecycleTransition{
+		From:       l.state,
+		To:         to,
+		Reason:     reason,
+		OccurredAt: occurredAt,
+	}
+	l.state = to
+	l.history = append(l.history, transition)
+	l.events = append(l.events, ddd.NewEvent(lifecycleEvents[to], &LifecycleTransitionPayload{
+		SessionId:  l.ID(),
+		Transition: transition,
+	}))
+}
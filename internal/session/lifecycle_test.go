@@ -0,0 +1,109 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	now := time.Now()
+	lifecycle := NewSessionLifecycle("sessionId", "PodAdded", now)
+	assert.Equal(t, "sessionId", lifecycle.ID())
+	assert.Equal(t, SessionLifecycleAggregate, lifecycle.EntityName())
+	assert.Equal(t, Requested, lifecycle.State())
+
+	assert.Nil(t, lifecycle.Transition(Scheduled, "", now.Add(time.Second)))
+	assert.Nil(t, lifecycle.Transition(Scheduled, "", now.Add(2*time.Second)), "same state is a no-op")
+	assert.Nil(t, lifecycle.Transition(Ready, "", now.Add(3*time.Second)), "states may be skipped")
+	assert.Nil(t, lifecycle.Transition(Deleted, "PodRemoved", now.Add(4*time.Second)))
+	assert.Equal(t, Deleted, lifecycle.State())
+
+	history := lifecycle.History()
+	assert.Equal(t, []LifecycleTransition{
+		{To: Requested, Reason: "PodAdded", OccurredAt: now},
+		{From: Requested, To: Scheduled, OccurredAt: now.Add(time.Second)},
+		{From: Scheduled, To: Ready, OccurredAt: now.Add(3 * time.Second)},
+		{From: Ready, To: Deleted, Reason: "PodRemoved", OccurredAt: now.Add(4 * time.Second)},
+	}, history)
+
+	events := lifecycle.Events()
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, SessionRequestedEvent, events[0].EventName())
+	assert.Equal(t, SessionScheduledEvent, events[1].EventName())
+	assert.Equal(t, SessionReadyEvent, events[2].EventName())
+	assert.Equal(t, SessionDeletedEvent, events[3].EventName())
+	assert.Equal(t, &LifecycleTransitionPayload{SessionId: "sessionId", Transition: history[3]}, events[3].Payload())
+	lifecycle.ClearEvents()
+	assert.Equal(t, 0, len(lifecycle.Events()))
+}
+
+func TestSessionLifecycle_IllegalTransition(t *testing.T) {
+	scenarios := []struct {
+		desc string
+		path []LifecycleState
+		to   LifecycleState
+	}{
+		{desc: "ready after deleted", path: []LifecycleState{Ready, Deleted}, to: Ready},
+		{desc: "ready after failing", path: []LifecycleState{Failing}, to: Ready},
+		{desc: "ready after terminating", path: []LifecycleState{Ready, Terminating}, to: Ready},
+		{desc: "initializing after deleted", path: []LifecycleState{Deleted}, to: Ready},
+	}
+	for _, s := range scenarios {
+		t.Run(s.desc, func(t *testing.T) {
+			lifecycle := NewSessionLifecycle("sessionId", "", time.Now())
+			for _, state := range s.path {
+				assert.Nil(t, lifecycle.Transition(state, "", time.Now()))
+			}
+			from := lifecycle.State()
+			err := lifecycle.Transition(s.to, "", time.Now())
+			assert.True(t, NewInvalidLifecycleTransitionErr("sessionId", from, s.to).Is(err))
+			assert.Equal(t, from, lifecycle.State(), "state should not change")
+			assert.Equal(t, len(s.path)+1, len(lifecycle.History()), "history should not change")
+		})
+	}
+}
+
+func TestSessionLifecycle_StaleTransition(t *testing.T) {
+	scenarios := []struct {
+		desc string
+		path []LifecycleState
+		to   LifecycleState
+	}{
+		{desc: "readiness flap", path: []LifecycleState{Ready}, to: Initializing},
+		{desc: "scheduled after ready", path: []LifecycleState{Ready}, to: Scheduled},
+		{desc: "failing after terminating", path: []LifecycleState{Terminating}, to: Failing},
+		{desc: "requested again", path: []LifecycleState{Scheduled}, to: Requested},
+		{desc: "terminating after deleted", path: []LifecycleState{Deleted}, to: Terminating},
+	}
+	for _, s := range scenarios {
+		t.Run(s.desc, func(t *testing.T) {
+			lifecycle := NewSessionLifecycle("sessionId", "", time.Now())
+			for _, state := range s.path {
+				assert.Nil(t, lifecycle.Transition(state, "", time.Now()))
+			}
+			lifecycle.ClearEvents()
+			from := lifecycle.State()
+			assert.True(t, lifecycle.IsStale(s.to))
+			assert.Nil(t, lifecycle.Transition(s.to, "", time.Now()))
+			assert.Equal(t, from, lifecycle.State(), "state should not change")
+			assert.Equal(t, len(s.path)+1, len(lifecycle.History()), "history should not change")
+			assert.Equal(t, 0, len(lifecycle.Events()), "no-op should not be published")
+		})
+	}
+}
+
+func TestRestoreSessionLifecycle(t *testing.T) {
+	now := time.Now()
+	lifecycle := RestoreSessionLifecycle("sessionId", Ready, "", now)
+	assert.Equal(t, Ready, lifecycle.State())
+	assert.Equal(t, 0, len(lifecycle.Events()), "restored state is not published again")
+	assert.Equal(t, []LifecycleTransition{{To: Ready, OccurredAt: now}}, lifecycle.History())
+
+	// the pod is listed again after the restart
+	assert.Nil(t, lifecycle.Transition(Requested, "PodAdded", now))
+	assert.Equal(t, Ready, lifecycle.State())
+	assert.Nil(t, lifecycle.Transition(Terminating, "", now))
+	assert.Equal(t, 1, len(lifecycle.Events()))
+}
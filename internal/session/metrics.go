@@ -0,0 +1,67 @@
+package session
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//go:generate mockery --name ISessionMetrics
+type ISessionMetrics interface {
+	ObserveSessionReady(namespace string, agentPool string, durations SessionDurations)
+}
+
+type sessionMetrics struct {
+	nodeProvisionToPodScheduled *prometheus.HistogramVec
+	podScheduledToReady         *prometheus.HistogramVec
+	timeToReady                 *prometheus.HistogramVec
+}
+
+var _ ISessionMetrics = (*sessionMetrics)(nil)
+
+// gpu nodes take minutes to provision, image pulls of the session containers are slow as well
+var sessionStartupBuckets = []float64{1, 5, 10, 30, 60, 120, 180, 300, 600, 900, 1800}
+
+func NewSessionMetrics(registerer prometheus.Registerer) (ISessionMetrics, error) {
+	newHistogram := func(name string, help string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "session_monitor",
+			Name:      name,
+			Help:      help,
+			Buckets:   sessionStartupBuckets,
+		}, []string{"namespace", "agent_pool"})
+	}
+	metrics := &sessionMetrics{
+		nodeProvisionToPodScheduled: newHistogram("node_provision_to_pod_scheduled_seconds",
+			"Seconds from the node being provisioned to the session pod being scheduled on it."),
+		podScheduledToReady: newHistogram("pod_scheduled_to_ready_seconds",
+			"Seconds from the session pod being scheduled to being ready."),
+		timeToReady: newHistogram("time_to_ready_seconds",
+			"Seconds from the session pod being created to being ready."),
+	}
+	for _, collector := range []prometheus.Collector{
+		metrics.nodeProvisionToPodScheduled,
+		metrics.podScheduledToReady,
+		metrics.timeToReady,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+// missing durations are skipped, so are negative ones caused by clock skew between sources
+func (m *sessionMetrics) ObserveSessionReady(namespace string, agentPool string, durations SessionDurations) {
+	for _, observation := range []struct {
+		histogram *prometheus.HistogramVec
+		seconds   *int64
+	}{
+		{m.nodeProvisionToPodScheduled, durations.NodeProvisionToPodScheduled},
+		{m.podScheduledToReady, durations.PodScheduledToReady},
+		{m.timeToReady, durations.TimeToReady},
+	} {
+		if observation.seconds == nil || *observation.seconds < 0 {
+			continue
+		}
+		observation.histogram.WithLabelValues(namespace, agentPool).Observe(float64(*observation.seconds))
+	}
+}
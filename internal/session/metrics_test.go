@@ -0,0 +1,70 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewSessionMetrics(registry)
+	assert.Nil(t, err)
+
+	metrics.ObserveSessionReady("viz", "gpupool", NewSessionDurations(SessionTimestamps{
+		NodeProvisioned: 100,
+		PodCreated:      130,
+		PodScheduled:    160,
+		Ready:           190,
+	}))
+	// pod scheduled on a node provisioned before the monitor saw it
+	metrics.ObserveSessionReady("viz", "gpupool", NewSessionDurations(SessionTimestamps{
+		NodeProvisioned: 200,
+		PodScheduled:    150,
+		Ready:           170,
+	}))
+	assert.Equal(t, 3, testutil.CollectAndCount(registry))
+	expected := `
+# HELP session_monitor_time_to_ready_seconds Seconds from the session pod being created to being ready.
+# TYPE session_monitor_time_to_ready_seconds histogram
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="1"} 0
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="5"} 0
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="10"} 0
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="30"} 0
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="60"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="120"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="180"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="300"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="600"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="900"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="1800"} 1
+session_monitor_time_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="+Inf"} 1
+session_monitor_time_to_ready_seconds_sum{agent_pool="gpupool",namespace="viz"} 60
+session_monitor_time_to_ready_seconds_count{agent_pool="gpupool",namespace="viz"} 1
+`
+	assert.Nil(t, testutil.CollectAndCompare(registry, strings.NewReader(expected), "session_monitor_time_to_ready_seconds"))
+	assert.Nil(t, testutil.CollectAndCompare(registry, strings.NewReader(`
+# HELP session_monitor_pod_scheduled_to_ready_seconds Seconds from the session pod being scheduled to being ready.
+# TYPE session_monitor_pod_scheduled_to_ready_seconds histogram
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="1"} 0
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="5"} 0
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="10"} 0
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="30"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="60"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="120"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="180"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="300"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="600"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="900"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="1800"} 2
+session_monitor_pod_scheduled_to_ready_seconds_bucket{agent_pool="gpupool",namespace="viz",le="+Inf"} 2
+session_monitor_pod_scheduled_to_ready_seconds_sum{agent_pool="gpupool",namespace="viz"} 50
+session_monitor_pod_scheduled_to_ready_seconds_count{agent_pool="gpupool",namespace="viz"} 2
+`), "session_monitor_pod_scheduled_to_ready_seconds"))
+
+	_, err = NewSessionMetrics(registry)
+	assert.NotNil(t, err, "collectors can be registered only once")
+}
@@ -9,19 +9,57 @@ type MockISessionService struct {
 	mock.Mock
 }
 
+// AddSessionWarning provides a mock function with given fields: _a0
+func (_m *MockISessionService) AddSessionWarning(_a0 *AddSessionWarningActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*AddSessionWarningActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindSessionByPod provides a mock function with given fields: namespace, podName
+func (_m *MockISessionService) FindSessionByPod(namespace string, podName string) (string, bool) {
+	ret := _m.Called(namespace, podName)
+
+	var r0 string
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string, string) (string, bool)); ok {
+		return rf(namespace, podName)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(namespace, podName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) bool); ok {
+		r1 = rf(namespace, podName)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
 // GetNodeProvisionTimeStamp provides a mock function with given fields: NodeName
-func (_m *MockISessionService) GetNodeProvisionTimeStamp(NodeName string) (int64, error) {
+func (_m *MockISessionService) GetNodeProvisionTimeStamp(NodeName string) (Timestamp, error) {
 	ret := _m.Called(NodeName)
 
-	var r0 int64
+	var r0 Timestamp
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int64, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (Timestamp, error)); ok {
 		return rf(NodeName)
 	}
-	if rf, ok := ret.Get(0).(func(string) int64); ok {
+	if rf, ok := ret.Get(0).(func(string) Timestamp); ok {
 		r0 = rf(NodeName)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(Timestamp)
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
@@ -34,18 +72,94 @@ func (_m *MockISessionService) GetNodeProvisionTimeStamp(NodeName string) (int64
 }
 
 // GetPodScheduleTimeStamp provides a mock function with given fields: sessionId
-func (_m *MockISessionService) GetPodScheduleTimeStamp(sessionId string) (int64, error) {
+func (_m *MockISessionService) GetPodScheduleTimeStamp(sessionId string) (Timestamp, error) {
+	ret := _m.Called(sessionId)
+
+	var r0 Timestamp
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (Timestamp, error)); ok {
+		return rf(sessionId)
+	}
+	if rf, ok := ret.Get(0).(func(string) Timestamp); ok {
+		r0 = rf(sessionId)
+	} else {
+		r0 = ret.Get(0).(Timestamp)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sessionId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSession provides a mock function with given fields: sessionId
+func (_m *MockISessionService) GetSession(sessionId string) (*SessionView, error) {
+	ret := _m.Called(sessionId)
+
+	var r0 *SessionView
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*SessionView, error)); ok {
+		return rf(sessionId)
+	}
+	if rf, ok := ret.Get(0).(func(string) *SessionView); ok {
+		r0 = rf(sessionId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SessionView)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sessionId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSessionLifecycle provides a mock function with given fields: sessionId
+func (_m *MockISessionService) GetSessionLifecycle(sessionId string) (*SessionLifecycle, error) {
+	ret := _m.Called(sessionId)
+
+	var r0 *SessionLifecycle
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*SessionLifecycle, error)); ok {
+		return rf(sessionId)
+	}
+	if rf, ok := ret.Get(0).(func(string) *SessionLifecycle); ok {
+		r0 = rf(sessionId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*SessionLifecycle)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sessionId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsSessionEnqueued provides a mock function with given fields: sessionId
+func (_m *MockISessionService) IsSessionEnqueued(sessionId string) (bool, error) {
 	ret := _m.Called(sessionId)
 
-	var r0 int64
+	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int64, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (bool, error)); ok {
 		return rf(sessionId)
 	}
-	if rf, ok := ret.Get(0).(func(string) int64); ok {
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
 		r0 = rf(sessionId)
 	} else {
-		r0 = ret.Get(0).(int64)
+		r0 = ret.Get(0).(bool)
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
@@ -57,6 +171,126 @@ func (_m *MockISessionService) GetPodScheduleTimeStamp(sessionId string) (int64,
 	return r0, r1
 }
 
+// ListRecordedSessions provides a mock function with given fields:
+func (_m *MockISessionService) ListRecordedSessions() (map[string]RecordedSession, error) {
+	ret := _m.Called()
+
+	var r0 map[string]RecordedSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (map[string]RecordedSession, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() map[string]RecordedSession); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]RecordedSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSessions provides a mock function with given fields: after, limit
+func (_m *MockISessionService) ListSessions(after string, limit int) ([]*SessionView, error) {
+	ret := _m.Called(after, limit)
+
+	var r0 []*SessionView
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]*SessionView, error)); ok {
+		return rf(after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) []*SessionView); ok {
+		r0 = rf(after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SessionView)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSessionsOnNode provides a mock function with given fields: nodeName
+func (_m *MockISessionService) ListSessionsOnNode(nodeName string) ([]*SessionView, error) {
+	ret := _m.Called(nodeName)
+
+	var r0 []*SessionView
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]*SessionView, error)); ok {
+		return rf(nodeName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []*SessionView); ok {
+		r0 = rf(nodeName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SessionView)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(nodeName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PruneNodes provides a mock function with given fields: nodeNames
+func (_m *MockISessionService) PruneNodes(nodeNames []string) error {
+	ret := _m.Called(nodeNames)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string) error); ok {
+		r0 = rf(nodeNames)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeNode provides a mock function with given fields: nodeName
+func (_m *MockISessionService) PurgeNode(nodeName string) error {
+	ret := _m.Called(nodeName)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(nodeName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeSession provides a mock function with given fields: sessionId
+func (_m *MockISessionService) PurgeSession(sessionId string) error {
+	ret := _m.Called(sessionId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(sessionId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetNodeProvisionTimeStamp provides a mock function with given fields: _a0
 func (_m *MockISessionService) SetNodeProvisionTimeStamp(_a0 *SetNodeProvisionTimeStampActionPayload) error {
 	ret := _m.Called(_a0)
@@ -71,6 +305,34 @@ func (_m *MockISessionService) SetNodeProvisionTimeStamp(_a0 *SetNodeProvisionTi
 	return r0
 }
 
+// SetPodCreateTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetPodCreateTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPodInitializeTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetPodInitializeTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetPodScheduleTimeStamp provides a mock function with given fields: _a0
 func (_m *MockISessionService) SetPodScheduleTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
 	ret := _m.Called(_a0)
@@ -85,12 +347,74 @@ func (_m *MockISessionService) SetPodScheduleTimeStamp(_a0 *UpdateSessionTimeSta
 	return r0
 }
 
+// SetReadyTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetReadyTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetSessionDeletable provides a mock function with given fields: _a0
-func (_m *MockISessionService) SetSessionDeletable(_a0 *SetSessionDeletableActionPayload) error {
+func (_m *MockISessionService) SetSessionDeletable(_a0 *SetSessionDeletableActionPayload) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*SetSessionDeletableActionPayload) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*SetSessionDeletableActionPayload) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(*SetSessionDeletableActionPayload) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetSessionFailed provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetSessionFailed(_a0 *SetSessionFailedActionPayload) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*SetSessionFailedActionPayload) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*SetSessionFailedActionPayload) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(*SetSessionFailedActionPayload) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetSessionPod provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetSessionPod(_a0 *SetSessionPodActionPayload) error {
 	ret := _m.Called(_a0)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*SetSessionDeletableActionPayload) error); ok {
+	if rf, ok := ret.Get(0).(func(*SetSessionPodActionPayload) error); ok {
 		r0 = rf(_a0)
 	} else {
 		r0 = ret.Error(0)
@@ -100,11 +424,49 @@ func (_m *MockISessionService) SetSessionDeletable(_a0 *SetSessionDeletableActio
 }
 
 // SetSessionReady provides a mock function with given fields: _a0
-func (_m *MockISessionService) SetSessionReady(_a0 *SetSessionReadyActionPayload) error {
+func (_m *MockISessionService) SetSessionReady(_a0 *SetSessionReadyActionPayload) (bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*SetSessionReadyActionPayload) (bool, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(*SetSessionReadyActionPayload) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(*SetSessionReadyActionPayload) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetVolumeAttachTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetVolumeAttachTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetVolumeBindTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetVolumeBindTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
 	ret := _m.Called(_a0)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*SetSessionReadyActionPayload) error); ok {
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
 		r0 = rf(_a0)
 	} else {
 		r0 = ret.Error(0)
@@ -113,6 +475,44 @@ func (_m *MockISessionService) SetSessionReady(_a0 *SetSessionReadyActionPayload
 	return r0
 }
 
+// SetVolumeClaimTimeStamp provides a mock function with given fields: _a0
+func (_m *MockISessionService) SetVolumeClaimTimeStamp(_a0 *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*UpdateSessionTimeStampLikeFieldActionPayload) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TransitionSession provides a mock function with given fields: sessionId, to, reason
+func (_m *MockISessionService) TransitionSession(sessionId string, to LifecycleState, reason string) (bool, error) {
+	ret := _m.Called(sessionId, to, reason)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, LifecycleState, string) (bool, error)); ok {
+		return rf(sessionId, to, reason)
+	}
+	if rf, ok := ret.Get(0).(func(string, LifecycleState, string) bool); ok {
+		r0 = rf(sessionId, to, reason)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, LifecycleState, string) error); ok {
+		r1 = rf(sessionId, to, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewMockISessionService creates a new instance of MockISessionService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockISessionService(t interface {
@@ -8,14 +8,34 @@ const (
 	DeleteSession  StreamTaskType = "DeleteSession"
 )
 
+// defined by the monitor, not part of the shared stream definition. the task is added to
+// app.session_failed_stream_key with the TaskType, TaskInfo and TaskCreateTimeStamp fields of the other streams
+const SessionFailed StreamTaskType = "SessionFailed"
+
 type SetNodeProvisionTimeStampActionPayload struct {
 	NodeName  string
 	Timestamp int64
+	Source    TimestampSource
+}
+
+type SetSessionPodActionPayload struct {
+	SessionId     string
+	PodName       string
+	Namespace     string
+	NodeName      string
+	PodInternalIp string
+	Image         string
+}
+
+type AddSessionWarningActionPayload struct {
+	SessionId string
+	Warning   SessionWarning
 }
 
 type UpdateSessionTimeStampLikeFieldActionPayload struct {
 	SessionId string
 	Timestamp int64
+	Source    TimestampSource
 }
 
 // must match the definition in "https://dev.azure.com/slb-swt/slbCloud3DViz/_git/viz-3d-service-infrastructure?path=/src/models/redis/stream.ts"
@@ -23,12 +43,50 @@ type SetSessionReadyActionPayload struct {
 	SessionId              string `json:"sessionId" binding:"required"`
 	NodeName               string `json:"nodeName" binding:"required"`
 	NodeProvisionTimeStamp int64  `json:"nodeProvisionTimeStamp"`
+	PodCreateTimeStamp     int64  `json:"podCreateTimeStamp,omitempty"`
 	PodScheduleTimeStamp   int64  `json:"podScheduleTimeStamp"`
 	PodInternalIp          string `json:"podInternalIp"`
+	PodInitializeTimeStamp int64  `json:"podInitializeTimeStamp,omitempty"`
+	ReadyTimeStamp         int64  `json:"readyTimeStamp"`
+	// where each timestamp was taken from, the redis clock is only a fallback
+	NodeProvisionTimeStampSource TimestampSource `json:"nodeProvisionTimeStampSource,omitempty"`
+	PodScheduleTimeStampSource   TimestampSource `json:"podScheduleTimeStampSource,omitempty"`
+	PodInitializeTimeStampSource TimestampSource `json:"podInitializeTimeStampSource,omitempty"`
+	ReadyTimeStampSource         TimestampSource `json:"readyTimeStampSource,omitempty"`
+	// seconds, nil when one of the timestamps is missing
+	NodeProvisionToPodScheduledSeconds *int64 `json:"nodeProvisionToPodScheduledSeconds,omitempty"`
+	PodScheduledToReadySeconds         *int64 `json:"podScheduledToReadySeconds,omitempty"`
+	TimeToReadySeconds                 *int64 `json:"timeToReadySeconds,omitempty"`
+	// seconds from the pod failing to schedule to its new node being ready, nil when no node was added for it
+	ScaleUpSeconds *int64 `json:"scaleUpSeconds,omitempty"`
 }
 
 // must match the definition in "https://dev.azure.com/slb-swt/slbCloud3DViz/_git/viz-3d-service-infrastructure?path=/src/models/redis/stream.ts"
 type SetSessionDeletableActionPayload struct {
+	SessionId      string `json:"sessionId" binding:"required"`
+	CallerId       string `json:"callerId" binding:"required"`
+	FailureReason  string `json:"failureReason,omitempty"`
+	FailureMessage string `json:"failureMessage,omitempty"`
+	ExitCode       *int32 `json:"exitCode,omitempty"`
+	// set when the cluster terminated a healthy session, the broker may reschedule
+	DisruptionCause   string `json:"disruptionCause,omitempty"`
+	DisruptionReason  string `json:"disruptionReason,omitempty"`
+	DisruptionMessage string `json:"disruptionMessage,omitempty"`
+	// set when the monitor reaped a session past its max lifetime or idle timeout
+	ExpiryReason  string `json:"expiryReason,omitempty"`
+	ExpiryMessage string `json:"expiryMessage,omitempty"`
+	// latest cluster warnings of the session pod, attached to failures by the session service
+	Warnings []SessionWarning `json:"warnings,omitempty"`
+}
+
+// TaskInfo of a SessionFailed task, its json is the contract with the consumers of app.session_failed_stream_key
+// and fields are only added. reason is the PodScheduled reason of a pod pending too long, PendingTimeout
+// when there is none, or SessionUnreachable
+type SetSessionFailedActionPayload struct {
 	SessionId string `json:"sessionId" binding:"required"`
 	CallerId  string `json:"callerId" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+	Message   string `json:"message,omitempty"`
+	// latest cluster warnings of the session pod, attached by the session service
+	Warnings []SessionWarning `json:"warnings,omitempty"`
 }
@@ -0,0 +1,44 @@
+package session
+
+import "sync"
+
+// serializes the updates of a session that are persisted, without blocking the other sessions
+type sessionLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	sync.Mutex
+	// holders and waiters, the lock is dropped when the last one unlocks
+	refs int
+}
+
+func newSessionLocks() *sessionLocks {
+	return &sessionLocks{
+		locks: map[string]*sessionLock{},
+	}
+}
+
+// returns the unlock func
+func (l *sessionLocks) lock(sessionId string) func() {
+	l.mutex.Lock()
+	lock, exist := l.locks[sessionId]
+	if !exist {
+		lock = &sessionLock{}
+		l.locks[sessionId] = lock
+	}
+	lock.refs++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, sessionId)
+		}
+		l.mutex.Unlock()
+	}
+}
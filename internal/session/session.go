@@ -4,41 +4,127 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
 	"go.uber.org/zap"
 )
 
+const defaultListSessionsLimit = 100
+
 //go:generate mockery --name ISessionService
 type ISessionService interface {
-	SetSessionReady(*SetSessionReadyActionPayload) error
-	SetSessionDeletable(*SetSessionDeletableActionPayload) error
+	// true when the task was submitted, false when it was submitted before
+	SetSessionReady(*SetSessionReadyActionPayload) (bool, error)
+	// true once the enqueue task of the session was submitted
+	IsSessionEnqueued(sessionId string) (bool, error)
+	SetSessionDeletable(*SetSessionDeletableActionPayload) (bool, error)
+	SetSessionFailed(*SetSessionFailedActionPayload) (bool, error)
 	SetNodeProvisionTimeStamp(*SetNodeProvisionTimeStampActionPayload) error
+	SetPodCreateTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
 	SetPodScheduleTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
-	GetNodeProvisionTimeStamp(NodeName string) (int64, error)
-	GetPodScheduleTimeStamp(sessionId string) (int64, error)
+	SetPodInitializeTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
+	SetReadyTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
+	SetVolumeClaimTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
+	SetVolumeBindTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
+	SetVolumeAttachTimeStamp(*UpdateSessionTimeStampLikeFieldActionPayload) error
+	GetNodeProvisionTimeStamp(NodeName string) (Timestamp, error)
+	GetPodScheduleTimeStamp(sessionId string) (Timestamp, error)
+	// true when the session changed state, false for repeated or stale reports
+	TransitionSession(sessionId string, to LifecycleState, reason string) (bool, error)
+	GetSessionLifecycle(sessionId string) (*SessionLifecycle, error)
+	PurgeSession(sessionId string) error
+	PurgeNode(nodeName string) error
+	// timestamps of nodes which are not listed are purged, their delete events were missed
+	PruneNodes(nodeNames []string) error
+	SetSessionPod(*SetSessionPodActionPayload) error
+	// deleted sessions are kept until their timestamps expire
+	GetSession(sessionId string) (*SessionView, error)
+	// at most limit sessions ordered by id, starting after the given session id, a limit <= 0 lists the default page size
+	ListSessions(after string, limit int) ([]*SessionView, error)
+	// sessions whose pod is scheduled on the node, deleted ones included, ordered by id
+	ListSessionsOnNode(nodeName string) ([]*SessionView, error)
+	ListRecordedSessions() (map[string]RecordedSession, error)
+	// the session of a pod whose details were set, false when the pod is unknown
+	FindSessionByPod(namespace string, podName string) (string, bool)
+	AddSessionWarning(*AddSessionWarningActionPayload) error
 }
 
 type sessionService struct {
-	ctx    context.Context
-	logger *zap.Logger
-	config config.IConfig
-	kvRepo repository.IKVRepository
+	ctx            context.Context
+	logger         *zap.Logger
+	config         config.IConfig
+	kvRepo         repository.IKVRepository
+	timestampStore ITimestampStore
+	lifecycleStore ILifecycleStore
+	dispatcher     ddd.IEventDispatcher[ddd.IEvent]
+	mutex          sync.Mutex
+	// held across the lifecycle store I/O of a session, so it is persisted in order
+	sessionLocks *sessionLocks
+	now          func() time.Time
+	// guards only the idempotency cache, redis is not called with it held
+	tasksMutex sync.Mutex
+	// idempotency key to its expiry, zero when it does not expire
+	recordedTasks map[string]time.Time
+	lifecycles    map[string]*SessionLifecycle
+	pods          map[string]SessionPod
+	// namespace/name of the known pods to their session
+	podSessions map[string]string
+	// node name to the sessions of the live and retired pods scheduled on it
+	nodeSessions map[string]map[string]struct{}
+	warnings     map[string][]SessionWarning
+	retired      map[string]retiredSession
+}
+
+// a purged session as last seen, kept as long as its timestamps
+type retiredSession struct {
+	lifecycle *SessionLifecycle
+	pod       SessionPod
+	warnings  []SessionWarning
+	expires   time.Time
 }
 
 var _ ISessionService = (*sessionService)(nil)
 
-func NewSessionService(ctx context.Context, logger *zap.Logger, config config.IConfig, kvRepo repository.IKVRepository) ISessionService {
+func NewSessionService(ctx context.Context, logger *zap.Logger, config config.IConfig, kvRepo repository.IKVRepository,
+	timestampStore ITimestampStore, lifecycleStore ILifecycleStore, dispatcher ddd.IEventDispatcher[ddd.IEvent]) ISessionService {
 	return &sessionService{
-		ctx,
-		logger,
-		config,
-		kvRepo,
+		ctx:            ctx,
+		logger:         logger,
+		config:         config,
+		kvRepo:         kvRepo,
+		timestampStore: timestampStore,
+		lifecycleStore: lifecycleStore,
+		dispatcher:     dispatcher,
+		sessionLocks:   newSessionLocks(),
+		now:            time.Now,
+		recordedTasks:  map[string]time.Time{},
+		lifecycles:     map[string]*SessionLifecycle{},
+		pods:           map[string]SessionPod{},
+		podSessions:    map[string]string{},
+		nodeSessions:   map[string]map[string]struct{}{},
+		warnings:       map[string][]SessionWarning{},
+		retired:        map[string]retiredSession{},
 	}
 }
 
-func (svc *sessionService) SetSessionReady(payload *SetSessionReadyActionPayload) error {
+func (svc *sessionService) SetSessionReady(payload *SetSessionReadyActionPayload) (bool, error) {
+	// the reconciler and the informer both report the ready pod, the session is enqueued once
+	unlock := svc.sessionLocks.lock(payload.SessionId)
+	defer unlock()
+	enqueued, err := svc.IsSessionEnqueued(payload.SessionId)
+	if err != nil {
+		return false, err
+	}
+	if enqueued {
+		svc.logger.Sugar().Infof("SetSessionReady skipped, session %s is already enqueued", payload.SessionId)
+		return false, nil
+	}
 	// reuse viper as config store
 	streamKey := svc.config.Get("app.enqueue_session_stream_key").(string)
 	svc.logger.Sugar().Infof("SetSessionReady streamKey: %s", streamKey)
@@ -47,64 +133,550 @@ func (svc *sessionService) SetSessionReady(payload *SetSessionReadyActionPayload
 	currentServerUnixTimestamp, err := svc.kvRepo.GetServerTimestamp(svc.ctx)
 	if err != nil {
 		svc.logger.Sugar().Errorf("GetServerTimestamp has error: %ds", err.Error())
-		return err
+		return false, err
 	}
 	svc.logger.Sugar().Infof("GetServerTimestamp: %d", currentServerUnixTimestamp)
 	payloadToKvStore := []interface{}{"TaskType", string(EnqueueSession), "TaskInfo", string(out), "TaskCreateTimeStamp", currentServerUnixTimestamp}
 	streamId, err := svc.kvRepo.AddStreamEvent(svc.ctx, streamKey, "*", payloadToKvStore)
+	if err != nil {
+		return false, err
+	}
 	svc.logger.Sugar().Infof("SetSessionReady create streamTask: %s", streamId)
-	return err
+	// the task is in the stream, a lost record may enqueue the session twice but never drops it
+	if err := svc.record(EnqueueSession, payload.SessionId); err != nil {
+		svc.logger.Sugar().Errorf("Record enqueued session %s has error: %s", payload.SessionId, err.Error())
+	}
+	return true, nil
+}
+
+func (svc *sessionService) IsSessionEnqueued(sessionId string) (bool, error) {
+	return svc.isRecorded(EnqueueSession, sessionId)
 }
 
-func (svc *sessionService) SetSessionDeletable(payload *SetSessionDeletableActionPayload) error {
+func (svc *sessionService) SetSessionDeletable(payload *SetSessionDeletableActionPayload) (bool, error) {
+	// a failed or terminating pod keeps receiving status updates, the session is deletable only once
+	unlock := svc.sessionLocks.lock(payload.SessionId)
+	defer unlock()
+	// claimed in redis, the other replicas skip the session while it is submitted
+	claimed, err := svc.claim(DeleteSession, payload.SessionId)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		svc.logger.Sugar().Infof("SetSessionDeletable skipped, session %s is already deletable", payload.SessionId)
+		return false, nil
+	}
+	if payload.FailureReason != "" && payload.Warnings == nil {
+		payload.Warnings = svc.sessionWarnings(payload.SessionId)
+	}
 	// reuse viper as config store
 	streamKey := svc.config.Get("app.delete_session_stream_key").(string)
 	out, _ := json.Marshal(payload)
 	svc.logger.Sugar().Info(string(out))
 	currentServerUnixTimestamp, err := svc.kvRepo.GetServerTimestamp(svc.ctx)
 	if err != nil {
-		return err
+		svc.release(DeleteSession, payload.SessionId)
+		return false, err
 	}
 	payloadToKvStore := []interface{}{"TaskType", string(DeleteSession), "TaskInfo", string(out), "TaskCreateTimeStamp", currentServerUnixTimestamp}
 	streamId, err := svc.kvRepo.AddStreamEvent(svc.ctx, streamKey, "*", payloadToKvStore)
+	if err != nil {
+		svc.release(DeleteSession, payload.SessionId)
+		return false, err
+	}
 	svc.logger.Sugar().Infof("SetSessionDeletable create streamTask: %s", streamId)
-	return err
+	// a crash before it is recorded lets the claim expire, the session is deleted again but never lost
+	if err := svc.record(DeleteSession, payload.SessionId); err != nil {
+		svc.logger.Sugar().Errorf("Record deletable session %s has error: %s", payload.SessionId, err.Error())
+	}
+	return true, nil
+}
+
+func (svc *sessionService) SetSessionFailed(payload *SetSessionFailedActionPayload) (bool, error) {
+	// the broker is told once per session
+	unlock := svc.sessionLocks.lock(payload.SessionId)
+	defer unlock()
+	// claimed in redis, the other replicas skip the session while it is submitted
+	claimed, err := svc.claim(SessionFailed, payload.SessionId)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		svc.logger.Sugar().Infof("SetSessionFailed skipped, session %s is already failed", payload.SessionId)
+		return false, nil
+	}
+	// reuse viper as config store
+	streamKey := svc.config.Get("app.session_failed_stream_key").(string)
+	if payload.Warnings == nil {
+		payload.Warnings = svc.sessionWarnings(payload.SessionId)
+	}
+	out, _ := json.Marshal(payload)
+	svc.logger.Sugar().Infof("SetSessionFailed payload to submit: %s", string(out))
+	currentServerUnixTimestamp, err := svc.kvRepo.GetServerTimestamp(svc.ctx)
+	if err != nil {
+		svc.release(SessionFailed, payload.SessionId)
+		return false, err
+	}
+	payloadToKvStore := []interface{}{"TaskType", string(SessionFailed), "TaskInfo", string(out), "TaskCreateTimeStamp", currentServerUnixTimestamp}
+	streamId, err := svc.kvRepo.AddStreamEvent(svc.ctx, streamKey, "*", payloadToKvStore)
+	if err != nil {
+		svc.release(SessionFailed, payload.SessionId)
+		return false, err
+	}
+	svc.logger.Sugar().Infof("SetSessionFailed create streamTask: %s", streamId)
+	if err := svc.record(SessionFailed, payload.SessionId); err != nil {
+		svc.logger.Sugar().Errorf("Record failed session %s has error: %s", payload.SessionId, err.Error())
+	}
+	return true, nil
 }
 
 func (svc *sessionService) SetNodeProvisionTimeStamp(payload *SetNodeProvisionTimeStampActionPayload) error {
-	nodeName, timestamp := payload.NodeName, payload.Timestamp
-	nodeProvisionTimestampStoreKey := fmt.Sprintf("NodeProvisionTimeStamp.%s", nodeName)
-	svc.logger.Sugar().Infow("SetNodeProvisionTimeStamp", "nodeName", nodeName, "timestamp", timestamp, "key", nodeProvisionTimestampStoreKey)
-	svc.config.Set(nodeProvisionTimestampStoreKey, timestamp)
-	return nil
+	nodeName, timestamp, source := payload.NodeName, payload.Timestamp, payload.Source
+	svc.logger.Sugar().Infow("SetNodeProvisionTimeStamp", "nodeName", nodeName, "timestamp", timestamp, "source", source)
+	return svc.timestampStore.SetTimestamp(svc.ctx, NodeTimestampKey(nodeName), NodeProvisionTimeStamp, Timestamp{
+		Value:  timestamp,
+		Source: source,
+	})
+}
+
+func (svc *sessionService) SetPodCreateTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(PodCreateTimeStamp, payload)
 }
 
 func (svc *sessionService) SetPodScheduleTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
-	sessionId, timestamp := payload.SessionId, payload.Timestamp
-	podScheduledTimestampStoreKey := fmt.Sprintf("PodScheduleTimeStamp.%s", sessionId)
-	svc.logger.Sugar().Infow("SetPodScheduleTimeStamp", "sessionId", sessionId, "timestamp", timestamp, "key", podScheduledTimestampStoreKey)
-	svc.config.Set(podScheduledTimestampStoreKey, timestamp)
-	return nil
+	return svc.setSessionTimestamp(PodScheduleTimeStamp, payload)
 }
 
-func (svc *sessionService) GetNodeProvisionTimeStamp(nodeName string) (int64, error) {
-	nodeProvisionTimestampStoreKey := fmt.Sprintf("NodeProvisionTimeStamp.%s", nodeName)
-	svc.logger.Sugar().Infow("GetNodeProvisionTimeStamp", "key", nodeProvisionTimestampStoreKey)
-	val, ok := svc.config.Get(nodeProvisionTimestampStoreKey).(int64)
-	if ok {
-		return val, nil
-	} else {
-		return 0, NewInvalidStoreKeyErr(nodeProvisionTimestampStoreKey)
+func (svc *sessionService) SetPodInitializeTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(PodInitializeTimeStamp, payload)
+}
+
+func (svc *sessionService) SetReadyTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(ReadyTimeStamp, payload)
+}
+
+func (svc *sessionService) SetVolumeClaimTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(VolumeClaimTimeStamp, payload)
+}
+
+func (svc *sessionService) SetVolumeBindTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(VolumeBindTimeStamp, payload)
+}
+
+func (svc *sessionService) SetVolumeAttachTimeStamp(payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	return svc.setSessionTimestamp(VolumeAttachTimeStamp, payload)
+}
+
+func (svc *sessionService) setSessionTimestamp(field string, payload *UpdateSessionTimeStampLikeFieldActionPayload) error {
+	sessionId, timestamp, source := payload.SessionId, payload.Timestamp, payload.Source
+	svc.logger.Sugar().Infow("Set"+field, "sessionId", sessionId, "timestamp", timestamp, "source", source)
+	return svc.timestampStore.SetTimestamp(svc.ctx, SessionTimestampKey(sessionId), field, Timestamp{
+		Value:  timestamp,
+		Source: source,
+	})
+}
+
+func (svc *sessionService) GetNodeProvisionTimeStamp(nodeName string) (Timestamp, error) {
+	svc.logger.Sugar().Infow("GetNodeProvisionTimeStamp", "nodeName", nodeName)
+	return svc.timestampStore.GetTimestamp(svc.ctx, NodeTimestampKey(nodeName), NodeProvisionTimeStamp)
+}
+
+func (svc *sessionService) GetPodScheduleTimeStamp(sessionId string) (Timestamp, error) {
+	svc.logger.Sugar().Infow("GetPodScheduleTimeStamp", "sessionId", sessionId)
+	return svc.timestampStore.GetTimestamp(svc.ctx, SessionTimestampKey(sessionId), PodScheduleTimeStamp)
+}
+
+// sessions first seen after a restart continue from their recorded state, unknown sessions start as Requested.
+// repeated and stale reports leave the session as it is and are not applied.
+// illegal transitions are rejected, the caller must not act on them
+func (svc *sessionService) TransitionSession(sessionId string, to LifecycleState, reason string) (bool, error) {
+	unlock := svc.sessionLocks.lock(sessionId)
+	defer unlock()
+	now := svc.now()
+	svc.mutex.Lock()
+	lifecycle, exist := svc.lifecycles[sessionId]
+	svc.mutex.Unlock()
+	if !exist {
+		lifecycle = svc.restoreLifecycle(sessionId, reason, now)
 	}
+
+	svc.mutex.Lock()
+	from := lifecycle.State()
+	stale := lifecycle.IsStale(to)
+	err := lifecycle.Transition(to, reason, now)
+	events := lifecycle.Events()
+	lifecycle.ClearEvents()
+	svc.mutex.Unlock()
+
+	if err != nil {
+		svc.logger.Sugar().Warnw("Illegal session lifecycle transition", "SessionId", sessionId, "From", from, "To", to, "Reason", reason)
+		return false, err
+	}
+	if len(events) == 0 {
+		if stale {
+			svc.logger.Sugar().Infow("Stale session lifecycle transition ignored", "SessionId", sessionId, "From", from, "To", to, "Reason", reason)
+		}
+		return false, nil
+	}
+	svc.logger.Sugar().Infow("Session lifecycle transition", "SessionId", sessionId, "From", from, "To", to, "Reason", reason)
+	// recorded under the session lock, concurrent transitions of a session are persisted in order
+	if err = svc.lifecycleStore.SetState(svc.ctx, sessionId, to, reason, now.Unix()); err != nil {
+		svc.logger.Sugar().Errorf("SetState %s of session %s has error: %s", to, sessionId, err.Error())
+	}
+	svc.recordTransitionTimestamp(sessionId, from, to)
+	return true, svc.dispatcher.Publish(svc.ctx, events...)
 }
 
-func (svc *sessionService) GetPodScheduleTimeStamp(sessionId string) (int64, error) {
-	podScheduledTimestampStoreKey := fmt.Sprintf("PodScheduleTimeStamp.%s", sessionId)
-	svc.logger.Sugar().Infow("GetPodScheduleTimeStamp", "key", podScheduledTimestampStoreKey)
-	val, ok := svc.config.Get(podScheduledTimestampStoreKey).(int64)
-	if ok {
-		return val, nil
+// called with the session lock held, the recorded lifecycle is read outside the service lock
+func (svc *sessionService) restoreLifecycle(sessionId string, reason string, now time.Time) *SessionLifecycle {
+	recorded, exist, err := svc.lifecycleStore.Get(svc.ctx, sessionId)
+	if err != nil {
+		svc.logger.Sugar().Errorf("Get recorded lifecycle of session %s has error: %s", sessionId, err.Error())
+	}
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	var lifecycle *SessionLifecycle
+	if !exist || recorded.State == "" {
+		lifecycle = NewSessionLifecycle(sessionId, reason, now)
 	} else {
-		return 0, NewInvalidStoreKeyErr(podScheduledTimestampStoreKey)
+		svc.logger.Sugar().Infow("Session lifecycle restored", "SessionId", sessionId, "State", recorded.State)
+		if recorded.PodName != "" || recorded.Namespace != "" {
+			pod := svc.pods[sessionId]
+			pod.PodName, pod.Namespace = recorded.PodName, recorded.Namespace
+			svc.setPod(sessionId, pod)
+		}
+		lifecycle = RestoreSessionLifecycle(sessionId, recorded.State, recorded.Reason, time.Unix(recorded.UpdatedAt, 0))
+	}
+	svc.lifecycles[sessionId] = lifecycle
+	return lifecycle
+}
+
+// ready is recorded by the pod handler from the pod condition
+func (svc *sessionService) recordTransitionTimestamp(sessionId string, from LifecycleState, to LifecycleState) {
+	switch to {
+	case Failing, Terminating, Deleted:
+		// the first of them is when the session started to go away
+		if from == Failing || from == Terminating {
+			return
+		}
+	default:
+		return
+	}
+	timestamp, err := svc.kvRepo.GetServerTimestamp(svc.ctx)
+	if err != nil {
+		svc.logger.Sugar().Errorf("GetServerTimestamp has error: %s", err.Error())
+		timestamp = svc.now().Unix()
+	}
+	if err = svc.timestampStore.SetTimestamp(svc.ctx, SessionTimestampKey(sessionId), DeleteTimeStamp, Timestamp{
+		Value:  timestamp,
+		Source: ServerTimeSource,
+	}); err != nil {
+		svc.logger.Sugar().Errorf("SetTimestamp %s of session %s has error: %s", DeleteTimeStamp, sessionId, err.Error())
+	}
+}
+
+func (svc *sessionService) GetSessionLifecycle(sessionId string) (*SessionLifecycle, error) {
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	lifecycle, exist := svc.lifecycles[sessionId]
+	if !exist {
+		return nil, NewInvalidStoreKeyErr(fmt.Sprintf("SessionLifecycle.%s", sessionId))
+	}
+	return lifecycle.Snapshot(), nil
+}
+
+// drop the state kept for a session whose pod is gone. its view and timestamps are kept until
+// app.timestamp_ttl_seconds passed, idempotency records stay in redis until they expire,
+// replicas may still process stale updates
+func (svc *sessionService) PurgeSession(sessionId string) error {
+	svc.logger.Sugar().Infow("PurgeSession", "SessionId", sessionId)
+	unlock := svc.sessionLocks.lock(sessionId)
+	defer unlock()
+	now := svc.now()
+	svc.mutex.Lock()
+	for retiredId, retired := range svc.retired {
+		if !now.Before(retired.expires) {
+			delete(svc.retired, retiredId)
+			if svc.pods[retiredId].NodeName != retired.pod.NodeName {
+				svc.indexNode(retiredId, retired.pod.NodeName, "")
+			}
+		}
+	}
+	lifecycle, exist := svc.lifecycles[sessionId]
+	if exist {
+		svc.retired[sessionId] = retiredSession{
+			lifecycle: lifecycle.Snapshot(),
+			pod:       svc.pods[sessionId],
+			warnings:  svc.copyWarnings(sessionId),
+			expires:   now.Add(config.GetSeconds(svc.config, "app.timestamp_ttl_seconds", defaultTimestampTTL)),
+		}
+	}
+	delete(svc.lifecycles, sessionId)
+	svc.setPod(sessionId, SessionPod{})
+	delete(svc.pods, sessionId)
+	// the retired view stays on its node
+	if exist {
+		svc.indexNode(sessionId, "", svc.retired[sessionId].pod.NodeName)
+	}
+	delete(svc.warnings, sessionId)
+	svc.mutex.Unlock()
+	svc.uncacheTasks(svc.idempotencyKey(EnqueueSession, sessionId), svc.idempotencyKey(DeleteSession, sessionId), svc.idempotencyKey(SessionFailed, sessionId))
+	return svc.lifecycleStore.Delete(svc.ctx, sessionId)
+}
+
+func (svc *sessionService) PurgeNode(nodeName string) error {
+	svc.logger.Sugar().Infow("PurgeNode", "NodeName", nodeName)
+	return svc.timestampStore.DeleteTimestamps(svc.ctx, NodeTimestampKey(nodeName))
+}
+
+func (svc *sessionService) PruneNodes(nodeNames []string) error {
+	listed := map[string]bool{}
+	for _, nodeName := range nodeNames {
+		listed[nodeName] = true
+	}
+	keys, err := svc.timestampStore.ListKeys(svc.ctx, nodeTimestampKeyPrefix)
+	if err != nil {
+		return err
+	}
+	stale := []string{}
+	for _, key := range keys {
+		if nodeName := strings.TrimPrefix(key, nodeTimestampKeyPrefix); !listed[nodeName] {
+			stale = append(stale, nodeName)
+		}
+	}
+	for _, nodeName := range stale {
+		if err := svc.PurgeNode(nodeName); err != nil {
+			return err
+		}
+	}
+	if len(stale) > 0 {
+		svc.logger.Sugar().Infow("Pruned node timestamps", "Nodes", stale)
+	}
+	return nil
+}
+
+// non-empty fields overwrite the known pod details
+func (svc *sessionService) SetSessionPod(payload *SetSessionPodActionPayload) error {
+	unlock := svc.sessionLocks.lock(payload.SessionId)
+	defer unlock()
+	svc.mutex.Lock()
+	pod := svc.pods[payload.SessionId]
+	known := pod
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&pod.PodName, payload.PodName},
+		{&pod.Namespace, payload.Namespace},
+		{&pod.NodeName, payload.NodeName},
+		{&pod.PodInternalIp, payload.PodInternalIp},
+		{&pod.Image, payload.Image},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+	svc.setPod(payload.SessionId, pod)
+	svc.mutex.Unlock()
+	// the reconciler needs the namespace of sessions whose pod is gone
+	if pod.PodName == known.PodName && pod.Namespace == known.Namespace {
+		return nil
+	}
+	return svc.lifecycleStore.SetPod(svc.ctx, payload.SessionId, pod.PodName, pod.Namespace)
+}
+
+func (svc *sessionService) GetSession(sessionId string) (*SessionView, error) {
+	now := svc.now()
+	svc.mutex.Lock()
+	var (
+		lifecycle *SessionLifecycle
+		pod       SessionPod
+		warnings  []SessionWarning
+	)
+	if live, exist := svc.lifecycles[sessionId]; exist {
+		lifecycle, pod, warnings = live.Snapshot(), svc.pods[sessionId], svc.copyWarnings(sessionId)
+	} else if retired, exist := svc.retired[sessionId]; exist && now.Before(retired.expires) {
+		lifecycle, pod, warnings = retired.lifecycle, retired.pod, retired.warnings
+	}
+	svc.mutex.Unlock()
+	if lifecycle == nil {
+		return nil, NewInvalidStoreKeyErr(fmt.Sprintf("SessionLifecycle.%s", sessionId))
+	}
+
+	timestamps, err := svc.timestampStore.GetTimestamps(svc.ctx, SessionTimestampKey(sessionId))
+	if err != nil {
+		return nil, err
+	}
+	view := SessionTimestamps{
+		PodCreated:     timestamps[PodCreateTimeStamp].Value,
+		PodScheduled:   timestamps[PodScheduleTimeStamp].Value,
+		PodInitialized: timestamps[PodInitializeTimeStamp].Value,
+		Ready:          timestamps[ReadyTimeStamp].Value,
+		Deleted:        timestamps[DeleteTimeStamp].Value,
+		VolumeClaimed:  timestamps[VolumeClaimTimeStamp].Value,
+		VolumeBound:    timestamps[VolumeBindTimeStamp].Value,
+		VolumeAttached: timestamps[VolumeAttachTimeStamp].Value,
+	}
+	if pod.NodeName != "" {
+		nodeTimestamps, err := svc.timestampStore.GetTimestamps(svc.ctx, NodeTimestampKey(pod.NodeName))
+		if err != nil {
+			return nil, err
+		}
+		view.NodeProvisioned = nodeTimestamps[NodeProvisionTimeStamp].Value
+	}
+	sessionView := NewSessionView(lifecycle, pod, view)
+	sessionView.Warnings = warnings
+	return sessionView, nil
+}
+
+// every session costs store round trips, only the requested page is read
+func (svc *sessionService) ListSessions(after string, limit int) ([]*SessionView, error) {
+	if limit <= 0 {
+		limit = defaultListSessionsLimit
+	}
+	now := svc.now()
+	svc.mutex.Lock()
+	sessionIds := make([]string, 0, len(svc.lifecycles)+len(svc.retired))
+	for sessionId := range svc.lifecycles {
+		if sessionId > after {
+			sessionIds = append(sessionIds, sessionId)
+		}
+	}
+	for sessionId, retired := range svc.retired {
+		if _, live := svc.lifecycles[sessionId]; !live && sessionId > after && now.Before(retired.expires) {
+			sessionIds = append(sessionIds, sessionId)
+		}
+	}
+	svc.mutex.Unlock()
+	sort.Strings(sessionIds)
+	if len(sessionIds) > limit {
+		sessionIds = sessionIds[:limit]
+	}
+
+	sessions := make([]*SessionView, 0, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		view, err := svc.GetSession(sessionId)
+		// expired in the meantime
+		if _, notFound := err.(*InvalidStoreKeyErr); notFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, view)
+	}
+	return sessions, nil
+}
+
+// looked up in the node index, only the sessions of the node are read
+func (svc *sessionService) ListSessionsOnNode(nodeName string) ([]*SessionView, error) {
+	svc.mutex.Lock()
+	sessionIds := make([]string, 0, len(svc.nodeSessions[nodeName]))
+	for sessionId := range svc.nodeSessions[nodeName] {
+		sessionIds = append(sessionIds, sessionId)
+	}
+	svc.mutex.Unlock()
+	sort.Strings(sessionIds)
+
+	sessions := make([]*SessionView, 0, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		view, err := svc.GetSession(sessionId)
+		// expired or moved in the meantime
+		if _, notFound := err.(*InvalidStoreKeyErr); notFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if view.NodeName == nodeName {
+			sessions = append(sessions, view)
+		}
+	}
+	return sessions, nil
+}
+
+// lifecycle states as recorded in the store, shared between replicas and restarts
+func (svc *sessionService) ListRecordedSessions() (map[string]RecordedSession, error) {
+	return svc.lifecycleStore.List(svc.ctx)
+}
+
+func (svc *sessionService) FindSessionByPod(namespace string, podName string) (string, bool) {
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	sessionId, exist := svc.podSessions[podKey(namespace, podName)]
+	return sessionId, exist
+}
+
+// called with the lock held, keeps the pod index in step with the pods of the sessions
+func (svc *sessionService) setPod(sessionId string, pod SessionPod) {
+	if known := svc.pods[sessionId]; known.PodName != "" {
+		if key := podKey(known.Namespace, known.PodName); svc.podSessions[key] == sessionId {
+			delete(svc.podSessions, key)
+		}
+	}
+	if known := svc.pods[sessionId]; known.NodeName != pod.NodeName {
+		svc.indexNode(sessionId, known.NodeName, pod.NodeName)
+	}
+	svc.pods[sessionId] = pod
+	if pod.PodName != "" {
+		svc.podSessions[podKey(pod.Namespace, pod.PodName)] = sessionId
+	}
+}
+
+// called with the lock held, empty node names are not indexed
+func (svc *sessionService) indexNode(sessionId string, from string, to string) {
+	if sessionIds, exist := svc.nodeSessions[from]; exist {
+		delete(sessionIds, sessionId)
+		if len(sessionIds) == 0 {
+			delete(svc.nodeSessions, from)
+		}
+	}
+	if to == "" {
+		return
+	}
+	if _, exist := svc.nodeSessions[to]; !exist {
+		svc.nodeSessions[to] = map[string]struct{}{}
+	}
+	svc.nodeSessions[to][sessionId] = struct{}{}
+}
+
+func podKey(namespace string, podName string) string {
+	return namespace + "/" + podName
+}
+
+// a warning repeating the reason of the same object replaces the previous one, the latest
+// app.session_warnings_limit warnings are kept, oldest first
+func (svc *sessionService) AddSessionWarning(payload *AddSessionWarningActionPayload) error {
+	limit := config.GetInt(svc.config, "app.session_warnings_limit", 5)
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	warnings := []SessionWarning{}
+	for _, warning := range svc.warnings[payload.SessionId] {
+		if warning.Reason != payload.Warning.Reason || warning.Object != payload.Warning.Object {
+			warnings = append(warnings, warning)
+		}
+	}
+	warnings = append(warnings, payload.Warning)
+	sort.SliceStable(warnings, func(a, b int) bool {
+		return warnings[a].LastSeen < warnings[b].LastSeen
+	})
+	if len(warnings) > limit {
+		warnings = warnings[len(warnings)-limit:]
+	}
+	svc.warnings[payload.SessionId] = warnings
+	return nil
+}
+
+func (svc *sessionService) sessionWarnings(sessionId string) []SessionWarning {
+	svc.mutex.Lock()
+	defer svc.mutex.Unlock()
+	return svc.copyWarnings(sessionId)
+}
+
+// called with the lock held, nil without warnings
+func (svc *sessionService) copyWarnings(sessionId string) []SessionWarning {
+	if len(svc.warnings[sessionId]) == 0 {
+		return nil
 	}
+	return append([]SessionWarning{}, svc.warnings[sessionId]...)
 }
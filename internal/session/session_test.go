@@ -3,11 +3,16 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
 )
@@ -21,11 +26,18 @@ func TestSetSessionReady(t *testing.T) {
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
 	mockConfig.On("Get", "app.enqueue_session_stream_key").Return(mockEnqueueSessionStreamKey, nil).Once()
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
+	mockKVRepository.On("Get", ctx, "idempotency.EnqueueSession.sessionId").Return("", nil).Once()
 	mockKVRepository.On("GetServerTimestamp", ctx).Return(mockServerTimestamp, nil).Once()
 	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	// recorded once the task is in the stream
+	mockKVRepository.On("Set", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		return object.Key == "idempotency.EnqueueSession.sessionId" && object.Expiration == time.Hour
+	})).Return("OK", nil).Once()
 	mockPayload := SetSessionReadyActionPayload{
 		SessionId:              "sessionId",
 		NodeName:               "nodeName",
@@ -35,14 +47,74 @@ func TestSetSessionReady(t *testing.T) {
 	}
 	mockPayloadBuf, _ := json.Marshal(mockPayload)
 
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
-	err := sessionService.SetSessionReady(&mockPayload)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := sessionService.SetSessionReady(&mockPayload)
 	assert.Nil(t, err, "sessionService.SetSessionReady should not throw error")
+	assert.True(t, submitted)
 	mockKVRepository.AssertNumberOfCalls(t, "GetServerTimestamp", 1)
 	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
 	mockKVRepository.AssertCalled(t, "AddStreamEvent", ctx, mockEnqueueSessionStreamKey, "*", []interface{}{"TaskType",
 		string(EnqueueSession), "TaskInfo", string(mockPayloadBuf),
 		"TaskCreateTimeStamp", mockServerTimestamp})
+
+	// redeliveries of the ready pod are not enqueued again
+	enqueued, err := sessionService.IsSessionEnqueued("sessionId")
+	assert.Nil(t, err)
+	assert.True(t, enqueued)
+	submitted, err = sessionService.SetSessionReady(&mockPayload)
+	assert.Nil(t, err)
+	assert.False(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
+}
+
+// the reconciler and the informer report the same ready pod concurrently
+func TestSetSessionReady_Concurrent(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.enqueue_session_stream_key").Return("enqueue_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("Get", ctx, "idempotency.EnqueueSession.sessionId").Return("", nil)
+	// the first report is still submitting when the second one checks the session
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).After(50 * time.Millisecond)
+	mockKVRepository.On("AddStreamEvent", ctx, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil)
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("OK", nil)
+	sessionService := NewSessionService(ctx, logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig,
+		mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sessionService.SetSessionReady(&SetSessionReadyActionPayload{SessionId: "sessionId"})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
+}
+
+// nothing is recorded when the task did not reach the stream, the next ready report retries
+func TestSetSessionReady_NotSubmitted(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.enqueue_session_stream_key").Return("enqueue_session_stream_key", nil)
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("Get", ctx, "idempotency.EnqueueSession.sessionId").Return("", nil).Twice()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Once()
+	mockKVRepository.On("AddStreamEvent", ctx, mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("stream is down")).Once()
+	sessionService := NewSessionService(ctx, logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig,
+		mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+
+	submitted, err := sessionService.SetSessionReady(&SetSessionReadyActionPayload{SessionId: "sessionId"})
+	assert.NotNil(t, err)
+	assert.False(t, submitted)
+	enqueued, err := sessionService.IsSessionEnqueued("sessionId")
+	assert.Nil(t, err)
+	assert.False(t, enqueued)
 }
 
 func TestSetSessionDeletable(t *testing.T) {
@@ -53,20 +125,38 @@ func TestSetSessionDeletable(t *testing.T) {
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
 	mockConfig.On("Get", "app.delete_session_stream_key").Return(mockDeleteSessionStreamKey, nil).Once()
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
+	// claimed before the task is submitted
+	mockKVRepository.On("SetIfNotExists", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		return object.Key == "idempotency.DeleteSession.sessionId" && object.Payload == claimedTask && object.Expiration == time.Minute
+	})).Return(true, nil).Once()
 	mockKVRepository.On("GetServerTimestamp", ctx).Return(mockServerTimestamp, nil).Once()
 	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	// recorded once the task is in the stream
+	mockKVRepository.On("Set", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		return object.Key == "idempotency.DeleteSession.sessionId" && object.Expiration == time.Hour
+	})).Return("OK", nil).Once()
 	mockPayload := SetSessionDeletableActionPayload{
 		SessionId: "sessionId",
 		CallerId:  "Session-monitor-service",
 	}
 	mockPayloadBuf, _ := json.Marshal(mockPayload)
 
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
-	err := sessionService.SetSessionDeletable(&mockPayload)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := sessionService.SetSessionDeletable(&mockPayload)
 	assert.Nil(t, err, "sessionService.SetSessionDeletable should not throw error")
+	assert.True(t, submitted)
+	// further status updates of the same pod
+	submitted, err = sessionService.SetSessionDeletable(&mockPayload)
+	assert.Nil(t, err, "sessionService.SetSessionDeletable should not throw error")
+	assert.False(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "SetIfNotExists", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "Set", 1)
 	mockKVRepository.AssertNumberOfCalls(t, "GetServerTimestamp", 1)
 	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
 	mockKVRepository.AssertCalled(t, "AddStreamEvent", ctx, mockDeleteSessionStreamKey, "*", []interface{}{"TaskType",
@@ -74,6 +164,275 @@ func TestSetSessionDeletable(t *testing.T) {
 		"TaskCreateTimeStamp", mockServerTimestamp})
 }
 
+func TestSetSessionDeletable_DeletableByAnotherReplica(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(false, nil).Once()
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := sessionService.SetSessionDeletable(&SetSessionDeletableActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	})
+	assert.Nil(t, err, "sessionService.SetSessionDeletable should not throw error")
+	assert.False(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 0)
+}
+
+// replicas share the redis key, only the replica holding the claim submits the task
+func TestSetSessionDeletable_SharedRepository(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.delete_session_stream_key").Return("delete_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Once()
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(false, nil)
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Once()
+	mockKVRepository.On("AddStreamEvent", ctx, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("OK", nil).Once()
+
+	replicas := []ISessionService{
+		NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{}),
+		NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{}),
+	}
+	var wg sync.WaitGroup
+	submitted := make([]bool, len(replicas))
+	for i, replica := range replicas {
+		wg.Add(1)
+		go func(i int, replica ISessionService) {
+			defer wg.Done()
+			var err error
+			submitted[i], err = replica.SetSessionDeletable(&SetSessionDeletableActionPayload{
+				SessionId: "sessionId",
+				CallerId:  "Session-monitor-service",
+			})
+			assert.Nil(t, err)
+		}(i, replica)
+	}
+	wg.Wait()
+	assert.NotEqual(t, submitted[0], submitted[1])
+	mockKVRepository.AssertNumberOfCalls(t, "SetIfNotExists", 2)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
+}
+
+// the claim is released when the task did not reach the stream, the next status update retries
+func TestSetSessionDeletable_NotSubmitted(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.delete_session_stream_key").Return("delete_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Twice()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Twice()
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("stream is down")).Once()
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	mockKVRepository.On("DeleteKeys", ctx, "idempotency.DeleteSession.sessionId").Return(int64(1), nil).Once()
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("OK", nil).Once()
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	payload := &SetSessionDeletableActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	}
+	submitted, err := sessionService.SetSessionDeletable(payload)
+	assert.NotNil(t, err)
+	assert.False(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "DeleteKeys", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "Set", 0)
+	// retry on the next status update
+	submitted, err = sessionService.SetSessionDeletable(payload)
+	assert.Nil(t, err)
+	assert.True(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 2)
+	mockKVRepository.AssertNumberOfCalls(t, "Set", 1)
+}
+
+// the task stays submitted when only its record failed, the claim expires and later updates may submit it again
+func TestSetSessionDeletable_RecordFailed(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.delete_session_stream_key").Return("delete_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Once()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Once()
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("", errors.New("redis is down")).Once()
+
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := service.SetSessionDeletable(&SetSessionDeletableActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	})
+	assert.Nil(t, err)
+	assert.True(t, submitted)
+	assert.Equal(t, 0, len(service.(*sessionService).recordedTasks))
+	mockKVRepository.AssertExpectations(t)
+}
+
+// other sessions are not blocked while redis is called
+func TestSetSessionDeletable_RedisOutsideLock(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.delete_session_stream_key").Return("delete_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	svc := service.(*sessionService)
+	assertUnlocked := func(mock.Arguments) {
+		assert.True(t, svc.mutex.TryLock())
+		svc.mutex.Unlock()
+		assert.True(t, svc.tasksMutex.TryLock())
+		svc.tasksMutex.Unlock()
+	}
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Run(assertUnlocked).Once()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Run(assertUnlocked).Once()
+	mockKVRepository.On("AddStreamEvent", ctx, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Run(assertUnlocked).Once()
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("OK", nil).Run(assertUnlocked).Once()
+
+	submitted, err := service.SetSessionDeletable(&SetSessionDeletableActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	})
+	assert.Nil(t, err)
+	assert.True(t, submitted)
+	assert.Equal(t, 1, len(svc.recordedTasks))
+	mockKVRepository.AssertExpectations(t)
+}
+
+func TestSetSessionDeletable_LocalCacheExpires(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.delete_session_stream_key").Return("delete_session_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Once()
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(false, nil).Once()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(88888888888), nil).Once()
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	mockKVRepository.On("Set", ctx, mock.Anything).Return("OK", nil).Once()
+
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	svc := service.(*sessionService)
+	svc.now = func() time.Time { return now }
+	payload := &SetSessionDeletableActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	}
+	_, err := service.SetSessionDeletable(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(svc.recordedTasks))
+
+	// the local entry expired with its redis key, redis is asked again
+	now = now.Add(time.Hour)
+	_, err = service.SetSessionDeletable(payload)
+	assert.Nil(t, err)
+	mockKVRepository.AssertNumberOfCalls(t, "SetIfNotExists", 2)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
+}
+
+func TestSetSessionFailed(t *testing.T) {
+	mockSessionFailedStreamKey := "session_failed_stream_key"
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockServerTimestamp := int64(88888888888)
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_failed_stream_key").Return(mockSessionFailedStreamKey, nil).Once()
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_ttl_seconds").Return(3600)
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		return object.Key == "idempotency.SessionFailed.sessionId" && object.Payload == claimedTask
+	})).Return(true, nil).Once()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(mockServerTimestamp, nil).Once()
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	mockKVRepository.On("Set", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		return object.Key == "idempotency.SessionFailed.sessionId"
+	})).Return("OK", nil).Once()
+	mockPayload := SetSessionFailedActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+		Reason:    "Unschedulable",
+		Message:   "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+	}
+	mockPayloadBuf, _ := json.Marshal(mockPayload)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := sessionService.SetSessionFailed(&mockPayload)
+	assert.Nil(t, err, "sessionService.SetSessionFailed should not throw error")
+	assert.True(t, submitted)
+	// the broker is told once
+	submitted, err = sessionService.SetSessionFailed(&mockPayload)
+	assert.Nil(t, err)
+	assert.False(t, submitted)
+	mockKVRepository.AssertNumberOfCalls(t, "SetIfNotExists", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "Set", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "GetServerTimestamp", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "AddStreamEvent", 1)
+	mockKVRepository.AssertCalled(t, "AddStreamEvent", ctx, mockSessionFailedStreamKey, "*", []interface{}{"TaskType",
+		string(SessionFailed), "TaskInfo", string(mockPayloadBuf),
+		"TaskCreateTimeStamp", mockServerTimestamp})
+}
+
+// the claim is released before the task reached the stream
+func TestSetSessionFailed_NotSubmitted(t *testing.T) {
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_failed_stream_key").Return("session_failed_stream_key")
+	mockConfig.On("Get", "app.session_idempotency_key_prefix").Return("idempotency")
+	mockConfig.On("Get", "app.session_idempotency_claim_ttl_seconds").Return(60)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockKVRepository.On("SetIfNotExists", ctx, mock.Anything).Return(true, nil).Once()
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(int64(0), errors.New("redis is down")).Once()
+	mockKVRepository.On("DeleteKeys", ctx, "idempotency.SessionFailed.sessionId").Return(int64(1), nil).Once()
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, &MockITimestampStore{}, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	submitted, err := sessionService.SetSessionFailed(&SetSessionFailedActionPayload{
+		SessionId: "sessionId",
+		CallerId:  "Session-monitor-service",
+	})
+	assert.NotNil(t, err)
+	assert.False(t, submitted)
+}
+
 func TestSetNodeProvisionTimeStamp(t *testing.T) {
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockNodeName := "nodeName"
@@ -81,7 +440,11 @@ func TestSetNodeProvisionTimeStamp(t *testing.T) {
 
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	mockTimestampStore := &MockITimestampStore{}
+	mockTimestampStore.On("SetTimestamp", ctx, "Node.nodeName", NodeProvisionTimeStamp, Timestamp{
+		Value:  mockNodeProvisioningTimestamp,
+		Source: NodeCreationSource,
+	}).Return(nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
@@ -89,13 +452,12 @@ func TestSetNodeProvisionTimeStamp(t *testing.T) {
 	mockPayload := SetNodeProvisionTimeStampActionPayload{
 		NodeName:  mockNodeName,
 		Timestamp: mockNodeProvisioningTimestamp,
+		Source:    NodeCreationSource,
 	}
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	err := sessionService.SetNodeProvisionTimeStamp(&mockPayload)
 	assert.Nil(t, err, "sessionService.SetNodeProvisionTimeStamp should not throw error")
-
-	mockConfig.AssertNumberOfCalls(t, "Set", 1)
-	mockConfig.AssertCalled(t, "Set", "NodeProvisionTimeStamp.nodeName", mockNodeProvisioningTimestamp)
+	mockTimestampStore.AssertNumberOfCalls(t, "SetTimestamp", 1)
 }
 
 func TestSetPodScheduleTimeStamp(t *testing.T) {
@@ -105,7 +467,11 @@ func TestSetPodScheduleTimeStamp(t *testing.T) {
 
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	mockTimestampStore := &MockITimestampStore{}
+	mockTimestampStore.On("SetTimestamp", ctx, "Session.sessionId", PodScheduleTimeStamp, Timestamp{
+		Value:  mockSetPodScheduleTimeStamp,
+		Source: PodConditionSource,
+	}).Return(nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
@@ -113,13 +479,12 @@ func TestSetPodScheduleTimeStamp(t *testing.T) {
 	mockPayload := UpdateSessionTimeStampLikeFieldActionPayload{
 		SessionId: mockSessionId,
 		Timestamp: mockSetPodScheduleTimeStamp,
+		Source:    PodConditionSource,
 	}
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	err := sessionService.SetPodScheduleTimeStamp(&mockPayload)
 	assert.Nil(t, err, "sessionService.SetPodScheduleTimeStamp should not throw error")
-
-	mockConfig.AssertNumberOfCalls(t, "Set", 1)
-	mockConfig.AssertCalled(t, "Set", "PodScheduleTimeStamp.sessionId", mockSetPodScheduleTimeStamp)
+	mockTimestampStore.AssertNumberOfCalls(t, "SetTimestamp", 1)
 }
 
 func TestGetNodeProvisionTimeStamp(t *testing.T) {
@@ -129,16 +494,19 @@ func TestGetNodeProvisionTimeStamp(t *testing.T) {
 
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Get", mock.Anything).Return(mockNodeProvisioningTimestamp).Once()
+	mockTimestampStore := &MockITimestampStore{}
+	mockTimestampStore.On("GetTimestamp", ctx, "Node.nodeName", NodeProvisionTimeStamp).Return(Timestamp{
+		Value:  mockNodeProvisioningTimestamp,
+		Source: NodeCreationSource,
+	}, nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	timestamp, err := sessionService.GetNodeProvisionTimeStamp(mockNodeName)
 	assert.Nil(t, err, "sessionService.GetNodeProvisionTimeStamp should not throw error")
-	assert.Equal(t, mockNodeProvisioningTimestamp, timestamp)
-	mockConfig.AssertNumberOfCalls(t, "Get", 1)
-	mockConfig.AssertCalled(t, "Get", "NodeProvisionTimeStamp.nodeName")
+	assert.Equal(t, Timestamp{Value: mockNodeProvisioningTimestamp, Source: NodeCreationSource}, timestamp)
+	mockTimestampStore.AssertNumberOfCalls(t, "GetTimestamp", 1)
 }
 
 func TestGetNodeProvisionTimeStampKeyNotFound(t *testing.T) {
@@ -146,13 +514,12 @@ func TestGetNodeProvisionTimeStampKeyNotFound(t *testing.T) {
 	mockNodeName := "nodeName"
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Get", mock.Anything).Return(nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	_, err := sessionService.GetNodeProvisionTimeStamp(mockNodeName)
-	assert.True(t, NewInvalidStoreKeyErr("NodeProvisionTimeStamp.nodeName").Is(err))
+	assert.True(t, NewInvalidStoreKeyErr("Node.nodeName.NodeProvisionTimeStamp").Is(err))
 }
 
 func TestGetPodScheduleTimeStamp(t *testing.T) {
@@ -162,16 +529,19 @@ func TestGetPodScheduleTimeStamp(t *testing.T) {
 
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Get", mock.Anything).Return(mockPodScheduleTimestamp).Once()
+	mockTimestampStore := &MockITimestampStore{}
+	mockTimestampStore.On("GetTimestamp", ctx, "Session.sessionId", PodScheduleTimeStamp).Return(Timestamp{
+		Value:  mockPodScheduleTimestamp,
+		Source: ServerTimeSource,
+	}, nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	timestamp, err := sessionService.GetPodScheduleTimeStamp(mockSessionId)
 	assert.Nil(t, err, "sessionService.GetPodScheduleTimeStamp should not throw error")
-	assert.Equal(t, mockPodScheduleTimestamp, timestamp)
-	mockConfig.AssertNumberOfCalls(t, "Get", 1)
-	mockConfig.AssertCalled(t, "Get", "PodScheduleTimeStamp.sessionId")
+	assert.Equal(t, Timestamp{Value: mockPodScheduleTimestamp, Source: ServerTimeSource}, timestamp)
+	mockTimestampStore.AssertNumberOfCalls(t, "GetTimestamp", 1)
 }
 
 func TestGetPodScheduleTimeStampKeyNotFound(t *testing.T) {
@@ -179,11 +549,570 @@ func TestGetPodScheduleTimeStampKeyNotFound(t *testing.T) {
 	mockSessionId := "sessionId"
 	ctx := context.TODO()
 	mockConfig := &config.MockIConfig{}
-	mockConfig.On("Get", mock.Anything).Return(nil).Once()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
-	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository)
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
 	_, err := sessionService.GetPodScheduleTimeStamp(mockSessionId)
-	assert.True(t, NewInvalidStoreKeyErr("PodScheduleTimeStamp.sessionId").Is(err))
+	assert.True(t, NewInvalidStoreKeyErr("Session.sessionId.PodScheduleTimeStamp").Is(err))
+}
+
+func TestTransitionSession(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", mock.Anything).Return(int64(100), nil)
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	_, err := sessionService.GetSessionLifecycle("sessionId")
+	assert.True(t, NewInvalidStoreKeyErr("SessionLifecycle.sessionId").Is(err))
+
+	// first seen session is created as Requested
+	applied, err := sessionService.TransitionSession("sessionId", Scheduled, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	applied, err = sessionService.TransitionSession("sessionId", Scheduled, "")
+	assert.Nil(t, err)
+	assert.False(t, applied)
+	// the pod is listed again
+	applied, err = sessionService.TransitionSession("sessionId", Requested, "PodAdded")
+	assert.Nil(t, err)
+	assert.False(t, applied)
+	applied, err = sessionService.TransitionSession("sessionId", Deleted, "PodRemoved")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	applied, err = sessionService.TransitionSession("sessionId", Ready, "")
+	assert.True(t, NewInvalidLifecycleTransitionErr("sessionId", Deleted, Ready).Is(err))
+	assert.False(t, applied)
+
+	lifecycle, err := sessionService.GetSessionLifecycle("sessionId")
+	assert.Nil(t, err)
+	assert.Equal(t, Deleted, lifecycle.State())
+	assert.Equal(t, 3, len(lifecycle.History()))
+	mockEventDispatcher.AssertNumberOfCalls(t, "Publish", 2)
+}
+
+func TestTransitionSession_Restored(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	lifecycleStore := NewMemoryLifecycleStore()
+	// recorded before the restart
+	assert.Nil(t, lifecycleStore.SetState(ctx, "sessionId", Ready, "", 100))
+	assert.Nil(t, lifecycleStore.SetPod(ctx, "sessionId", "pod-1", "viz"))
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), lifecycleStore, mockEventDispatcher)
+	// listed again after the restart
+	applied, err := sessionService.TransitionSession("sessionId", Requested, "PodAdded")
+	assert.Nil(t, err)
+	assert.False(t, applied)
+	applied, err = sessionService.TransitionSession("sessionId", Ready, "")
+	assert.Nil(t, err)
+	assert.False(t, applied)
+	mockEventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+
+	view, err := sessionService.GetSession("sessionId")
+	assert.Nil(t, err)
+	assert.Equal(t, SessionPod{PodName: "pod-1", Namespace: "viz"}, view.SessionPod)
+
+	// unknown sessions start as Requested and are recorded
+	applied, err = sessionService.TransitionSession("session-2", Scheduled, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-2",
+		PodName:   "pod-2",
+		Namespace: "viz",
+		Image:     "viz3d-2024.1",
+	}))
+	recorded, err := sessionService.ListRecordedSessions()
+	assert.Nil(t, err)
+	assert.Equal(t, Scheduled, recorded["session-2"].State)
+	assert.Equal(t, "pod-2", recorded["session-2"].PodName)
+	view, err = sessionService.GetSession("session-2")
+	assert.Nil(t, err)
+	assert.Equal(t, "viz3d-2024.1", view.Image)
+	assert.Equal(t, Ready, recorded["sessionId"].State)
+}
+
+// the recorded state follows the last applied transition
+func TestTransitionSession_Concurrent(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	lifecycleStore := NewMemoryLifecycleStore()
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), lifecycleStore, mockEventDispatcher)
+	var wg sync.WaitGroup
+	for _, to := range []LifecycleState{Scheduled, Initializing, Ready} {
+		wg.Add(1)
+		go func(to LifecycleState) {
+			defer wg.Done()
+			sessionService.TransitionSession("sessionId", to, "")
+		}(to)
+	}
+	wg.Wait()
+
+	lifecycle, err := sessionService.GetSessionLifecycle("sessionId")
+	assert.Nil(t, err)
+	recorded, err := sessionService.ListRecordedSessions()
+	assert.Nil(t, err)
+	assert.Equal(t, lifecycle.State(), recorded["sessionId"].State)
+}
+
+// blocks the lifecycle reads of a session until released
+type blockingLifecycleStore struct {
+	ILifecycleStore
+	sessionId string
+	blocked   chan struct{}
+	release   chan struct{}
+}
+
+func (s *blockingLifecycleStore) Get(ctx context.Context, sessionId string) (RecordedSession, bool, error) {
+	if sessionId == s.sessionId {
+		close(s.blocked)
+		<-s.release
+	}
+	return s.ILifecycleStore.Get(ctx, sessionId)
+}
+
+// the lifecycle store of a session is not accessed under the service lock
+func TestTransitionSession_StoreOutsideLock(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	lifecycleStore := &blockingLifecycleStore{
+		ILifecycleStore: NewMemoryLifecycleStore(),
+		sessionId:       "session-1",
+		blocked:         make(chan struct{}),
+		release:         make(chan struct{}),
+	}
+
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), lifecycleStore, mockEventDispatcher)
+	now := time.Unix(200, 0)
+	service.(*sessionService).now = func() time.Time { return now }
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		applied, err := service.TransitionSession("session-1", Scheduled, "")
+		assert.Nil(t, err)
+		assert.True(t, applied)
+	}()
+	<-lifecycleStore.blocked
+
+	applied, err := service.TransitionSession("session-2", Scheduled, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	_, err = service.GetSessionLifecycle("session-2")
+	assert.Nil(t, err)
+	close(lifecycleStore.release)
+	<-done
+
+	recorded, err := service.ListRecordedSessions()
+	assert.Nil(t, err)
+	assert.Equal(t, Scheduled, recorded["session-1"].State)
+	assert.Equal(t, int64(200), recorded["session-1"].UpdatedAt)
+	lifecycle, err := service.GetSessionLifecycle("session-1")
+	assert.Nil(t, err)
+	assert.Equal(t, now, lifecycle.History()[0].OccurredAt)
+}
+
+func TestPurgeSession(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", mock.Anything).Return(int64(100), nil)
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.timestamp_ttl_seconds").Return(3600)
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+	timestampStore := NewMemoryTimestampStore(time.Hour)
+
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, timestampStore, NewMemoryLifecycleStore(), mockEventDispatcher)
+	now := time.Now()
+	service.(*sessionService).now = func() time.Time { return now }
+	assert.Nil(t, service.SetPodScheduleTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "sessionId",
+		Timestamp: 100,
+	}))
+	applied, err := service.TransitionSession("sessionId", Deleted, "PodRemoved")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+
+	err = service.PurgeSession("sessionId")
+	assert.Nil(t, err)
+	_, err = service.GetSessionLifecycle("sessionId")
+	assert.True(t, NewInvalidStoreKeyErr("SessionLifecycle.sessionId").Is(err))
+	recorded, err := service.ListRecordedSessions()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(recorded))
+
+	// the deleted session is kept as long as its timestamps
+	view, err := service.GetSession("sessionId")
+	assert.Nil(t, err)
+	assert.Equal(t, Deleted, view.State)
+	assert.Equal(t, int64(100), view.Timestamps.PodScheduled)
+	assert.Equal(t, int64(100), view.Timestamps.Deleted)
+	views, err := service.ListSessions("", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(views))
+
+	now = now.Add(time.Hour)
+	_, err = service.GetSession("sessionId")
+	assert.True(t, NewInvalidStoreKeyErr("SessionLifecycle.sessionId").Is(err))
+	views, err = service.ListSessions("", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(views))
+}
+
+func TestPurgeNode(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockTimestampStore := &MockITimestampStore{}
+	mockTimestampStore.On("DeleteTimestamps", ctx, "Node.nodeName").Return(nil).Once()
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	err := sessionService.PurgeNode("nodeName")
+	assert.Nil(t, err)
+	mockTimestampStore.AssertNumberOfCalls(t, "DeleteTimestamps", 1)
+}
+
+// nodes deleted while the monitor was down
+func TestPruneNodes(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockTimestampStore := NewMockITimestampStore(t)
+	mockTimestampStore.On("ListKeys", ctx, "Node.").Return([]string{"Node.node-1", "Node.node-2", "Node.node-3"}, nil).Once()
+	mockTimestampStore.On("DeleteTimestamps", ctx, "Node.node-2").Return(nil).Once()
+
+	sessionService := NewSessionService(ctx, logger, &config.MockIConfig{}, &repository.MockIKVRepository{}, mockTimestampStore, NewMemoryLifecycleStore(), &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	assert.Nil(t, sessionService.PruneNodes([]string{"node-1", "node-3"}))
+}
+
+func TestGetSession(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", mock.Anything).Return(int64(900), nil).Once()
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	applied, err := sessionService.TransitionSession("session-2", Requested, "PodAdded")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-1",
+		PodName:   "pod-1",
+		Namespace: "viz",
+	}))
+	assert.Nil(t, sessionService.SetNodeProvisionTimeStamp(&SetNodeProvisionTimeStampActionPayload{
+		NodeName:  "node-1",
+		Timestamp: 100,
+	}))
+	assert.Nil(t, sessionService.SetPodCreateTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 380,
+		Source:    PodCreationSource,
+	}))
+	assert.Nil(t, sessionService.SetPodScheduleTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 400,
+	}))
+	assert.Nil(t, sessionService.SetVolumeClaimTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 390,
+		Source:    VolumeClaimCreationSource,
+	}))
+	assert.Nil(t, sessionService.SetVolumeBindTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 405,
+		Source:    InformerObservedSource,
+	}))
+	assert.Nil(t, sessionService.SetVolumeAttachTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 425,
+		Source:    InformerObservedSource,
+	}))
+	applied, err = sessionService.TransitionSession("session-1", Scheduled, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId:     "session-1",
+		NodeName:      "node-1",
+		PodInternalIp: "10.0.0.1",
+	}))
+	applied, err = sessionService.TransitionSession("session-1", Ready, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetPodInitializeTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 430,
+		Source:    PodConditionSource,
+	}))
+	assert.Nil(t, sessionService.SetReadyTimeStamp(&UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "session-1",
+		Timestamp: 460,
+		Source:    PodConditionSource,
+	}))
+	applied, err = sessionService.TransitionSession("session-1", Terminating, "")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	applied, err = sessionService.TransitionSession("session-1", Deleted, "PodRemoved")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+
+	view, err := sessionService.GetSession("session-1")
+	assert.Nil(t, err)
+	assert.Equal(t, SessionPod{PodName: "pod-1", Namespace: "viz", NodeName: "node-1", PodInternalIp: "10.0.0.1"}, view.SessionPod)
+	assert.Equal(t, Deleted, view.State)
+	assert.Equal(t, SessionTimestamps{NodeProvisioned: 100, PodCreated: 380, PodScheduled: 400, PodInitialized: 430, Ready: 460, Deleted: 900,
+		VolumeClaimed: 390, VolumeBound: 405, VolumeAttached: 425}, view.Timestamps)
+	assert.Equal(t, int64(300), *view.Durations.NodeProvisionToPodScheduled)
+	assert.Equal(t, int64(60), *view.Durations.PodScheduledToReady)
+	// the node was provisioned before the session was requested
+	assert.Equal(t, int64(80), *view.Durations.TimeToReady)
+	assert.Equal(t, int64(440), *view.Durations.ReadyToDeleted)
+	assert.Equal(t, int64(15), *view.Durations.VolumeBind)
+	assert.Equal(t, int64(25), *view.Durations.VolumeAttach)
+	assert.Equal(t, 5, len(view.History))
+
+	_, err = sessionService.GetSession("unknown")
+	assert.True(t, NewInvalidStoreKeyErr("SessionLifecycle.unknown").Is(err))
+
+	views, err := sessionService.ListSessions("", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(views))
+	assert.Equal(t, "session-1", views[0].SessionId)
+	assert.Equal(t, "session-2", views[1].SessionId)
+	assert.Equal(t, Requested, views[1].State)
+	assert.Nil(t, views[1].Durations.TimeToReady)
+
+	views, err = sessionService.ListSessions("", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(views))
+	assert.Equal(t, "session-1", views[0].SessionId)
+	views, err = sessionService.ListSessions("session-1", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(views))
+	assert.Equal(t, "session-2", views[0].SessionId)
+	views, err = sessionService.ListSessions("session-2", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(views))
+}
+
+func TestListSessions_InvalidLimit(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.timestamp_ttl_seconds").Return(nil)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, &repository.MockIKVRepository{}, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	for _, sessionId := range []string{"session-1", "session-2"} {
+		_, err := sessionService.TransitionSession(sessionId, Requested, "PodAdded")
+		assert.Nil(t, err)
+	}
+	// the default page instead of a panic
+	for _, limit := range []int{0, -1} {
+		views, err := sessionService.ListSessions("", limit)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(views), limit)
+	}
+}
+
+func TestListSessionsOnNode(t *testing.T) {
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", mock.Anything).Return(int64(100), nil)
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.timestamp_ttl_seconds").Return(3600)
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+	mockEventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	service := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	now := time.Now()
+	service.(*sessionService).now = func() time.Time { return now }
+	sessionIdsOn := func(nodeName string) []string {
+		views, err := service.ListSessionsOnNode(nodeName)
+		assert.Nil(t, err)
+		sessionIds := []string{}
+		for _, view := range views {
+			sessionIds = append(sessionIds, view.SessionId)
+		}
+		return sessionIds
+	}
+	for _, sessionId := range []string{"session-2", "session-1", "session-3"} {
+		_, err := service.TransitionSession(sessionId, Scheduled, "")
+		assert.Nil(t, err)
+		assert.Nil(t, service.SetSessionPod(&SetSessionPodActionPayload{
+			SessionId: sessionId,
+			PodName:   "pod-" + sessionId,
+			Namespace: "viz",
+			NodeName:  "node-1",
+		}))
+	}
+	assert.Nil(t, service.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-3",
+		NodeName:  "node-2",
+	}))
+	assert.Equal(t, []string{"session-1", "session-2"}, sessionIdsOn("node-1"))
+	assert.Equal(t, []string{"session-3"}, sessionIdsOn("node-2"))
+
+	// deleted sessions stay on their node until they expire
+	_, err := service.TransitionSession("session-1", Deleted, "PodRemoved")
+	assert.Nil(t, err)
+	assert.Nil(t, service.PurgeSession("session-1"))
+	views, err := service.ListSessionsOnNode("node-1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(views))
+	assert.Equal(t, Deleted, views[0].State)
+
+	now = now.Add(2 * time.Hour)
+	assert.Nil(t, service.PurgeSession("session-2"))
+	assert.Equal(t, []string{"session-2"}, sessionIdsOn("node-1"))
+	assert.NotContains(t, service.(*sessionService).nodeSessions["node-1"], "session-1")
+	assert.Empty(t, sessionIdsOn("node-3"))
+}
+
+func TestAddSessionWarning(t *testing.T) {
+	mockSessionFailedStreamKey := "session_failed_stream_key"
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", mock.Anything).Return(int64(900), nil)
+	mockKVRepository.On("AddStreamEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("streamId-1", nil).Once()
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_warnings_limit").Return(2)
+	mockConfig.On("Get", "app.session_failed_stream_key").Return(mockSessionFailedStreamKey).Once()
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	mockKVRepository.On("SetIfNotExists", mock.Anything, mock.Anything).Return(true, nil).Once()
+	mockKVRepository.On("Set", mock.Anything, mock.Anything).Return("OK", nil).Once()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, mockKVRepository, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	applied, err := sessionService.TransitionSession("session-1", Requested, "PodAdded")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-1",
+		PodName:   "pod-1",
+		Namespace: "viz",
+	}))
+	sessionId, exist := sessionService.FindSessionByPod("viz", "pod-1")
+	assert.True(t, exist)
+	assert.Equal(t, "session-1", sessionId)
+	_, exist = sessionService.FindSessionByPod("default", "pod-1")
+	assert.False(t, exist)
+
+	for _, warning := range []SessionWarning{
+		{Reason: "FailedScheduling", Object: "Pod/pod-1", Count: 1, LastSeen: 100},
+		{Reason: "FailedMount", Object: "Pod/pod-1", Count: 1, LastSeen: 110},
+		// same reason of the same object replaces the previous warning
+		{Reason: "FailedScheduling", Object: "Pod/pod-1", Count: 4, LastSeen: 130},
+		{Reason: "BackOff", Object: "Pod/pod-1", Count: 1, LastSeen: 120},
+	} {
+		assert.Nil(t, sessionService.AddSessionWarning(&AddSessionWarningActionPayload{
+			SessionId: "session-1",
+			Warning:   warning,
+		}))
+	}
+	view, err := sessionService.GetSession("session-1")
+	assert.Nil(t, err)
+	assert.Equal(t, []SessionWarning{
+		{Reason: "BackOff", Object: "Pod/pod-1", Count: 1, LastSeen: 120},
+		{Reason: "FailedScheduling", Object: "Pod/pod-1", Count: 4, LastSeen: 130},
+	}, view.Warnings)
+
+	payload := &SetSessionFailedActionPayload{
+		SessionId: "session-1",
+		CallerId:  "Session-monitor-service",
+		Reason:    "Unschedulable",
+	}
+	_, err = sessionService.SetSessionFailed(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, view.Warnings, payload.Warnings)
+	mockKVRepository.AssertCalled(t, "AddStreamEvent", ctx, mockSessionFailedStreamKey, "*", mock.MatchedBy(func(values []interface{}) bool {
+		return strings.Contains(values[3].(string), `"warnings":[{"reason":"BackOff"`)
+	}))
+}
+
+func TestFindSessionByPod(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+
+	sessionService := NewSessionService(ctx, logger, mockConfig, &repository.MockIKVRepository{}, NewMemoryTimestampStore(time.Hour), NewMemoryLifecycleStore(), mockEventDispatcher)
+	applied, err := sessionService.TransitionSession("session-1", Requested, "PodAdded")
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-1",
+		PodName:   "pod-1",
+		Namespace: "viz",
+	}))
+	// the pod of the session is recreated
+	assert.Nil(t, sessionService.SetSessionPod(&SetSessionPodActionPayload{
+		SessionId: "session-1",
+		PodName:   "pod-2",
+	}))
+	_, exist := sessionService.FindSessionByPod("viz", "pod-1")
+	assert.False(t, exist)
+	sessionId, exist := sessionService.FindSessionByPod("viz", "pod-2")
+	assert.True(t, exist)
+	assert.Equal(t, "session-1", sessionId)
+
+	assert.Nil(t, sessionService.PurgeSession("session-1"))
+	_, exist = sessionService.FindSessionByPod("viz", "pod-2")
+	assert.False(t, exist)
 }
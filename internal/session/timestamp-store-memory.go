@@ -0,0 +1,114 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type timestampEntry struct {
+	timestamps map[string]Timestamp
+	expiresAt  time.Time
+}
+
+type memoryTimestampStore struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	entries map[string]*timestampEntry
+}
+
+var _ ITimestampStore = (*memoryTimestampStore)(nil)
+
+// every write renews the ttl of the key, ttl 0 keeps entries until deleted, node keys never expire
+func NewMemoryTimestampStore(ttl time.Duration) ITimestampStore {
+	return &memoryTimestampStore{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*timestampEntry{},
+	}
+}
+
+func (s *memoryTimestampStore) SetTimestamp(ctx context.Context, key string, field string, timestamp Timestamp) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evictExpired()
+	entry, exist := s.entries[key]
+	if !exist {
+		entry = &timestampEntry{
+			timestamps: map[string]Timestamp{},
+		}
+		s.entries[key] = entry
+	}
+	entry.timestamps[field] = timestamp
+	if ttl := timestampTTL(key, s.ttl); ttl > 0 {
+		entry.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *memoryTimestampStore) GetTimestamp(ctx context.Context, key string, field string) (Timestamp, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if entry := s.get(key); entry != nil {
+		if timestamp, exist := entry.timestamps[field]; exist {
+			return timestamp, nil
+		}
+	}
+	return Timestamp{}, NewInvalidStoreKeyErr(fmt.Sprintf("%s.%s", key, field))
+}
+
+func (s *memoryTimestampStore) GetTimestamps(ctx context.Context, key string) (map[string]Timestamp, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	timestamps := map[string]Timestamp{}
+	if entry := s.get(key); entry != nil {
+		for field, timestamp := range entry.timestamps {
+			timestamps[field] = timestamp
+		}
+	}
+	return timestamps, nil
+}
+
+func (s *memoryTimestampStore) DeleteTimestamps(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryTimestampStore) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys := []string{}
+	for key, entry := range s.entries {
+		if strings.HasPrefix(key, prefix) && !s.isExpired(entry) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *memoryTimestampStore) get(key string) *timestampEntry {
+	entry, exist := s.entries[key]
+	if !exist || s.isExpired(entry) {
+		return nil
+	}
+	return entry
+}
+
+func (s *memoryTimestampStore) isExpired(entry *timestampEntry) bool {
+	return !entry.expiresAt.IsZero() && !s.now().Before(entry.expiresAt)
+}
+
+func (s *memoryTimestampStore) evictExpired() {
+	for key, entry := range s.entries {
+		if s.isExpired(entry) {
+			delete(s.entries, key)
+		}
+	}
+}
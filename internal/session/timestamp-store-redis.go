@@ -0,0 +1,94 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+)
+
+// the source of a timestamp is kept next to it in the same hash
+const timestampSourceSuffix = "Source"
+
+// one redis hash per key, fields are the timestamp names
+type redisTimestampStore struct {
+	kvRepo repository.IKVRepository
+	prefix string
+	ttl    time.Duration
+}
+
+var _ ITimestampStore = (*redisTimestampStore)(nil)
+
+func NewRedisTimestampStore(kvRepo repository.IKVRepository, prefix string, ttl time.Duration) ITimestampStore {
+	return &redisTimestampStore{
+		kvRepo: kvRepo,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (s *redisTimestampStore) SetTimestamp(ctx context.Context, key string, field string, timestamp Timestamp) error {
+	_, err := s.kvRepo.SetHash(ctx, &repository.Object{
+		Key: s.hashKey(key),
+		Payload: map[string]interface{}{
+			field:                         timestamp.Value,
+			field + timestampSourceSuffix: string(timestamp.Source),
+		},
+		Expiration: timestampTTL(key, s.ttl),
+	})
+	return err
+}
+
+func (s *redisTimestampStore) GetTimestamp(ctx context.Context, key string, field string) (Timestamp, error) {
+	timestamps, err := s.GetTimestamps(ctx, key)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	timestamp, exist := timestamps[field]
+	if !exist {
+		return Timestamp{}, NewInvalidStoreKeyErr(fmt.Sprintf("%s.%s", key, field))
+	}
+	return timestamp, nil
+}
+
+// fields which are not integers are skipped
+func (s *redisTimestampStore) GetTimestamps(ctx context.Context, key string) (map[string]Timestamp, error) {
+	values, err := s.kvRepo.GetHash(ctx, s.hashKey(key))
+	if err != nil {
+		return nil, err
+	}
+	timestamps := map[string]Timestamp{}
+	for field, value := range values {
+		if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+			timestamps[field] = Timestamp{
+				Value:  timestamp,
+				Source: TimestampSource(values[field+timestampSourceSuffix]),
+			}
+		}
+	}
+	return timestamps, nil
+}
+
+func (s *redisTimestampStore) DeleteTimestamps(ctx context.Context, key string) error {
+	_, err := s.kvRepo.DeleteKeys(ctx, s.hashKey(key))
+	return err
+}
+
+func (s *redisTimestampStore) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	hashKeys, err := s.kvRepo.ScanKeys(ctx, s.hashKey(prefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(hashKeys))
+	for _, hashKey := range hashKeys {
+		keys = append(keys, strings.TrimPrefix(hashKey, s.hashKey("")))
+	}
+	return keys, nil
+}
+
+func (s *redisTimestampStore) hashKey(key string) string {
+	return fmt.Sprintf("%s.%s", s.prefix, key)
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTimestampKeyPrefix = "SessionMonitor.Timestamps"
+	defaultTimestampTTL       = 24 * time.Hour
+	nodeTimestampKeyPrefix    = "Node."
+)
+
+// timestamp fields
+const (
+	NodeProvisionTimeStamp = "NodeProvisionTimeStamp"
+	PodCreateTimeStamp     = "PodCreateTimeStamp"
+	PodScheduleTimeStamp   = "PodScheduleTimeStamp"
+	PodInitializeTimeStamp = "PodInitializeTimeStamp"
+	ReadyTimeStamp         = "ReadyTimeStamp"
+	DeleteTimeStamp        = "DeleteTimeStamp"
+	// volumes of the session pod, latest of its persistent volume claims
+	VolumeClaimTimeStamp  = "VolumeClaimTimeStamp"
+	VolumeBindTimeStamp   = "VolumeBindTimeStamp"
+	VolumeAttachTimeStamp = "VolumeAttachTimeStamp"
+)
+
+type TimestampSource string
+
+const (
+	PodConditionSource        TimestampSource = "PodCondition"
+	PodCreationSource         TimestampSource = "PodCreationTimestamp"
+	NodeCreationSource        TimestampSource = "NodeCreationTimestamp"
+	VolumeClaimCreationSource TimestampSource = "VolumeClaimCreationTimestamp"
+	// pvc and volume attachment status carry no transition time, the informer saw the change
+	InformerObservedSource TimestampSource = "InformerObserved"
+	// redis TIME when the update was handled, late if the monitor was down or lagging
+	ServerTimeSource TimestampSource = "ServerTime"
+)
+
+// unix seconds, source is empty for timestamps recorded before sources were tracked
+type Timestamp struct {
+	Value  int64           `json:"value"`
+	Source TimestampSource `json:"source,omitempty"`
+}
+
+func SessionTimestampKey(sessionId string) string {
+	return fmt.Sprintf("Session.%s", sessionId)
+}
+
+func NodeTimestampKey(nodeName string) string {
+	return nodeTimestampKeyPrefix + nodeName
+}
+
+// nodes are provisioned once and live for days, their timestamps are kept until the node is purged,
+// or pruned once the node informer synced when its delete event was missed
+func timestampTTL(key string, ttl time.Duration) time.Duration {
+	if strings.HasPrefix(key, nodeTimestampKeyPrefix) {
+		return 0
+	}
+	return ttl
+}
+
+// timestamps grouped by owner key, e.g. Session.<sessionId> or Node.<nodeName>
+//
+//go:generate mockery --name ITimestampStore
+type ITimestampStore interface {
+	SetTimestamp(ctx context.Context, key string, field string, timestamp Timestamp) error
+	GetTimestamp(ctx context.Context, key string, field string) (Timestamp, error)
+	GetTimestamps(ctx context.Context, key string) (map[string]Timestamp, error)
+	DeleteTimestamps(ctx context.Context, key string) error
+	// stored keys starting with the prefix, e.g. Node.
+	ListKeys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// app.timestamp_store: redis (default) survives restarts and is shared between replicas, memory is per process.
+// app.timestamp_ttl_seconds applies to session timestamps
+func NewTimestampStore(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository) ITimestampStore {
+	ttl := config.GetSeconds(cfg, "app.timestamp_ttl_seconds", defaultTimestampTTL)
+	storeType := config.GetString(cfg, "app.timestamp_store", "redis")
+	logger.Sugar().Infow("NewTimestampStore", "Type", storeType, "TTL", ttl)
+	if storeType == "memory" {
+		return NewMemoryTimestampStore(ttl)
+	}
+	return NewRedisTimestampStore(kvRepo, config.GetString(cfg, "app.timestamp_key_prefix", defaultTimestampKeyPrefix), ttl)
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+)
+
+func TestNewTimestampStore(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.timestamp_store").Return("memory").Once()
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	_, ok := NewTimestampStore(logger, mockConfig, &repository.MockIKVRepository{}).(*memoryTimestampStore)
+	assert.True(t, ok, "memory store is configured")
+
+	mockConfig = &config.MockIConfig{}
+	mockConfig.On("Get", mock.Anything).Return(nil)
+	store, ok := NewTimestampStore(logger, mockConfig, &repository.MockIKVRepository{}).(*redisTimestampStore)
+	assert.True(t, ok, "redis store is the default")
+	assert.Equal(t, defaultTimestampKeyPrefix, store.prefix)
+	assert.Equal(t, defaultTimestampTTL, store.ttl)
+}
+
+func TestMemoryTimestampStore(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Now()
+	store := NewMemoryTimestampStore(time.Minute).(*memoryTimestampStore)
+	store.now = func() time.Time { return now }
+
+	assert.Nil(t, store.SetTimestamp(ctx, "Session.sessionId", PodScheduleTimeStamp, Timestamp{Value: 100, Source: PodConditionSource}))
+	assert.Nil(t, store.SetTimestamp(ctx, "Session.sessionId", ReadyTimeStamp, Timestamp{Value: 200, Source: ServerTimeSource}))
+	timestamp, err := store.GetTimestamp(ctx, "Session.sessionId", PodScheduleTimeStamp)
+	assert.Nil(t, err)
+	assert.Equal(t, Timestamp{Value: 100, Source: PodConditionSource}, timestamp)
+	timestamps, err := store.GetTimestamps(ctx, "Session.sessionId")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Timestamp{
+		PodScheduleTimeStamp: {Value: 100, Source: PodConditionSource},
+		ReadyTimeStamp:       {Value: 200, Source: ServerTimeSource},
+	}, timestamps)
+
+	// expired entries are neither readable nor kept
+	now = now.Add(time.Minute)
+	_, err = store.GetTimestamp(ctx, "Session.sessionId", PodScheduleTimeStamp)
+	assert.True(t, NewInvalidStoreKeyErr("Session.sessionId.PodScheduleTimeStamp").Is(err))
+	assert.Nil(t, store.SetTimestamp(ctx, "Node.nodeName", NodeProvisionTimeStamp, Timestamp{Value: 300}))
+	assert.Equal(t, 1, len(store.entries))
+
+	// node timestamps are kept until the node is purged
+	now = now.Add(48 * time.Hour)
+	timestamp, err = store.GetTimestamp(ctx, "Node.nodeName", NodeProvisionTimeStamp)
+	assert.Nil(t, err)
+	assert.Equal(t, Timestamp{Value: 300}, timestamp)
+	assert.Nil(t, store.SetTimestamp(ctx, "Session.sessionId", ReadyTimeStamp, Timestamp{Value: 400}))
+	keys, err := store.ListKeys(ctx, "Node.")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Node.nodeName"}, keys)
+	assert.Nil(t, store.DeleteTimestamps(ctx, "Node.nodeName"))
+	timestamps, err = store.GetTimestamps(ctx, "Node.nodeName")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(timestamps))
+}
+
+func TestRedisTimestampStore(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("SetHash", ctx, &repository.Object{
+		Key: "prefix.Session.sessionId",
+		Payload: map[string]interface{}{
+			PodScheduleTimeStamp:                         int64(100),
+			PodScheduleTimeStamp + timestampSourceSuffix: "PodCondition",
+		},
+		Expiration: time.Hour,
+	}).Return(int64(1), nil).Once()
+	mockKVRepository.On("GetHash", ctx, "prefix.Session.sessionId").Return(map[string]string{
+		PodScheduleTimeStamp:                         "100",
+		PodScheduleTimeStamp + timestampSourceSuffix: "PodCondition",
+		// recorded before sources were tracked
+		NodeProvisionTimeStamp: "50",
+		"bogus":                "not a timestamp",
+	}, nil)
+	mockKVRepository.On("SetHash", ctx, &repository.Object{
+		Key: "prefix.Node.nodeName",
+		Payload: map[string]interface{}{
+			NodeProvisionTimeStamp:                         int64(50),
+			NodeProvisionTimeStamp + timestampSourceSuffix: "NodeCreationTimestamp",
+		},
+	}).Return(int64(1), nil).Once()
+	mockKVRepository.On("DeleteKeys", ctx, "prefix.Session.sessionId").Return(int64(1), nil).Once()
+	mockKVRepository.On("ScanKeys", ctx, "prefix.Node.*").Return([]string{"prefix.Node.nodeName"}, nil).Once()
+
+	store := NewRedisTimestampStore(mockKVRepository, "prefix", time.Hour)
+	assert.Nil(t, store.SetTimestamp(ctx, "Session.sessionId", PodScheduleTimeStamp, Timestamp{Value: 100, Source: PodConditionSource}))
+	timestamp, err := store.GetTimestamp(ctx, "Session.sessionId", PodScheduleTimeStamp)
+	assert.Nil(t, err)
+	assert.Equal(t, Timestamp{Value: 100, Source: PodConditionSource}, timestamp)
+	timestamps, err := store.GetTimestamps(ctx, "Session.sessionId")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Timestamp{
+		PodScheduleTimeStamp:   {Value: 100, Source: PodConditionSource},
+		NodeProvisionTimeStamp: {Value: 50},
+	}, timestamps)
+	_, err = store.GetTimestamp(ctx, "Session.sessionId", ReadyTimeStamp)
+	assert.True(t, NewInvalidStoreKeyErr("Session.sessionId.ReadyTimeStamp").Is(err))
+	assert.Nil(t, store.SetTimestamp(ctx, "Node.nodeName", NodeProvisionTimeStamp, Timestamp{Value: 50, Source: NodeCreationSource}))
+	keys, err := store.ListKeys(ctx, "Node.")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Node.nodeName"}, keys)
+	assert.Nil(t, store.DeleteTimestamps(ctx, "Session.sessionId"))
+	mockKVRepository.AssertNumberOfCalls(t, "DeleteKeys", 1)
+	mockKVRepository.AssertExpectations(t)
+}
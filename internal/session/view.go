@@ -0,0 +1,88 @@
+package session
+
+// pod details of a session, empty fields are unknown yet
+type SessionPod struct {
+	PodName       string `json:"podName,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	NodeName      string `json:"nodeName,omitempty"`
+	PodInternalIp string `json:"podInternalIp,omitempty"`
+	// value of the session image label, the node module checks it against the gpu driver of the node
+	Image string `json:"image,omitempty"`
+}
+
+// unix seconds, 0 when not reached yet
+type SessionTimestamps struct {
+	NodeProvisioned int64 `json:"nodeProvisioned,omitempty"`
+	PodCreated      int64 `json:"podCreated,omitempty"`
+	PodScheduled    int64 `json:"podScheduled,omitempty"`
+	PodInitialized  int64 `json:"podInitialized,omitempty"`
+	Ready           int64 `json:"ready,omitempty"`
+	Deleted         int64 `json:"deleted,omitempty"`
+	VolumeClaimed   int64 `json:"volumeClaimed,omitempty"`
+	VolumeBound     int64 `json:"volumeBound,omitempty"`
+	VolumeAttached  int64 `json:"volumeAttached,omitempty"`
+}
+
+// seconds, nil when one of the timestamps is missing
+type SessionDurations struct {
+	NodeProvisionToPodScheduled *int64 `json:"nodeProvisionToPodScheduledSeconds,omitempty"`
+	PodScheduledToReady         *int64 `json:"podScheduledToReadySeconds,omitempty"`
+	// from the pod being created, a warm node was provisioned long before the session was requested
+	TimeToReady    *int64 `json:"timeToReadySeconds,omitempty"`
+	ReadyToDeleted *int64 `json:"readyToDeletedSeconds,omitempty"`
+	VolumeBind     *int64 `json:"volumeBindSeconds,omitempty"`
+	// volumes are attached to the node once the pod is scheduled
+	VolumeAttach *int64 `json:"volumeAttachSeconds,omitempty"`
+}
+
+// latest occurrence of a warning the cluster reported for the session pod, e.g. FailedScheduling or BackOff.
+// unix seconds
+type SessionWarning struct {
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+	Object    string `json:"object,omitempty"`
+	Count     int32  `json:"count,omitempty"`
+	FirstSeen int64  `json:"firstSeen,omitempty"`
+	LastSeen  int64  `json:"lastSeen"`
+}
+
+// read model answering "why did my session take 6 minutes"
+type SessionView struct {
+	SessionId string `json:"sessionId"`
+	SessionPod
+	State      LifecycleState        `json:"state"`
+	Timestamps SessionTimestamps     `json:"timestamps"`
+	Durations  SessionDurations      `json:"durations"`
+	History    []LifecycleTransition `json:"history"`
+	Warnings   []SessionWarning      `json:"warnings,omitempty"`
+}
+
+func NewSessionView(lifecycle *SessionLifecycle, pod SessionPod, timestamps SessionTimestamps) *SessionView {
+	return &SessionView{
+		SessionId:  lifecycle.ID(),
+		SessionPod: pod,
+		State:      lifecycle.State(),
+		Timestamps: timestamps,
+		Durations:  NewSessionDurations(timestamps),
+		History:    lifecycle.History(),
+	}
+}
+
+func NewSessionDurations(timestamps SessionTimestamps) SessionDurations {
+	return SessionDurations{
+		NodeProvisionToPodScheduled: elapsed(timestamps.NodeProvisioned, timestamps.PodScheduled),
+		PodScheduledToReady:         elapsed(timestamps.PodScheduled, timestamps.Ready),
+		TimeToReady:                 elapsed(timestamps.PodCreated, timestamps.Ready),
+		ReadyToDeleted:              elapsed(timestamps.Ready, timestamps.Deleted),
+		VolumeBind:                  elapsed(timestamps.VolumeClaimed, timestamps.VolumeBound),
+		VolumeAttach:                elapsed(timestamps.PodScheduled, timestamps.VolumeAttached),
+	}
+}
+
+func elapsed(from int64, to int64) *int64 {
+	if from == 0 || to == 0 {
+		return nil
+	}
+	seconds := to - from
+	return &seconds
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/xcheng85/session-monitor-k8s/k8s/internal/handler"
 )
 
@@ -25,6 +26,7 @@ func (router *K8sRouter) Register() error {
 	r := chi.NewRouter()
 	r.Get("/livenessProbe", router.handler.GetLivenessProbe)
 	r.Get("/readinessProbe", router.handler.GetReadinessProbe)
+	r.Method("GET", "/metrics", promhttp.Handler())
 	// mounting path must be unique
 	router.mux.Mount("/", r)
 	return nil
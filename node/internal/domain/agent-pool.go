@@ -0,0 +1,113 @@
+package domain
+
+const AgentPoolAggregate = "nodes.AgentPoolAggregate"
+
+// a node of the agent pool as last seen
+type AgentPoolMember struct {
+	Labels        map[string]string `json:"labels"`
+	DriverVersion string            `json:"driverVersion,omitempty"`
+	// unix seconds, creation time of the node
+	ProvisionTimestamp int64 `json:"provisionTimestamp,omitempty"`
+}
+
+// nodes sharing the agentpool label. the summary fields are derived from the members on every change
+type AgentPool struct {
+	Name      string                     `json:"name"`
+	Members   map[string]AgentPoolMember `json:"members"`
+	NodeCount int                        `json:"nodeCount"`
+	// labels every member has with the same value
+	CommonLabels map[string]string `json:"commonLabels"`
+	// member count per driver version, members without driver labels are not counted
+	DriverVersions   map[string]int `json:"driverVersions"`
+	MinDriverVersion string         `json:"minDriverVersion,omitempty"`
+	MaxDriverVersion string         `json:"maxDriverVersion,omitempty"`
+	// unix seconds
+	FirstProvisioned int64 `json:"firstProvisioned,omitempty"`
+	LastProvisioned  int64 `json:"lastProvisioned,omitempty"`
+}
+
+func NewAgentPool(name string) *AgentPool {
+	pool := &AgentPool{
+		Name:    name,
+		Members: map[string]AgentPoolMember{},
+	}
+	pool.summarize()
+	return pool
+}
+
+// updates do not carry the provision time, the known one is kept
+func (p *AgentPool) SetMember(node *Node) {
+	member := AgentPoolMember{
+		Labels:             map[string]string{},
+		DriverVersion:      node.DriverVersion,
+		ProvisionTimestamp: node.CreationTimestamp,
+	}
+	if node.Labels != nil {
+		for key, value := range *node.Labels {
+			member.Labels[key] = value
+		}
+	}
+	if member.ProvisionTimestamp == 0 {
+		member.ProvisionTimestamp = p.Members[node.Name].ProvisionTimestamp
+	}
+	p.Members[node.Name] = member
+	p.summarize()
+}
+
+// false when the node is not a member
+func (p *AgentPool) RemoveMember(nodeName string) bool {
+	if _, exist := p.Members[nodeName]; !exist {
+		return false
+	}
+	delete(p.Members, nodeName)
+	p.summarize()
+	return true
+}
+
+// labels every kept member has with the same value, false when no member is kept
+func (p AgentPool) CommonLabelsOf(keep func(AgentPoolMember) bool) (map[string]string, bool) {
+	labels, kept := map[string]string{}, false
+	for _, member := range p.Members {
+		if !keep(member) {
+			continue
+		}
+		if !kept {
+			for key, value := range member.Labels {
+				labels[key] = value
+			}
+			kept = true
+		}
+		for key, value := range labels {
+			if member.Labels[key] != value {
+				delete(labels, key)
+			}
+		}
+	}
+	return labels, kept
+}
+
+// the summary maps are replaced, never changed in place, copies of the pool can read them safely
+func (p *AgentPool) summarize() {
+	p.NodeCount = len(p.Members)
+	p.CommonLabels, _ = p.CommonLabelsOf(func(AgentPoolMember) bool { return true })
+	p.DriverVersions = map[string]int{}
+	p.MinDriverVersion, p.MaxDriverVersion = "", ""
+	p.FirstProvisioned, p.LastProvisioned = 0, 0
+	for _, member := range p.Members {
+		if version := member.DriverVersion; version != "" {
+			p.DriverVersions[version]++
+			if p.MinDriverVersion == "" || CompareDriverVersions(version, p.MinDriverVersion) < 0 {
+				p.MinDriverVersion = version
+			}
+			if p.MaxDriverVersion == "" || CompareDriverVersions(version, p.MaxDriverVersion) > 0 {
+				p.MaxDriverVersion = version
+			}
+		}
+		if timestamp := member.ProvisionTimestamp; timestamp > 0 {
+			if p.FirstProvisioned == 0 || timestamp < p.FirstProvisioned {
+				p.FirstProvisioned = timestamp
+			}
+			p.LastProvisioned = max(p.LastProvisioned, timestamp)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentPool(t *testing.T) {
+	pool := NewAgentPool("viz3d")
+	assert.Equal(t, 0, pool.NodeCount)
+	assert.Empty(t, pool.CommonLabels)
+
+	labels1 := map[string]string{"agentpool": "viz3d", "accelerator": "nvidia", "kubernetes.io/hostname": "node-1"}
+	pool.SetMember(&Node{Name: "node-1", DriverVersion: "535.104.05", Labels: &labels1, CreationTimestamp: 100, ReadyTimestamp: 160})
+	labels2 := map[string]string{"agentpool": "viz3d", "accelerator": "nvidia", "kubernetes.io/hostname": "node-2"}
+	pool.SetMember(&Node{Name: "node-2", DriverVersion: "525.147.05", Labels: &labels2, CreationTimestamp: 300})
+	labels3 := map[string]string{"agentpool": "viz3d", "accelerator": "nvidia", "kubernetes.io/hostname": "node-3"}
+	pool.SetMember(&Node{Name: "node-3", DriverVersion: "535.104.05", Labels: &labels3, CreationTimestamp: 200})
+
+	assert.Equal(t, 3, pool.NodeCount)
+	assert.Equal(t, map[string]string{"agentpool": "viz3d", "accelerator": "nvidia"}, pool.CommonLabels)
+	assert.Equal(t, map[string]int{"535.104.05": 2, "525.147.05": 1}, pool.DriverVersions)
+	assert.Equal(t, "525.147.05", pool.MinDriverVersion)
+	assert.Equal(t, "535.104.05", pool.MaxDriverVersion)
+	assert.Equal(t, int64(100), pool.FirstProvisioned)
+	assert.Equal(t, int64(300), pool.LastProvisioned)
+
+	// updates keep the known provision time
+	labels1["accelerator"] = "none"
+	pool.SetMember(&Node{Name: "node-1", DriverVersion: "535.104.05", Labels: &labels1})
+	assert.Equal(t, int64(100), pool.Members["node-1"].ProvisionTimestamp)
+	assert.Equal(t, map[string]string{"agentpool": "viz3d"}, pool.CommonLabels)
+
+	labels, kept := pool.CommonLabelsOf(func(member AgentPoolMember) bool { return member.DriverVersion == "525.147.05" })
+	assert.True(t, kept)
+	assert.Equal(t, labels2, labels)
+	_, kept = pool.CommonLabelsOf(func(member AgentPoolMember) bool { return false })
+	assert.False(t, kept)
+
+	assert.True(t, pool.RemoveMember("node-2"))
+	assert.False(t, pool.RemoveMember("node-2"))
+	assert.Equal(t, 2, pool.NodeCount)
+	assert.Equal(t, map[string]int{"535.104.05": 2}, pool.DriverVersions)
+	assert.Equal(t, "535.104.05", pool.MinDriverVersion)
+	assert.Equal(t, int64(200), pool.LastProvisioned)
+}
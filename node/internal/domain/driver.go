@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compares dotted driver versions part by part as numbers, 535.104.05 is newer than 535.54.03.
+// a missing part counts as 0, a non numeric part as well
+func CompareDriverVersions(a string, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		aPart, bPart := driverVersionPart(aParts, i), driverVersionPart(bParts, i)
+		if aPart < bPart {
+			return -1
+		}
+		if aPart > bPart {
+			return 1
+		}
+	}
+	return 0
+}
+
+func driverVersionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	part, _ := strconv.Atoi(parts[i])
+	return part
+}
+
+// a session whose image needs a newer driver than the one of its node
+type DriverIncompatibleSession struct {
+	NodeName         string `json:"nodeName"`
+	Image            string `json:"image"`
+	DriverVersion    string `json:"driverVersion"`
+	MinDriverVersion string `json:"minDriverVersion"`
+	Since            int64  `json:"since"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareDriverVersions(t *testing.T) {
+	assert.Equal(t, 1, CompareDriverVersions("535.104.05", "535.54.03"))
+	assert.Equal(t, -1, CompareDriverVersions("525.147.05", "535.54.03"))
+	assert.Equal(t, 0, CompareDriverVersions("535.104.05", "535.104.5"))
+	assert.Equal(t, 0, CompareDriverVersions("535", "535.0.0"))
+	assert.Equal(t, -1, CompareDriverVersions("", "470.0.0"))
+}
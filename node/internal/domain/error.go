@@ -25,3 +25,45 @@ func (s *BadNodeLabelErr) Is(target error) bool {
 func NewBadNodeLabelErr(label *map[string]string) *BadNodeLabelErr {
 	return &BadNodeLabelErr{label}
 }
+
+type InvalidNodeSelectorErr struct {
+	name   string
+	reason string
+}
+
+func (r *InvalidNodeSelectorErr) Error() string {
+	return fmt.Sprintf("node selector %s is invalid: %s", r.name, r.reason)
+}
+
+func (s *InvalidNodeSelectorErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidNodeSelectorErr)
+	if !ok {
+		return false
+	}
+	return s.name == targetErr.name && s.reason == targetErr.reason
+}
+
+func NewInvalidNodeSelectorErr(name string, reason string) *InvalidNodeSelectorErr {
+	return &InvalidNodeSelectorErr{name, reason}
+}
+
+type InvalidDriverCompatibilityErr struct {
+	image  string
+	reason string
+}
+
+func (r *InvalidDriverCompatibilityErr) Error() string {
+	return fmt.Sprintf("driver compatibility of image %s is invalid: %s", r.image, r.reason)
+}
+
+func (s *InvalidDriverCompatibilityErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidDriverCompatibilityErr)
+	if !ok {
+		return false
+	}
+	return s.image == targetErr.image && s.reason == targetErr.reason
+}
+
+func NewInvalidDriverCompatibilityErr(image string, reason string) *InvalidDriverCompatibilityErr {
+	return &InvalidDriverCompatibilityErr{image, reason}
+}
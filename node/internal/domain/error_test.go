@@ -10,3 +10,17 @@ func TestBadNodeLabelErr(t *testing.T) {
 	err := NewBadNodeLabelErr(nil)
 	assert.Equal(t, "label: *map[string]string is incorrect", err.Error(), "error message does not match")
 }
+
+func TestInvalidNodeSelectorErr(t *testing.T) {
+	err := NewInvalidNodeSelectorErr("gpu", "name is duplicated")
+	assert.Equal(t, "node selector gpu is invalid: name is duplicated", err.Error())
+	assert.ErrorIs(t, err, NewInvalidNodeSelectorErr("gpu", "name is duplicated"))
+	assert.NotErrorIs(t, err, NewInvalidNodeSelectorErr("cpu", "name is duplicated"))
+}
+
+func TestInvalidDriverCompatibilityErr(t *testing.T) {
+	err := NewInvalidDriverCompatibilityErr("viz3d", "min_driver_version is missing")
+	assert.Equal(t, "driver compatibility of image viz3d is invalid: min_driver_version is missing", err.Error())
+	assert.ErrorIs(t, err, NewInvalidDriverCompatibilityErr("viz3d", "min_driver_version is missing"))
+	assert.NotErrorIs(t, err, NewInvalidDriverCompatibilityErr("viz2d", "min_driver_version is missing"))
+}
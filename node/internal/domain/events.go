@@ -1,13 +1,20 @@
 package domain
 
 const (
-	NodeNilEvent                 = "NodeNilEvent"
-	NodeInformerErrorEvent       = "NodeInformerErrorEvent"
-	NodeAddEvent                 = "NodeAddEvent"
-	NodeDeleteEvent              = "NodeDeleteEvent"
-	NodeUpdateEvent              = "NodeUpdateEvent"
-	NodeRecordNodeProvisionEvent = "NodeRecordNodeProvisionEvent"
-	NodeUpdateLabelsCacheEvent   = "NodeUpdateLabelsCacheEvent"
+	NodeNilEvent                  = "NodeNilEvent"
+	NodeInformerErrorEvent        = "NodeInformerErrorEvent"
+	NodeAddEvent                  = "NodeAddEvent"
+	NodeDeleteEvent               = "NodeDeleteEvent"
+	NodeUpdateEvent               = "NodeUpdateEvent"
+	NodeRecordNodeProvisionEvent  = "NodeRecordNodeProvisionEvent"
+	NodeUpdateLabelsCacheEvent    = "NodeUpdateLabelsCacheEvent"
+	NodeNotReadyEvent             = "NodeNotReadyEvent"
+	NodeReadyEvent                = "NodeReadyEvent"
+	NodeCordonedEvent             = "NodeCordonedEvent"
+	NodeUncordonedEvent           = "NodeUncordonedEvent"
+	NodeTaintsChangedEvent        = "NodeTaintsChangedEvent"
+	NodeDriverVersionChangedEvent = "NodeDriverVersionChangedEvent"
+	NodeEvictionPendingEvent      = "NodeEvictionPendingEvent"
 )
 
 type NodeInformerErrorPayload struct {
@@ -17,3 +24,23 @@ type NodeInformerErrorPayload struct {
 type NodeEventPayload struct {
 	Node *Node
 }
+
+// NoSchedule and NoExecute taints added to or removed from the node
+type NodeTaintsChangedPayload struct {
+	Node    *Node
+	Added   []Taint
+	Removed []Taint
+}
+
+// the gpu driver labels of the node changed, the previous version is empty when the labels were missing
+type NodeDriverVersionChangedPayload struct {
+	Node                  *Node
+	PreviousDriverVersion string
+}
+
+// the spot node is about to be evicted, signal is the node condition type or taint key announcing it
+type NodeEvictionPendingPayload struct {
+	Node    *Node
+	Signal  string
+	Message string
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "fmt"
+
+// causes of a node disruption, sent as disruption cause when the sessions on the node are marked deletable
+const (
+	NodeNotReadyDisruption   = "NodeNotReady"
+	NodeCordonedDisruption   = "NodeCordoned"
+	NoExecuteTaintDisruption = "NodeNoExecuteTaint"
+	SpotEvictionDisruption   = "SpotEviction"
+)
+
+// only taints keeping sessions off the node or evicting them are tracked
+const (
+	NoScheduleTaintEffect = "NoSchedule"
+	NoExecuteTaintEffect  = "NoExecute"
+)
+
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+func (t Taint) String() string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// a flagged session runs on a disrupted node, the broker moves its user before the node dies
+type SessionAtRisk struct {
+	NodeName string `json:"nodeName"`
+	Cause    string `json:"cause"`
+	Message  string `json:"message,omitempty"`
+	Since    int64  `json:"since"`
+}
+
+// empty cause while the node is healthy. not ready wins over cordoned, cordoned over NoExecute taints
+func (n *Node) Disruption() (cause string, message string) {
+	if !n.Ready {
+		return NodeNotReadyDisruption, fmt.Sprintf("node is not ready: %s %s", n.ReadyReason, n.ReadyMessage)
+	}
+	if n.Unschedulable {
+		return NodeCordonedDisruption, "node is cordoned"
+	}
+	for _, taint := range n.Taints {
+		if taint.Effect == NoExecuteTaintEffect {
+			return NoExecuteTaintDisruption, fmt.Sprintf("node has taint %s", taint)
+		}
+	}
+	return "", ""
+}
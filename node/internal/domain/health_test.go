@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeDisruption(t *testing.T) {
+	node := &Node{ReadyReason: "KubeletNotReady", ReadyMessage: "PLEG is not healthy", Unschedulable: true}
+	cause, message := node.Disruption()
+	assert.Equal(t, NodeNotReadyDisruption, cause)
+	assert.Equal(t, "node is not ready: KubeletNotReady PLEG is not healthy", message)
+
+	node.Ready = true
+	cause, _ = node.Disruption()
+	assert.Equal(t, NodeCordonedDisruption, cause)
+
+	node.Unschedulable = false
+	node.Taints = []Taint{{Key: "gpu", Effect: NoScheduleTaintEffect}, {Key: "drain", Value: "true", Effect: NoExecuteTaintEffect}}
+	cause, message = node.Disruption()
+	assert.Equal(t, NoExecuteTaintDisruption, cause)
+	assert.Equal(t, "node has taint drain=true:NoExecute", message)
+
+	node.Taints = node.Taints[:1]
+	cause, _ = node.Disruption()
+	assert.Equal(t, "", cause)
+}
@@ -1,7 +1,25 @@
 package domain
 
+import "github.com/xcheng85/session-monitor-k8s/internal/gpu"
+
 type Node struct {
 	Name          string `json:"name,omitempty"`
 	DriverVersion string `json:"driverversion,omitempty"`
 	Labels        *map[string]string
+	// node class of the first matching node selector
+	Class string `json:"class,omitempty"`
+	// spot nodes are evicted by the cloud provider when it needs the capacity back
+	Spot bool `json:"spot,omitempty"`
+	// unix seconds, ReadyTimestamp is 0 while the node is not ready
+	CreationTimestamp int64
+	ReadyTimestamp    int64
+	// gpu resources of the node status, nvidia.com/gpu and mig slices
+	GpuCapacity    gpu.Resources
+	GpuAllocatable gpu.Resources
+	// health of the node, set on updates only
+	Ready         bool
+	ReadyReason   string
+	ReadyMessage  string
+	Unschedulable bool
+	Taints        []Taint
 }
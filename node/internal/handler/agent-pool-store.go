@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+	"go.uber.org/zap"
+)
+
+// agent pool aggregates built from the node events, the informer lists every node again after a restart
+//
+//go:generate mockery --name IAgentPoolStore
+type IAgentPoolStore interface {
+	// a relabelled node leaves its previous pool
+	SetNode(ctx context.Context, node *domain.Node) error
+	RemoveNode(ctx context.Context, nodeName string) error
+	Get(name string) (domain.AgentPool, bool)
+	List() []domain.AgentPool
+	// listed maps the observed nodes of the synced node informer cache to their agent pool,
+	// the pools whose nodes were all deleted while the monitor was down are removed
+	Prune(ctx context.Context, listed map[string]string) error
+}
+
+// every change is written to one redis hash, fields are the agent pool names with json values.
+// the lock is held while writing so the hash never goes back to an older view
+type agentPoolStore struct {
+	logger    *zap.Logger
+	kvRepo    repository.IKVRepository
+	hashKey   string
+	mutex     sync.Mutex
+	pools     map[string]*domain.AgentPool
+	nodePools map[string]string
+}
+
+var _ IAgentPoolStore = (*agentPoolStore)(nil)
+
+func NewAgentPoolStore(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository) IAgentPoolStore {
+	return &agentPoolStore{
+		logger:    logger,
+		kvRepo:    kvRepo,
+		hashKey:   config.GetString(cfg, "app.agent_pools_key", "SessionMonitor.AgentPools"),
+		pools:     map[string]*domain.AgentPool{},
+		nodePools: map[string]string{},
+	}
+}
+
+func (s *agentPoolStore) SetNode(ctx context.Context, node *domain.Node) error {
+	agentPoolName := ""
+	if node.Labels != nil {
+		agentPoolName = (*node.Labels)["agentpool"]
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if previous, exist := s.nodePools[node.Name]; exist && previous != agentPoolName {
+		if err := s.removeMember(ctx, previous, node.Name); err != nil {
+			return err
+		}
+	}
+	pool, exist := s.pools[agentPoolName]
+	if !exist {
+		pool = domain.NewAgentPool(agentPoolName)
+		s.pools[agentPoolName] = pool
+	}
+	pool.SetMember(node)
+	s.nodePools[node.Name] = agentPoolName
+	return s.write(ctx, pool)
+}
+
+func (s *agentPoolStore) RemoveNode(ctx context.Context, nodeName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	agentPoolName, exist := s.nodePools[nodeName]
+	if !exist {
+		return nil
+	}
+	return s.removeMember(ctx, agentPoolName, nodeName)
+}
+
+func (s *agentPoolStore) Get(name string) (domain.AgentPool, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pool, exist := s.pools[name]
+	if !exist {
+		return domain.AgentPool{}, false
+	}
+	return copyAgentPool(pool), true
+}
+
+func (s *agentPoolStore) List() []domain.AgentPool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pools := make([]domain.AgentPool, 0, len(s.pools))
+	for _, pool := range s.pools {
+		pools = append(pools, copyAgentPool(pool))
+	}
+	sort.Slice(pools, func(a, b int) bool {
+		return pools[a].Name < pools[b].Name
+	})
+	return pools
+}
+
+func (s *agentPoolStore) Prune(ctx context.Context, listed map[string]string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keep := map[string]bool{}
+	for _, agentPoolName := range listed {
+		keep[agentPoolName] = true
+	}
+	// the delete events of nodes gone since the list may still be queued
+	for nodeName, agentPoolName := range s.nodePools {
+		if _, exist := listed[nodeName]; !exist {
+			s.logger.Sugar().Infow("Pruned agent pool member", "Name", nodeName, "AgentPool", agentPoolName)
+			if err := s.removeMember(ctx, agentPoolName, nodeName); err != nil {
+				return err
+			}
+		}
+	}
+	for agentPoolName := range s.pools {
+		keep[agentPoolName] = true
+	}
+	values, err := s.kvRepo.GetHash(ctx, s.hashKey)
+	if err != nil {
+		s.logger.Sugar().Errorf("Read agent pools has error: %s", err.Error())
+		return err
+	}
+	stale := []string{}
+	for agentPoolName := range values {
+		if !keep[agentPoolName] {
+			stale = append(stale, agentPoolName)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	sort.Strings(stale)
+	if _, err := s.kvRepo.RemoveFromHash(ctx, s.hashKey, stale...); err != nil {
+		s.logger.Sugar().Errorf("Prune agent pools has error: %s", err.Error())
+		return err
+	}
+	s.logger.Sugar().Infow("Pruned agent pools", "AgentPools", stale)
+	return nil
+}
+
+// caller holds the lock. the pool is dropped with its last member
+func (s *agentPoolStore) removeMember(ctx context.Context, agentPoolName string, nodeName string) error {
+	delete(s.nodePools, nodeName)
+	pool, exist := s.pools[agentPoolName]
+	if !exist || !pool.RemoveMember(nodeName) {
+		return nil
+	}
+	if pool.NodeCount > 0 {
+		return s.write(ctx, pool)
+	}
+	delete(s.pools, agentPoolName)
+	if _, err := s.kvRepo.RemoveFromHash(ctx, s.hashKey, agentPoolName); err != nil {
+		s.logger.Sugar().Errorf("Remove agent pool %s has error: %s", agentPoolName, err.Error())
+		return err
+	}
+	return nil
+}
+
+// caller holds the lock
+func (s *agentPoolStore) write(ctx context.Context, pool *domain.AgentPool) error {
+	view, _ := json.Marshal(pool)
+	if _, err := s.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     s.hashKey,
+		Payload: map[string]interface{}{pool.Name: string(view)},
+	}); err != nil {
+		s.logger.Sugar().Errorf("Write agent pool %s has error: %s", pool.Name, err.Error())
+		return err
+	}
+	return nil
+}
+
+// the summary maps are replaced on every change, only the members are copied
+func copyAgentPool(pool *domain.AgentPool) domain.AgentPool {
+	view := *pool
+	view.Members = make(map[string]domain.AgentPoolMember, len(pool.Members))
+	for name, member := range pool.Members {
+		view.Members[name] = member
+	}
+	return view
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+)
+
+func agentPoolField(name string, nodeCount int) interface{} {
+	return mock.MatchedBy(func(object *repository.Object) bool {
+		value, exist := object.Payload.(map[string]interface{})[name]
+		pool := domain.AgentPool{}
+		return object.Key == "SessionMonitor.AgentPools" && exist &&
+			json.Unmarshal([]byte(value.(string)), &pool) == nil && pool.NodeCount == nodeCount
+	})
+}
+
+func TestAgentPoolStore(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz3d", 1)).Return(int64(1), nil).Twice()
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz3d", 2)).Return(int64(0), nil).Once()
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz2d", 1)).Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.AgentPools", "viz2d").Return(int64(1), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.agent_pools_key").Return(nil)
+	store := NewAgentPoolStore(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	viz3d := map[string]string{"agentpool": "viz3d", "accelerator": "nvidia"}
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-1", DriverVersion: "535.104.05", Labels: &viz3d, CreationTimestamp: 100}))
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-2", DriverVersion: "525.147.05", Labels: &viz3d, CreationTimestamp: 200}))
+	pool, exist := store.Get("viz3d")
+	assert.True(t, exist)
+	assert.Equal(t, 2, pool.NodeCount)
+	assert.Equal(t, viz3d, pool.CommonLabels)
+	assert.Equal(t, "525.147.05", pool.MinDriverVersion)
+
+	// node-2 is relabelled into another pool
+	viz2d := map[string]string{"agentpool": "viz2d", "accelerator": "nvidia"}
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-2", DriverVersion: "525.147.05", Labels: &viz2d}))
+	pool, _ = store.Get("viz3d")
+	assert.Equal(t, 1, pool.NodeCount)
+	assert.Len(t, store.List(), 2)
+
+	assert.Nil(t, store.RemoveNode(ctx, "node-2"))
+	// unknown node
+	assert.Nil(t, store.RemoveNode(ctx, "node-2"))
+	_, exist = store.Get("viz2d")
+	assert.False(t, exist)
+	assert.Equal(t, "viz3d", store.List()[0].Name)
+}
+
+func TestAgentPoolStore_Prune(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz3d", 1)).Return(int64(1), nil).Twice()
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz3d", 2)).Return(int64(0), nil).Once()
+	mockKVRepository.On("SetHash", ctx, agentPoolField("viz2d", 1)).Return(int64(1), nil).Once()
+	// node-3 is gone, viz2d leaves with it
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.AgentPools", "viz2d").Return(int64(1), nil).Once()
+	mockKVRepository.On("GetHash", ctx, "SessionMonitor.AgentPools").Return(map[string]string{
+		"viz3d": "{}",
+		// all nodes deleted while the monitor was down
+		"viz1d": "{}",
+		// listed, its add event is still queued
+		"viz4d": "{}",
+	}, nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.AgentPools", "viz1d").Return(int64(1), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.agent_pools_key").Return(nil)
+	store := NewAgentPoolStore(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	viz3d := map[string]string{"agentpool": "viz3d"}
+	viz2d := map[string]string{"agentpool": "viz2d"}
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-1", Labels: &viz3d}))
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-2", Labels: &viz3d}))
+	assert.Nil(t, store.SetNode(ctx, &domain.Node{Name: "node-3", Labels: &viz2d}))
+
+	assert.Nil(t, store.Prune(ctx, map[string]string{"node-1": "viz3d", "node-4": "viz4d"}))
+	pool, exist := store.Get("viz3d")
+	assert.True(t, exist)
+	assert.Equal(t, 1, pool.NodeCount)
+	_, exist = store.Get("viz2d")
+	assert.False(t, exist)
+}
@@ -3,10 +3,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
 	"go.uber.org/zap"
@@ -17,6 +21,10 @@ type domainEventHandlers[T ddd.IEvent] struct {
 	config         config.IConfig
 	repository     repository.IKVRepository // query server timestamp
 	sessionService session.ISessionService  // set node ts cache
+	gpuInventory   gpu.IInventory
+	compatibility  *DriverCompatibility
+	agentPools     IAgentPoolStore
+	scaleUps       scaleup.ITracker
 }
 
 var _ ddd.IEventHandler[ddd.IEvent] = (*domainEventHandlers[ddd.IEvent])(nil)
@@ -27,12 +35,20 @@ func NewDomainEventHandlers(
 	subscriber ddd.IEventDispatcher[ddd.IEvent],
 	repository repository.IKVRepository,
 	sessionService session.ISessionService,
+	gpuInventory gpu.IInventory,
+	compatibility *DriverCompatibility,
+	agentPools IAgentPoolStore,
+	scaleUps scaleup.ITracker,
 ) ddd.IEventHandler[ddd.IEvent] {
 	handler := &domainEventHandlers[ddd.IEvent]{
 		logger,
 		config,
 		repository,
 		sessionService,
+		gpuInventory,
+		compatibility,
+		agentPools,
+		scaleUps,
 	}
 	subscriber.Subscribe(handler,
 		domain.NodeAddEvent,
@@ -41,6 +57,13 @@ func NewDomainEventHandlers(
 		domain.NodeRecordNodeProvisionEvent,
 		domain.NodeUpdateLabelsCacheEvent,
 		domain.NodeInformerErrorEvent,
+		domain.NodeNotReadyEvent,
+		domain.NodeReadyEvent,
+		domain.NodeCordonedEvent,
+		domain.NodeUncordonedEvent,
+		domain.NodeTaintsChangedEvent,
+		domain.NodeDriverVersionChangedEvent,
+		domain.NodeEvictionPendingEvent,
 	)
 	return handler
 }
@@ -58,6 +81,17 @@ func (d domainEventHandlers[T]) HandleEvent(ctx context.Context, event T) (err e
 		return d.onNodeUpdateLabelsCache(ctx, event)
 	case domain.NodeRecordNodeProvisionEvent:
 		return d.onRecordNodeProvisionTimestamp(ctx, event)
+	case domain.NodeReadyEvent:
+		d.recordScaleUp(event.Payload().(*domain.NodeEventPayload).Node)
+		return d.onNodeHealthChanged(ctx, event, event.Payload().(*domain.NodeEventPayload).Node)
+	case domain.NodeNotReadyEvent, domain.NodeCordonedEvent, domain.NodeUncordonedEvent:
+		return d.onNodeHealthChanged(ctx, event, event.Payload().(*domain.NodeEventPayload).Node)
+	case domain.NodeTaintsChangedEvent:
+		return d.onNodeHealthChanged(ctx, event, event.Payload().(*domain.NodeTaintsChangedPayload).Node)
+	case domain.NodeDriverVersionChangedEvent:
+		return d.onNodeDriverVersionChanged(ctx, event)
+	case domain.NodeEvictionPendingEvent:
+		return d.onNodeEvictionPending(ctx, event)
 	}
 	return nil
 }
@@ -66,51 +100,130 @@ func (d domainEventHandlers[T]) onNodeAdded(ctx context.Context, event ddd.IEven
 	payload := event.Payload().(*domain.NodeEventPayload)
 	name, driverVersion := payload.Node.Name, payload.Node.DriverVersion
 	d.logger.Sugar().Infow("Node is added", "Name", name, "DriverVersion", driverVersion)
-	return nil
+	// listed after a restart, or joined while the monitor was down. not a scale up the monitor saw
+	if payload.Node.ReadyTimestamp != 0 {
+		d.scaleUps.NodeSeenReady(name)
+	}
+	if err := d.setAgentPoolMember(ctx, payload.Node); err != nil {
+		return err
+	}
+	return d.setGpuInventory(ctx, payload.Node)
+}
+
+func (d domainEventHandlers[T]) setAgentPoolMember(ctx context.Context, node *domain.Node) error {
+	err := d.agentPools.SetNode(ctx, node)
+	if err != nil {
+		d.logger.Sugar().Errorf("SetNode %s in agent pools has error: %s", node.Name, err.Error())
+	}
+	return err
+}
+
+func (d domainEventHandlers[T]) setGpuInventory(ctx context.Context, node *domain.Node) error {
+	agentPoolName := ""
+	if node.Labels != nil {
+		agentPoolName = (*node.Labels)["agentpool"]
+	}
+	err := d.gpuInventory.SetNode(ctx, node.Name, agentPoolName, node.GpuCapacity, node.GpuAllocatable)
+	if err != nil {
+		d.logger.Sugar().Errorf("SetNode %s in gpu inventory has error: %s", node.Name, err.Error())
+	}
+	return err
 }
 
 func (d domainEventHandlers[T]) onNodeDeleted(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.NodeEventPayload)
 	name := payload.Node.Name
 	d.logger.Sugar().Infow("Node is deleted", "Name", name)
-	return nil
+	if err := d.sessionService.PurgeNode(name); err != nil {
+		d.logger.Sugar().Errorf("PurgeNode %s has error: %s", name, err.Error())
+		return err
+	}
+	if err := d.gpuInventory.RemoveNode(ctx, name); err != nil {
+		d.logger.Sugar().Errorf("RemoveNode %s from gpu inventory has error: %s", name, err.Error())
+		return err
+	}
+	d.scaleUps.RemoveNode(name)
+	if err := d.agentPools.RemoveNode(ctx, name); err != nil {
+		d.logger.Sugar().Errorf("RemoveNode %s from agent pools has error: %s", name, err.Error())
+		return err
+	}
+	if err := d.unflagSessions(ctx, name, d.sessionsAtRiskKey(), d.driverIncompatibleSessionsKey()); err != nil {
+		return err
+	}
+	if payload.Node.Labels == nil {
+		return nil
+	}
+	return d.cacheAgentPoolLabels(ctx, (*payload.Node.Labels)["agentpool"])
 }
 
 func (d domainEventHandlers[T]) onNodeUpdated(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.NodeEventPayload)
 	name, driverVersion := payload.Node.Name, payload.Node.DriverVersion
 	d.logger.Sugar().Infow("Node is updated", "Name", name, "DriverVersion", driverVersion)
-	return nil
+	if err := d.setAgentPoolMember(ctx, payload.Node); err != nil {
+		return err
+	}
+	return d.setGpuInventory(ctx, payload.Node)
 }
 
+// the creation timestamp is the provision time of every node, added or re-listed after a restart.
+// the redis clock is the last resort, it is late when the monitor was down
 func (d domainEventHandlers[T]) onRecordNodeProvisionTimestamp(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.NodeEventPayload)
 	nodeName := payload.Node.Name
 	d.logger.Sugar().Infof("onRecordNodeProvisionTimestamp: %s", nodeName)
-	// to do use repository to get server timestamp
-	serverTimestamp, err := d.repository.GetServerTimestamp(ctx)
-	if err != nil {
-		d.logger.Sugar().Infof("repository.GetServerTimestamp has error: %s", err.Error())
+	timestamp, source := payload.Node.CreationTimestamp, session.NodeCreationSource
+	if timestamp == 0 {
+		serverTimestamp, err := d.repository.GetServerTimestamp(ctx)
+		if err != nil {
+			d.logger.Sugar().Infof("repository.GetServerTimestamp has error: %s", err.Error())
+		}
+		timestamp, source = serverTimestamp, session.ServerTimeSource
 	}
 
-	d.logger.Sugar().Infof("Node %s is scheduled at %d", nodeName, serverTimestamp)
-	err = d.sessionService.SetNodeProvisionTimeStamp(&session.SetNodeProvisionTimeStampActionPayload{
+	d.logger.Sugar().Infof("Node %s is provisioned at %d from %s", nodeName, timestamp, source)
+	return d.sessionService.SetNodeProvisionTimeStamp(&session.SetNodeProvisionTimeStampActionPayload{
 		NodeName:  nodeName,
-		Timestamp: serverTimestamp,
+		Timestamp: timestamp,
+		Source:    source,
 	})
-	return err
 }
 
 func (d domainEventHandlers[T]) onNodeUpdateLabelsCache(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.NodeEventPayload)
-	nodeLables := payload.Node.Labels
-	agentPoolName := (*nodeLables)["agentpool"]
-	j, err := json.Marshal(nodeLables)
+	agentPoolName := (*payload.Node.Labels)["agentpool"]
+	if images := d.compatibility.IncompatibleImages(payload.Node.DriverVersion); len(images) > 0 {
+		d.logger.Sugar().Warnw("Node driver is incompatible, node is kept out of the agent pool cache", "Name", payload.Node.Name,
+			"AgentPool", agentPoolName, "DriverVersion", payload.Node.DriverVersion, "IncompatibleImages", images)
+	}
+	return d.cacheAgentPoolLabels(ctx, agentPoolName)
+}
+
+// the agent pool aggregate is the membership of the cache. the cached labels are the ones all members with
+// a compatible driver agree on, the agent pool leaves the gpu agent pool set with the last of them
+func (d domainEventHandlers[T]) cacheAgentPoolLabels(ctx context.Context, agentPoolName string) error {
+	gpuAgentPoolSetKey := d.config.Get("app.gpu_agent_pool_set_key").(string)
+	var poolLabels map[string]string
+	compatible := false
+	if pool, exist := d.agentPools.Get(agentPoolName); exist {
+		poolLabels, compatible = pool.CommonLabelsOf(func(member domain.AgentPoolMember) bool {
+			return len(d.compatibility.IncompatibleImages(member.DriverVersion)) == 0
+		})
+	}
+	if !compatible {
+		d.logger.Sugar().Infow("Agent pool has no compatible nodes, it is removed from the cache", "AgentPool", agentPoolName)
+		if _, err := d.repository.RemoveFromUnsortedSet(ctx, gpuAgentPoolSetKey, agentPoolName); err != nil {
+			d.logger.Sugar().Errorf("RemoveFromUnsortedSet has error: %s", err.Error())
+			return err
+		}
+		_, err := d.repository.DeleteKeys(ctx, agentPoolName)
+		return err
+	}
+	j, err := json.Marshal(poolLabels)
 	if err != nil {
 		d.logger.Sugar().Errorf("agentPoolName %s json marshal node labels has error: %s", agentPoolName, err.Error())
-		return domain.NewBadNodeLabelErr(nodeLables)
+		return domain.NewBadNodeLabelErr(&poolLabels)
 	}
-	gpuAgentPoolSetKey := d.config.Get("app.gpu_agent_pool_set_key").(string)
 	// transaction
 	var numKeysAdded int64
 	numKeysAdded, err = d.repository.AddToUnsortedSet(ctx, gpuAgentPoolSetKey, &repository.Object{
@@ -123,3 +236,188 @@ func (d domainEventHandlers[T]) onNodeUpdateLabelsCache(ctx context.Context, eve
 	d.logger.Sugar().Infof("AddToUnsortedSet: %d key(s) are added", numKeysAdded)
 	return err
 }
+
+// a node turning ready for the first time may be the one the cluster autoscaler added for pending session pods
+func (d domainEventHandlers[T]) recordScaleUp(node *domain.Node) {
+	if node.Labels == nil || node.CreationTimestamp == 0 || node.ReadyTimestamp == 0 {
+		return
+	}
+	agentPoolName := (*node.Labels)["agentpool"]
+	scaleUp, triggered := d.scaleUps.NodeReady(node.Name, agentPoolName,
+		time.Unix(node.CreationTimestamp, 0), time.Unix(node.ReadyTimestamp, 0))
+	if triggered {
+		d.logger.Sugar().Infow("Node is added by a scale up", "Name", node.Name, "AgentPool", agentPoolName,
+			"TriggeredAt", scaleUp.TriggeredAt, "Seconds", scaleUp.Seconds)
+	}
+}
+
+// sessions on a disrupted node are flagged in the at risk hash and, with app.node_disruption_mark_deletable,
+// marked deletable. the flags are removed once the node is healthy again or deleted
+func (d domainEventHandlers[T]) onNodeHealthChanged(ctx context.Context, event ddd.IEvent, node *domain.Node) error {
+	cause, message := node.Disruption()
+	d.logger.Sugar().Infow("Node health changed", "Name", node.Name, "Event", event.EventName(), "Cause", cause, "Message", message)
+	if cause == "" {
+		return d.unflagSessions(ctx, node.Name, d.sessionsAtRiskKey())
+	}
+	sessionIds, err := d.sessionsOn(node.Name, true)
+	if err != nil || len(sessionIds) == 0 {
+		return err
+	}
+	flags := map[string]interface{}{}
+	for _, sessionId := range sessionIds {
+		flag, _ := json.Marshal(&domain.SessionAtRisk{
+			NodeName: node.Name,
+			Cause:    cause,
+			Message:  message,
+			Since:    event.OccurredAt().Unix(),
+		})
+		flags[sessionId] = string(flag)
+	}
+	d.logger.Sugar().Warnw("Sessions at risk", "Name", node.Name, "Cause", cause, "SessionIds", sessionIds)
+	if _, err = d.repository.SetHash(ctx, &repository.Object{
+		Key:     d.sessionsAtRiskKey(),
+		Payload: flags,
+	}); err != nil {
+		d.logger.Sugar().Errorf("Flag sessions at risk has error: %s", err.Error())
+		return err
+	}
+	if !config.GetBool(d.config, "app.node_disruption_mark_deletable", false) {
+		return nil
+	}
+	return d.setSessionsDeletable(sessionIds, cause, node.ReadyReason, message)
+}
+
+// one session that cannot be marked does not keep the others on the node from being marked
+func (d domainEventHandlers[T]) setSessionsDeletable(sessionIds []string, cause string, reason string, message string) error {
+	var errs []error
+	for _, sessionId := range sessionIds {
+		if _, err := d.sessionService.TransitionSession(sessionId, session.Terminating, cause); err != nil {
+			d.logger.Sugar().Errorf("TransitionSession %s to %s has error: %s", sessionId, session.Terminating, err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := d.sessionService.SetSessionDeletable(&session.SetSessionDeletableActionPayload{
+			SessionId:         sessionId,
+			CallerId:          "Session-monitor-service",
+			DisruptionCause:   cause,
+			DisruptionReason:  reason,
+			DisruptionMessage: message,
+		}); err != nil {
+			d.logger.Sugar().Errorf("SetSessionDeletable %s has error: %s", sessionId, err.Error())
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// the pods of an evicted spot node vanish without failing, the live sessions on the node are
+// marked deletable while the node is still there
+func (d domainEventHandlers[T]) onNodeEvictionPending(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.NodeEvictionPendingPayload)
+	node := payload.Node
+	sessionIds, err := d.sessionsOn(node.Name, true)
+	if err != nil || len(sessionIds) == 0 {
+		return err
+	}
+	d.logger.Sugar().Warnw("Sessions on spot node pending eviction are deletable", "Name", node.Name,
+		"Signal", payload.Signal, "SessionIds", sessionIds)
+	return d.setSessionsDeletable(sessionIds, domain.SpotEvictionDisruption, payload.Signal, payload.Message)
+}
+
+// live sessions whose image needs a newer driver are reported in the driver incompatible hash,
+// the reports of the other sessions on the node are removed, e.g. after a driver upgrade
+func (d domainEventHandlers[T]) onNodeDriverVersionChanged(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.NodeDriverVersionChangedPayload)
+	node := payload.Node
+	d.logger.Sugar().Infow("Node driver version changed", "Name", node.Name,
+		"PreviousDriverVersion", payload.PreviousDriverVersion, "DriverVersion", node.DriverVersion)
+	views, err := d.sessionViewsOn(node.Name, true)
+	if err != nil || len(views) == 0 {
+		return err
+	}
+	reports, compatibleSessionIds := map[string]interface{}{}, []string{}
+	for _, view := range views {
+		if d.compatibility.Compatible(view.Image, node.DriverVersion) {
+			compatibleSessionIds = append(compatibleSessionIds, view.SessionId)
+			continue
+		}
+		minDriverVersion, _ := d.compatibility.MinDriverVersion(view.Image)
+		report, _ := json.Marshal(&domain.DriverIncompatibleSession{
+			NodeName:         node.Name,
+			Image:            view.Image,
+			DriverVersion:    node.DriverVersion,
+			MinDriverVersion: minDriverVersion,
+			Since:            event.OccurredAt().Unix(),
+		})
+		reports[view.SessionId] = string(report)
+		d.logger.Sugar().Warnw("Session is incompatible with the node driver", "SessionId", view.SessionId, "Name", node.Name,
+			"Image", view.Image, "DriverVersion", node.DriverVersion, "MinDriverVersion", minDriverVersion)
+	}
+	if len(reports) > 0 {
+		if _, err = d.repository.SetHash(ctx, &repository.Object{
+			Key:     d.driverIncompatibleSessionsKey(),
+			Payload: reports,
+		}); err != nil {
+			d.logger.Sugar().Errorf("Report driver incompatible sessions has error: %s", err.Error())
+			return err
+		}
+	}
+	if len(compatibleSessionIds) > 0 {
+		if _, err = d.repository.RemoveFromHash(ctx, d.driverIncompatibleSessionsKey(), compatibleSessionIds...); err != nil {
+			d.logger.Sugar().Errorf("Remove driver incompatible sessions has error: %s", err.Error())
+		}
+	}
+	return err
+}
+
+// sessions marked deletable in the meantime are unflagged too
+func (d domainEventHandlers[T]) unflagSessions(ctx context.Context, nodeName string, keys ...string) error {
+	sessionIds, err := d.sessionsOn(nodeName, false)
+	if err != nil || len(sessionIds) == 0 {
+		return err
+	}
+	for _, key := range keys {
+		if _, err = d.repository.RemoveFromHash(ctx, key, sessionIds...); err != nil {
+			d.logger.Sugar().Errorf("Unflag sessions in %s has error: %s", key, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+func (d domainEventHandlers[T]) sessionsOn(nodeName string, live bool) ([]string, error) {
+	views, err := d.sessionViewsOn(nodeName, live)
+	if err != nil {
+		return nil, err
+	}
+	sessionIds := []string{}
+	for _, view := range views {
+		sessionIds = append(sessionIds, view.SessionId)
+	}
+	return sessionIds, nil
+}
+
+// sessions whose pod is scheduled on the node, live ones are not terminating or failing
+func (d domainEventHandlers[T]) sessionViewsOn(nodeName string, live bool) ([]*session.SessionView, error) {
+	views, err := d.sessionService.ListSessionsOnNode(nodeName)
+	if err != nil {
+		d.logger.Sugar().Errorf("ListSessionsOnNode %s has error: %s", nodeName, err.Error())
+		return nil, err
+	}
+	onNode := []*session.SessionView{}
+	for _, view := range views {
+		state := view.State
+		if !live || state != session.Terminating && state != session.Failing && state != session.Deleted {
+			onNode = append(onNode, view)
+		}
+	}
+	return onNode, nil
+}
+
+func (d domainEventHandlers[T]) sessionsAtRiskKey() string {
+	return config.GetString(d.config, "app.sessions_at_risk_key", "SessionMonitor.SessionsAtRisk")
+}
+
+func (d domainEventHandlers[T]) driverIncompatibleSessionsKey() string {
+	return config.GetString(d.config, "app.driver_incompatible_sessions_key", "SessionMonitor.DriverIncompatibleSessions")
+}
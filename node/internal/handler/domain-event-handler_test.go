@@ -2,19 +2,42 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
 	"go.uber.org/zap"
 )
 
+// no pod is waiting for a node
+func newScaleUps() *scaleup.MockITracker {
+	scaleUps := &scaleup.MockITracker{}
+	scaleUps.On("NodeReady", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(scaleup.ScaleUp{}, false).Maybe()
+	scaleUps.On("RemoveNode", mock.Anything).Return().Maybe()
+	scaleUps.On("NodeSeenReady", mock.Anything).Return().Maybe()
+	return scaleUps
+}
+
+// agent pools without any member
+func newAgentPools() *MockIAgentPoolStore {
+	agentPools := &MockIAgentPoolStore{}
+	agentPools.On("SetNode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	agentPools.On("RemoveNode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	agentPools.On("Get", mock.Anything).Return(domain.AgentPool{}, false).Maybe()
+	return agentPools
+}
+
 func TestHandleEvent(t *testing.T) {
 	scenarios := []struct {
 		desc                  string
@@ -23,6 +46,8 @@ func TestHandleEvent(t *testing.T) {
 		inEventDispatcherMock func() *ddd.MockIEventDispatcher[ddd.IEvent]
 		inKVRepositoryMock    func() *repository.MockIKVRepository
 		inSessionServiceMock  func() *session.MockISessionService
+		inGpuInventoryMock    func() *gpu.MockIInventory
+		inAgentPoolsMock      func() *MockIAgentPoolStore
 		inPayload             func() ddd.IEvent
 		expectedError         error
 	}{
@@ -37,7 +62,8 @@ func TestHandleEvent(t *testing.T) {
 			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
 				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				return &mockEventDispatcher
 			},
 			inKVRepositoryMock: func() *repository.MockIKVRepository {
@@ -46,15 +72,23 @@ func TestHandleEvent(t *testing.T) {
 			inSessionServiceMock: func() *session.MockISessionService {
 				return &session.MockISessionService{}
 			},
+			inGpuInventoryMock: func() *gpu.MockIInventory {
+				mockGpuInventory := &gpu.MockIInventory{}
+				mockGpuInventory.On("SetNode", mock.Anything, "nodeName", "viz",
+					gpu.Resources{"nvidia.com/gpu": 2}, gpu.Resources{"nvidia.com/gpu": 2}).Return(nil).Once()
+				return mockGpuInventory
+			},
 			inPayload: func() ddd.IEvent {
 				nodeLables := map[string]string{
 					"accelerator": "nvidia",
 					"agentpool":   "viz",
 				}
 				nodeDomain := &domain.Node{
-					Name:          "nodeName",
-					DriverVersion: "525.0.0",
-					Labels:        &nodeLables,
+					Name:           "nodeName",
+					DriverVersion:  "525.0.0",
+					Labels:         &nodeLables,
+					GpuCapacity:    gpu.Resources{"nvidia.com/gpu": 2},
+					GpuAllocatable: gpu.Resources{"nvidia.com/gpu": 2},
 				}
 				event := ddd.NewEvent(
 					domain.NodeAddEvent,
@@ -76,7 +110,8 @@ func TestHandleEvent(t *testing.T) {
 			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
 				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				return &mockEventDispatcher
 			},
 			inKVRepositoryMock: func() *repository.MockIKVRepository {
@@ -85,6 +120,11 @@ func TestHandleEvent(t *testing.T) {
 			inSessionServiceMock: func() *session.MockISessionService {
 				return &session.MockISessionService{}
 			},
+			inGpuInventoryMock: func() *gpu.MockIInventory {
+				mockGpuInventory := &gpu.MockIInventory{}
+				mockGpuInventory.On("SetNode", mock.Anything, "nodeName", "viz", gpu.Resources(nil), gpu.Resources(nil)).Return(nil).Once()
+				return mockGpuInventory
+			},
 			inPayload: func() ddd.IEvent {
 				nodeLables := map[string]string{
 					"accelerator": "nvidia",
@@ -110,19 +150,99 @@ func TestHandleEvent(t *testing.T) {
 				LogLevel: logger.DEBUG,
 			}),
 			inConfigMock: func() *config.MockIConfig {
-				return &config.MockIConfig{}
+				mockConfig := &config.MockIConfig{}
+				mockConfig.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools", nil).Once()
+				mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+				mockConfig.On("Get", "app.driver_incompatible_sessions_key").Return(nil).Once()
+				return mockConfig
 			},
 			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
 				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				return &mockEventDispatcher
 			},
 			inKVRepositoryMock: func() *repository.MockIKVRepository {
-				return &repository.MockIKVRepository{}
+				mockKVRepository := &repository.MockIKVRepository{}
+				mockKVRepository.On("AddToUnsortedSet", mock.Anything, "GpuNodePools", &repository.Object{
+					Key:     "viz",
+					Payload: `{"accelerator":"nvidia","agentpool":"viz"}`,
+				}).Return(int64(0), nil).Once()
+				mockKVRepository.On("RemoveFromHash", mock.Anything, "SessionMonitor.SessionsAtRisk", "sessionId").Return(int64(1), nil).Once()
+				mockKVRepository.On("RemoveFromHash", mock.Anything, "SessionMonitor.DriverIncompatibleSessions", "sessionId").Return(int64(1), nil).Once()
+				return mockKVRepository
 			},
 			inSessionServiceMock: func() *session.MockISessionService {
-				return &session.MockISessionService{}
+				mockSessionService := &session.MockISessionService{}
+				mockSessionService.On("PurgeNode", "nodeName").Return(nil).Once()
+				mockSessionService.On("ListSessionsOnNode", "nodeName").Return([]*session.SessionView{
+					{SessionId: "sessionId", SessionPod: session.SessionPod{NodeName: "nodeName"}, State: session.Ready},
+				}, nil).Once()
+				return mockSessionService
+			},
+			inGpuInventoryMock: func() *gpu.MockIInventory {
+				mockGpuInventory := &gpu.MockIInventory{}
+				mockGpuInventory.On("RemoveNode", mock.Anything, "nodeName").Return(nil).Once()
+				return mockGpuInventory
+			},
+			inAgentPoolsMock: func() *MockIAgentPoolStore {
+				agentPools := &MockIAgentPoolStore{}
+				agentPools.On("RemoveNode", mock.Anything, "nodeName").Return(nil).Once()
+				agentPools.On("Get", "viz").Return(agentPoolOf(
+					&domain.Node{Name: "otherNodeName", DriverVersion: "525.0.0", Labels: &map[string]string{"accelerator": "nvidia", "agentpool": "viz"}},
+				), true).Once()
+				return agentPools
+			},
+			inPayload: func() ddd.IEvent {
+				nodeLables := map[string]string{
+					"accelerator": "nvidia",
+					"agentpool":   "viz",
+				}
+				nodeDomain := &domain.Node{
+					Name:          "nodeName",
+					DriverVersion: "525.0.0",
+					Labels:        &nodeLables,
+				}
+				event := ddd.NewEvent(
+					domain.NodeDeleteEvent,
+					&domain.NodeEventPayload{
+						Node: nodeDomain,
+					})
+				return event
+			},
+			expectedError: nil,
+		},
+		{
+			desc: "Delete Last Node Of Agent Pool",
+			inLogger: logger.NewZapLogger(logger.LogConfig{
+				LogLevel: logger.DEBUG,
+			}),
+			inConfigMock: func() *config.MockIConfig {
+				mockConfig := &config.MockIConfig{}
+				mockConfig.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools", nil).Once()
+				mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+				mockConfig.On("Get", "app.driver_incompatible_sessions_key").Return(nil).Once()
+				return mockConfig
+			},
+			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
+				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				return &mockEventDispatcher
+			},
+			inKVRepositoryMock: func() *repository.MockIKVRepository {
+				mockKVRepository := &repository.MockIKVRepository{}
+				mockKVRepository.On("RemoveFromUnsortedSet", mock.Anything, "GpuNodePools", "viz").Return(int64(1), nil).Once()
+				mockKVRepository.On("DeleteKeys", mock.Anything, "viz").Return(int64(1), nil).Once()
+				return mockKVRepository
+			},
+			inSessionServiceMock: func() *session.MockISessionService {
+				mockSessionService := &session.MockISessionService{}
+				mockSessionService.On("PurgeNode", "nodeName").Return(nil).Once()
+				mockSessionService.On("ListSessionsOnNode", "nodeName").Return([]*session.SessionView{}, nil).Once()
+				return mockSessionService
 			},
 			inPayload: func() ddd.IEvent {
 				nodeLables := map[string]string{
@@ -156,17 +276,28 @@ func TestHandleEvent(t *testing.T) {
 			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
 				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				return &mockEventDispatcher
 			},
 			inKVRepositoryMock: func() *repository.MockIKVRepository {
 				mockKVRepository := &repository.MockIKVRepository{}
-				mockKVRepository.On("AddToUnsortedSet", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+				mockKVRepository.On("AddToUnsortedSet", mock.Anything, "GpuNodePools", &repository.Object{
+					Key:     "viz",
+					Payload: `{"accelerator":"nvidia","agentpool":"viz"}`,
+				}).Return(int64(1), nil).Once()
 				return mockKVRepository
 			},
 			inSessionServiceMock: func() *session.MockISessionService {
 				return &session.MockISessionService{}
 			},
+			inAgentPoolsMock: func() *MockIAgentPoolStore {
+				agentPools := &MockIAgentPoolStore{}
+				agentPools.On("Get", "viz").Return(agentPoolOf(
+					&domain.Node{Name: "nodeName", DriverVersion: "525.0.0", Labels: &map[string]string{"accelerator": "nvidia", "agentpool": "viz"}},
+				), true).Once()
+				return agentPools
+			},
 			inPayload: func() ddd.IEvent {
 				nodeLables := map[string]string{
 					"accelerator": "nvidia",
@@ -198,7 +329,8 @@ func TestHandleEvent(t *testing.T) {
 			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
 				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				return &mockEventDispatcher
 			},
 			inKVRepositoryMock: func() *repository.MockIKVRepository {
@@ -211,6 +343,7 @@ func TestHandleEvent(t *testing.T) {
 				mockSessionService.On("SetNodeProvisionTimeStamp", &session.SetNodeProvisionTimeStampActionPayload{
 					NodeName:  "nodeName",
 					Timestamp: 8888888888,
+					Source:    session.ServerTimeSource,
 				}).Return(nil)
 				return mockSessionService
 			},
@@ -233,6 +366,105 @@ func TestHandleEvent(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			desc: "Record Node Provision Timestamp Ignores Ready Condition",
+			inLogger: logger.NewZapLogger(logger.LogConfig{
+				LogLevel: logger.DEBUG,
+			}),
+			inConfigMock: func() *config.MockIConfig {
+				mockConfig := &config.MockIConfig{}
+				return mockConfig
+			},
+			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
+				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				return &mockEventDispatcher
+			},
+			inKVRepositoryMock: func() *repository.MockIKVRepository {
+				mockKVRepository := &repository.MockIKVRepository{}
+				return mockKVRepository
+			},
+			inSessionServiceMock: func() *session.MockISessionService {
+				mockSessionService := &session.MockISessionService{}
+				mockSessionService.On("SetNodeProvisionTimeStamp", &session.SetNodeProvisionTimeStampActionPayload{
+					NodeName:  "nodeName",
+					Timestamp: 6666666666,
+					Source:    session.NodeCreationSource,
+				}).Return(nil)
+				return mockSessionService
+			},
+			inPayload: func() ddd.IEvent {
+				nodeLables := map[string]string{
+					"accelerator": "nvidia",
+					"agentpool":   "viz",
+				}
+				nodeDomain := &domain.Node{
+					Name:              "nodeName",
+					DriverVersion:     "525.0.0",
+					Labels:            &nodeLables,
+					CreationTimestamp: 6666666666,
+					ReadyTimestamp:    7777777777,
+				}
+				event := ddd.NewEvent(
+					domain.NodeRecordNodeProvisionEvent,
+					&domain.NodeEventPayload{
+						Node: nodeDomain,
+					})
+				return event
+			},
+			expectedError: nil,
+		},
+		{
+			desc: "Record Node Provision Timestamp From Creation Timestamp",
+			inLogger: logger.NewZapLogger(logger.LogConfig{
+				LogLevel: logger.DEBUG,
+			}),
+			inConfigMock: func() *config.MockIConfig {
+				mockConfig := &config.MockIConfig{}
+				return mockConfig
+			},
+			inEventDispatcherMock: func() *ddd.MockIEventDispatcher[ddd.IEvent] {
+				mockEventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+				mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				return &mockEventDispatcher
+			},
+			inKVRepositoryMock: func() *repository.MockIKVRepository {
+				mockKVRepository := &repository.MockIKVRepository{}
+				return mockKVRepository
+			},
+			inSessionServiceMock: func() *session.MockISessionService {
+				mockSessionService := &session.MockISessionService{}
+				mockSessionService.On("SetNodeProvisionTimeStamp", &session.SetNodeProvisionTimeStampActionPayload{
+					NodeName:  "nodeName",
+					Timestamp: 6666666666,
+					Source:    session.NodeCreationSource,
+				}).Return(nil)
+				return mockSessionService
+			},
+			inPayload: func() ddd.IEvent {
+				nodeLables := map[string]string{
+					"accelerator": "nvidia",
+					"agentpool":   "viz",
+				}
+				nodeDomain := &domain.Node{
+					Name:              "nodeName",
+					DriverVersion:     "525.0.0",
+					Labels:            &nodeLables,
+					CreationTimestamp: 6666666666,
+				}
+				event := ddd.NewEvent(
+					domain.NodeRecordNodeProvisionEvent,
+					&domain.NodeEventPayload{
+						Node: nodeDomain,
+					})
+				return event
+			},
+			expectedError: nil,
+		},
 	}
 	for _, s := range scenarios {
 		scenario := s
@@ -240,14 +472,411 @@ func TestHandleEvent(t *testing.T) {
 			ctx := context.TODO()
 			logger := scenario.inLogger
 			config := scenario.inConfigMock()
+			config.On("Get", "app.driver_compatibility").Return(nil)
+			compatibility, err := NewDriverCompatibility(config)
+			assert.Nil(t, err)
 			eventDispatcher := scenario.inEventDispatcherMock()
 			kvRepository := scenario.inKVRepositoryMock()
 			sessionService := scenario.inSessionServiceMock()
 			payload := scenario.inPayload()
+			gpuInventory := &gpu.MockIInventory{}
+			gpuInventory.On("SetNode", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			gpuInventory.On("RemoveNode", mock.Anything, mock.Anything).Return(nil).Maybe()
+			if scenario.inGpuInventoryMock != nil {
+				gpuInventory = scenario.inGpuInventoryMock()
+			}
+
+			agentPools := newAgentPools()
+			if scenario.inAgentPoolsMock != nil {
+				agentPools = scenario.inAgentPoolsMock()
+			}
 
-			eventHandler := NewDomainEventHandlers(logger, config, eventDispatcher, kvRepository, sessionService)
-			err := eventHandler.HandleEvent(ctx, payload)
+			eventHandler := NewDomainEventHandlers(logger, config, eventDispatcher, kvRepository, sessionService, gpuInventory,
+				compatibility, agentPools, newScaleUps())
+			err = eventHandler.HandleEvent(ctx, payload)
 			assert.Equal(t, scenario.expectedError, err, scenario.desc)
+			kvRepository.AssertExpectations(t)
+			sessionService.AssertExpectations(t)
+			gpuInventory.AssertExpectations(t)
 		})
 	}
 }
+
+func newNodeHealthHandler(t *testing.T, config *config.MockIConfig, kvRepository *repository.MockIKVRepository,
+	sessionService *session.MockISessionService) ddd.IEventHandler[ddd.IEvent] {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	config.On("Get", "app.driver_compatibility").Return(nil)
+	compatibility, err := NewDriverCompatibility(config)
+	assert.Nil(t, err)
+	return NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}),
+		config, eventDispatcher, kvRepository, sessionService, &gpu.MockIInventory{}, compatibility, newAgentPools(), newScaleUps())
+}
+
+func sessionsOnNode() []*session.SessionView {
+	return []*session.SessionView{
+		{SessionId: "ready", SessionPod: session.SessionPod{NodeName: "nodeName"}, State: session.Ready},
+		{SessionId: "scheduled", SessionPod: session.SessionPod{NodeName: "nodeName"}, State: session.Scheduled},
+		{SessionId: "terminating", SessionPod: session.SessionPod{NodeName: "nodeName"}, State: session.Terminating},
+	}
+}
+
+func TestHandleEvent_NodeNotReady(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+	mockConfig.On("Get", "app.node_disruption_mark_deletable").Return(true).Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		flags := object.Payload.(map[string]interface{})
+		return object.Key == "SessionMonitor.SessionsAtRisk" && len(flags) == 2 &&
+			strings.Contains(flags["ready"].(string), "\"cause\":\"NodeNotReady\"")
+	})).Return(int64(2), nil).Once()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+	for _, sessionId := range []string{"ready", "scheduled"} {
+		sessionId := sessionId
+		mockSessionService.On("TransitionSession", sessionId, session.Terminating, domain.NodeNotReadyDisruption).Return(true, nil).Once()
+		mockSessionService.On("SetSessionDeletable", mock.MatchedBy(func(payload *session.SetSessionDeletableActionPayload) bool {
+			return payload.SessionId == sessionId && payload.DisruptionCause == domain.NodeNotReadyDisruption &&
+				payload.DisruptionReason == "KubeletNotReady"
+		})).Return(true, nil).Once()
+	}
+
+	h := newNodeHealthHandler(t, mockConfig, mockKVRepository, mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeNotReadyEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:         "nodeName",
+			ReadyReason:  "KubeletNotReady",
+			ReadyMessage: "PLEG is not healthy",
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+// a session that cannot be marked does not keep the others from being marked
+func TestHandleEvent_NodeNotReady_ContinueOnError(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+	mockConfig.On("Get", "app.node_disruption_mark_deletable").Return(true).Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.Anything).Return(int64(2), nil).Once()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+	transitionErr := session.NewInvalidLifecycleTransitionErr("ready", session.Deleted, session.Terminating)
+	mockSessionService.On("TransitionSession", "ready", session.Terminating, domain.NodeNotReadyDisruption).Return(false, transitionErr).Once()
+	mockSessionService.On("TransitionSession", "scheduled", session.Terminating, domain.NodeNotReadyDisruption).Return(true, nil).Once()
+	mockSessionService.On("SetSessionDeletable", mock.MatchedBy(func(payload *session.SetSessionDeletableActionPayload) bool {
+		return payload.SessionId == "scheduled"
+	})).Return(true, nil).Once()
+
+	h := newNodeHealthHandler(t, mockConfig, mockKVRepository, mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeNotReadyEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:        "nodeName",
+			ReadyReason: "KubeletNotReady",
+		},
+	}))
+	assert.ErrorIs(t, err, transitionErr)
+}
+
+func TestHandleEvent_NodeCordoned_FlagOnly(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+	mockConfig.On("Get", "app.node_disruption_mark_deletable").Return(nil).Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		flags := object.Payload.(map[string]interface{})
+		return strings.Contains(flags["scheduled"].(string), "\"cause\":\"NodeCordoned\"")
+	})).Return(int64(2), nil).Once()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+
+	h := newNodeHealthHandler(t, mockConfig, mockKVRepository, mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeCordonedEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:          "nodeName",
+			Ready:         true,
+			Unschedulable: true,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+func TestHandleEvent_NodeUncordoned(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.sessions_at_risk_key").Return("SessionsAtRisk").Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionsAtRisk", "ready", "scheduled", "terminating").Return(int64(3), nil).Once()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+
+	h := newNodeHealthHandler(t, mockConfig, mockKVRepository, mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeUncordonedEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:  "nodeName",
+			Ready: true,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+func newDriverVersionHandler(t *testing.T, config *config.MockIConfig, kvRepository *repository.MockIKVRepository,
+	sessionService *session.MockISessionService) ddd.IEventHandler[ddd.IEvent] {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	config.On("Get", "app.driver_compatibility").Return([]interface{}{
+		map[string]interface{}{"image": "viz3d-2024.1", "min_driver_version": "535.104.05"},
+		map[string]interface{}{"image": "viz3d-2023.2", "min_driver_version": "525.60.13"},
+	})
+	compatibility, err := NewDriverCompatibility(config)
+	assert.Nil(t, err)
+	return NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}),
+		config, eventDispatcher, kvRepository, sessionService, &gpu.MockIInventory{}, compatibility, newAgentPools(), newScaleUps())
+}
+
+func TestHandleEvent_NodeDriverVersionChanged(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.driver_incompatible_sessions_key").Return(nil).Twice()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.MatchedBy(func(object *repository.Object) bool {
+		reports := object.Payload.(map[string]interface{})
+		return object.Key == "SessionMonitor.DriverIncompatibleSessions" && len(reports) == 1 &&
+			strings.Contains(reports["ready"].(string), "\"minDriverVersion\":\"535.104.05\"")
+	})).Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.DriverIncompatibleSessions", "scheduled", "unknownImage").
+		Return(int64(0), nil).Once()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return([]*session.SessionView{
+		{SessionId: "ready", SessionPod: session.SessionPod{NodeName: "nodeName", Image: "viz3d-2024.1"}, State: session.Ready},
+		{SessionId: "scheduled", SessionPod: session.SessionPod{NodeName: "nodeName", Image: "viz3d-2023.2"}, State: session.Scheduled},
+		{SessionId: "unknownImage", SessionPod: session.SessionPod{NodeName: "nodeName"}, State: session.Ready},
+		{SessionId: "terminating", SessionPod: session.SessionPod{NodeName: "nodeName", Image: "viz3d-2024.1"}, State: session.Terminating},
+	}, nil).Once()
+
+	h := newDriverVersionHandler(t, mockConfig, mockKVRepository, mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeDriverVersionChangedEvent, &domain.NodeDriverVersionChangedPayload{
+		Node: &domain.Node{
+			Name:          "nodeName",
+			DriverVersion: "525.147.05",
+		},
+		PreviousDriverVersion: "535.104.05",
+	}))
+	assert.Nil(t, err)
+}
+
+func newLabelsCacheHandler(t *testing.T, config *config.MockIConfig, kvRepository *repository.MockIKVRepository,
+	pool domain.AgentPool) ddd.IEventHandler[ddd.IEvent] {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	agentPools := NewMockIAgentPoolStore(t)
+	agentPools.On("Get", pool.Name).Return(pool, true).Once()
+	config.On("Get", "app.driver_compatibility").Return([]interface{}{
+		map[string]interface{}{"image": "viz3d-2024.1", "min_driver_version": "535.104.05"},
+		map[string]interface{}{"image": "viz3d-2023.2", "min_driver_version": "525.60.13"},
+	})
+	compatibility, err := NewDriverCompatibility(config)
+	assert.Nil(t, err)
+	return NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), config, eventDispatcher,
+		kvRepository, session.NewMockISessionService(t), &gpu.MockIInventory{}, compatibility, agentPools, newScaleUps())
+}
+
+func agentPoolOf(nodes ...*domain.Node) domain.AgentPool {
+	pool := domain.NewAgentPool("viz")
+	for _, node := range nodes {
+		pool.SetMember(node)
+	}
+	return *pool
+}
+
+// members with incompatible drivers do not shape the cached labels
+func TestHandleEvent_NodeUpdateLabelsCache_IncompatibleDriver(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools").Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("AddToUnsortedSet", ctx, "GpuNodePools", &repository.Object{
+		Key:     "viz",
+		Payload: `{"accelerator":"nvidia","agentpool":"viz","nvidia.com/cuda.driver.major":"535"}`,
+	}).Return(int64(0), nil).Once()
+
+	incompatibleLabels := map[string]string{"agentpool": "viz", "accelerator": "nvidia", "nvidia.com/cuda.driver.major": "525"}
+	compatibleLabels := map[string]string{"agentpool": "viz", "accelerator": "nvidia", "nvidia.com/cuda.driver.major": "535"}
+	h := newLabelsCacheHandler(t, mockConfig, mockKVRepository, agentPoolOf(
+		&domain.Node{Name: "nodeName", DriverVersion: "525.147.05", Labels: &incompatibleLabels},
+		&domain.Node{Name: "otherNodeName", DriverVersion: "535.104.05", Labels: &compatibleLabels},
+	))
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeUpdateLabelsCacheEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:          "nodeName",
+			DriverVersion: "525.147.05",
+			Labels:        &incompatibleLabels,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+func TestHandleEvent_NodeUpdateLabelsCache_NoCompatibleNode(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools").Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("RemoveFromUnsortedSet", ctx, "GpuNodePools", "viz").Return(int64(1), nil).Once()
+	mockKVRepository.On("DeleteKeys", ctx, "viz").Return(int64(1), nil).Once()
+
+	nodeLabels := map[string]string{"agentpool": "viz"}
+	h := newLabelsCacheHandler(t, mockConfig, mockKVRepository, agentPoolOf(
+		&domain.Node{Name: "nodeName", DriverVersion: "525.147.05", Labels: &nodeLabels},
+	))
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeUpdateLabelsCacheEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:          "nodeName",
+			DriverVersion: "525.147.05",
+			Labels:        &nodeLabels,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+func TestHandleEvent_NodeUpdateLabelsCache_PoolLabels(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools").Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("AddToUnsortedSet", ctx, "GpuNodePools", &repository.Object{
+		Key:     "viz",
+		Payload: `{"accelerator":"nvidia","agentpool":"viz"}`,
+	}).Return(int64(0), nil).Once()
+
+	nodeLabels := map[string]string{"accelerator": "nvidia", "agentpool": "viz", "kubernetes.io/hostname": "nodeName"}
+	otherLabels := map[string]string{"accelerator": "nvidia", "agentpool": "viz", "kubernetes.io/hostname": "otherNodeName"}
+	h := newLabelsCacheHandler(t, mockConfig, mockKVRepository, agentPoolOf(
+		&domain.Node{Name: "nodeName", DriverVersion: "535.104.05", Labels: &nodeLabels},
+		&domain.Node{Name: "otherNodeName", DriverVersion: "535.104.05", Labels: &otherLabels},
+	))
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeUpdateLabelsCacheEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:          "nodeName",
+			DriverVersion: "535.104.05",
+			Labels:        &nodeLabels,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+func TestHandleEvent_NodeReady_ScaleUp(t *testing.T) {
+	ctx := context.TODO()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.sessions_at_risk_key").Return(nil).Once()
+	mockConfig.On("Get", "app.driver_compatibility").Return(nil).Once()
+	compatibility, err := NewDriverCompatibility(mockConfig)
+	assert.Nil(t, err)
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return([]*session.SessionView{}, nil).Once()
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("NodeReady", "nodeName", "viz", time.Unix(1000, 0), time.Unix(1240, 0)).
+		Return(scaleup.ScaleUp{AgentPool: "viz", NodeName: "nodeName", TriggeredAt: 900, Seconds: 340}, true).Once()
+
+	h := NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, eventDispatcher,
+		repository.NewMockIKVRepository(t), mockSessionService, &gpu.MockIInventory{}, compatibility, newAgentPools(), scaleUps)
+	nodeLabels := map[string]string{"agentpool": "viz"}
+	err = h.HandleEvent(ctx, ddd.NewEvent(domain.NodeReadyEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:              "nodeName",
+			Labels:            &nodeLabels,
+			Ready:             true,
+			CreationTimestamp: 1000,
+			ReadyTimestamp:    1240,
+		},
+	}))
+	assert.Nil(t, err)
+}
+
+// a node ready when it is listed did not join for the pods waiting now
+func TestHandleEvent_NodeAdded_Ready(t *testing.T) {
+	ctx := context.TODO()
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	gpuInventory := &gpu.MockIInventory{}
+	gpuInventory.On("SetNode", mock.Anything, "nodeName", "viz", mock.Anything, mock.Anything).Return(nil).Once()
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("NodeSeenReady", "nodeName").Return().Once()
+	mockConfig := config.NewMockIConfig(t)
+	mockConfig.On("Get", "app.driver_compatibility").Return(nil).Once()
+	compatibility, err := NewDriverCompatibility(mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, eventDispatcher,
+		repository.NewMockIKVRepository(t), session.NewMockISessionService(t), gpuInventory, compatibility, newAgentPools(), scaleUps)
+	nodeLabels := map[string]string{"agentpool": "viz"}
+	err = h.HandleEvent(ctx, ddd.NewEvent(domain.NodeAddEvent, &domain.NodeEventPayload{
+		Node: &domain.Node{
+			Name:              "nodeName",
+			Labels:            &nodeLabels,
+			CreationTimestamp: 1000,
+			ReadyTimestamp:    1240,
+		},
+	}))
+	assert.Nil(t, err)
+	gpuInventory.AssertExpectations(t)
+}
+
+func TestHandleEvent_NodeEvictionPending(t *testing.T) {
+	ctx := context.TODO()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+	for _, sessionId := range []string{"ready", "scheduled"} {
+		sessionId := sessionId
+		mockSessionService.On("TransitionSession", sessionId, session.Terminating, domain.SpotEvictionDisruption).Return(true, nil).Once()
+		mockSessionService.On("SetSessionDeletable", mock.MatchedBy(func(payload *session.SetSessionDeletableActionPayload) bool {
+			return payload.SessionId == sessionId && payload.DisruptionCause == domain.SpotEvictionDisruption &&
+				payload.DisruptionReason == "VMEventScheduled"
+		})).Return(true, nil).Once()
+	}
+
+	h := newNodeHealthHandler(t, config.NewMockIConfig(t), repository.NewMockIKVRepository(t), mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeEvictionPendingEvent, &domain.NodeEvictionPendingPayload{
+		Node:    &domain.Node{Name: "nodeName", Spot: true},
+		Signal:  "VMEventScheduled",
+		Message: "VMEventScheduled Preempt @ Mon, 19 Oct 2026 10:00:00 GMT",
+	}))
+	assert.Nil(t, err)
+}
+
+// the remaining sessions are marked before their pods vanish with the node
+func TestHandleEvent_NodeEvictionPending_ContinueOnError(t *testing.T) {
+	ctx := context.TODO()
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessionsOnNode", "nodeName").Return(sessionsOnNode(), nil).Once()
+	mockSessionService.On("TransitionSession", mock.Anything, session.Terminating, domain.SpotEvictionDisruption).Return(true, nil).Twice()
+	redisErr := errors.New("redis is down")
+	mockSessionService.On("SetSessionDeletable", mock.MatchedBy(func(payload *session.SetSessionDeletableActionPayload) bool {
+		return payload.SessionId == "ready"
+	})).Return(false, redisErr).Once()
+	mockSessionService.On("SetSessionDeletable", mock.MatchedBy(func(payload *session.SetSessionDeletableActionPayload) bool {
+		return payload.SessionId == "scheduled"
+	})).Return(true, nil).Once()
+
+	h := newNodeHealthHandler(t, config.NewMockIConfig(t), repository.NewMockIKVRepository(t), mockSessionService)
+	err := h.HandleEvent(ctx, ddd.NewEvent(domain.NodeEvictionPendingEvent, &domain.NodeEvictionPendingPayload{
+		Node:   &domain.Node{Name: "nodeName", Spot: true},
+		Signal: "VMEventScheduled",
+	}))
+	assert.ErrorIs(t, err, redisErr)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"sort"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+)
+
+// minimum gpu driver per session image. the broker places sessions of any image on the nodes of the
+// agent pool cache, so a node below the minimum of one image is kept out of the cache
+type DriverCompatibility struct {
+	minDriverVersions map[string]string
+}
+
+// app.driver_compatibility:
+//
+//   - image: viz3d-2024.1
+//     min_driver_version: 535.104.05
+//
+// images are the values of the sessionImage pod label. without entries every driver is compatible
+func NewDriverCompatibility(cfg config.IConfig) (*DriverCompatibility, error) {
+	compatibility := &DriverCompatibility{
+		minDriverVersions: map[string]string{},
+	}
+	for _, e := range cast.ToSlice(cfg.Get("app.driver_compatibility")) {
+		m := cast.ToStringMap(e)
+		image, minDriverVersion := cast.ToString(m["image"]), cast.ToString(m["min_driver_version"])
+		if image == "" {
+			return nil, domain.NewInvalidDriverCompatibilityErr(image, "image is missing")
+		}
+		if minDriverVersion == "" {
+			return nil, domain.NewInvalidDriverCompatibilityErr(image, "min_driver_version is missing")
+		}
+		if _, exist := compatibility.minDriverVersions[image]; exist {
+			return nil, domain.NewInvalidDriverCompatibilityErr(image, "image is duplicated")
+		}
+		compatibility.minDriverVersions[image] = minDriverVersion
+	}
+	return compatibility, nil
+}
+
+// the minimum driver of the image, false when the image is not in the matrix
+func (c *DriverCompatibility) MinDriverVersion(image string) (string, bool) {
+	minDriverVersion, exist := c.minDriverVersions[image]
+	return minDriverVersion, exist
+}
+
+// images missing in the matrix run on any driver
+func (c *DriverCompatibility) Compatible(image string, driverVersion string) bool {
+	minDriverVersion, exist := c.minDriverVersions[image]
+	return !exist || domain.CompareDriverVersions(driverVersion, minDriverVersion) >= 0
+}
+
+// sorted images the driver is too old for
+func (c *DriverCompatibility) IncompatibleImages(driverVersion string) []string {
+	images := []string{}
+	for image := range c.minDriverVersions {
+		if !c.Compatible(image, driverVersion) {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
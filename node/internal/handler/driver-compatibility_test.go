@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+)
+
+// compatibility from the matrix mocked by the test
+func TestNewDriverCompatibility(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.driver_compatibility").Return([]interface{}{
+		map[string]interface{}{"image": "viz3d-2024.1", "min_driver_version": "535.104.05"},
+		map[string]interface{}{"image": "viz3d-2023.2", "min_driver_version": "525.60.13"},
+	})
+	compatibility, err := NewDriverCompatibility(cfg)
+	assert.Nil(t, err)
+	minDriverVersion, exist := compatibility.MinDriverVersion("viz3d-2024.1")
+	assert.True(t, exist)
+	assert.Equal(t, "535.104.05", minDriverVersion)
+	_, exist = compatibility.MinDriverVersion("viz2d")
+	assert.False(t, exist)
+
+	assert.True(t, compatibility.Compatible("viz3d-2024.1", "535.129.03"))
+	assert.False(t, compatibility.Compatible("viz3d-2024.1", "535.54.03"))
+	assert.True(t, compatibility.Compatible("viz2d", "470.0.0"))
+
+	assert.Equal(t, []string{"viz3d-2023.2", "viz3d-2024.1"}, compatibility.IncompatibleImages("470.199.02"))
+	assert.Equal(t, []string{"viz3d-2024.1"}, compatibility.IncompatibleImages("525.147.05"))
+	assert.Empty(t, compatibility.IncompatibleImages("550.54.15"))
+
+	cfg = &config.MockIConfig{}
+	cfg.On("Get", "app.driver_compatibility").Return(nil)
+	compatibility, err = NewDriverCompatibility(cfg)
+	assert.Nil(t, err)
+	assert.Empty(t, compatibility.IncompatibleImages("470.199.02"))
+}
+
+func TestNewDriverCompatibility_Invalid(t *testing.T) {
+	tests := []struct {
+		matrix []interface{}
+		err    error
+	}{
+		{
+			[]interface{}{map[string]interface{}{"min_driver_version": "535.104.05"}},
+			domain.NewInvalidDriverCompatibilityErr("", "image is missing"),
+		},
+		{
+			[]interface{}{map[string]interface{}{"image": "viz3d"}},
+			domain.NewInvalidDriverCompatibilityErr("viz3d", "min_driver_version is missing"),
+		},
+		{
+			[]interface{}{
+				map[string]interface{}{"image": "viz3d", "min_driver_version": "535.104.05"},
+				map[string]interface{}{"image": "viz3d", "min_driver_version": "525.60.13"},
+			},
+			domain.NewInvalidDriverCompatibilityErr("viz3d", "image is duplicated"),
+		},
+	}
+	for _, test := range tests {
+		cfg := &config.MockIConfig{}
+		cfg.On("Get", "app.driver_compatibility").Return(test.matrix)
+		_, err := NewDriverCompatibility(cfg)
+		assert.ErrorIs(t, err, test.err)
+	}
+}
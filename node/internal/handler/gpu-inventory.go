@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
+	http_utils "github.com/xcheng85/session-monitor-k8s/internal/http"
+	"go.uber.org/zap"
+)
+
+// free and used gpus per node and agent pool, read by the session broker before placing sessions
+//
+//go:generate mockery --name IGpuInventoryHandler
+type IGpuInventoryHandler interface {
+	ListNodes(w http.ResponseWriter, r *http.Request)
+	GetNode(w http.ResponseWriter, r *http.Request)
+	ListPools(w http.ResponseWriter, r *http.Request)
+	GetPool(w http.ResponseWriter, r *http.Request)
+}
+
+type gpuInventoryHandler struct {
+	logger       *zap.Logger
+	gpuInventory gpu.IInventory
+}
+
+func NewGpuInventoryHandler(logger *zap.Logger, gpuInventory gpu.IInventory) IGpuInventoryHandler {
+	return &gpuInventoryHandler{
+		logger:       logger,
+		gpuInventory: gpuInventory,
+	}
+}
+
+func (handler gpuInventoryHandler) ListNodes(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, handler.gpuInventory.Nodes())
+}
+
+func (handler gpuInventoryHandler) GetNode(w http.ResponseWriter, r *http.Request) {
+	node, exist := handler.gpuInventory.Node(chi.URLParam(r, "nodeName"))
+	if !exist {
+		render.Render(w, r, http_utils.ErrNotFound)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, node)
+}
+
+func (handler gpuInventoryHandler) ListPools(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, handler.gpuInventory.Pools())
+}
+
+func (handler gpuInventoryHandler) GetPool(w http.ResponseWriter, r *http.Request) {
+	pool, exist := handler.gpuInventory.Pool(chi.URLParam(r, "agentPool"))
+	if !exist {
+		render.Render(w, r, http_utils.ErrNotFound)
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, pool)
+}
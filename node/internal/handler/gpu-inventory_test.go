@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
+	"go.uber.org/zap"
+)
+
+func newGpuInventoryRequest(t *testing.T, path string, key string, value string) *http.Request {
+	request, err := http.NewRequest("GET", path, nil)
+	require.NoError(t, err)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add(key, value)
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func TestGpuInventoryHandlerGetNode(t *testing.T) {
+	mockGpuInventory := gpu.NewMockIInventory(t)
+	mockGpuInventory.On("Node", "node-1").Return(gpu.NodeInventory{
+		Name:      "node-1",
+		AgentPool: "viz",
+		Free:      gpu.Resources{"nvidia.com/gpu": 1},
+	}, true).Once()
+	handler := NewGpuInventoryHandler(zap.NewNop(), mockGpuInventory)
+	response := httptest.NewRecorder()
+	handler.GetNode(response, newGpuInventoryRequest(t, "/gpus/nodes/node-1", "nodeName", "node-1"))
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"agentPool\":\"viz\"")
+	assert.Contains(t, response.Body.String(), "\"free\":{\"nvidia.com/gpu\":1}")
+}
+
+func TestGpuInventoryHandlerGetNode_NotFound(t *testing.T) {
+	mockGpuInventory := gpu.NewMockIInventory(t)
+	mockGpuInventory.On("Node", "unknown").Return(gpu.NodeInventory{}, false).Once()
+	handler := NewGpuInventoryHandler(zap.NewNop(), mockGpuInventory)
+	response := httptest.NewRecorder()
+	handler.GetNode(response, newGpuInventoryRequest(t, "/gpus/nodes/unknown", "nodeName", "unknown"))
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestGpuInventoryHandlerGetPool(t *testing.T) {
+	mockGpuInventory := gpu.NewMockIInventory(t)
+	mockGpuInventory.On("Pool", "viz").Return(gpu.PoolInventory{Name: "viz", Nodes: 2}, true).Once()
+	mockGpuInventory.On("Pool", "cpu").Return(gpu.PoolInventory{Name: "cpu"}, false).Once()
+	handler := NewGpuInventoryHandler(zap.NewNop(), mockGpuInventory)
+	response := httptest.NewRecorder()
+	handler.GetPool(response, newGpuInventoryRequest(t, "/gpus/pools/viz", "agentPool", "viz"))
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"nodes\":2")
+	response = httptest.NewRecorder()
+	handler.GetPool(response, newGpuInventoryRequest(t, "/gpus/pools/cpu", "agentPool", "cpu"))
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestGpuInventoryHandlerList(t *testing.T) {
+	mockGpuInventory := gpu.NewMockIInventory(t)
+	mockGpuInventory.On("Nodes").Return([]gpu.NodeInventory{{Name: "node-1"}, {Name: "node-2"}}).Once()
+	mockGpuInventory.On("Pools").Return([]gpu.PoolInventory{{Name: "viz"}}).Once()
+	handler := NewGpuInventoryHandler(zap.NewNop(), mockGpuInventory)
+	response := httptest.NewRecorder()
+	handler.ListNodes(response, httptest.NewRequest("GET", "/gpus/nodes", nil))
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"name\":\"node-2\"")
+	response = httptest.NewRecorder()
+	handler.ListPools(response, httptest.NewRequest("GET", "/gpus/pools", nil))
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"name\":\"viz\"")
+}
@@ -7,11 +7,17 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
 )
 
 func TestNewNodeEventHandler(t *testing.T) {
@@ -23,7 +29,7 @@ func TestNewNodeEventHandler(t *testing.T) {
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
 
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, &NodeSelectors{}, &SpotNodes{})
 	assert.NotNil(t, h, "Node Event Handler should not be nil")
 }
 
@@ -39,7 +45,7 @@ func TestCustomWatchErrorHandler(t *testing.T) {
 	err := errors.New("errors from k8s api server")
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, &NodeSelectors{}, &SpotNodes{})
 	h.CustomWatchErrorHandler(nil, err)
 
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
@@ -62,20 +68,22 @@ func TestOnAddObject(t *testing.T) {
 		argsTypeless[i] = arg
 	}
 	config.On("Get", "app.gpu_observee_labels").Return(argsTypeless).Once()
+	config.On("Get", "app.spot_nodes").Return(nil)
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Node",
 			"apiVersion": "v1",
 			"metadata": map[string]interface{}{
-				"name":            "aks-viz3d4-33002848-vmss0001nc",
-				"uid":             "test_uid",
-				"resourceVersion": "test_resourceVersion",
+				"name":              "aks-viz3d4-33002848-vmss0001nc",
+				"creationTimestamp": "2024-05-01T10:00:00Z",
+				"uid":               "test_uid",
+				"resourceVersion":   "test_resourceVersion",
 				"labels": map[string]interface{}{
 					"accelerator":           "nvidia",
 					"agentpool":             "viz3d",
@@ -86,10 +94,36 @@ func TestOnAddObject(t *testing.T) {
 				},
 			},
 			"spec": map[string]interface{}{},
+			"status": map[string]interface{}{
+				"capacity": map[string]interface{}{
+					"cpu":                    "36",
+					"nvidia.com/gpu":         "1",
+					"nvidia.com/mig-1g.10gb": "7",
+				},
+				"allocatable": map[string]interface{}{
+					"cpu":                    "35",
+					"nvidia.com/gpu":         "1",
+					"nvidia.com/mig-1g.10gb": "7",
+				},
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":               "Ready",
+						"status":             "True",
+						"lastTransitionTime": "2024-05-01T10:02:00Z",
+					},
+				},
+			},
 		},
 	}
 	h.OnAddObject(payload)
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(3).(ddd.IEvent)
+	assert.Equal(t, domain.NodeRecordNodeProvisionEvent, event.EventName())
+	node := event.Payload().(*domain.NodeEventPayload).Node
+	assert.Equal(t, int64(1714557600), node.CreationTimestamp)
+	assert.Equal(t, int64(1714557720), node.ReadyTimestamp)
+	assert.Equal(t, legacySelectorClass, node.Class)
+	assert.Equal(t, gpu.Resources{"nvidia.com/gpu": 1, "nvidia.com/mig-1g.10gb": 7}, node.GpuAllocatable)
 }
 
 func TestOnAddObjectIgnoreNode(t *testing.T) {
@@ -107,12 +141,13 @@ func TestOnAddObjectIgnoreNode(t *testing.T) {
 		argsTypeless[i] = arg
 	}
 	config.On("Get", "app.gpu_observee_labels").Return(argsTypeless).Once()
+	config.On("Get", "app.spot_nodes").Return(nil)
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Node",
@@ -150,12 +185,13 @@ func TestOnUpdateObjectWithInvalidDriver(t *testing.T) {
 		argsTypeless[i] = arg
 	}
 	config.On("Get", "app.gpu_observee_labels").Return(argsTypeless).Once()
+	config.On("Get", "app.spot_nodes").Return(nil)
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Node",
@@ -197,12 +233,13 @@ func TestOnUpdateObjectWithValidDriver(t *testing.T) {
 		argsTypeless[i] = arg
 	}
 	config.On("Get", "app.gpu_observee_labels").Return(argsTypeless).Once()
+	config.On("Get", "app.spot_nodes").Return(nil)
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Node",
@@ -247,12 +284,13 @@ func TestOnDeleteObject(t *testing.T) {
 		argsTypeless[i] = arg
 	}
 	config.On("Get", "app.gpu_observee_labels").Return(argsTypeless).Once()
+	config.On("Get", "app.spot_nodes").Return(nil)
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler)
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Node",
@@ -330,3 +368,432 @@ func TestParseGPUDriverVersionMissingLables(t *testing.T) {
 	assert.Equal(t, "", driverVersion, "Parse invalid gpu labels should return empty string")
 	assert.Equal(t, "Driver Version is missing!", err.Error(), "parseGPUDriverVersion should have err")
 }
+
+func TestNodeHealthEvents(t *testing.T) {
+	readyNode := func(status v1.ConditionStatus, unschedulable bool, taints ...v1.Taint) *v1.Node {
+		return &v1.Node{
+			Spec: v1.NodeSpec{
+				Unschedulable: unschedulable,
+				Taints:        taints,
+			},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: status}},
+			},
+		}
+	}
+	toDomain := func(node *v1.Node) *domain.Node {
+		return &domain.Node{
+			Name:          "nodeName",
+			Ready:         nodeReadyCondition(node).Status == v1.ConditionTrue,
+			Unschedulable: node.Spec.Unschedulable,
+			Taints:        nodeTaints(node),
+		}
+	}
+	eventNames := func(events []ddd.IEvent) []string {
+		names := []string{}
+		for _, event := range events {
+			names = append(names, event.EventName())
+		}
+		return names
+	}
+	unreachable := v1.Taint{Key: "node.kubernetes.io/unreachable", Effect: v1.TaintEffectNoExecute}
+	unschedulable := v1.Taint{Key: "node.kubernetes.io/unschedulable", Effect: v1.TaintEffectNoSchedule}
+	preferNoSchedule := v1.Taint{Key: "spot", Value: "true", Effect: v1.TaintEffectPreferNoSchedule}
+
+	events := nodeHealthEvents(readyNode(v1.ConditionTrue, false), toDomain(readyNode(v1.ConditionUnknown, false, unreachable)))
+	assert.Equal(t, []string{domain.NodeNotReadyEvent, domain.NodeTaintsChangedEvent}, eventNames(events))
+	payload := events[1].Payload().(*domain.NodeTaintsChangedPayload)
+	assert.Equal(t, []domain.Taint{{Key: "node.kubernetes.io/unreachable", Effect: "NoExecute"}}, payload.Added)
+	assert.Empty(t, payload.Removed)
+
+	events = nodeHealthEvents(readyNode(v1.ConditionFalse, false, unreachable), toDomain(readyNode(v1.ConditionTrue, false)))
+	assert.Equal(t, []string{domain.NodeReadyEvent, domain.NodeTaintsChangedEvent}, eventNames(events))
+	assert.Len(t, events[1].Payload().(*domain.NodeTaintsChangedPayload).Removed, 1)
+
+	events = nodeHealthEvents(readyNode(v1.ConditionTrue, false), toDomain(readyNode(v1.ConditionTrue, true, unschedulable)))
+	assert.Equal(t, []string{domain.NodeCordonedEvent, domain.NodeTaintsChangedEvent}, eventNames(events))
+
+	events = nodeHealthEvents(readyNode(v1.ConditionTrue, true, unschedulable), toDomain(readyNode(v1.ConditionTrue, false)))
+	assert.Equal(t, []string{domain.NodeUncordonedEvent, domain.NodeTaintsChangedEvent}, eventNames(events))
+
+	// PreferNoSchedule is not tracked
+	events = nodeHealthEvents(readyNode(v1.ConditionTrue, false), toDomain(readyNode(v1.ConditionTrue, false, preferNoSchedule)))
+	assert.Empty(t, events)
+
+	// added nodes are compared to a healthy one
+	events = nodeHealthEvents(nil, toDomain(readyNode(v1.ConditionFalse, true, unschedulable)))
+	assert.Equal(t, []string{domain.NodeNotReadyEvent, domain.NodeCordonedEvent, domain.NodeTaintsChangedEvent}, eventNames(events))
+	events = nodeHealthEvents(nil, toDomain(readyNode(v1.ConditionTrue, false)))
+	assert.Empty(t, events)
+}
+
+// the monitor restarted while the node was disrupted
+func TestOnAddObject_NodeNotReady(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	// add, labels cache and provision events, plus not ready and cordoned
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	h.OnAddObject(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Node",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name": "aks-viz3d4-33002848-vmss0001nc",
+				"labels": map[string]interface{}{
+					"accelerator": "nvidia",
+					"agentpool":   "viz3d",
+				},
+			},
+			"spec": map[string]interface{}{
+				"unschedulable": true,
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":    "Ready",
+						"status":  "False",
+						"reason":  "KubeletNotReady",
+						"message": "PLEG is not healthy",
+					},
+				},
+			},
+		},
+	})
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(4).(ddd.IEvent)
+	assert.Equal(t, domain.NodeNotReadyEvent, event.EventName())
+	added := event.Payload().(*domain.NodeEventPayload).Node
+	assert.False(t, added.Ready)
+	assert.Equal(t, "KubeletNotReady", added.ReadyReason)
+	assert.True(t, added.Unschedulable)
+	assert.Equal(t, domain.NodeCordonedEvent, eventDispatcher.Calls[0].Arguments.Get(5).(ddd.IEvent).EventName())
+}
+
+func TestOnUpdateObject_NodeNotReady(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	node := func(status string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Node",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name": "aks-viz3d4-33002848-vmss0001nc",
+					"labels": map[string]interface{}{
+						"accelerator": "nvidia",
+						"agentpool":   "viz3d",
+					},
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{
+							"type":    "Ready",
+							"status":  status,
+							"reason":  "KubeletNotReady",
+							"message": "PLEG is not healthy",
+						},
+					},
+				},
+			},
+		}
+	}
+	h.OnUpdateObject(node("True"), node("False"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(2).(ddd.IEvent)
+	assert.Equal(t, domain.NodeNotReadyEvent, event.EventName())
+	updated := event.Payload().(*domain.NodeEventPayload).Node
+	assert.False(t, updated.Ready)
+	assert.Equal(t, "KubeletNotReady", updated.ReadyReason)
+}
+
+func TestOnUpdateObject_DriverVersionChanged(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	// update and labels cache events, plus the driver version change
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	node := func(major string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Node",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name": "aks-viz3d4-33002848-vmss0001nc",
+					"labels": map[string]interface{}{
+						"accelerator":                  "nvidia",
+						"agentpool":                    "viz3d",
+						"nvidia.com/cuda.driver.major": major,
+						"nvidia.com/cuda.driver.minor": "104",
+						"nvidia.com/cuda.driver.rev":   "05",
+					},
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "True"},
+					},
+				},
+			},
+		}
+	}
+	// same driver, no change event
+	h.OnUpdateObject(node("535"), node("535"))
+	// upgraded driver
+	h.OnUpdateObject(node("525"), node("535"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 2)
+	event := eventDispatcher.Calls[1].Arguments.Get(3).(ddd.IEvent)
+	assert.Equal(t, domain.NodeDriverVersionChangedEvent, event.EventName())
+	payload := event.Payload().(*domain.NodeDriverVersionChangedPayload)
+	assert.Equal(t, "525.104.05", payload.PreviousDriverVersion)
+	assert.Equal(t, "535.104.05", payload.Node.DriverVersion)
+}
+
+// the gpu feature discovery labelled the node, its driver version did not change
+func TestOnUpdateObject_DriverVersionLabelled(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	// update and labels cache events only
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	node := func(labels map[string]interface{}) *unstructured.Unstructured {
+		labels["accelerator"] = "nvidia"
+		labels["agentpool"] = "viz3d"
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Node",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name":   "aks-viz3d4-33002848-vmss0001nc",
+					"labels": labels,
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "True"},
+					},
+				},
+			},
+		}
+	}
+	h.OnUpdateObject(node(map[string]interface{}{}), node(map[string]interface{}{
+		"nvidia.com/cuda.driver.major": "535",
+		"nvidia.com/cuda.driver.minor": "104",
+		"nvidia.com/cuda.driver.rev":   "05",
+	}))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	for _, arg := range eventDispatcher.Calls[0].Arguments[1:] {
+		assert.NotEqual(t, domain.NodeDriverVersionChangedEvent, arg.(ddd.IEvent).EventName())
+	}
+}
+
+func TestOnUpdateObject_Unselected(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	node := func(accelerator string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Node",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name": "aks-viz3d4-33002848-vmss0001nc",
+					"labels": map[string]interface{}{
+						"accelerator": accelerator,
+						"agentpool":   "viz3d",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		}
+	}
+	// never observed, nothing to remove
+	h.OnUpdateObject(node("none"), node("none"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+	// relabelled out of the selection
+	h.OnUpdateObject(node("nvidia"), node("none"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.NodeDeleteEvent, event.EventName())
+	removed := event.Payload().(*domain.NodeEventPayload).Node
+	assert.Equal(t, "aks-viz3d4-33002848-vmss0001nc", removed.Name)
+	assert.Equal(t, "viz3d", (*removed.Labels)["agentpool"])
+	assert.Equal(t, "nvidia", (*removed.Labels)["accelerator"])
+}
+
+func TestOnUpdateObject_EvictionPending(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"})
+	config.On("Get", "app.spot_nodes").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	// update and eviction pending events
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	h := NewNodeEventHandler(ctx, logger, config, &eventDispatcher, &eventHandler, newNodeSelectors(t, config), NewSpotNodes(config))
+	node := func(scheduled string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Node",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name": "aks-spot-33002848-vmss0001nc",
+					"labels": map[string]interface{}{
+						"accelerator":                           "nvidia",
+						"agentpool":                             "spot",
+						"kubernetes.azure.com/scalesetpriority": "spot",
+					},
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "True"},
+						map[string]interface{}{"type": "VMEventScheduled", "status": scheduled, "reason": "VMEventScheduled",
+							"message": "Preempt @ Mon, 19 Oct 2026 10:00:00 GMT"},
+					},
+				},
+			},
+		}
+	}
+	h.OnUpdateObject(node("False"), node("True"))
+	// already pending, nothing new
+	h.OnUpdateObject(node("True"), node("True"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 2)
+	event := eventDispatcher.Calls[0].Arguments.Get(2).(ddd.IEvent)
+	assert.Equal(t, domain.NodeEvictionPendingEvent, event.EventName())
+	payload := event.Payload().(*domain.NodeEvictionPendingPayload)
+	assert.True(t, payload.Node.Spot)
+	assert.Equal(t, "VMEventScheduled", payload.Signal)
+	assert.Len(t, eventDispatcher.Calls[1].Arguments, 2)
+}
+
+func TestPruneGpuInventory(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"}).Once()
+	node := func(name string, labels map[string]interface{}) interface{} {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   name,
+					"labels": labels,
+				},
+			},
+		}
+	}
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("List").Return([]interface{}{
+		node("node-1", map[string]interface{}{"accelerator": "nvidia", "agentpool": "viz"}),
+		// not observed
+		node("node-2", map[string]interface{}{"agentpool": "cpu"}),
+	}).Once()
+	gpuInventory := gpu.NewMockIInventory(t)
+	gpuInventory.On("Prune", ctx, map[string]string{"node-1": "viz"}).Return(nil).Once()
+
+	assert.Nil(t, PruneGpuInventory(ctx, logger, newNodeSelectors(t, config), informer, gpuInventory))
+}
+
+func TestPruneNodeTimestamps(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"}).Once()
+	node := func(name string, labels map[string]interface{}) interface{} {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   name,
+					"labels": labels,
+				},
+			},
+		}
+	}
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("List").Return([]interface{}{
+		node("node-2", map[string]interface{}{"accelerator": "nvidia", "agentpool": "viz"}),
+		node("node-1", map[string]interface{}{"accelerator": "nvidia", "agentpool": "viz"}),
+		// not observed
+		node("node-3", map[string]interface{}{"agentpool": "cpu"}),
+	}).Once()
+	sessionService := session.NewMockISessionService(t)
+	sessionService.On("PruneNodes", []string{"node-1", "node-2"}).Return(nil).Once()
+
+	assert.Nil(t, PruneNodeTimestamps(logger, newNodeSelectors(t, config), informer, sessionService))
+}
+
+func TestPruneAgentPools(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	config := &config.MockIConfig{}
+	config.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"}).Once()
+	config.On("Get", "app.gpu_agent_pool_set_key").Return("GpuNodePools").Once()
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("List").Return([]interface{}{
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   "node-1",
+					"labels": map[string]interface{}{"accelerator": "nvidia", "agentpool": "viz"},
+				},
+			},
+		},
+	}).Once()
+	agentPools := NewMockIAgentPoolStore(t)
+	agentPools.On("Prune", ctx, map[string]string{"node-1": "viz"}).Return(nil).Once()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("GetUnsortedSetMembers", ctx, "GpuNodePools").Return([]string{"viz", "gone-2", "gone-1"}, nil).Once()
+	mockKVRepository.On("RemoveFromUnsortedSet", ctx, "GpuNodePools", "gone-1", "gone-2").Return(int64(2), nil).Once()
+	mockKVRepository.On("DeleteKeys", ctx, "gone-1", "gone-2").Return(int64(2), nil).Once()
+
+	assert.Nil(t, PruneAgentPools(ctx, logger, config, newNodeSelectors(t, config), informer, agentPools, mockKVRepository))
+}
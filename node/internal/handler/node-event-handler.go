@@ -3,10 +3,15 @@ package handler
 import (
 	"context"
 	"errors"
+	"sort"
 
+	"github.com/thoas/go-funk"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
@@ -28,6 +33,8 @@ type NodeEventHandler struct {
 	logger                *zap.Logger
 	config                config.IConfig
 	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	selectors             *NodeSelectors
+	spotNodes             *SpotNodes
 }
 
 func NewNodeEventHandler(
@@ -35,12 +42,16 @@ func NewNodeEventHandler(
 	logger *zap.Logger,
 	config config.IConfig,
 	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent],
-	domainEventHandler ddd.IEventHandler[ddd.IEvent]) k8s.IK8sEventHandler {
+	domainEventHandler ddd.IEventHandler[ddd.IEvent],
+	selectors *NodeSelectors,
+	spotNodes *SpotNodes) k8s.IK8sEventHandler {
 	return &NodeEventHandler{
 		ctx,
 		logger,
 		config,
 		domainEventDispatcher,
+		selectors,
+		spotNodes,
 	}
 }
 
@@ -54,16 +65,14 @@ func (handler *NodeEventHandler) CustomWatchErrorHandler(r *cache.Reflector, err
 	))
 }
 
-func (handler *NodeEventHandler) shouldIgnore(nodeLables *map[string]string, gpuObserveeMap *map[string]string) bool {
-	nodeShouldBeIgnored := false
-	for key, value := range *gpuObserveeMap {
-		value2, exist := (*nodeLables)[key]
-		if !exist || value2 != value {
-			nodeShouldBeIgnored = true
-			break
-		}
+// the class of the node, false when no node selector matches
+func (handler *NodeEventHandler) classify(node *v1.Node) (string, bool) {
+	selector, observed := handler.selectors.Select(node.Labels)
+	if observed {
+		handler.logger.Sugar().Infow("Node is observed", "Name", node.Name, "AgentPool", node.Labels["agentpool"],
+			"Selector", selector.Name, "Class", selector.Class)
 	}
-	return nodeShouldBeIgnored
+	return selector.Class, observed
 }
 
 func (handler *NodeEventHandler) OnAddObject(obj interface{}) {
@@ -71,27 +80,13 @@ func (handler *NodeEventHandler) OnAddObject(obj interface{}) {
 	if err != nil {
 		handler.logger.Sugar().Error("OnAddObject:", err)
 	} else {
-		name := node.Name
-		agentPoolName := node.Labels["agentpool"]
-		gpu_observee_labels_interface := handler.config.Get("app.gpu_observee_labels").([]interface{})
-		gpuObserveeMap := map[string]string{}
-		for i := 0; i < len(gpu_observee_labels_interface)/2; i++ {
-			k, v := gpu_observee_labels_interface[2*i].(string), gpu_observee_labels_interface[2*i+1].(string)
-			gpuObserveeMap[k] = v
-		}
-		if !handler.shouldIgnore(&node.Labels, &gpuObserveeMap) {
-			handler.logger.Sugar().Infof("[OnAddObject] observer agentPoolName: %f", agentPoolName)
+		if class, observed := handler.classify(&node); observed {
 			driverVersion, _ := parseGPUDriverVersion(&node.Labels, handler.logger)
 			// two event to dispatch
 			// 1. updateGPUNodeAgentPoolLabelsCache
 			// 2. record node provision timestamp
-			nodeDomain := &domain.Node{
-				Name:          name,
-				DriverVersion: driverVersion,
-				Labels:        &node.Labels,
-			}
-			handler.domainEventDispatcher.Publish(
-				handler.ctx,
+			nodeDomain := handler.nodeDomain(&node, class, driverVersion)
+			events := []ddd.IEvent{
 				ddd.NewEvent(
 					domain.NodeAddEvent,
 					&domain.NodeEventPayload{
@@ -108,7 +103,13 @@ func (handler *NodeEventHandler) OnAddObject(obj interface{}) {
 						Node: nodeDomain,
 					},
 				),
-			)
+			}
+			// the monitor restarted while the node was disrupted or the eviction was pending
+			events = append(events, nodeHealthEvents(nil, nodeDomain)...)
+			if event, pending := handler.evictionPendingEvent(nil, &node, nodeDomain); pending {
+				events = append(events, event)
+			}
+			handler.domainEventDispatcher.Publish(handler.ctx, events...)
 		}
 	}
 }
@@ -118,69 +119,87 @@ func (handler *NodeEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
 	if err != nil {
 		handler.logger.Sugar().Error("OnUpdateObject:", err)
 	} else {
-		name := node.Name
-		agentPoolName := node.Labels["agentpool"]
-		gpu_observee_labels_interface := handler.config.Get("app.gpu_observee_labels").([]interface{})
-		gpuObserveeMap := map[string]string{}
-		for i := 0; i < len(gpu_observee_labels_interface)/2; i++ {
-			k, v := gpu_observee_labels_interface[2*i].(string), gpu_observee_labels_interface[2*i+1].(string)
-			gpuObserveeMap[k] = v
-		}
-		if !handler.shouldIgnore(&node.Labels, &gpuObserveeMap) {
-			handler.logger.Sugar().Infof("[OnDeleteObject] observer agentPoolName: %f", agentPoolName)
+		if class, observed := handler.classify(&node); observed {
 			driverVersion, driverVersionErr := parseGPUDriverVersion(&node.Labels, handler.logger)
 			// one event to dispatch
 			// 1. updateGPUNodeAgentPoolLabelsCache only driverVersion is ready
-			nodeDomain := &domain.Node{
-				Name:          name,
-				DriverVersion: driverVersion,
-				Labels:        &node.Labels,
+			nodeDomain := handler.nodeDomain(&node, class, driverVersion)
+			events := []ddd.IEvent{
+				ddd.NewEvent(
+					domain.NodeUpdateEvent,
+					&domain.NodeEventPayload{
+						Node: nodeDomain,
+					}),
 			}
 			if driverVersionErr == nil {
-				handler.domainEventDispatcher.Publish(
-					handler.ctx,
-					ddd.NewEvent(
-						domain.NodeUpdateEvent,
-						&domain.NodeEventPayload{
-							Node: nodeDomain,
-						}),
-					ddd.NewEvent(
-						domain.NodeUpdateLabelsCacheEvent,
-						&domain.NodeEventPayload{
-							Node: nodeDomain,
-						}))
-			} else {
-				handler.domainEventDispatcher.Publish(
-					handler.ctx,
-					ddd.NewEvent(
-						domain.NodeUpdateEvent,
-						&domain.NodeEventPayload{
-							Node: nodeDomain,
-						}))
+				events = append(events, ddd.NewEvent(
+					domain.NodeUpdateLabelsCacheEvent,
+					&domain.NodeEventPayload{
+						Node: nodeDomain,
+					}))
+			}
+			if oldObj != nil {
+				if oldNode, err := parseNode(oldObj.(*unstructured.Unstructured)); err == nil {
+					events = append(events, nodeHealthEvents(&oldNode, nodeDomain)...)
+					// labels are missing until the gpu feature discovery ran, removed labels are not a change
+					oldDriverVersion, _ := parseGPUDriverVersion(&oldNode.Labels, handler.logger)
+					if driverVersionErr == nil && oldDriverVersion != "" && oldDriverVersion != driverVersion {
+						events = append(events, ddd.NewEvent(
+							domain.NodeDriverVersionChangedEvent,
+							&domain.NodeDriverVersionChangedPayload{
+								Node:                  nodeDomain,
+								PreviousDriverVersion: oldDriverVersion,
+							}))
+					}
+					if event, pending := handler.evictionPendingEvent(&oldNode, &node, nodeDomain); pending {
+						events = append(events, event)
+					}
+				}
 			}
+			handler.domainEventDispatcher.Publish(handler.ctx, events...)
+		} else if oldObj != nil {
+			handler.unobserve(oldObj.(*unstructured.Unstructured))
 		}
 	}
 }
 
+// a node relabelled out of the selection is removed as if it was deleted,
+// the old labels name the agent pool it leaves
+func (handler *NodeEventHandler) unobserve(oldObj *unstructured.Unstructured) {
+	oldNode, err := parseNode(oldObj)
+	if err != nil {
+		return
+	}
+	selector, observed := handler.selectors.Select(oldNode.Labels)
+	if !observed {
+		return
+	}
+	handler.logger.Sugar().Infow("Node is no longer observed", "Name", oldNode.Name, "AgentPool", oldNode.Labels["agentpool"],
+		"Selector", selector.Name, "Class", selector.Class)
+	handler.domainEventDispatcher.Publish(
+		handler.ctx,
+		ddd.NewEvent(
+			domain.NodeDeleteEvent,
+			&domain.NodeEventPayload{
+				Node: &domain.Node{
+					Name:   oldNode.Name,
+					Labels: &oldNode.Labels,
+					Class:  selector.Class,
+				},
+			}))
+}
+
 func (handler *NodeEventHandler) OnDeleteObject(obj interface{}) {
 	node, err := parseNode(obj.(*unstructured.Unstructured))
 	if err != nil {
 		handler.logger.Sugar().Error("OnDeleteObject:", err)
 	} else {
 		name := node.Name
-		agentPoolName := node.Labels["agentpool"]
-		gpu_observee_labels_interface := handler.config.Get("app.gpu_observee_labels").([]interface{})
-		gpuObserveeMap := map[string]string{}
-		for i := 0; i < len(gpu_observee_labels_interface)/2; i++ {
-			k, v := gpu_observee_labels_interface[2*i].(string), gpu_observee_labels_interface[2*i+1].(string)
-			gpuObserveeMap[k] = v
-		}
-		handler.logger.Sugar().Info(gpuObserveeMap)
-		if !handler.shouldIgnore(&node.Labels, &gpuObserveeMap) {
-			handler.logger.Sugar().Infof("[OnDeleteObject] observer agentPoolName: %f", agentPoolName)
+		if class, observed := handler.classify(&node); observed {
 			nodeDomain := &domain.Node{
 				Name:   name,
 				Labels: &node.Labels,
+				Class:  class,
 			}
 			handler.domainEventDispatcher.Publish(
 				handler.ctx,
@@ -194,6 +213,207 @@ func (handler *NodeEventHandler) OnDeleteObject(obj interface{}) {
 	}
 }
 
+func (handler *NodeEventHandler) nodeDomain(node *v1.Node, class string, driverVersion string) *domain.Node {
+	ready := nodeReadyCondition(node)
+	return &domain.Node{
+		Name:              node.Name,
+		DriverVersion:     driverVersion,
+		Labels:            &node.Labels,
+		Class:             class,
+		Spot:              handler.spotNodes.IsSpot(node),
+		CreationTimestamp: nodeCreationTimestamp(node),
+		ReadyTimestamp:    nodeReadyTimestamp(node),
+		GpuCapacity:       gpu.ResourcesOf(node.Status.Capacity),
+		GpuAllocatable:    gpu.ResourcesOf(node.Status.Allocatable),
+		Ready:             ready.Status == v1.ConditionTrue,
+		ReadyReason:       ready.Reason,
+		ReadyMessage:      ready.Message,
+		Unschedulable:     node.Spec.Unschedulable,
+		Taints:            nodeTaints(node),
+	}
+}
+
+// an eviction signal of a spot node the old node did not have yet, old node is nil on add
+func (handler *NodeEventHandler) evictionPendingEvent(oldNode *v1.Node, node *v1.Node, nodeDomain *domain.Node) (ddd.IEvent, bool) {
+	if !nodeDomain.Spot {
+		return nil, false
+	}
+	signal, message, pending := handler.spotNodes.EvictionSignal(node)
+	if !pending {
+		return nil, false
+	}
+	if oldNode != nil {
+		if oldSignal, _, oldPending := handler.spotNodes.EvictionSignal(oldNode); oldPending && oldSignal == signal {
+			return nil, false
+		}
+	}
+	handler.logger.Sugar().Warnw("Spot node eviction is pending", "Name", node.Name, "Signal", signal, "Message", message)
+	return ddd.NewEvent(
+		domain.NodeEvictionPendingEvent,
+		&domain.NodeEvictionPendingPayload{
+			Node:    nodeDomain,
+			Signal:  signal,
+			Message: message,
+		}), true
+}
+
+func nodeCreationTimestamp(node *v1.Node) int64 {
+	if node.CreationTimestamp.IsZero() {
+		return 0
+	}
+	return node.CreationTimestamp.Unix()
+}
+
+func nodeReadyTimestamp(node *v1.Node) int64 {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Unix()
+		}
+	}
+	return 0
+}
+
+// readiness, cordon and taint transitions between the old and the new node.
+// old node is nil on add, the node is compared to a healthy one and only its disruptions are reported
+func nodeHealthEvents(oldNode *v1.Node, node *domain.Node) []ddd.IEvent {
+	events := []ddd.IEvent{}
+	payload := &domain.NodeEventPayload{
+		Node: node,
+	}
+	if oldNode == nil {
+		oldNode = &v1.Node{
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+			},
+		}
+	}
+	if wasReady := nodeReadyCondition(oldNode).Status == v1.ConditionTrue; wasReady && !node.Ready {
+		events = append(events, ddd.NewEvent(domain.NodeNotReadyEvent, payload))
+	} else if !wasReady && node.Ready {
+		events = append(events, ddd.NewEvent(domain.NodeReadyEvent, payload))
+	}
+	if !oldNode.Spec.Unschedulable && node.Unschedulable {
+		events = append(events, ddd.NewEvent(domain.NodeCordonedEvent, payload))
+	} else if oldNode.Spec.Unschedulable && !node.Unschedulable {
+		events = append(events, ddd.NewEvent(domain.NodeUncordonedEvent, payload))
+	}
+	oldTaints := nodeTaints(oldNode)
+	added, removed := taintDiff(node.Taints, oldTaints), taintDiff(oldTaints, node.Taints)
+	if len(added) > 0 || len(removed) > 0 {
+		events = append(events, ddd.NewEvent(domain.NodeTaintsChangedEvent, &domain.NodeTaintsChangedPayload{
+			Node:    node,
+			Added:   added,
+			Removed: removed,
+		}))
+	}
+	return events
+}
+
+// a node without ready condition is not ready
+func nodeReadyCondition(node *v1.Node) v1.NodeCondition {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition
+		}
+	}
+	return v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionUnknown}
+}
+
+func nodeTaints(node *v1.Node) []domain.Taint {
+	taints := []domain.Taint{}
+	for _, taint := range node.Spec.Taints {
+		if effect := string(taint.Effect); effect == domain.NoScheduleTaintEffect || effect == domain.NoExecuteTaintEffect {
+			taints = append(taints, domain.Taint{Key: taint.Key, Value: taint.Value, Effect: effect})
+		}
+	}
+	return taints
+}
+
+// taints of a missing in b
+func taintDiff(a []domain.Taint, b []domain.Taint) []domain.Taint {
+	diff := []domain.Taint{}
+	for _, taint := range a {
+		if !funk.Contains(b, taint) {
+			diff = append(diff, taint)
+		}
+	}
+	return diff
+}
+
+// deletes missed while the monitor was down never reach the handler, once the informer
+// synced the gpu inventory keeps only the observed nodes in its cache
+func PruneGpuInventory(ctx context.Context, logger *zap.Logger, selectors *NodeSelectors,
+	informer k8s.IK8sInformer, gpuInventory gpu.IInventory) error {
+	return gpuInventory.Prune(ctx, listObservedNodes(logger, selectors, informer))
+}
+
+// same for the agent pools, pools without listed nodes also leave the gpu agent pool set with their cached labels
+func PruneAgentPools(ctx context.Context, logger *zap.Logger, cfg config.IConfig, selectors *NodeSelectors,
+	informer k8s.IK8sInformer, agentPools IAgentPoolStore, kvRepo repository.IKVRepository) error {
+	listed := listObservedNodes(logger, selectors, informer)
+	if err := agentPools.Prune(ctx, listed); err != nil {
+		return err
+	}
+	listedPools := map[string]bool{}
+	for _, agentPoolName := range listed {
+		listedPools[agentPoolName] = true
+	}
+	gpuAgentPoolSetKey := config.GetString(cfg, "app.gpu_agent_pool_set_key", "GpuNodePools")
+	members, err := kvRepo.GetUnsortedSetMembers(ctx, gpuAgentPoolSetKey)
+	if err != nil {
+		logger.Sugar().Errorf("GetUnsortedSetMembers has error: %s", err.Error())
+		return err
+	}
+	stale := []string{}
+	for _, agentPoolName := range members {
+		if !listedPools[agentPoolName] {
+			stale = append(stale, agentPoolName)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	sort.Strings(stale)
+	if _, err = kvRepo.RemoveFromUnsortedSet(ctx, gpuAgentPoolSetKey, stale...); err != nil {
+		logger.Sugar().Errorf("RemoveFromUnsortedSet has error: %s", err.Error())
+		return err
+	}
+	logger.Sugar().Infow("Pruned gpu agent pool set", "AgentPools", stale)
+	_, err = kvRepo.DeleteKeys(ctx, stale...)
+	return err
+}
+
+// and for the provision timestamps of the nodes, they have no ttl
+func PruneNodeTimestamps(logger *zap.Logger, selectors *NodeSelectors, informer k8s.IK8sInformer,
+	sessionService session.ISessionService) error {
+	nodeNames := []string{}
+	for nodeName := range listObservedNodes(logger, selectors, informer) {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	return sessionService.PruneNodes(nodeNames)
+}
+
+// observed nodes of the informer cache to their agent pool
+func listObservedNodes(logger *zap.Logger, selectors *NodeSelectors, informer k8s.IK8sInformer) map[string]string {
+	listed := map[string]string{}
+	for _, obj := range informer.List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		node, err := parseNode(u)
+		if err != nil {
+			logger.Sugar().Error("listObservedNodes:", err)
+			continue
+		}
+		if _, observed := selectors.Select(node.Labels); observed {
+			listed[node.Name] = node.Labels["agentpool"]
+		}
+	}
+	return listed
+}
+
 func parseNode(u *unstructured.Unstructured) (node v1.Node, err error) {
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &node)
 	return node, err
@@ -203,10 +423,7 @@ func parseGPUDriverVersion(nodeLables *map[string]string, logger *zap.Logger) (s
 	driverVersionMajor, driverVersionMajorExist := (*nodeLables)[string(NVIDIA_DRIVER_VERSION_MAJOR)]
 	driverVersionMinor, driverVersionMinorExist := (*nodeLables)[string(NVIDIA_DRIVER_VERSION_MINOR)]
 	driverVersionRev, driverVersionRevExist := (*nodeLables)[string(NVIDIA_DRIVER_VERSION_REV)]
-	logger.Sugar().Info(nodeLables)
-	logger.Sugar().Info(driverVersionMajor)
-	logger.Sugar().Info(driverVersionMinor)
-	logger.Sugar().Info(driverVersionRev)
+	logger.Sugar().Debugw("GPU driver version labels", "Major", driverVersionMajor, "Minor", driverVersionMinor, "Rev", driverVersionRev)
 	if driverVersionMajorExist && driverVersionMinorExist && driverVersionRevExist {
 		driverVersion := driverVersionMajor + "." + driverVersionMinor + "." + driverVersionRev
 		return driverVersion, nil
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// name and class of the selector built from the legacy app.gpu_observee_labels pairs
+const (
+	legacySelectorName  = "gpu_observee_labels"
+	legacySelectorClass = "gpu"
+)
+
+// a kubernetes label selector mapped to a node class
+type NodeSelector struct {
+	Name     string
+	Class    string
+	selector labels.Selector
+}
+
+// nodes matching none of the selectors are ignored, first matching selector wins.
+// without any selector every node is observed
+type NodeSelectors struct {
+	selectors []NodeSelector
+}
+
+// configured as
+//
+//	app:
+//	  node_selectors:
+//	    - name: viz3d
+//	      class: gpu
+//	      match_labels: {accelerator: nvidia}
+//	      match_expressions:
+//	        - {key: lightops.slb.com/role, operator: In, values: [3dviz, 3dviz-large]}
+//
+// operators are In, NotIn, Exists and DoesNotExist.
+// app.gpu_observee_labels is read only when no selector is configured, its key value pairs become one selector
+func NewNodeSelectors(cfg config.IConfig) (*NodeSelectors, error) {
+	selectors := &NodeSelectors{
+		selectors: []NodeSelector{},
+	}
+	for _, s := range cast.ToSlice(cfg.Get("app.node_selectors")) {
+		m := cast.ToStringMap(s)
+		name := cast.ToString(m["name"])
+		requirements := []metav1.LabelSelectorRequirement{}
+		for _, e := range cast.ToSlice(m["match_expressions"]) {
+			expression := cast.ToStringMap(e)
+			requirements = append(requirements, metav1.LabelSelectorRequirement{
+				Key:      cast.ToString(expression["key"]),
+				Operator: metav1.LabelSelectorOperator(cast.ToString(expression["operator"])),
+				Values:   cast.ToStringSlice(expression["values"]),
+			})
+		}
+		labelSelector := &metav1.LabelSelector{
+			MatchLabels:      cast.ToStringMapString(m["match_labels"]),
+			MatchExpressions: requirements,
+		}
+		if err := selectors.add(name, cast.ToString(m["class"]), labelSelector); err != nil {
+			return nil, err
+		}
+	}
+	if len(selectors.selectors) > 0 {
+		return selectors, nil
+	}
+	pairs := cast.ToStringSlice(cfg.Get("app.gpu_observee_labels"))
+	if len(pairs) == 0 {
+		return selectors, nil
+	}
+	if len(pairs)%2 != 0 {
+		return nil, domain.NewInvalidNodeSelectorErr(legacySelectorName,
+			fmt.Sprintf("odd number of entries, label %s has no value", pairs[len(pairs)-1]))
+	}
+	matchLabels := map[string]string{}
+	for i := 0; i < len(pairs); i += 2 {
+		matchLabels[pairs[i]] = pairs[i+1]
+	}
+	if err := selectors.add(legacySelectorName, legacySelectorClass, &metav1.LabelSelector{MatchLabels: matchLabels}); err != nil {
+		return nil, err
+	}
+	return selectors, nil
+}
+
+func (s *NodeSelectors) add(name string, class string, labelSelector *metav1.LabelSelector) error {
+	if name == "" {
+		return domain.NewInvalidNodeSelectorErr(name, "name is missing")
+	}
+	if class == "" {
+		return domain.NewInvalidNodeSelectorErr(name, "class is missing")
+	}
+	for _, selector := range s.selectors {
+		if selector.Name == name {
+			return domain.NewInvalidNodeSelectorErr(name, "name is duplicated")
+		}
+	}
+	// an empty selector matches every node, most likely a typo in the config
+	if len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0 {
+		return domain.NewInvalidNodeSelectorErr(name, "no labels or expressions to match")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return domain.NewInvalidNodeSelectorErr(name, err.Error())
+	}
+	s.selectors = append(s.selectors, NodeSelector{
+		Name:     name,
+		Class:    class,
+		selector: selector,
+	})
+	return nil
+}
+
+// the first selector matching the node labels, false when the node is not observed
+func (s *NodeSelectors) Select(nodeLabels map[string]string) (NodeSelector, bool) {
+	if len(s.selectors) == 0 {
+		return NodeSelector{}, true
+	}
+	for _, selector := range s.selectors {
+		if selector.selector.Matches(labels.Set(nodeLabels)) {
+			return selector, true
+		}
+	}
+	return NodeSelector{}, false
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/domain"
+)
+
+// selectors from the legacy labels mocked by the test
+func newNodeSelectors(t *testing.T, cfg *config.MockIConfig) *NodeSelectors {
+	cfg.On("Get", "app.node_selectors").Return(nil)
+	selectors, err := NewNodeSelectors(cfg)
+	assert.Nil(t, err)
+	return selectors
+}
+
+func TestNewNodeSelectors(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.node_selectors").Return([]interface{}{
+		map[string]interface{}{
+			"name":  "viz3d",
+			"class": "gpu",
+			"match_labels": map[string]interface{}{
+				"accelerator": "nvidia",
+			},
+			"match_expressions": []interface{}{
+				map[string]interface{}{"key": "lightops.slb.com/role", "operator": "In", "values": []interface{}{"3dviz", "3dviz-large"}},
+				map[string]interface{}{"key": "kubernetes.azure.com/scalesetpriority", "operator": "DoesNotExist"},
+			},
+		},
+		map[string]interface{}{
+			"name":  "spot",
+			"class": "gpu-spot",
+			"match_expressions": []interface{}{
+				map[string]interface{}{"key": "accelerator", "operator": "Exists"},
+				map[string]interface{}{"key": "lightops.slb.com/role", "operator": "NotIn", "values": []interface{}{"system"}},
+			},
+		},
+	})
+	selectors, err := NewNodeSelectors(cfg)
+	assert.Nil(t, err)
+	cfg.AssertNotCalled(t, "Get", "app.gpu_observee_labels")
+
+	selector, observed := selectors.Select(map[string]string{
+		"accelerator":           "nvidia",
+		"lightops.slb.com/role": "3dviz-large",
+	})
+	assert.True(t, observed)
+	assert.Equal(t, "viz3d", selector.Name)
+	assert.Equal(t, "gpu", selector.Class)
+
+	selector, observed = selectors.Select(map[string]string{
+		"accelerator":                           "nvidia",
+		"lightops.slb.com/role":                 "3dviz",
+		"kubernetes.azure.com/scalesetpriority": "spot",
+	})
+	assert.True(t, observed)
+	assert.Equal(t, "gpu-spot", selector.Class)
+
+	_, observed = selectors.Select(map[string]string{
+		"accelerator":           "nvidia",
+		"lightops.slb.com/role": "system",
+	})
+	assert.False(t, observed)
+}
+
+func TestNewNodeSelectors_Legacy(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia", "lightops.slb.com/role", "3dviz"})
+	selectors := newNodeSelectors(t, cfg)
+
+	selector, observed := selectors.Select(map[string]string{
+		"accelerator":           "nvidia",
+		"lightops.slb.com/role": "3dviz",
+		"agentpool":             "viz3d",
+	})
+	assert.True(t, observed)
+	assert.Equal(t, legacySelectorName, selector.Name)
+	assert.Equal(t, legacySelectorClass, selector.Class)
+
+	_, observed = selectors.Select(map[string]string{
+		"accelerator": "nvidia",
+	})
+	assert.False(t, observed)
+}
+
+func TestNewNodeSelectors_LegacyOddLength(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.node_selectors").Return(nil)
+	cfg.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia", "lightops.slb.com/role"})
+	_, err := NewNodeSelectors(cfg)
+	assert.ErrorIs(t, err, domain.NewInvalidNodeSelectorErr(legacySelectorName,
+		"odd number of entries, label lightops.slb.com/role has no value"))
+}
+
+func TestNewNodeSelectors_Empty(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.node_selectors").Return(nil)
+	cfg.On("Get", "app.gpu_observee_labels").Return(nil)
+	selectors, err := NewNodeSelectors(cfg)
+	assert.Nil(t, err)
+	_, observed := selectors.Select(map[string]string{"agentpool": "system"})
+	assert.True(t, observed)
+}
+
+func TestNewNodeSelectors_Invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []interface{}
+		err       error
+	}{
+		{
+			"missing class",
+			[]interface{}{
+				map[string]interface{}{"name": "viz3d", "match_labels": map[string]interface{}{"accelerator": "nvidia"}},
+			},
+			domain.NewInvalidNodeSelectorErr("viz3d", "class is missing"),
+		},
+		{
+			"duplicated name",
+			[]interface{}{
+				map[string]interface{}{"name": "viz3d", "class": "gpu", "match_labels": map[string]interface{}{"accelerator": "nvidia"}},
+				map[string]interface{}{"name": "viz3d", "class": "cpu", "match_labels": map[string]interface{}{"accelerator": "none"}},
+			},
+			domain.NewInvalidNodeSelectorErr("viz3d", "name is duplicated"),
+		},
+		{
+			"nothing to match",
+			[]interface{}{
+				map[string]interface{}{"name": "viz3d", "class": "gpu"},
+			},
+			domain.NewInvalidNodeSelectorErr("viz3d", "no labels or expressions to match"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.MockIConfig{}
+			cfg.On("Get", "app.node_selectors").Return(test.selectors)
+			_, err := NewNodeSelectors(cfg)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestNewNodeSelectors_InvalidOperator(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"key": "accelerator", "operator": "Equals", "values": []interface{}{"nvidia"}},
+		{"key": "accelerator", "operator": "In"},
+		{"key": "accelerator", "operator": "Exists", "values": []interface{}{"nvidia"}},
+	}
+	for _, expression := range tests {
+		cfg := &config.MockIConfig{}
+		cfg.On("Get", "app.node_selectors").Return([]interface{}{
+			map[string]interface{}{"name": "viz3d", "class": "gpu", "match_expressions": []interface{}{expression}},
+		})
+		_, err := NewNodeSelectors(cfg)
+		assert.IsType(t, &domain.InvalidNodeSelectorErr{}, err)
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// defaults of app.spot_nodes, azure marks spot nodes with a label and a NoSchedule taint.
+// node problem detector reports the scheduled preemption as VMEventScheduled condition,
+// the node termination handlers of aws and gcp taint the node
+var (
+	defaultSpotLabels             = map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}
+	defaultSpotTaints             = []string{"kubernetes.azure.com/scalesetpriority=spot"}
+	defaultSpotEvictionConditions = []string{"VMEventScheduled"}
+	defaultSpotEvictionTaints     = []string{"aws-node-termination-handler/spot-itn", "cloud.google.com/impending-node-termination"}
+)
+
+// recognises spot nodes and the signals of their imminent eviction
+type SpotNodes struct {
+	labels             map[string]string
+	taints             []string
+	evictionConditions []string
+	evictionTaints     []string
+}
+
+// app.spot_nodes:
+//
+//	labels: {kubernetes.azure.com/scalesetpriority: spot}
+//	taints: [kubernetes.azure.com/scalesetpriority=spot]
+//	eviction_conditions: [VMEventScheduled]
+//	eviction_taints: [aws-node-termination-handler/spot-itn]
+//
+// a node is spot when one of the labels or taints matches, taints are key or key=value.
+// an eviction is pending once one of the conditions is true or one of the taints is set
+func NewSpotNodes(cfg config.IConfig) *SpotNodes {
+	m := cast.ToStringMap(cfg.Get("app.spot_nodes"))
+	spotNodes := &SpotNodes{
+		labels:             defaultSpotLabels,
+		taints:             defaultSpotTaints,
+		evictionConditions: defaultSpotEvictionConditions,
+		evictionTaints:     defaultSpotEvictionTaints,
+	}
+	if labels, exist := m["labels"]; exist {
+		spotNodes.labels = cast.ToStringMapString(labels)
+	}
+	if taints, exist := m["taints"]; exist {
+		spotNodes.taints = cast.ToStringSlice(taints)
+	}
+	if conditions, exist := m["eviction_conditions"]; exist {
+		spotNodes.evictionConditions = cast.ToStringSlice(conditions)
+	}
+	if taints, exist := m["eviction_taints"]; exist {
+		spotNodes.evictionTaints = cast.ToStringSlice(taints)
+	}
+	return spotNodes
+}
+
+func (s *SpotNodes) IsSpot(node *v1.Node) bool {
+	for key, value := range s.labels {
+		if node.Labels[key] == value {
+			return true
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, spotTaint := range s.taints {
+			if matchTaint(taint, spotTaint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the condition type or taint key announcing the eviction with a message, false when none is set
+func (s *SpotNodes) EvictionSignal(node *v1.Node) (signal string, message string, pending bool) {
+	for _, condition := range node.Status.Conditions {
+		for _, conditionType := range s.evictionConditions {
+			if string(condition.Type) == conditionType && condition.Status == v1.ConditionTrue {
+				return conditionType, fmt.Sprintf("%s %s", condition.Reason, condition.Message), true
+			}
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, evictionTaint := range s.evictionTaints {
+			if matchTaint(taint, evictionTaint) {
+				return taint.Key, fmt.Sprintf("node has taint %s", taint.ToString()), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// pattern is key or key=value, the effect is ignored
+func matchTaint(taint v1.Taint, pattern string) bool {
+	key, value, withValue := strings.Cut(pattern, "=")
+	return taint.Key == key && (!withValue || taint.Value == value)
+}
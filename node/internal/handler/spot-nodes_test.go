@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func spotNode(labels map[string]string, taints []v1.Taint, conditions ...v1.NodeCondition) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "aks-spot-33002848-vmss0001nc", Labels: labels},
+		Spec:       v1.NodeSpec{Taints: taints},
+		Status:     v1.NodeStatus{Conditions: conditions},
+	}
+}
+
+func TestSpotNodes_Defaults(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.spot_nodes").Return(nil)
+	spotNodes := NewSpotNodes(cfg)
+
+	assert.True(t, spotNodes.IsSpot(spotNode(map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}, nil)))
+	assert.True(t, spotNodes.IsSpot(spotNode(nil, []v1.Taint{
+		{Key: "kubernetes.azure.com/scalesetpriority", Value: "spot", Effect: v1.TaintEffectNoSchedule},
+	})))
+	assert.False(t, spotNodes.IsSpot(spotNode(map[string]string{"kubernetes.azure.com/scalesetpriority": "regular"}, nil)))
+
+	_, _, pending := spotNodes.EvictionSignal(spotNode(nil, nil, v1.NodeCondition{Type: "VMEventScheduled", Status: v1.ConditionFalse}))
+	assert.False(t, pending)
+
+	signal, message, pending := spotNodes.EvictionSignal(spotNode(nil, nil, v1.NodeCondition{
+		Type: "VMEventScheduled", Status: v1.ConditionTrue, Reason: "VMEventScheduled", Message: "Preempt @ Mon, 19 Oct 2026 10:00:00 GMT",
+	}))
+	assert.True(t, pending)
+	assert.Equal(t, "VMEventScheduled", signal)
+	assert.Contains(t, message, "Preempt")
+
+	signal, _, pending = spotNodes.EvictionSignal(spotNode(nil, []v1.Taint{
+		{Key: "aws-node-termination-handler/spot-itn", Value: "1697709600", Effect: v1.TaintEffectNoExecute},
+	}))
+	assert.True(t, pending)
+	assert.Equal(t, "aws-node-termination-handler/spot-itn", signal)
+}
+
+func TestSpotNodes_Config(t *testing.T) {
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.spot_nodes").Return(map[string]interface{}{
+		"labels":              map[string]interface{}{"cloud.google.com/gke-spot": "true"},
+		"taints":              []interface{}{},
+		"eviction_conditions": []interface{}{"PreemptScheduled"},
+		"eviction_taints":     []interface{}{"node.kubernetes.io/spot-eviction=imminent"},
+	})
+	spotNodes := NewSpotNodes(cfg)
+
+	assert.True(t, spotNodes.IsSpot(spotNode(map[string]string{"cloud.google.com/gke-spot": "true"}, nil)))
+	assert.False(t, spotNodes.IsSpot(spotNode(map[string]string{"kubernetes.azure.com/scalesetpriority": "spot"}, nil)))
+
+	_, _, pending := spotNodes.EvictionSignal(spotNode(nil, nil, v1.NodeCondition{Type: "VMEventScheduled", Status: v1.ConditionTrue}))
+	assert.False(t, pending)
+	_, _, pending = spotNodes.EvictionSignal(spotNode(nil, nil, v1.NodeCondition{Type: "PreemptScheduled", Status: v1.ConditionTrue}))
+	assert.True(t, pending)
+	_, _, pending = spotNodes.EvictionSignal(spotNode(nil, []v1.Taint{
+		{Key: "node.kubernetes.io/spot-eviction", Value: "later", Effect: v1.TaintEffectNoSchedule},
+	}))
+	assert.False(t, pending, "value of the taint does not match")
+}
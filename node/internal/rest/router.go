@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/handler"
+)
+
+type GpuInventoryRouter struct {
+	handler handler.IGpuInventoryHandler
+	mux     *chi.Mux
+	ctx     context.Context
+}
+
+func NewGpuInventoryRouter(handler handler.IGpuInventoryHandler, ctx context.Context, mux *chi.Mux) *GpuInventoryRouter {
+	return &GpuInventoryRouter{
+		handler: handler,
+		mux:     mux,
+		ctx:     ctx,
+	}
+}
+
+func (router *GpuInventoryRouter) Register() error {
+	r := chi.NewRouter()
+	r.Get("/nodes", router.handler.ListNodes)
+	r.Get("/nodes/{nodeName}", router.handler.GetNode)
+	r.Get("/pools", router.handler.ListPools)
+	r.Get("/pools/{agentPool}", router.handler.GetPool)
+	// mounting path must be unique
+	router.mux.Mount("/gpus", r)
+	return nil
+}
@@ -0,0 +1,33 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/test"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/handler"
+)
+
+func TestNewGpuInventoryRouter_Register(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	mux := chi.NewRouter()
+	mockHandler := handler.NewMockIGpuInventoryHandler(t)
+	mockHandler.On("ListNodes", mock.Anything, mock.Anything).Return().Once()
+	mockHandler.On("GetNode", mock.Anything, mock.Anything).Return().Once()
+	mockHandler.On("ListPools", mock.Anything, mock.Anything).Return().Once()
+	mockHandler.On("GetPool", mock.Anything, mock.Anything).Return().Once()
+	router := NewGpuInventoryRouter(mockHandler, ctx, mux)
+	router.Register()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	for _, path := range []string{"/gpus/nodes", "/gpus/nodes/node-1", "/gpus/pools", "/gpus/pools/viz"} {
+		if _, body := test.TestRequest(t, ts, "GET", path, nil); body != "" {
+			t.Fatalf(body)
+		}
+	}
+}
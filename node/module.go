@@ -6,13 +6,18 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/internal/worker"
 	"github.com/xcheng85/session-monitor-k8s/node/internal/handler"
+	"github.com/xcheng85/session-monitor-k8s/node/internal/rest"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
 )
 
 type NodeMonitoringModule struct{}
@@ -45,6 +50,22 @@ func (m NodeMonitoringModule) Startup(ctx context.Context, mono module.IModuleCo
 	if err != nil {
 		return nil, err
 	}
+	err = container.Provide(handler.NewNodeSelectors)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewDriverCompatibility)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewSpotNodes)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewAgentPoolStore)
+	if err != nil {
+		return nil, err
+	}
 	err = container.Provide(handler.NewNodeEventHandler)
 	if err != nil {
 		return nil, err
@@ -61,7 +82,58 @@ func (m NodeMonitoringModule) Startup(ctx context.Context, mono module.IModuleCo
 	if err != nil {
 		return nil, err
 	}
-	err = container.Provide(session.NewSessionService)
+	err = container.Provide(func() session.ISessionService {
+		return mono.SessionService()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() gpu.IInventory {
+		return mono.GpuInventory()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() scaleup.ITracker {
+		return mono.ScaleUpTracker()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewGpuInventoryHandler)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(rest.NewGpuInventoryRouter)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Invoke(func(r *rest.GpuInventoryRouter) error {
+		return r.Register()
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the app runs the informer, the gpu inventory, the agent pools and the node timestamps are pruned once its cache is synced
+	err = container.Provide(func(logger *zap.Logger, cfg config.IConfig, selectors *handler.NodeSelectors,
+		informer k8s.IK8sInformer, gpuInventory gpu.IInventory, agentPools handler.IAgentPoolStore,
+		kvRepo repository.IKVRepository, sessionService session.ISessionService) worker.Worker {
+		return func(ctx context.Context) error {
+			if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+				return nil
+			}
+			if err := handler.PruneGpuInventory(ctx, logger, selectors, informer, gpuInventory); err != nil {
+				logger.Sugar().Errorf("Prune gpu inventory has error: %s", err.Error())
+			}
+			if err := handler.PruneAgentPools(ctx, logger, cfg, selectors, informer, agentPools, kvRepo); err != nil {
+				logger.Sugar().Errorf("Prune agent pools has error: %s", err.Error())
+			}
+			if err := handler.PruneNodeTimestamps(logger, selectors, informer, sessionService); err != nil {
+				logger.Sugar().Errorf("Prune node timestamps has error: %s", err.Error())
+			}
+			return nil
+		}
+	}, dig.Group("workers"))
 	if err != nil {
 		return nil, err
 	}
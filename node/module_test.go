@@ -5,13 +5,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 )
 
 func Test_ModuleStartup(t *testing.T) {
@@ -28,8 +32,18 @@ func Test_ModuleStartup(t *testing.T) {
 	mockModuleCtx.On("Config").Return(mockConfig).Once()
 	mockModuleCtx.On("EventDispatcher").Return(mockEventDispatcher).Once()
 	mockModuleCtx.On("KvRepository").Return(mockKVRepository).Once()
+	mockModuleCtx.On("SessionService").Return(&session.MockISessionService{}).Once()
+	mockModuleCtx.On("GpuInventory").Return(&gpu.MockIInventory{}).Once()
+	mockModuleCtx.On("ScaleUpTracker").Return(&scaleup.MockITracker{}).Once()
+	mockModuleCtx.On("Mux").Return(chi.NewRouter()).Once()
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockConfig.On("Get", "app.node_selectors").Return(nil).Once()
+	mockConfig.On("Get", "app.gpu_observee_labels").Return([]interface{}{"accelerator", "nvidia"}).Once()
+	mockConfig.On("Get", "app.driver_compatibility").Return(nil).Once()
+	mockConfig.On("Get", "app.spot_nodes").Return(nil).Once()
+	mockConfig.On("Get", "app.agent_pools_key").Return(nil).Once()
 	mockConfig.On("Get", "app.kube_config").Return("", nil).Once()
 
 	module := NewNodeMonitoringModule()
@@ -0,0 +1,25 @@
+package domain
+
+type ContainerRole string
+
+const (
+	// must be running and ready, any termination fails the session. default for containers
+	CriticalContainer ContainerRole = "critical"
+	// must be running and ready, or have exited with code 0
+	RequiredContainer ContainerRole = "required"
+	// may exit with code 0 and does not gate readiness. default for init containers
+	CompletableContainer ContainerRole = "completable"
+	// neither gates readiness nor fails the session, e.g. log shipper sidecars
+	IgnoredContainer ContainerRole = "ignored"
+)
+
+// pod annotation overriding the configured rules, e.g. "log-shipper=ignored,warmup=completable"
+const ContainerRolesAnnotation = "session-monitor/container-roles"
+
+func (r ContainerRole) IsValid() bool {
+	switch r {
+	case CriticalContainer, RequiredContainer, CompletableContainer, IgnoredContainer:
+		return true
+	}
+	return false
+}
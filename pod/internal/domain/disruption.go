@@ -0,0 +1,16 @@
+package domain
+
+// the session was healthy, the broker may reschedule it instead of reporting a user error
+type DisruptionCause string
+
+const (
+	Eviction     DisruptionCause = "Eviction"
+	Preemption   DisruptionCause = "Preemption"
+	NodeShutdown DisruptionCause = "NodeShutdown"
+)
+
+type Disruption struct {
+	Cause   DisruptionCause `json:"cause"`
+	Reason  string          `json:"reason,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
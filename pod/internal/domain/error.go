@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+)
+
+type InvalidContainerRoleErr struct {
+	container string
+	role      string
+}
+
+func (r *InvalidContainerRoleErr) Error() string {
+	return fmt.Sprintf("container: %s has invalid role: %s", r.container, r.role)
+}
+
+// assert style in golang
+func (s *InvalidContainerRoleErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidContainerRoleErr)
+	if !ok {
+		return false
+	}
+	return s.container == targetErr.container && s.role == targetErr.role
+}
+
+func NewInvalidContainerRoleErr(container string, role string) *InvalidContainerRoleErr {
+	return &InvalidContainerRoleErr{container, role}
+}
+
+type InvalidProbeModeErr struct {
+	mode string
+}
+
+func (r *InvalidProbeModeErr) Error() string {
+	return fmt.Sprintf("reachability probe has invalid mode: %s", r.mode)
+}
+
+func (s *InvalidProbeModeErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidProbeModeErr)
+	if !ok {
+		return false
+	}
+	return s.mode == targetErr.mode
+}
+
+func NewInvalidProbeModeErr(mode string) *InvalidProbeModeErr {
+	return &InvalidProbeModeErr{mode}
+}
+
+type SessionUnreachableErr struct {
+	sessionId string
+	address   string
+	attempts  int
+	cause     error
+}
+
+func (r *SessionUnreachableErr) Error() string {
+	return fmt.Sprintf("session: %s is unreachable at %s after %d attempts: %s", r.sessionId, r.address, r.attempts, r.cause)
+}
+
+func (s *SessionUnreachableErr) Is(target error) bool {
+	targetErr, ok := target.(*SessionUnreachableErr)
+	if !ok {
+		return false
+	}
+	return s.sessionId == targetErr.sessionId && s.address == targetErr.address
+}
+
+func NewSessionUnreachableErr(sessionId string, address string, attempts int, cause error) *SessionUnreachableErr {
+	return &SessionUnreachableErr{sessionId, address, attempts, cause}
+}
+
+type InvalidMaxLifetimeRuleErr struct {
+	namespace string
+	label     string
+	seconds   int
+}
+
+func (r *InvalidMaxLifetimeRuleErr) Error() string {
+	return fmt.Sprintf("max lifetime rule is invalid, namespace: %q label: %q seconds: %d", r.namespace, r.label, r.seconds)
+}
+
+func (s *InvalidMaxLifetimeRuleErr) Is(target error) bool {
+	targetErr, ok := target.(*InvalidMaxLifetimeRuleErr)
+	if !ok {
+		return false
+	}
+	return s.namespace == targetErr.namespace && s.label == targetErr.label && s.seconds == targetErr.seconds
+}
+
+func NewInvalidMaxLifetimeRuleErr(namespace string, label string, seconds int) *InvalidMaxLifetimeRuleErr {
+	return &InvalidMaxLifetimeRuleErr{namespace, label, seconds}
+}
+
+type PodStatusForbiddenErr struct {
+	namespace string
+	name      string
+	cause     error
+}
+
+func (r *PodStatusForbiddenErr) Error() string {
+	return fmt.Sprintf("writing the status of pod %s/%s is forbidden, the service account of the session monitor needs "+
+		"a role in namespace %s with verbs [patch] on pods and [create patch] on events: %s", r.namespace, r.name, r.namespace, r.cause)
+}
+
+func (s *PodStatusForbiddenErr) Is(target error) bool {
+	targetErr, ok := target.(*PodStatusForbiddenErr)
+	if !ok {
+		return false
+	}
+	return s.namespace == targetErr.namespace && s.name == targetErr.name
+}
+
+func NewPodStatusForbiddenErr(namespace string, name string, cause error) *PodStatusForbiddenErr {
+	return &PodStatusForbiddenErr{namespace, name, cause}
+}
+
+type UnsupportedVolumeKindErr struct {
+	kind string
+}
+
+func (r *UnsupportedVolumeKindErr) Error() string {
+	return fmt.Sprintf("volume tracker does not support kind: %s", r.kind)
+}
+
+func (s *UnsupportedVolumeKindErr) Is(target error) bool {
+	targetErr, ok := target.(*UnsupportedVolumeKindErr)
+	if !ok {
+		return false
+	}
+	return s.kind == targetErr.kind
+}
+
+func NewUnsupportedVolumeKindErr(kind string) *UnsupportedVolumeKindErr {
+	return &UnsupportedVolumeKindErr{kind}
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidContainerRoleErr(t *testing.T) {
+	err := NewInvalidContainerRoleErr("log-shipper", "bogus")
+	assert.Equal(t, "container: log-shipper has invalid role: bogus", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewInvalidContainerRoleErr("log-shipper", "bogus")), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewInvalidContainerRoleErr("app", "bogus")), "should not be equal error valuewise")
+}
+
+func TestInvalidProbeModeErr(t *testing.T) {
+	err := NewInvalidProbeModeErr("udp")
+	assert.Equal(t, "reachability probe has invalid mode: udp", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewInvalidProbeModeErr("udp")), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewInvalidProbeModeErr("icmp")), "should not be equal error valuewise")
+}
+
+func TestSessionUnreachableErr(t *testing.T) {
+	err := NewSessionUnreachableErr("sessionId", "10.0.0.1:8443", 3, errors.New("connection refused"))
+	assert.Equal(t, "session: sessionId is unreachable at 10.0.0.1:8443 after 3 attempts: connection refused", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewSessionUnreachableErr("sessionId", "10.0.0.1:8443", 1, nil)), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewSessionUnreachableErr("other", "10.0.0.1:8443", 3, nil)), "should not be equal error valuewise")
+}
+
+func TestInvalidMaxLifetimeRuleErr(t *testing.T) {
+	err := NewInvalidMaxLifetimeRuleErr("ns", "", 0)
+	assert.Equal(t, `max lifetime rule is invalid, namespace: "ns" label: "" seconds: 0`, err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewInvalidMaxLifetimeRuleErr("ns", "", 0)), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewInvalidMaxLifetimeRuleErr("ns", "tier=trial", 0)), "should not be equal error valuewise")
+}
+
+func TestPodStatusForbiddenErr(t *testing.T) {
+	err := NewPodStatusForbiddenErr("viz", "pod-1", errors.New("pods \"pod-1\" is forbidden"))
+	assert.Equal(t, "writing the status of pod viz/pod-1 is forbidden, the service account of the session monitor needs "+
+		"a role in namespace viz with verbs [patch] on pods and [create patch] on events: pods \"pod-1\" is forbidden", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewPodStatusForbiddenErr("viz", "pod-1", nil)), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewPodStatusForbiddenErr("viz", "pod-2", nil)), "should not be equal error valuewise")
+}
+
+func TestUnsupportedVolumeKindErr(t *testing.T) {
+	err := NewUnsupportedVolumeKindErr("PersistentVolume")
+	assert.Equal(t, "volume tracker does not support kind: PersistentVolume", err.Error(), "error message does not match")
+	assert.Equal(t, true, err.Is(NewUnsupportedVolumeKindErr("PersistentVolume")), "should be equal error valuewise")
+	assert.Equal(t, false, err.Is(NewUnsupportedVolumeKindErr("StorageClass")), "should not be equal error valuewise")
+}
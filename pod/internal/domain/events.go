@@ -1,12 +1,24 @@
 package domain
 
+import "time"
+
 const (
 	PodNilEvent               = "PodNilEvent"
 	PodInformerErrorEvent     = "PodInformerErrorEvent"
 	PodAddEvent               = "PodAddEvent"
 	PodDeleteEvent            = "PodDeleteEvent"
 	PodReadyEvent             = "PodReadyEvent"
+	PodInitializingEvent      = "PodInitializingEvent"
+	PodRemovedEvent           = "PodRemovedEvent"
 	PodRecordPodScheduleEvent = "PodRecordPodScheduleEvent"
+	PodPendingTimeoutEvent    = "PodPendingTimeoutEvent"
+	PodEvictedEvent           = "PodEvictedEvent"
+	PodPreemptedEvent         = "PodPreemptedEvent"
+	PodNodeShutdownEvent      = "PodNodeShutdownEvent"
+	PodSessionExpiredEvent    = "PodSessionExpiredEvent"
+	PodVolumeUpdateEvent      = "PodVolumeUpdateEvent"
+	PodReachableEvent         = "PodReachableEvent"
+	PodUnreachableEvent       = "PodUnreachableEvent"
 )
 
 type PodInformerErrorPayload struct {
@@ -14,5 +26,36 @@ type PodInformerErrorPayload struct {
 }
 
 type PodEventPayload struct {
+	Pod        *Pod
+	Failure    *ContainerFailure
+	Disruption *Disruption
+}
+
+// reason and message are copied from the PodScheduled condition,
+// unless the pod is WaitingForVolume or WaitingForGpu
+type PodPendingTimeoutPayload struct {
+	Pod          *Pod
+	PendingSince time.Time
+	Reason       string
+	Message      string
+}
+
+// the session outlived its max lifetime or stopped sending heartbeats
+type PodSessionExpiredPayload struct {
+	Pod     *Pod
+	Since   time.Time
+	Reason  string
+	Message string
+}
+
+// the volumes of the session pod were claimed, bound or attached
+type PodVolumePayload struct {
+	Pod   *Pod
+	Times VolumeTimes
+}
+
+// result of the reachability probe of a ready session pod
+type PodUnreachablePayload struct {
 	Pod *Pod
+	Err error
 }
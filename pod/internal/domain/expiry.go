@@ -0,0 +1,10 @@
+package domain
+
+// pod annotation overriding the max lifetime of the session, in seconds
+const MaxLifetimeAnnotation = "session-monitor/max-lifetime-seconds"
+
+// reasons reported in the session deletable event
+const (
+	MaxLifetimeExceededReason = "MaxLifetimeExceeded"
+	IdleTimeoutReason         = "IdleTimeout"
+)
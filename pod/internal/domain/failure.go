@@ -0,0 +1,38 @@
+package domain
+
+type FailureReason string
+
+// waiting and terminated reasons reported by the kubelet, plus the ones derived by the monitor
+const (
+	OOMKilled                  FailureReason = "OOMKilled"
+	CrashLoopBackOff           FailureReason = "CrashLoopBackOff"
+	ImagePullBackOff           FailureReason = "ImagePullBackOff"
+	ErrImagePull               FailureReason = "ErrImagePull"
+	InvalidImageName           FailureReason = "InvalidImageName"
+	CreateContainerConfigError FailureReason = "CreateContainerConfigError"
+	NonZeroExitCode            FailureReason = "NonZeroExitCode"
+	RestartLimitExceeded       FailureReason = "RestartLimitExceeded"
+	ContainerTerminated        FailureReason = "ContainerTerminated"
+)
+
+// lower value is the more meaningful error for the user
+var FailureReasonPriority = map[FailureReason]int{
+	OOMKilled:                  0,
+	CreateContainerConfigError: 1,
+	ImagePullBackOff:           2,
+	ErrImagePull:               3,
+	InvalidImageName:           4,
+	CrashLoopBackOff:           5,
+	NonZeroExitCode:            6,
+	RestartLimitExceeded:       7,
+	ContainerTerminated:        8,
+}
+
+type ContainerFailure struct {
+	Reason        FailureReason `json:"reason"`
+	ContainerName string        `json:"containerName"`
+	InitContainer bool          `json:"initContainer,omitempty"`
+	ExitCode      *int32        `json:"exitCode,omitempty"`
+	RestartCount  int32         `json:"restartCount,omitempty"`
+	Message       string        `json:"message,omitempty"`
+}
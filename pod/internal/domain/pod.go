@@ -2,10 +2,26 @@ package domain
 
 const PodAggregate = "pods.CustomerAggregate"
 
+// pod label with the image of the session, matched against the driver compatibility matrix of the node module
+const ImageLabel = "sessionImage"
+
 type Pod struct {
 	Name      string `json:"name,omitempty"`
 	Namespace string `json:"namespace,omitempty"`
 	SessionId string `json:"sessionId,omitempty"`
 	NodeName  string `json:"nodeName,omitempty"`
 	Ip        string `json:"ip,omitempty"`
+	AgentPool string `json:"agentPool,omitempty"`
+	ProbePort string `json:"probePort,omitempty"`
+	Image     string `json:"image,omitempty"`
+	// unix seconds of the pod creation timestamp, 0 when unknown
+	Created int64 `json:"created,omitempty"`
+	// unix seconds of the last transition of the pod conditions to true, 0 when not true
+	ConditionTimes PodConditionTimes `json:"conditionTimes,omitempty"`
+}
+
+type PodConditionTimes struct {
+	Scheduled   int64 `json:"scheduled,omitempty"`
+	Initialized int64 `json:"initialized,omitempty"`
+	Ready       int64 `json:"ready,omitempty"`
 }
@@ -0,0 +1,24 @@
+package domain
+
+type ProbeMode string
+
+const (
+	// the session accepts tcp connections
+	TCPProbe ProbeMode = "tcp"
+	// the session answers http requests with a status below 400
+	HTTPProbe ProbeMode = "http"
+)
+
+// pod annotation with the port of the streaming server, pods without it are not probed
+const ProbePortAnnotation = "session-monitor/probe-port"
+
+// reason reported in the session failed event
+const SessionUnreachableReason = "SessionUnreachable"
+
+func (m ProbeMode) IsValid() bool {
+	switch m {
+	case TCPProbe, HTTPProbe:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package domain
+
+// annotations written back to session pods, so operators see the decisions of the monitor with kubectl
+const (
+	StateAnnotation         = "session-monitor/state"
+	EnqueuedAtAnnotation    = "session-monitor/enqueuedAt"
+	FailureReasonAnnotation = "session-monitor/failureReason"
+)
+
+// reasons of the kubernetes events recorded on session pods
+const (
+	SessionEnqueuedEventReason  = "SessionEnqueued"
+	SessionDeletableEventReason = "SessionDeletable"
+	SessionFailedEventReason    = "SessionFailed"
+)
@@ -0,0 +1,15 @@
+package domain
+
+// pending reasons reported in the session failed event, the scheduler reports Unschedulable for both
+const (
+	WaitingForVolumeReason = "WaitingForVolume"
+	WaitingForGpuReason    = "WaitingForGpu"
+)
+
+// unix seconds of the persistent volume claims of a session pod, 0 when unknown.
+// claimed and bound are the latest over the claims, attached the latest over the volume attachments
+type VolumeTimes struct {
+	Claimed  int64 `json:"claimed,omitempty"`
+	Bound    int64 `json:"bound,omitempty"`
+	Attached int64 `json:"attached,omitempty"`
+}
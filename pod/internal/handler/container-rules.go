@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+// per-container roles, configured by container name and overridable per pod by annotation
+type ContainerRules struct {
+	roles map[string]domain.ContainerRole
+}
+
+// app.container_rules: [{name: log-shipper, role: ignored}]
+func NewContainerRules(cfg config.IConfig) (*ContainerRules, error) {
+	rules := &ContainerRules{
+		roles: map[string]domain.ContainerRole{},
+	}
+	for _, rule := range cast.ToSlice(cfg.Get("app.container_rules")) {
+		r := cast.ToStringMapString(rule)
+		name, role := r["name"], domain.ContainerRole(r["role"])
+		if name == "" || !role.IsValid() {
+			return nil, domain.NewInvalidContainerRoleErr(name, string(role))
+		}
+		rules.roles[name] = role
+	}
+	return rules, nil
+}
+
+// annotation first, then configured rules, then the default of the container kind
+func (rules *ContainerRules) RoleOf(pod *v1.Pod, containerName string, initContainer bool) domain.ContainerRole {
+	if role, exist := parseContainerRolesAnnotation(pod.ObjectMeta.Annotations[domain.ContainerRolesAnnotation])[containerName]; exist {
+		return role
+	}
+	if role, exist := rules.roles[containerName]; exist {
+		return role
+	}
+	if initContainer {
+		return domain.CompletableContainer
+	}
+	return domain.CriticalContainer
+}
+
+// pod conditions fast path, otherwise evaluate container statuses against the rules.
+// a helper container exiting 0 turns ContainersReady false although the session is usable.
+func (rules *ContainerRules) IsReady(pod *v1.Pod, conditions map[v1.PodConditionType]v1.PodCondition) bool {
+	if conditions[v1.PodScheduled].Status != v1.ConditionTrue {
+		return false
+	}
+	if conditions[v1.PodInitialized].Status == v1.ConditionTrue && conditions[v1.ContainersReady].Status == v1.ConditionTrue &&
+		conditions[v1.PodReady].Status == v1.ConditionTrue {
+		return true
+	}
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return false
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if !isContainerSatisfied(status, rules.RoleOf(pod, status.Name, true)) {
+			return false
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if !isContainerSatisfied(status, rules.RoleOf(pod, status.Name, false)) {
+			return false
+		}
+	}
+	return true
+}
+
+// failed containers are left to the failure classifier
+func isContainerSatisfied(status v1.ContainerStatus, role domain.ContainerRole) bool {
+	running := status.State.Running != nil && status.Ready
+	completed := status.State.Terminated != nil && status.State.Terminated.ExitCode == 0
+	switch role {
+	case domain.IgnoredContainer, domain.CompletableContainer:
+		return true
+	case domain.RequiredContainer:
+		return running || completed
+	default:
+		return running
+	}
+}
+
+// "log-shipper=ignored,warmup=completable", invalid entries are skipped
+func parseContainerRolesAnnotation(annotation string) map[string]domain.ContainerRole {
+	roles := map[string]domain.ContainerRole{}
+	if annotation == "" {
+		return roles
+	}
+	for _, entry := range strings.Split(annotation, ",") {
+		name, role, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if found && domain.ContainerRole(role).IsValid() {
+			roles[name] = domain.ContainerRole(role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewContainerRules(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.container_rules").Return([]interface{}{
+		map[string]interface{}{"name": "log-shipper", "role": "ignored"},
+		map[string]interface{}{"name": "warmup", "role": "completable"},
+	}).Once()
+	rules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				domain.ContainerRolesAnnotation: "warmup=required, bogus",
+			},
+		},
+	}
+	assert.Equal(t, domain.IgnoredContainer, rules.RoleOf(pod, "log-shipper", false))
+	assert.Equal(t, domain.RequiredContainer, rules.RoleOf(pod, "warmup", false), "annotation overrides config")
+	assert.Equal(t, domain.CriticalContainer, rules.RoleOf(pod, "app", false))
+	assert.Equal(t, domain.CompletableContainer, rules.RoleOf(pod, "init", true))
+}
+
+func TestNewContainerRules_InvalidRole(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.container_rules").Return([]interface{}{
+		map[string]interface{}{"name": "log-shipper", "role": "bogus"},
+	}).Once()
+	_, err := NewContainerRules(mockConfig)
+	assert.True(t, domain.NewInvalidContainerRoleErr("log-shipper", "bogus").Is(err))
+}
+
+func TestContainerRules_IsReady(t *testing.T) {
+	scheduled := map[v1.PodConditionType]v1.PodCondition{
+		v1.PodScheduled:    {Type: v1.PodScheduled, Status: v1.ConditionTrue},
+		v1.PodInitialized:  {Type: v1.PodInitialized, Status: v1.ConditionTrue},
+		v1.ContainersReady: {Type: v1.ContainersReady, Status: v1.ConditionFalse},
+		v1.PodReady:        {Type: v1.PodReady, Status: v1.ConditionFalse},
+	}
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+	completed := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}
+	scenarios := []struct {
+		desc        string
+		annotation  string
+		inContainer []v1.ContainerStatus
+		expected    bool
+	}{
+		{
+			desc: "helper completed by default is not ready",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "helper", State: completed},
+			},
+			expected: false,
+		},
+		{
+			desc:       "completable helper exited 0",
+			annotation: "helper=completable",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "helper", State: completed},
+			},
+			expected: true,
+		},
+		{
+			desc:       "completable helper still running",
+			annotation: "helper=completable",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "helper", Ready: false, State: running},
+			},
+			expected: true,
+		},
+		{
+			desc:       "required warmup exited 0",
+			annotation: "warmup=required",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "warmup", State: completed},
+			},
+			expected: true,
+		},
+		{
+			desc:       "required warmup not ready",
+			annotation: "warmup=required",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "warmup", Ready: false, State: running},
+			},
+			expected: false,
+		},
+		{
+			desc:       "ignored sidecar not ready",
+			annotation: "sidecar=ignored",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: running},
+				{Name: "sidecar", Ready: false, State: running},
+			},
+			expected: true,
+		},
+		{
+			desc:       "required container not ready",
+			annotation: "sidecar=ignored",
+			inContainer: []v1.ContainerStatus{
+				{Name: "app", Ready: false, State: running},
+				{Name: "sidecar", Ready: true, State: running},
+			},
+			expected: false,
+		},
+	}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	rules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	for _, s := range scenarios {
+		t.Run(s.desc, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						domain.ContainerRolesAnnotation: s.annotation,
+					},
+				},
+				Status: v1.PodStatus{
+					ContainerStatuses: s.inContainer,
+				},
+			}
+			assert.Equal(t, s.expected, rules.IsReady(pod, scheduled))
+		})
+	}
+}
+
+func TestFailureClassifier_Classify_CompletableHelper(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				domain.ContainerRolesAnnotation: "helper=completable,log-shipper=ignored",
+			},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "app", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				{Name: "helper", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}},
+				{Name: "log-shipper", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+			},
+		},
+	}
+	assert.Nil(t, classifier.Classify(pod))
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+// condition added by the control plane before it deletes a pod (k8s 1.26+)
+const podDisruptionTarget v1.PodConditionType = "DisruptionTarget"
+
+// pod.status.reason set by the kubelet or the scheduler
+var disruptionStatusReasons = map[string]domain.DisruptionCause{
+	"Evicted":      domain.Eviction,
+	"Preempting":   domain.Preemption,
+	"Shutdown":     domain.NodeShutdown,
+	"NodeShutdown": domain.NodeShutdown,
+	"Terminated":   domain.NodeShutdown,
+	"NodeLost":     domain.NodeShutdown,
+}
+
+// reasons of the DisruptionTarget condition
+var disruptionConditionReasons = map[string]domain.DisruptionCause{
+	"PreemptionByKubeScheduler": domain.Preemption,
+	"PreemptionByScheduler":     domain.Preemption,
+	"EvictionByEvictionAPI":     domain.Eviction,
+	"DeletionByTaintManager":    domain.Eviction,
+	"TerminationByKubelet":      domain.Eviction,
+	"DeletionByPodGC":           domain.NodeShutdown,
+}
+
+var disruptionEvents = map[domain.DisruptionCause]string{
+	domain.Eviction:     domain.PodEvictedEvent,
+	domain.Preemption:   domain.PodPreemptedEvent,
+	domain.NodeShutdown: domain.PodNodeShutdownEvent,
+}
+
+// returns nil unless the pod is terminated by the cluster rather than by the session itself
+func detectDisruption(pod *v1.Pod, conditions map[v1.PodConditionType]v1.PodCondition) *domain.Disruption {
+	if cause, exist := disruptionStatusReasons[pod.Status.Reason]; exist {
+		return &domain.Disruption{
+			Cause:   cause,
+			Reason:  pod.Status.Reason,
+			Message: pod.Status.Message,
+		}
+	}
+	condition, exist := conditions[podDisruptionTarget]
+	if !exist || condition.Status != v1.ConditionTrue {
+		return nil
+	}
+	cause, known := disruptionConditionReasons[condition.Reason]
+	if !known {
+		cause = domain.Eviction
+	}
+	return &domain.Disruption{
+		Cause:   cause,
+		Reason:  condition.Reason,
+		Message: condition.Message,
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestDetectDisruption(t *testing.T) {
+	scenarios := []struct {
+		desc         string
+		inStatus     v1.PodStatus
+		expected     *domain.Disruption
+		expectedName string
+	}{
+		{
+			desc: "evicted for node pressure",
+			inStatus: v1.PodStatus{
+				Phase:   v1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: memory.",
+			},
+			expected:     &domain.Disruption{Cause: domain.Eviction, Reason: "Evicted", Message: "The node was low on resource: memory."},
+			expectedName: domain.PodEvictedEvent,
+		},
+		{
+			desc: "preempted by higher priority pod",
+			inStatus: v1.PodStatus{
+				Phase: v1.PodRunning,
+				Conditions: []v1.PodCondition{
+					{Type: "DisruptionTarget", Status: v1.ConditionTrue, Reason: "PreemptionByKubeScheduler", Message: "preempted by viz/pod-1"},
+				},
+			},
+			expected:     &domain.Disruption{Cause: domain.Preemption, Reason: "PreemptionByKubeScheduler", Message: "preempted by viz/pod-1"},
+			expectedName: domain.PodPreemptedEvent,
+		},
+		{
+			desc: "graceful node shutdown",
+			inStatus: v1.PodStatus{
+				Phase:  v1.PodFailed,
+				Reason: "Shutdown",
+			},
+			expected:     &domain.Disruption{Cause: domain.NodeShutdown, Reason: "Shutdown"},
+			expectedName: domain.PodNodeShutdownEvent,
+		},
+		{
+			desc: "unknown disruption target reason",
+			inStatus: v1.PodStatus{
+				Conditions: []v1.PodCondition{
+					{Type: "DisruptionTarget", Status: v1.ConditionTrue, Reason: "SomethingNew"},
+				},
+			},
+			expected:     &domain.Disruption{Cause: domain.Eviction, Reason: "SomethingNew"},
+			expectedName: domain.PodEvictedEvent,
+		},
+		{
+			desc: "user error",
+			inStatus: v1.PodStatus{
+				Phase: v1.PodFailed,
+				Conditions: []v1.PodCondition{
+					{Type: "DisruptionTarget", Status: v1.ConditionFalse},
+				},
+			},
+			expected: nil,
+		},
+	}
+	for _, s := range scenarios {
+		t.Run(s.desc, func(t *testing.T) {
+			pod := &v1.Pod{
+				Status: s.inStatus,
+			}
+			conditions := map[v1.PodConditionType]v1.PodCondition{}
+			for _, c := range s.inStatus.Conditions {
+				conditions[c.Type] = c
+			}
+			disruption := detectDisruption(pod, conditions)
+			assert.Equal(t, s.expected, disruption)
+			if disruption != nil {
+				assert.Equal(t, s.expectedName, disruptionEvents[disruption.Cause])
+			}
+		})
+	}
+}
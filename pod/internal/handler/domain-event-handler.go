@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
 	"go.uber.org/zap"
@@ -13,6 +14,10 @@ type domainEventHandlers[T ddd.IEvent] struct {
 	logger         *zap.Logger
 	repository     repository.IKVRepository
 	sessionService session.ISessionService
+	sessionMetrics session.ISessionMetrics
+	probe          *ReachabilityProbe
+	statusWriter   *PodStatusWriter
+	scaleUps       scaleup.ITracker
 }
 
 var _ ddd.IEventHandler[ddd.IEvent] = (*domainEventHandlers[ddd.IEvent])(nil)
@@ -21,17 +26,35 @@ func NewDomainEventHandlers(logger *zap.Logger,
 	subscriber ddd.IEventDispatcher[ddd.IEvent],
 	repository repository.IKVRepository,
 	sessionService session.ISessionService,
+	sessionMetrics session.ISessionMetrics,
+	probe *ReachabilityProbe,
+	statusWriter *PodStatusWriter,
+	scaleUps scaleup.ITracker,
 ) ddd.IEventHandler[ddd.IEvent] {
 	handler := &domainEventHandlers[ddd.IEvent]{
 		logger,
 		repository,
 		sessionService,
+		sessionMetrics,
+		probe,
+		statusWriter,
+		scaleUps,
 	}
 	subscriber.Subscribe(handler,
 		domain.PodAddEvent,
 		domain.PodDeleteEvent,
 		domain.PodReadyEvent,
+		domain.PodInitializingEvent,
+		domain.PodRemovedEvent,
 		domain.PodRecordPodScheduleEvent,
+		domain.PodPendingTimeoutEvent,
+		domain.PodEvictedEvent,
+		domain.PodPreemptedEvent,
+		domain.PodNodeShutdownEvent,
+		domain.PodSessionExpiredEvent,
+		domain.PodVolumeUpdateEvent,
+		domain.PodReachableEvent,
+		domain.PodUnreachableEvent,
 		domain.PodInformerErrorEvent,
 	)
 	return handler
@@ -47,6 +70,22 @@ func (d domainEventHandlers[T]) HandleEvent(ctx context.Context, event T) (err e
 		return d.onRecordPodScheduleTimestamp(ctx, event)
 	case domain.PodReadyEvent:
 		return d.onPodReady(ctx, event)
+	case domain.PodInitializingEvent:
+		return d.onPodInitializing(ctx, event)
+	case domain.PodRemovedEvent:
+		return d.onPodRemoved(ctx, event)
+	case domain.PodPendingTimeoutEvent:
+		return d.onPodPendingTimeout(ctx, event)
+	case domain.PodEvictedEvent, domain.PodPreemptedEvent, domain.PodNodeShutdownEvent:
+		return d.onPodDisrupted(ctx, event)
+	case domain.PodSessionExpiredEvent:
+		return d.onPodSessionExpired(ctx, event)
+	case domain.PodVolumeUpdateEvent:
+		return d.onPodVolumeUpdated(ctx, event)
+	case domain.PodReachableEvent:
+		return d.onPodReachable(ctx, event)
+	case domain.PodUnreachableEvent:
+		return d.onPodUnreachable(ctx, event)
 	}
 	return nil
 }
@@ -55,30 +94,173 @@ func (d domainEventHandlers[T]) onPodAdded(ctx context.Context, event ddd.IEvent
 	payload := event.Payload().(*domain.PodEventPayload)
 	name, namespace, sessionId := payload.Pod.Name, payload.Pod.Namespace, payload.Pod.SessionId
 	d.logger.Sugar().Infow("Pod is added", "Name", name, "Namespace", namespace, "SessionId", sessionId)
-	return nil
+	if sessionId == "" {
+		return nil
+	}
+	if _, err := d.sessionService.TransitionSession(sessionId, session.Requested, "PodAdded"); err != nil {
+		return err
+	}
+	if err := d.setSessionPod(payload.Pod); err != nil {
+		return err
+	}
+	if payload.Pod.Created == 0 {
+		return nil
+	}
+	return d.sessionService.SetPodCreateTimeStamp(&session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: payload.Pod.Created,
+		Source:    session.PodCreationSource,
+	})
+}
+
+func (d domainEventHandlers[T]) onPodInitializing(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodEventPayload)
+	if _, err := d.sessionService.TransitionSession(payload.Pod.SessionId, session.Initializing, ""); err != nil {
+		return err
+	}
+	return d.setSessionPod(payload.Pod)
+}
+
+func (d domainEventHandlers[T]) setSessionPod(pod *domain.Pod) error {
+	return d.sessionService.SetSessionPod(&session.SetSessionPodActionPayload{
+		SessionId:     pod.SessionId,
+		PodName:       pod.Name,
+		Namespace:     pod.Namespace,
+		NodeName:      pod.NodeName,
+		PodInternalIp: pod.Ip,
+		Image:         pod.Image,
+	})
+}
+
+func (d domainEventHandlers[T]) onPodRemoved(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodEventPayload)
+	sessionId := payload.Pod.SessionId
+	_, err := d.sessionService.TransitionSession(sessionId, session.Deleted, "PodRemoved")
+	// the pod is gone for good, purge even if the transition was rejected
+	if purgeErr := d.sessionService.PurgeSession(sessionId); purgeErr != nil {
+		d.logger.Sugar().Errorf("PurgeSession %s has error: %s", sessionId, purgeErr.Error())
+		return purgeErr
+	}
+	return err
 }
 
 func (d domainEventHandlers[T]) onPodDeleted(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.PodEventPayload)
 	name, namespace, sessionId := payload.Pod.Name, payload.Pod.Namespace, payload.Pod.SessionId
 	d.logger.Sugar().Infow("Pod is deleted", "Name", name, "Namespace", namespace, "SessionId", sessionId)
-	err := d.sessionService.SetSessionDeletable(&session.SetSessionDeletableActionPayload{
+	deletable := &session.SetSessionDeletableActionPayload{
 		SessionId: sessionId,
 		CallerId:  "Session-monitor-service",
-	})
-	return err
+	}
+	state, reason := session.Terminating, ""
+	if failure := payload.Failure; failure != nil {
+		d.logger.Sugar().Infow("Session failed", "SessionId", sessionId, "Reason", failure.Reason,
+			"Container", failure.ContainerName, "ExitCode", failure.ExitCode, "Message", failure.Message)
+		deletable.FailureReason, deletable.FailureMessage, deletable.ExitCode =
+			string(failure.Reason), failure.Message, failure.ExitCode
+		state, reason = session.Failing, string(failure.Reason)
+	}
+	if _, err := d.sessionService.TransitionSession(sessionId, state, reason); err != nil {
+		return err
+	}
+	return d.setSessionDeletable(ctx, payload.Pod, state, deletable, reason, deletable.FailureMessage)
+}
+
+func (d domainEventHandlers[T]) onPodDisrupted(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodEventPayload)
+	name, namespace, sessionId, nodeName, disruption := payload.Pod.Name, payload.Pod.Namespace, payload.Pod.SessionId,
+		payload.Pod.NodeName, payload.Disruption
+	d.logger.Sugar().Infow("Pod is disrupted", "Name", name, "Namespace", namespace, "SessionId", sessionId,
+		"NodeName", nodeName, "Event", event.EventName(), "Cause", disruption.Cause, "Reason", disruption.Reason)
+	if _, err := d.sessionService.TransitionSession(sessionId, session.Terminating, string(disruption.Cause)); err != nil {
+		return err
+	}
+	return d.setSessionDeletable(ctx, payload.Pod, session.Terminating, &session.SetSessionDeletableActionPayload{
+		SessionId:         sessionId,
+		CallerId:          "Session-monitor-service",
+		DisruptionCause:   string(disruption.Cause),
+		DisruptionReason:  disruption.Reason,
+		DisruptionMessage: disruption.Message,
+	}, string(disruption.Cause), disruption.Message)
+}
+
+func (d domainEventHandlers[T]) onPodSessionExpired(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodSessionExpiredPayload)
+	name, namespace, sessionId := payload.Pod.Name, payload.Pod.Namespace, payload.Pod.SessionId
+	d.logger.Sugar().Infow("Session expired, session deletable", "Name", name, "Namespace", namespace,
+		"SessionId", sessionId, "Since", payload.Since, "Reason", payload.Reason)
+	if _, err := d.sessionService.TransitionSession(sessionId, session.Terminating, payload.Reason); err != nil {
+		return err
+	}
+	return d.setSessionDeletable(ctx, payload.Pod, session.Terminating, &session.SetSessionDeletableActionPayload{
+		SessionId:     sessionId,
+		CallerId:      "Session-monitor-service",
+		ExpiryReason:  payload.Reason,
+		ExpiryMessage: payload.Message,
+	}, payload.Reason, payload.Message)
+}
+
+func (d domainEventHandlers[T]) onPodPendingTimeout(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodPendingTimeoutPayload)
+	name, namespace, sessionId := payload.Pod.Name, payload.Pod.Namespace, payload.Pod.SessionId
+	d.logger.Sugar().Infow("Pod pending timeout, session failed", "Name", name, "Namespace", namespace,
+		"SessionId", sessionId, "PendingSince", payload.PendingSince, "Reason", payload.Reason)
+	reason := payload.Reason
+	// PodScheduled is true when pending on image pull or volumes
+	if reason == "" {
+		reason = "PendingTimeout"
+	}
+	if _, err := d.sessionService.TransitionSession(sessionId, session.Failing, reason); err != nil {
+		return err
+	}
+	return d.setSessionFailed(ctx, payload.Pod, reason, payload.Message)
+}
+
+// volume times of the session timeline, unknown times are left as they are
+func (d domainEventHandlers[T]) onPodVolumeUpdated(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodVolumePayload)
+	sessionId, times := payload.Pod.SessionId, payload.Times
+	d.logger.Sugar().Infow("Pod volumes are updated", "Name", payload.Pod.Name, "Namespace", payload.Pod.Namespace,
+		"SessionId", sessionId, "Claimed", times.Claimed, "Bound", times.Bound, "Attached", times.Attached)
+	for _, timestamp := range []struct {
+		value  int64
+		source session.TimestampSource
+		set    func(*session.UpdateSessionTimeStampLikeFieldActionPayload) error
+	}{
+		{times.Claimed, session.VolumeClaimCreationSource, d.sessionService.SetVolumeClaimTimeStamp},
+		{times.Bound, session.InformerObservedSource, d.sessionService.SetVolumeBindTimeStamp},
+		{times.Attached, session.InformerObservedSource, d.sessionService.SetVolumeAttachTimeStamp},
+	} {
+		if timestamp.value == 0 {
+			continue
+		}
+		if err := timestamp.set(&session.UpdateSessionTimeStampLikeFieldActionPayload{
+			SessionId: sessionId,
+			Timestamp: timestamp.value,
+			Source:    timestamp.source,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d domainEventHandlers[T]) onRecordPodScheduleTimestamp(ctx context.Context, event ddd.IEvent) error {
 	payload := event.Payload().(*domain.PodEventPayload)
 	sessionId := payload.Pod.SessionId
-	// to do use repository to get server timestamp
-	serverTimestamp, err := d.repository.GetServerTimestamp(ctx)
-	d.logger.Sugar().Infof("Session %s is scheduled at %d", sessionId, serverTimestamp)
+	scheduled, err := d.conditionTimestamp(ctx, payload.Pod.ConditionTimes.Scheduled)
+	d.logger.Sugar().Infof("Session %s is scheduled at %d from %s", sessionId, scheduled.Value, scheduled.Source)
+	if err == nil {
+		_, err = d.sessionService.TransitionSession(sessionId, session.Scheduled, "")
+	}
+	if err == nil {
+		err = d.setSessionPod(payload.Pod)
+	}
 	if err == nil {
 		err = d.sessionService.SetPodScheduleTimeStamp(&session.UpdateSessionTimeStampLikeFieldActionPayload{
 			SessionId: sessionId,
-			Timestamp: serverTimestamp,
+			Timestamp: scheduled.Value,
+			Source:    scheduled.Source,
 		})
 	}
 	return err
@@ -94,24 +276,184 @@ func (d domainEventHandlers[T]) onPodReady(ctx context.Context, event ddd.IEvent
 		"NodeName", nodeName,
 		"Ip", ip,
 	)
+	// a deleted or failing session must not be enqueued
+	becameReady, err := d.sessionService.TransitionSession(sessionId, session.Ready, "")
+	if err != nil {
+		return err
+	}
+	if err := d.setSessionPod(payload.Pod); err != nil {
+		return err
+	}
+	// ready pods are updated many times, every report retries until the enqueue was recorded
+	if !becameReady {
+		if !d.isSessionReady(sessionId) {
+			return nil
+		}
+		enqueued, err := d.sessionService.IsSessionEnqueued(sessionId)
+		if err != nil || enqueued {
+			return err
+		}
+	}
+	if d.probe.Enabled() {
+		d.probe.Submit(payload.Pod)
+		return nil
+	}
+	return d.enqueueSession(ctx, payload.Pod)
+}
+
+func (d domainEventHandlers[T]) onPodReachable(ctx context.Context, event ddd.IEvent) error {
+	pod := event.Payload().(*domain.PodEventPayload).Pod
+	if !d.isSessionReady(pod.SessionId) {
+		return nil
+	}
+	return d.enqueueSession(ctx, pod)
+}
 
-	nodeProvisionedTimeStamp, nodeErr := d.sessionService.GetNodeProvisionTimeStamp(nodeName)
+func (d domainEventHandlers[T]) onPodUnreachable(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.PodUnreachablePayload)
+	if !d.isSessionReady(payload.Pod.SessionId) {
+		return nil
+	}
+	return d.onSessionUnreachable(ctx, payload.Pod, payload.Err)
+}
+
+// metrics are observed once, when the enqueue task was submitted
+func (d domainEventHandlers[T]) enqueueSession(ctx context.Context, pod *domain.Pod) error {
+	name, namespace, sessionId, nodeName, ip := pod.Name, pod.Namespace, pod.SessionId, pod.NodeName, pod.Ip
+	conditionTimes := pod.ConditionTimes
+	// an unknown node leaves its durations out, the session is enqueued anyway
+	nodeProvisioned, nodeErr := d.sessionService.GetNodeProvisionTimeStamp(nodeName)
 	if nodeErr != nil {
-		d.logger.Sugar().Errorf("GetNodeProvisionTimeStamp has error: %s", nodeErr.Error())
-		return nodeErr
-	}
-	d.logger.Sugar().Infof("GetNodeProvisionTimeStamp: %d", nodeProvisionedTimeStamp)
-	podScheduledTimeStamp, podErr := d.sessionService.GetPodScheduleTimeStamp(sessionId)
-	if podErr != nil {
-		d.logger.Sugar().Errorf("GetPodScheduleTimeStamp has error: %s", podErr.Error())
-		return podErr
-	}
-	d.logger.Sugar().Infof("GetPodScheduleTimeStamp: %d", podScheduledTimeStamp)
-	return d.sessionService.SetSessionReady(&session.SetSessionReadyActionPayload{
-		SessionId:              sessionId,
-		NodeName:               nodeName,
-		PodInternalIp:          ip,
-		NodeProvisionTimeStamp: nodeProvisionedTimeStamp,
-		PodScheduleTimeStamp:   podScheduledTimeStamp,
+		d.logger.Sugar().Warnf("GetNodeProvisionTimeStamp has error: %s", nodeErr.Error())
+		nodeProvisioned = session.Timestamp{}
+	}
+	d.logger.Sugar().Infof("GetNodeProvisionTimeStamp: %d", nodeProvisioned.Value)
+	// the condition outlives a restart of the monitor, the recorded timestamp is the fallback
+	podScheduled := session.Timestamp{Value: conditionTimes.Scheduled, Source: session.PodConditionSource}
+	if podScheduled.Value == 0 {
+		var podErr error
+		podScheduled, podErr = d.sessionService.GetPodScheduleTimeStamp(sessionId)
+		if podErr != nil {
+			d.logger.Sugar().Errorf("GetPodScheduleTimeStamp has error: %s", podErr.Error())
+			return podErr
+		}
+	}
+	d.logger.Sugar().Infof("GetPodScheduleTimeStamp: %d", podScheduled.Value)
+	ready, err := d.conditionTimestamp(ctx, conditionTimes.Ready)
+	if err != nil {
+		d.logger.Sugar().Errorf("GetServerTimestamp has error: %s", err.Error())
+		return err
+	}
+	if err = d.sessionService.SetReadyTimeStamp(&session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: ready.Value,
+		Source:    ready.Source,
+	}); err != nil {
+		return err
+	}
+	var podInitialized session.Timestamp
+	if conditionTimes.Initialized != 0 {
+		podInitialized = session.Timestamp{Value: conditionTimes.Initialized, Source: session.PodConditionSource}
+		if err = d.sessionService.SetPodInitializeTimeStamp(&session.UpdateSessionTimeStampLikeFieldActionPayload{
+			SessionId: sessionId,
+			Timestamp: podInitialized.Value,
+			Source:    podInitialized.Source,
+		}); err != nil {
+			return err
+		}
+	}
+	durations := session.NewSessionDurations(session.SessionTimestamps{
+		NodeProvisioned: nodeProvisioned.Value,
+		PodCreated:      pod.Created,
+		PodScheduled:    podScheduled.Value,
+		Ready:           ready.Value,
 	})
+	// only sessions which waited for the cluster autoscaler to add their node
+	var scaleUpSeconds *int64
+	if scaleUp, waited := d.scaleUps.PodScaleUp(namespace+"/"+name, nodeName); waited {
+		scaleUpSeconds = &scaleUp.Seconds
+	}
+	submitted, err := d.sessionService.SetSessionReady(&session.SetSessionReadyActionPayload{
+		SessionId:                          sessionId,
+		NodeName:                           nodeName,
+		PodInternalIp:                      ip,
+		NodeProvisionTimeStamp:             nodeProvisioned.Value,
+		PodCreateTimeStamp:                 pod.Created,
+		PodScheduleTimeStamp:               podScheduled.Value,
+		PodInitializeTimeStamp:             podInitialized.Value,
+		ReadyTimeStamp:                     ready.Value,
+		NodeProvisionTimeStampSource:       nodeProvisioned.Source,
+		PodScheduleTimeStampSource:         podScheduled.Source,
+		PodInitializeTimeStampSource:       podInitialized.Source,
+		ReadyTimeStampSource:               ready.Source,
+		NodeProvisionToPodScheduledSeconds: durations.NodeProvisionToPodScheduled,
+		PodScheduledToReadySeconds:         durations.PodScheduledToReady,
+		TimeToReadySeconds:                 durations.TimeToReady,
+		ScaleUpSeconds:                     scaleUpSeconds,
+	})
+	if err != nil || !submitted {
+		return err
+	}
+	d.sessionMetrics.ObserveSessionReady(namespace, pod.AgentPool, durations)
+	d.statusWriter.Enqueued(ctx, pod)
+	return nil
+}
+
+// the pod may have been deleted while it was probed, or the ready report is older than its deletion
+func (d domainEventHandlers[T]) isSessionReady(sessionId string) bool {
+	lifecycle, err := d.sessionService.GetSessionLifecycle(sessionId)
+	if err != nil {
+		d.logger.Sugar().Infow("Session is gone, not enqueued", "SessionId", sessionId)
+		return false
+	}
+	if state := lifecycle.State(); state != session.Ready {
+		d.logger.Sugar().Infow("Session is no longer ready, not enqueued", "SessionId", sessionId, "State", state)
+		return false
+	}
+	return true
+}
+
+// the broker must not hand out a session its clients cannot connect to
+func (d domainEventHandlers[T]) onSessionUnreachable(ctx context.Context, pod *domain.Pod, unreachable error) error {
+	sessionId := pod.SessionId
+	d.logger.Sugar().Infow("Session is unreachable, session failed", "SessionId", sessionId, "Error", unreachable.Error())
+	if _, err := d.sessionService.TransitionSession(sessionId, session.Failing, domain.SessionUnreachableReason); err != nil {
+		return err
+	}
+	return d.setSessionFailed(ctx, pod, domain.SessionUnreachableReason, unreachable.Error())
+}
+
+func (d domainEventHandlers[T]) setSessionFailed(ctx context.Context, pod *domain.Pod, reason string, message string) error {
+	submitted, err := d.sessionService.SetSessionFailed(&session.SetSessionFailedActionPayload{
+		SessionId: pod.SessionId,
+		CallerId:  "Session-monitor-service",
+		Reason:    reason,
+		Message:   message,
+	})
+	// the pod is written back once, each write updates the pod again
+	if err != nil || !submitted {
+		return err
+	}
+	d.statusWriter.Failed(ctx, pod, reason, message)
+	return nil
+}
+
+// reason and message describe why the session is deleted, empty when the session ended normally
+func (d domainEventHandlers[T]) setSessionDeletable(ctx context.Context, pod *domain.Pod, state session.LifecycleState,
+	deletable *session.SetSessionDeletableActionPayload, reason string, message string) error {
+	submitted, err := d.sessionService.SetSessionDeletable(deletable)
+	if err != nil || !submitted {
+		return err
+	}
+	d.statusWriter.Deletable(ctx, pod, state, reason, message)
+	return nil
+}
+
+// the transition time of a pod condition, the redis clock when the condition carries none
+func (d domainEventHandlers[T]) conditionTimestamp(ctx context.Context, transitionTime int64) (session.Timestamp, error) {
+	if transitionTime != 0 {
+		return session.Timestamp{Value: transitionTime, Source: session.PodConditionSource}, nil
+	}
+	serverTimestamp, err := d.repository.GetServerTimestamp(ctx)
+	return session.Timestamp{Value: serverTimestamp, Source: session.ServerTimeSource}, err
 }
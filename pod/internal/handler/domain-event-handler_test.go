@@ -2,16 +2,21 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	// "github.com/stretchr/testify/mock"
 	// "github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	// "github.com/xcheng85/session-monitor-k8s/internal/repository"
 	// "github.com/xcheng85/session-monitor-k8s/internal/session"
@@ -31,9 +36,27 @@ var (
 		Ip:        podInternalIp,
 	}
 
+	readyPod = &domain.Pod{
+		Name:      "Name",
+		Namespace: "Namespace",
+		SessionId: sessionId,
+		NodeName:  nodeName,
+		Ip:        podInternalIp,
+		AgentPool: "viz",
+		Created:   podCreatedTimestamp,
+		ConditionTimes: domain.PodConditionTimes{
+			Scheduled:   podScheduledTimestamp,
+			Initialized: podInitializedTimestamp,
+			Ready:       podReadyTimestamp,
+		},
+	}
+
 	serverTimestamp          = int64(8888888888888)
 	nodeProvisionedTimestamp = int64(6888888888888)
+	podCreatedTimestamp      = int64(7888888888880)
 	podScheduledTimestamp    = int64(7888888888888)
+	podInitializedTimestamp  = int64(7888888888900)
+	podReadyTimestamp        = int64(7888888888960)
 )
 
 func TestNewDomainEventHandlers(t *testing.T) {
@@ -42,11 +65,17 @@ func TestNewDomainEventHandlers(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(context.TODO(), logger, mockConfig)
+	assert.Nil(t, err)
 
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
 	assert.NotNil(t, h, "Pod Event Handler should not be nil")
 }
 
@@ -57,17 +86,75 @@ func TestHandleEvent_PodAddEvent(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
+	addedPod := *pod
+	addedPod.Created = podCreatedTimestamp
 	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Requested, "PodAdded").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockSessionService.On("SetPodCreateTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: podCreatedTimestamp,
+		Source:    session.PodCreationSource,
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
 
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
-	err := h.HandleEvent(ctx, ddd.NewEvent(
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodAddEvent,
 		&domain.PodEventPayload{
-			Pod: pod,
+			Pod: &addedPod,
 		}))
 	assert.Nil(t, err, "Handle PodAddEvent should not throw err")
+	mockSessionService.AssertExpectations(t)
+}
+
+// after a restart the session continues from its recorded state, the pod details are restored anyway
+func TestHandleEvent_PodAddEvent_Restored(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Requested, "PodAdded").Return(false, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodAddEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
 }
 
 func TestHandleEvent_PodDeleted(t *testing.T) {
@@ -77,21 +164,174 @@ func TestHandleEvent_PodDeleted(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Terminating, "").Return(true, nil).Once()
 	mockSessionService.On("SetSessionDeletable", &session.SetSessionDeletableActionPayload{
 		SessionId: "SessionId",
 		CallerId:  "Session-monitor-service",
-	}).Return(nil).Once()
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodDeleteEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.Nil(t, err, "Handle PodDeleted Event should not throw err")
+}
+
+// the pod is written back when the session became deletable, not on every later status update
+func TestHandleEvent_PodDeleted_AlreadyDeletable(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionService := &session.MockISessionService{}
+	mockSessionService.On("TransitionSession", "sessionId", session.Terminating, "").Return(false, nil).Once()
+	mockSessionService.On("SetSessionDeletable", mock.Anything).Return(false, nil).Once()
+	writer, client, recorder := newFakePodStatusWriter(statusPod())
 
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, &repository.MockIKVRepository{}, mockSessionService, &session.MockISessionMetrics{},
+		&ReachabilityProbe{}, writer, newScaleUps())
 	err := h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodDeleteEvent,
+		&domain.PodEventPayload{
+			Pod: statusDomainPod,
+		}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
+	assert.Equal(t, 0, len(client.Actions()))
+	assert.Equal(t, 0, len(recorder.Events))
+}
+
+func TestHandleEvent_PodDeleted_Failure(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Failing, "OOMKilled").Return(true, nil).Once()
+	exitCode := int32(137)
+	mockSessionService.On("SetSessionDeletable", &session.SetSessionDeletableActionPayload{
+		SessionId:      "SessionId",
+		CallerId:       "Session-monitor-service",
+		FailureReason:  "OOMKilled",
+		FailureMessage: "memory limit exceeded",
+		ExitCode:       &exitCode,
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodDeleteEvent,
 		&domain.PodEventPayload{
 			Pod: pod,
+			Failure: &domain.ContainerFailure{
+				Reason:        domain.OOMKilled,
+				ContainerName: "app",
+				ExitCode:      &exitCode,
+				Message:       "memory limit exceeded",
+			},
 		}))
 	assert.Nil(t, err, "Handle PodDeleted Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionDeletable", 1)
+}
+
+func TestHandleEvent_PodPreempted(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Terminating, "Preemption").Return(true, nil).Once()
+	mockSessionService.On("SetSessionDeletable", &session.SetSessionDeletableActionPayload{
+		SessionId:         "SessionId",
+		CallerId:          "Session-monitor-service",
+		DisruptionCause:   "Preemption",
+		DisruptionReason:  "PreemptionByKubeScheduler",
+		DisruptionMessage: "preempted by viz/pod-1",
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodPreemptedEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+			Disruption: &domain.Disruption{
+				Cause:   domain.Preemption,
+				Reason:  "PreemptionByKubeScheduler",
+				Message: "preempted by viz/pod-1",
+			},
+		}))
+	assert.Nil(t, err, "Handle PodPreempted Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionDeletable", 1)
+}
+
+func TestHandleEvent_PodSessionExpired(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Terminating, domain.IdleTimeoutReason).Return(true, nil).Once()
+	mockSessionService.On("SetSessionDeletable", &session.SetSessionDeletableActionPayload{
+		SessionId:     "SessionId",
+		CallerId:      "Session-monitor-service",
+		ExpiryReason:  domain.IdleTimeoutReason,
+		ExpiryMessage: "session idle for 15m0s, idle timeout is 15m0s",
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodSessionExpiredEvent,
+		&domain.PodSessionExpiredPayload{
+			Pod:     pod,
+			Reason:  domain.IdleTimeoutReason,
+			Message: "session idle for 15m0s, idle timeout is 15m0s",
+		}))
+	assert.Nil(t, err, "Handle PodSessionExpired Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionDeletable", 1)
 }
 
 func TestHandleEvent_RecordPodScheduleTimestamp(t *testing.T) {
@@ -101,16 +341,31 @@ func TestHandleEvent_RecordPodScheduleTimestamp(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockKVRepository.On("GetServerTimestamp", ctx).Return(serverTimestamp, nil)
 	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Scheduled, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
 	mockSessionService.On("SetPodScheduleTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
 		SessionId: "SessionId",
 		Timestamp: serverTimestamp,
+		Source:    session.ServerTimeSource,
 	}).Return(nil).Once()
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
-	err := h.HandleEvent(ctx, ddd.NewEvent(
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodRecordPodScheduleEvent,
 		&domain.PodEventPayload{
 			Pod: pod,
@@ -118,6 +373,46 @@ func TestHandleEvent_RecordPodScheduleTimestamp(t *testing.T) {
 	assert.Nil(t, err, "Handle RecordPodScheduleTimestamp Event should not throw err")
 }
 
+func TestHandleEvent_RecordPodScheduleTimestamp_PodCondition(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Scheduled, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockSessionService.On("SetPodScheduleTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: "SessionId",
+		Timestamp: podScheduledTimestamp,
+		Source:    session.PodConditionSource,
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodRecordPodScheduleEvent,
+		&domain.PodEventPayload{
+			Pod: readyPod,
+		}))
+	assert.Nil(t, err, "Handle RecordPodScheduleTimestamp Event should not throw err")
+	mockKVRepository.AssertNumberOfCalls(t, "GetServerTimestamp", 0)
+	mockSessionService.AssertExpectations(t)
+}
+
 func TestHandleEvent_PodReady(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -125,61 +420,305 @@ func TestHandleEvent_PodReady(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockSessionService := &session.MockISessionService{}
-	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(nodeProvisionedTimestamp, nil)
-	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(podScheduledTimestamp, nil)
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	nodeProvisionToPodScheduled, podScheduledToReady, timeToReady :=
+		podScheduledTimestamp-nodeProvisionedTimestamp, podReadyTimestamp-podScheduledTimestamp, podReadyTimestamp-podCreatedTimestamp
+	mockSessionMetrics.On("ObserveSessionReady", "Namespace", "viz", session.SessionDurations{
+		NodeProvisionToPodScheduled: &nodeProvisionToPodScheduled,
+		PodScheduledToReady:         &podScheduledToReady,
+		TimeToReady:                 &timeToReady,
+	}).Return().Once()
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{
+		Value:  nodeProvisionedTimestamp,
+		Source: session.NodeCreationSource,
+	}, nil)
+	mockSessionService.On("SetReadyTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: podReadyTimestamp,
+		Source:    session.PodConditionSource,
+	}).Return(nil).Once()
+	mockSessionService.On("SetPodInitializeTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: podInitializedTimestamp,
+		Source:    session.PodConditionSource,
+	}).Return(nil).Once()
 	mockSessionService.On("SetSessionReady", &session.SetSessionReadyActionPayload{
-		SessionId:              sessionId,
-		NodeName:               nodeName,
-		PodInternalIp:          podInternalIp,
-		NodeProvisionTimeStamp: nodeProvisionedTimestamp,
-		PodScheduleTimeStamp:   podScheduledTimestamp,
-	}).Return(nil)
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
-	err := h.HandleEvent(ctx, ddd.NewEvent(
+		SessionId:                          sessionId,
+		NodeName:                           nodeName,
+		PodInternalIp:                      podInternalIp,
+		NodeProvisionTimeStamp:             nodeProvisionedTimestamp,
+		PodCreateTimeStamp:                 podCreatedTimestamp,
+		PodScheduleTimeStamp:               podScheduledTimestamp,
+		PodInitializeTimeStamp:             podInitializedTimestamp,
+		ReadyTimeStamp:                     podReadyTimestamp,
+		NodeProvisionTimeStampSource:       session.NodeCreationSource,
+		PodScheduleTimeStampSource:         session.PodConditionSource,
+		PodInitializeTimeStampSource:       session.PodConditionSource,
+		ReadyTimeStampSource:               session.PodConditionSource,
+		NodeProvisionToPodScheduledSeconds: &nodeProvisionToPodScheduled,
+		PodScheduledToReadySeconds:         &podScheduledToReady,
+		TimeToReadySeconds:                 &timeToReady,
+	}).Return(true, nil)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodReadyEvent,
 		&domain.PodEventPayload{
-			Pod: pod,
+			Pod: readyPod,
 		}))
 	assert.Nil(t, err, "Handle PodReady Event should not throw err")
 	mockSessionService.AssertNumberOfCalls(t, "GetNodeProvisionTimeStamp", 1)
-	mockSessionService.AssertNumberOfCalls(t, "GetPodScheduleTimeStamp", 1)
+	mockSessionService.AssertNumberOfCalls(t, "GetPodScheduleTimeStamp", 0)
 	mockSessionService.AssertNumberOfCalls(t, "SetSessionReady", 1)
+	mockKVRepository.AssertNumberOfCalls(t, "GetServerTimestamp", 0)
+	mockSessionMetrics.AssertExpectations(t)
 }
 
-func TestHandleEvent_PodReady_Negative_GetNodeProvisionTimeStamp(t *testing.T) {
+// pod conditions without transition times fall back to the recorded schedule time and the redis clock
+func TestHandleEvent_PodReady_ServerTimeFallback(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
 		LogLevel: logger.DEBUG,
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(serverTimestamp, nil).Once()
 	mockSessionService := &session.MockISessionService{}
-	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(int64(0), session.NewInvalidStoreKeyErr("bogus"))
-	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(podScheduledTimestamp, nil)
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	// the creation of the pod is unknown
+	nodeProvisionToPodScheduled, podScheduledToReady :=
+		podScheduledTimestamp-nodeProvisionedTimestamp, serverTimestamp-podScheduledTimestamp
+	mockSessionMetrics.On("ObserveSessionReady", "Namespace", "", session.SessionDurations{
+		NodeProvisionToPodScheduled: &nodeProvisionToPodScheduled,
+		PodScheduledToReady:         &podScheduledToReady,
+	}).Return().Once()
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{
+		Value:  nodeProvisionedTimestamp,
+		Source: session.NodeCreationSource,
+	}, nil)
+	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(session.Timestamp{
+		Value:  podScheduledTimestamp,
+		Source: session.ServerTimeSource,
+	}, nil)
+	mockSessionService.On("SetReadyTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: serverTimestamp,
+		Source:    session.ServerTimeSource,
+	}).Return(nil).Once()
 	mockSessionService.On("SetSessionReady", &session.SetSessionReadyActionPayload{
-		SessionId:              sessionId,
-		NodeName:               nodeName,
-		PodInternalIp:          podInternalIp,
-		NodeProvisionTimeStamp: nodeProvisionedTimestamp,
-		PodScheduleTimeStamp:   podScheduledTimestamp,
-	}).Return(nil)
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
-	err := h.HandleEvent(ctx, ddd.NewEvent(
+		SessionId:                          sessionId,
+		NodeName:                           nodeName,
+		PodInternalIp:                      podInternalIp,
+		NodeProvisionTimeStamp:             nodeProvisionedTimestamp,
+		PodScheduleTimeStamp:               podScheduledTimestamp,
+		ReadyTimeStamp:                     serverTimestamp,
+		NodeProvisionTimeStampSource:       session.NodeCreationSource,
+		PodScheduleTimeStampSource:         session.ServerTimeSource,
+		ReadyTimeStampSource:               session.ServerTimeSource,
+		NodeProvisionToPodScheduledSeconds: &nodeProvisionToPodScheduled,
+		PodScheduledToReadySeconds:         &podScheduledToReady,
+	}).Return(true, nil)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodReadyEvent,
 		&domain.PodEventPayload{
 			Pod: pod,
 		}))
-	assert.Equal(t, session.NewInvalidStoreKeyErr("bogus"), err, "Handle PodReady Event should not throw err")
-	mockSessionService.AssertNumberOfCalls(t, "GetNodeProvisionTimeStamp", 1)
-	mockSessionService.AssertNumberOfCalls(t, "GetPodScheduleTimeStamp", 0)
+	assert.Nil(t, err, "Handle PodReady Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "GetPodScheduleTimeStamp", 1)
+	mockSessionService.AssertNumberOfCalls(t, "SetPodInitializeTimeStamp", 0)
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionReady", 1)
+	mockSessionMetrics.AssertExpectations(t)
+}
+
+// the probe worker publishes its result to the dispatcher
+func runReachabilityProbe(t *testing.T, dispatcher ddd.IEventDispatcher[ddd.IEvent]) *ReachabilityProbe {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, dispatcher)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- probe.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return probe
+}
+
+func TestHandleEvent_PodReady_Unreachable(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	unreachablePod := *probedPod(listener.Addr().String())
+	listener.Close()
+	unreachablePod.NodeName = nodeName
+	dispatcher := ddd.NewEventDispatcher[ddd.IEvent]()
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := session.NewMockISessionMetrics(t)
+	mockSessionService.On("TransitionSession", "sessionId", session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetSessionLifecycle", "sessionId").
+		Return(session.RestoreSessionLifecycle("sessionId", session.Ready, "", time.Now()), nil).Once()
+	mockSessionService.On("TransitionSession", "sessionId", session.Failing, domain.SessionUnreachableReason).Return(true, nil).Once()
+	mockSessionService.On("SetSessionFailed", mock.MatchedBy(func(payload *session.SetSessionFailedActionPayload) bool {
+		return payload.SessionId == "sessionId" && payload.Reason == domain.SessionUnreachableReason
+	})).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, dispatcher, &repository.MockIKVRepository{}, mockSessionService, mockSessionMetrics,
+		runReachabilityProbe(t, dispatcher), writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: &unreachablePod,
+		}))
+	assert.Nil(t, err, "Handle PodReady Event should not throw err")
+	// probed by the worker
+	assert.Eventually(t, func() bool {
+		return mockSessionService.AssertExpectations(&testing.T{})
+	}, 5*time.Second, 10*time.Millisecond)
 	mockSessionService.AssertNumberOfCalls(t, "SetSessionReady", 0)
 }
 
+func TestHandleEvent_PodReady_Reachable(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+	reachablePod := *probedPod(listener.Addr().String())
+	reachablePod.NodeName = nodeName
+	reachablePod.ConditionTimes = domain.PodConditionTimes{
+		Scheduled: podScheduledTimestamp,
+		Ready:     serverTimestamp,
+	}
+	dispatcher := ddd.NewEventDispatcher[ddd.IEvent]()
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionMetrics.On("ObserveSessionReady", mock.Anything, mock.Anything, mock.Anything).Return().Once()
+	mockSessionService.On("TransitionSession", "sessionId", session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{}, session.NewInvalidStoreKeyErr("bogus"))
+	mockSessionService.On("SetReadyTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetSessionLifecycle", "sessionId").
+		Return(session.RestoreSessionLifecycle("sessionId", session.Ready, "", time.Now()), nil).Once()
+	mockSessionService.On("SetSessionReady", mock.MatchedBy(func(payload *session.SetSessionReadyActionPayload) bool {
+		return payload.SessionId == "sessionId"
+	})).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, dispatcher, &repository.MockIKVRepository{}, mockSessionService, mockSessionMetrics,
+		runReachabilityProbe(t, dispatcher), writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: &reachablePod,
+		}))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return mockSessionService.AssertExpectations(&testing.T{}) && mockSessionMetrics.AssertExpectations(&testing.T{})
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// the node timestamp is unknown, the session is enqueued without the node durations
+func TestHandleEvent_PodReady_UnknownNodeProvisionTimeStamp(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("GetServerTimestamp", ctx).Return(serverTimestamp, nil).Once()
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	podScheduledToReady := serverTimestamp - podScheduledTimestamp
+	mockSessionMetrics.On("ObserveSessionReady", "Namespace", "", session.SessionDurations{
+		PodScheduledToReady: &podScheduledToReady,
+	}).Return().Once()
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{}, session.NewInvalidStoreKeyErr("bogus"))
+	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(session.Timestamp{
+		Value:  podScheduledTimestamp,
+		Source: session.ServerTimeSource,
+	}, nil)
+	mockSessionService.On("SetReadyTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("SetSessionReady", &session.SetSessionReadyActionPayload{
+		SessionId:                  sessionId,
+		NodeName:                   nodeName,
+		PodInternalIp:              podInternalIp,
+		PodScheduleTimeStamp:       podScheduledTimestamp,
+		ReadyTimeStamp:             serverTimestamp,
+		PodScheduleTimeStampSource: session.ServerTimeSource,
+		ReadyTimeStampSource:       session.ServerTimeSource,
+		PodScheduledToReadySeconds: &podScheduledToReady,
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
+	mockSessionMetrics.AssertExpectations(t)
+}
+
 func TestHandleEvent_PodReady_Negative_GetPodScheduleTimeStamp(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -187,20 +726,37 @@ func TestHandleEvent_PodReady_Negative_GetPodScheduleTimeStamp(t *testing.T) {
 	})
 	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockKVRepository := &repository.MockIKVRepository{}
 	mockSessionService := &session.MockISessionService{}
-	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(nodeProvisionedTimestamp, nil)
-	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(int64(0), session.NewInvalidStoreKeyErr("bogus"))
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{
+		Value:  nodeProvisionedTimestamp,
+		Source: session.NodeCreationSource,
+	}, nil)
+	mockSessionService.On("GetPodScheduleTimeStamp", sessionId).Return(session.Timestamp{}, session.NewInvalidStoreKeyErr("bogus"))
 	mockSessionService.On("SetSessionReady", &session.SetSessionReadyActionPayload{
 		SessionId:              sessionId,
 		NodeName:               nodeName,
 		PodInternalIp:          podInternalIp,
 		NodeProvisionTimeStamp: nodeProvisionedTimestamp,
 		PodScheduleTimeStamp:   podScheduledTimestamp,
-	}).Return(nil)
-	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService)
-	err := h.HandleEvent(ctx, ddd.NewEvent(
+	}).Return(true, nil)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
 		domain.PodReadyEvent,
 		&domain.PodEventPayload{
 			Pod: pod,
@@ -210,3 +766,327 @@ func TestHandleEvent_PodReady_Negative_GetPodScheduleTimeStamp(t *testing.T) {
 	mockSessionService.AssertNumberOfCalls(t, "GetPodScheduleTimeStamp", 1)
 	mockSessionService.AssertNumberOfCalls(t, "SetSessionReady", 0)
 }
+
+func TestHandleEvent_PodPendingTimeout(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Failing, "Unschedulable").Return(true, nil).Once()
+	mockSessionService.On("SetSessionFailed", &session.SetSessionFailedActionPayload{
+		SessionId: sessionId,
+		CallerId:  "Session-monitor-service",
+		Reason:    "Unschedulable",
+		Message:   "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+	}).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodPendingTimeoutEvent,
+		&domain.PodPendingTimeoutPayload{
+			Pod:     pod,
+			Reason:  "Unschedulable",
+			Message: "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+		}))
+	assert.Nil(t, err, "Handle PodPendingTimeout Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionFailed", 1)
+}
+
+func TestHandleEvent_PodReady_AfterDeleted(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").
+		Return(false, session.NewInvalidLifecycleTransitionErr(sessionId, session.Deleted, session.Ready)).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.True(t, session.NewInvalidLifecycleTransitionErr(sessionId, session.Deleted, session.Ready).Is(err))
+	mockSessionService.AssertNumberOfCalls(t, "SetSessionReady", 0)
+}
+
+func TestHandleEvent_PodInitializing(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Initializing, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", &session.SetSessionPodActionPayload{
+		SessionId:     sessionId,
+		PodName:       "Name",
+		Namespace:     "Namespace",
+		NodeName:      nodeName,
+		PodInternalIp: podInternalIp,
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodInitializingEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.Nil(t, err, "Handle PodInitializing Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "TransitionSession", 1)
+}
+
+func TestHandleEvent_PodRemoved(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("TransitionSession", sessionId, session.Deleted, "PodRemoved").Return(true, nil).Once()
+	mockSessionService.On("PurgeSession", sessionId).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodRemovedEvent,
+		&domain.PodEventPayload{
+			Pod: pod,
+		}))
+	assert.Nil(t, err, "Handle PodRemoved Event should not throw err")
+	mockSessionService.AssertNumberOfCalls(t, "TransitionSession", 1)
+	mockSessionService.AssertNumberOfCalls(t, "PurgeSession", 1)
+}
+
+func TestHandleEvent_PodReady_ScaleUp(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionMetrics.On("ObserveSessionReady", "Namespace", "viz", mock.Anything).Return().Once()
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(true, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{
+		Value:  nodeProvisionedTimestamp,
+		Source: session.NodeCreationSource,
+	}, nil).Once()
+	mockSessionService.On("SetReadyTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("SetPodInitializeTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("SetSessionReady", mock.MatchedBy(func(payload *session.SetSessionReadyActionPayload) bool {
+		return payload.ScaleUpSeconds != nil && *payload.ScaleUpSeconds == 270
+	})).Return(true, nil).Once()
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("PodScaleUp", "Namespace/Name", nodeName).Return(scaleup.ScaleUp{
+		AgentPool: "viz",
+		NodeName:  nodeName,
+		Seconds:   270,
+	}, true).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, &repository.MockIKVRepository{}, mockSessionService, mockSessionMetrics,
+		&ReachabilityProbe{}, writer, scaleUps)
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: readyPod,
+		}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
+}
+
+// ready pods keep being updated, a session enqueued already is neither probed nor observed again
+func TestHandleEvent_PodReady_AlreadyEnqueued(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := session.NewMockISessionMetrics(t)
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(false, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetSessionLifecycle", sessionId).
+		Return(session.RestoreSessionLifecycle(sessionId, session.Ready, "", time.Now()), nil).Once()
+	mockSessionService.On("IsSessionEnqueued", sessionId).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger, mockConfig, nil)
+	assert.Nil(t, err)
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, &repository.MockIKVRepository{}, mockSessionService, mockSessionMetrics,
+		probe, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodReadyEvent,
+		&domain.PodEventPayload{
+			Pod: readyPod,
+		}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
+	mockSessionService.AssertNotCalled(t, "SetSessionReady", mock.Anything)
+	assert.Len(t, probe.queue, 0, "not probed again")
+}
+
+// the session became ready before but its enqueue failed, the next report enqueues it
+func TestHandleEvent_PodReady_NotEnqueued(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionService := &session.MockISessionService{}
+	mockSessionMetrics := session.NewMockISessionMetrics(t)
+	mockSessionMetrics.On("ObserveSessionReady", "Namespace", "viz", mock.Anything).Return().Once()
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(false, nil).Twice()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Twice()
+	mockSessionService.On("GetSessionLifecycle", sessionId).
+		Return(session.RestoreSessionLifecycle(sessionId, session.Ready, "", time.Now()), nil).Twice()
+	mockSessionService.On("IsSessionEnqueued", sessionId).Return(false, nil).Twice()
+	mockSessionService.On("GetNodeProvisionTimeStamp", nodeName).Return(session.Timestamp{
+		Value:  nodeProvisionedTimestamp,
+		Source: session.NodeCreationSource,
+	}, nil).Twice()
+	mockSessionService.On("SetReadyTimeStamp", mock.Anything).Return(errors.New("redis is down")).Once()
+	mockSessionService.On("SetReadyTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("SetPodInitializeTimeStamp", mock.Anything).Return(nil).Once()
+	mockSessionService.On("SetSessionReady", mock.Anything).Return(true, nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, &repository.MockIKVRepository{}, mockSessionService, mockSessionMetrics,
+		&ReachabilityProbe{}, writer, newScaleUps())
+	event := ddd.NewEvent(domain.PodReadyEvent, &domain.PodEventPayload{Pod: readyPod})
+	assert.NotNil(t, h.HandleEvent(ctx, event))
+	assert.Nil(t, h.HandleEvent(ctx, event))
+	mockSessionService.AssertExpectations(t)
+}
+
+// a ready report older than the deletion of the pod
+func TestHandleEvent_PodReady_Stale(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockSessionService := &session.MockISessionService{}
+	mockSessionService.On("TransitionSession", sessionId, session.Ready, "").Return(false, nil).Once()
+	mockSessionService.On("SetSessionPod", mock.Anything).Return(nil).Once()
+	mockSessionService.On("GetSessionLifecycle", sessionId).
+		Return(session.RestoreSessionLifecycle(sessionId, session.Terminating, "", time.Now()), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, &repository.MockIKVRepository{}, mockSessionService,
+		session.NewMockISessionMetrics(t), &ReachabilityProbe{}, writer, newScaleUps())
+	err = h.HandleEvent(ctx, ddd.NewEvent(domain.PodReadyEvent, &domain.PodEventPayload{Pod: readyPod}))
+	assert.Nil(t, err)
+	mockSessionService.AssertExpectations(t)
+	mockSessionService.AssertNotCalled(t, "IsSessionEnqueued", mock.Anything)
+	mockSessionService.AssertNotCalled(t, "SetSessionReady", mock.Anything)
+}
+
+func TestHandleEvent_PodVolumeUpdated(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockEventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionMetrics := &session.MockISessionMetrics{}
+	mockSessionService.On("SetVolumeClaimTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: podScheduledTimestamp - 30,
+		Source:    session.VolumeClaimCreationSource,
+	}).Return(nil).Once()
+	mockSessionService.On("SetVolumeBindTimeStamp", &session.UpdateSessionTimeStampLikeFieldActionPayload{
+		SessionId: sessionId,
+		Timestamp: podScheduledTimestamp - 10,
+		Source:    session.InformerObservedSource,
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(ctx, logger, mockConfig)
+	assert.Nil(t, err)
+	h := NewDomainEventHandlers(logger, mockEventDispatcher, mockKVRepository, mockSessionService, mockSessionMetrics, &ReachabilityProbe{}, writer, newScaleUps())
+	// not attached yet
+	err = h.HandleEvent(ctx, ddd.NewEvent(
+		domain.PodVolumeUpdateEvent,
+		&domain.PodVolumePayload{
+			Pod: pod,
+			Times: domain.VolumeTimes{
+				Claimed: podScheduledTimestamp - 30,
+				Bound:   podScheduledTimestamp - 10,
+			},
+		}))
+	assert.Nil(t, err, "Handle PodVolumeUpdate Event should not throw err")
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+const defaultContainerRestartThreshold = 3
+
+// waiting reasons the kubelet will not recover from without user action
+var fatalWaitingReasons = map[string]domain.FailureReason{
+	string(domain.CrashLoopBackOff):           domain.CrashLoopBackOff,
+	string(domain.ImagePullBackOff):           domain.ImagePullBackOff,
+	string(domain.InvalidImageName):           domain.InvalidImageName,
+	string(domain.CreateContainerConfigError): domain.CreateContainerConfigError,
+}
+
+// ErrImagePull is often a transient registry error, the kubelet retries it as ImagePullBackOff.
+// it is the failure only once the pod will not retry anymore
+var finalWaitingReasons = map[string]domain.FailureReason{
+	string(domain.ErrImagePull): domain.ErrImagePull,
+}
+
+type FailureClassifier struct {
+	restartThreshold int32
+	containerRules   *ContainerRules
+}
+
+// restart threshold 0 disables the restart count check
+func NewFailureClassifier(cfg config.IConfig, containerRules *ContainerRules) *FailureClassifier {
+	return &FailureClassifier{
+		restartThreshold: int32(config.GetInt(cfg, "app.container_restart_threshold", defaultContainerRestartThreshold)),
+		containerRules:   containerRules,
+	}
+}
+
+// returns the most meaningful failure among init containers and containers, nil if healthy
+func (c *FailureClassifier) Classify(pod *v1.Pod) *domain.ContainerFailure {
+	// terminal or deleted pods report their last waiting reason as it is
+	final := pod.ObjectMeta.DeletionTimestamp != nil || pod.Status.Phase == v1.PodFailed ||
+		pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodUnknown
+	var worst *domain.ContainerFailure
+	for _, status := range pod.Status.InitContainerStatuses {
+		worst = pickFailure(worst, c.classifyContainer(status, true, c.containerRules.RoleOf(pod, status.Name, true), final))
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		worst = pickFailure(worst, c.classifyContainer(status, false, c.containerRules.RoleOf(pod, status.Name, false), final))
+	}
+	return worst
+}
+
+func (c *FailureClassifier) classifyContainer(status v1.ContainerStatus, initContainer bool, role domain.ContainerRole, final bool) *domain.ContainerFailure {
+	if role == domain.IgnoredContainer {
+		return nil
+	}
+	failure := &domain.ContainerFailure{
+		ContainerName: status.Name,
+		InitContainer: initContainer,
+		RestartCount:  status.RestartCount,
+	}
+	lastTerminated := status.LastTerminationState.Terminated
+	if terminated := status.State.Terminated; terminated != nil {
+		failure.ExitCode, failure.Message = &terminated.ExitCode, terminated.Message
+		switch {
+		case terminated.Reason == string(domain.OOMKilled):
+			failure.Reason = domain.OOMKilled
+		case terminated.ExitCode != 0:
+			failure.Reason = domain.NonZeroExitCode
+		case role != domain.CriticalContainer:
+			// init containers and one-shot helpers are expected to complete
+			return nil
+		default:
+			failure.Reason = domain.ContainerTerminated
+		}
+		return failure
+	}
+	if waiting := status.State.Waiting; waiting != nil {
+		reason, fatal := fatalWaitingReasons[waiting.Reason]
+		if !fatal && final {
+			reason, fatal = finalWaitingReasons[waiting.Reason]
+		}
+		if fatal {
+			failure.Reason, failure.Message = reason, waiting.Message
+			// crash loop caused by memory limit is reported as OOMKilled
+			if lastTerminated != nil {
+				failure.ExitCode = &lastTerminated.ExitCode
+				if reason == domain.CrashLoopBackOff && lastTerminated.Reason == string(domain.OOMKilled) {
+					failure.Reason = domain.OOMKilled
+				}
+			}
+			return failure
+		}
+	}
+	// a running container recovered from its restarts, long lived sessions add up restarts over days
+	if c.restartThreshold > 0 && status.RestartCount >= c.restartThreshold && status.State.Running == nil {
+		failure.Reason = domain.RestartLimitExceeded
+		if lastTerminated != nil {
+			failure.ExitCode, failure.Message = &lastTerminated.ExitCode, lastTerminated.Message
+			if lastTerminated.Reason == string(domain.OOMKilled) {
+				failure.Reason = domain.OOMKilled
+			}
+		}
+		return failure
+	}
+	return nil
+}
+
+func pickFailure(current *domain.ContainerFailure, candidate *domain.ContainerFailure) *domain.ContainerFailure {
+	if current == nil {
+		return candidate
+	}
+	if candidate == nil {
+		return current
+	}
+	if domain.FailureReasonPriority[candidate.Reason] < domain.FailureReasonPriority[current.Reason] {
+		return candidate
+	}
+	return current
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func exitCode(code int32) *int32 {
+	return &code
+}
+
+func TestFailureClassifier_Classify(t *testing.T) {
+	scenarios := []struct {
+		desc                  string
+		inPhase               v1.PodPhase
+		inDeleted             bool
+		inInitContainerStatus []v1.ContainerStatus
+		inContainerStatus     []v1.ContainerStatus
+		expected              *domain.ContainerFailure
+	}{
+		{
+			desc: "healthy",
+			inInitContainerStatus: []v1.ContainerStatus{
+				{Name: "init", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}},
+			},
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			},
+			expected: nil,
+		},
+		{
+			desc: "OOMKilled",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.OOMKilled, ContainerName: "app", ExitCode: exitCode(137)},
+		},
+		{
+			desc: "CrashLoopBackOff caused by OOMKilled",
+			inContainerStatus: []v1.ContainerStatus{
+				{
+					Name:                 "app",
+					RestartCount:         1,
+					State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+				},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.OOMKilled, ContainerName: "app", ExitCode: exitCode(137),
+				RestartCount: 1, Message: "back-off 10s"},
+		},
+		{
+			desc: "CrashLoopBackOff",
+			inContainerStatus: []v1.ContainerStatus{
+				{
+					Name:                 "app",
+					RestartCount:         1,
+					State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+				},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.CrashLoopBackOff, ContainerName: "app", ExitCode: exitCode(1), RestartCount: 1},
+		},
+		{
+			desc: "ImagePullBackOff on init container",
+			inInitContainerStatus: []v1.ContainerStatus{
+				{Name: "init", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"}}},
+			},
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "PodInitializing"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.ImagePullBackOff, ContainerName: "init", InitContainer: true, Message: "not found"},
+		},
+		{
+			desc: "CreateContainerConfigError wins over ImagePullBackOff",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "sidecar", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CreateContainerConfigError", Message: "secret not found"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.CreateContainerConfigError, ContainerName: "app", Message: "secret not found"},
+		},
+		{
+			desc: "ErrImagePull is retried",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "i/o timeout"}}},
+			},
+			expected: nil,
+		},
+		{
+			desc:    "ErrImagePull on a failed pod",
+			inPhase: v1.PodFailed,
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "manifest unknown"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.ErrImagePull, ContainerName: "app", Message: "manifest unknown"},
+		},
+		{
+			desc:      "ErrImagePull on a deleted pod",
+			inPhase:   v1.PodPending,
+			inDeleted: true,
+			inInitContainerStatus: []v1.ContainerStatus{
+				{Name: "init", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "i/o timeout"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.ErrImagePull, ContainerName: "init", InitContainer: true, Message: "i/o timeout"},
+		},
+		{
+			desc: "InvalidImageName",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "InvalidImageName", Message: "couldn't parse image reference"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.InvalidImageName, ContainerName: "app", Message: "couldn't parse image reference"},
+		},
+		{
+			desc: "non-zero exit code",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.NonZeroExitCode, ContainerName: "app", ExitCode: exitCode(2)},
+		},
+		{
+			desc: "restart count over threshold",
+			inContainerStatus: []v1.ContainerStatus{
+				{
+					Name:                 "app",
+					RestartCount:         3,
+					State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "RunContainerError"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 139, Reason: "Error"}},
+				},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.RestartLimitExceeded, ContainerName: "app", ExitCode: exitCode(139), RestartCount: 3},
+		},
+		{
+			desc: "restart count over threshold while running",
+			inContainerStatus: []v1.ContainerStatus{
+				{
+					Name:                 "app",
+					Ready:                true,
+					RestartCount:         3,
+					State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 139, Reason: "Error"}},
+				},
+			},
+			expected: nil,
+		},
+		{
+			desc: "container completed",
+			inContainerStatus: []v1.ContainerStatus{
+				{Name: "app", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}},
+			},
+			expected: &domain.ContainerFailure{Reason: domain.ContainerTerminated, ContainerName: "app", ExitCode: exitCode(0)},
+		},
+	}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	for _, s := range scenarios {
+		t.Run(s.desc, func(t *testing.T) {
+			pod := &v1.Pod{
+				Status: v1.PodStatus{
+					Phase:                 s.inPhase,
+					InitContainerStatuses: s.inInitContainerStatus,
+					ContainerStatuses:     s.inContainerStatus,
+				},
+			}
+			if s.inDeleted {
+				pod.ObjectMeta.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			assert.Equal(t, s.expected, classifier.Classify(pod))
+		})
+	}
+}
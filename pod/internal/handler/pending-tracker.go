@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultPendingTimeout       = 10 * time.Minute
+	defaultPendingCheckInterval = 30 * time.Second
+)
+
+type pendingPod struct {
+	pod     *domain.Pod
+	since   time.Time
+	reason  string
+	message string
+	expired bool
+}
+
+// informer has no resync, a pod stuck in Pending may never be updated again.
+// tracker remembers pending pods and a worker checks them periodically.
+type PendingTracker struct {
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	volumeTracker         *VolumeTracker
+	timeout               time.Duration
+	interval              time.Duration
+	now                   func() time.Time
+	mutex                 sync.Mutex
+	pods                  map[string]*pendingPod
+}
+
+func NewPendingTracker(logger *zap.Logger, cfg config.IConfig,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent], volumeTracker *VolumeTracker) *PendingTracker {
+	return &PendingTracker{
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		volumeTracker:         volumeTracker,
+		timeout:               config.GetSeconds(cfg, "app.pod_pending_timeout_seconds", defaultPendingTimeout),
+		interval:              config.GetSeconds(cfg, "app.pod_pending_check_interval_seconds", defaultPendingCheckInterval),
+		now:                   time.Now,
+		pods:                  map[string]*pendingPod{},
+	}
+}
+
+// since is the pod creation time, so the timeout also holds across monitor restarts
+func (t *PendingTracker) Observe(pod *domain.Pod, since time.Time, reason string, message string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if since.IsZero() {
+		since = t.now()
+	}
+	entry, exist := t.pods[pod.SessionId]
+	if !exist {
+		entry = &pendingPod{
+			since: since,
+		}
+		t.pods[pod.SessionId] = entry
+	}
+	entry.pod, entry.reason, entry.message = pod, reason, message
+}
+
+func (t *PendingTracker) Forget(sessionId string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.pods, sessionId)
+}
+
+// messages of the scheduler, e.g. "0/3 nodes are available: 3 Insufficient nvidia.com/gpu."
+var (
+	unboundClaimMessages = []string{
+		"unbound immediate PersistentVolumeClaims",
+		"persistentvolumeclaim",
+	}
+	volumeNodeMessages = []string{
+		"volume node affinity conflict",
+		"didn't find available persistent volumes to bind",
+		"node(s) exceed max volume count",
+	}
+)
+
+// the reason of the PodScheduled condition is Unschedulable whatever the pod waits for.
+// unbound claims fail the pod on every node, they are checked before insufficient gpus
+func pendingReason(scheduled v1.PodCondition) (string, string) {
+	reason, message := scheduled.Reason, scheduled.Message
+	if scheduled.Status != v1.ConditionFalse || reason != v1.PodReasonUnschedulable {
+		return reason, message
+	}
+	containsAny := func(values []string) bool {
+		for _, value := range values {
+			if strings.Contains(message, value) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case containsAny(unboundClaimMessages):
+		return domain.WaitingForVolumeReason, message
+	case strings.Contains(message, "Insufficient "+gpu.GpuResource):
+		return domain.WaitingForGpuReason, message
+	case containsAny(volumeNodeMessages):
+		return domain.WaitingForVolumeReason, message
+	}
+	return reason, message
+}
+
+// publish PodPendingTimeoutEvent once for every pod pending longer than timeout.
+// volume status changes do not update the pod, unready volumes are checked when the pod expires
+func (t *PendingTracker) Expire(ctx context.Context) error {
+	events := []ddd.IEvent{}
+	t.mutex.Lock()
+	now := t.now()
+	for sessionId, entry := range t.pods {
+		if entry.expired || now.Sub(entry.since) < t.timeout {
+			continue
+		}
+		entry.expired = true
+		// claims waiting for their first consumer stay unbound while the pod waits for gpus
+		if entry.reason != domain.WaitingForGpuReason {
+			if message, waiting := t.volumeTracker.Waiting(sessionId); waiting {
+				entry.reason, entry.message = domain.WaitingForVolumeReason, message
+			}
+		}
+		t.logger.Sugar().Infow("Pod is pending too long", "Name", entry.pod.Name, "Namespace", entry.pod.Namespace,
+			"SessionId", sessionId, "PendingSince", entry.since, "Reason", entry.reason, "Message", entry.message)
+		events = append(events, ddd.NewEvent(
+			domain.PodPendingTimeoutEvent,
+			&domain.PodPendingTimeoutPayload{
+				Pod:          entry.pod,
+				PendingSince: entry.since,
+				Reason:       entry.reason,
+				Message:      entry.message,
+			},
+		))
+	}
+	t.mutex.Unlock()
+	if len(events) == 0 {
+		return nil
+	}
+	return t.domainEventDispatcher.Publish(ctx, events...)
+}
+
+// worker.Worker, registered by the module
+func (t *PendingTracker) Run(ctx context.Context) error {
+	if t.timeout <= 0 {
+		t.logger.Sugar().Info("Pending timeout tracker is disabled")
+		return nil
+	}
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := t.Expire(ctx); err != nil {
+				t.logger.Sugar().Errorf("Expire pending pods has error: %s", err.Error())
+			}
+		}
+	}
+}
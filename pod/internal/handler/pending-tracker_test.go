@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestPendingTracker_Expire(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(300).Once()
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(10).Once()
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	tracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	tracker.Observe(&domain.Pod{
+		Name:      "test_name",
+		Namespace: "test_namespace",
+		SessionId: "session-123",
+	}, now.Add(-1*time.Minute), "Unschedulable", "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.")
+
+	// within timeout
+	assert.Nil(t, tracker.Expire(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+
+	// past timeout, published exactly once
+	now = now.Add(5 * time.Minute)
+	assert.Nil(t, tracker.Expire(ctx))
+	assert.Nil(t, tracker.Expire(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodPendingTimeoutEvent, event.EventName())
+	payload := event.Payload().(*domain.PodPendingTimeoutPayload)
+	assert.Equal(t, "session-123", payload.Pod.SessionId)
+	assert.Equal(t, "Unschedulable", payload.Reason)
+	assert.Equal(t, "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.", payload.Message)
+}
+
+func TestPendingTracker_Forget(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	tracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	tracker.Observe(&domain.Pod{
+		SessionId: "session-123",
+	}, now, "", "")
+	tracker.Forget("session-123")
+
+	now = now.Add(time.Hour)
+	assert.Nil(t, tracker.Expire(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+}
+
+func TestPendingTracker_Expire_WaitingForVolume(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(true)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	volumeTracker.OnAddObject(newClaim("Pending", ""))
+	volumeTracker.TrackPod(newVolumePod(""))
+	tracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	// the scheduler is done, the kubelet waits for the volume
+	tracker.Observe(&domain.Pod{
+		SessionId: "session-123",
+	}, now, "", "")
+
+	now = now.Add(time.Hour)
+	assert.Nil(t, tracker.Expire(ctx))
+	event := eventDispatcher.Calls[len(eventDispatcher.Calls)-1].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodPendingTimeoutEvent, event.EventName())
+	payload := event.Payload().(*domain.PodPendingTimeoutPayload)
+	assert.Equal(t, domain.WaitingForVolumeReason, payload.Reason)
+	assert.Equal(t, `persistentvolumeclaim "data" is Pending`, payload.Message)
+}
+
+func TestPendingReason(t *testing.T) {
+	unschedulable := func(message string) v1.PodCondition {
+		return v1.PodCondition{
+			Type:    v1.PodScheduled,
+			Status:  v1.ConditionFalse,
+			Reason:  v1.PodReasonUnschedulable,
+			Message: message,
+		}
+	}
+	for _, test := range []struct {
+		scheduled v1.PodCondition
+		reason    string
+	}{
+		{unschedulable("0/3 nodes are available: 3 Insufficient nvidia.com/gpu."), domain.WaitingForGpuReason},
+		{unschedulable("0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims. preemption: 0/3 nodes are available: 3 Preemption is not helpful for scheduling."), domain.WaitingForVolumeReason},
+		{unschedulable(`0/3 nodes are available: persistentvolumeclaim "data" not found.`), domain.WaitingForVolumeReason},
+		{unschedulable("0/3 nodes are available: 1 Insufficient nvidia.com/gpu, 2 node(s) had volume node affinity conflict."), domain.WaitingForGpuReason},
+		{unschedulable("0/3 nodes are available: 3 node(s) had volume node affinity conflict."), domain.WaitingForVolumeReason},
+		{unschedulable("0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector."), v1.PodReasonUnschedulable},
+		{v1.PodCondition{Type: v1.PodScheduled, Status: v1.ConditionTrue}, ""},
+	} {
+		reason, message := pendingReason(test.scheduled)
+		assert.Equal(t, test.reason, reason, test.scheduled.Message)
+		assert.Equal(t, test.scheduled.Message, message)
+	}
+}
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
+	"time"
 
 	"github.com/thoas/go-funk"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
 	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
@@ -19,16 +21,37 @@ type PodEventHandler struct {
 	ctx                   context.Context
 	logger                *zap.Logger
 	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	pendingTracker        *PendingTracker
+	volumeTracker         *VolumeTracker
+	failureClassifier     *FailureClassifier
+	containerRules        *ContainerRules
+	sessionReaper         *SessionReaper
+	gpuInventory          gpu.IInventory
+	scaleUps              scaleup.ITracker
 }
 
 func NewPodEventHandler(ctx context.Context, logger *zap.Logger,
 	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent],
 	domainEventHandler ddd.IEventHandler[ddd.IEvent],
+	pendingTracker *PendingTracker,
+	volumeTracker *VolumeTracker,
+	failureClassifier *FailureClassifier,
+	containerRules *ContainerRules,
+	sessionReaper *SessionReaper,
+	gpuInventory gpu.IInventory,
+	scaleUps scaleup.ITracker,
 ) k8s.IK8sEventHandler {
 	return &PodEventHandler{
 		ctx,
 		logger,
 		domainEventDispatcher,
+		pendingTracker,
+		volumeTracker,
+		failureClassifier,
+		containerRules,
+		sessionReaper,
+		gpuInventory,
+		scaleUps,
 	}
 }
 
@@ -48,6 +71,15 @@ func (handler *PodEventHandler) OnAddObject(obj interface{}) {
 		handler.logger.Sugar().Error("OnAddObject:", err)
 	} else {
 		name, namespace, sessionId := pod.ObjectMeta.Name, pod.ObjectMeta.Namespace, pod.ObjectMeta.Labels["sessionId"]
+		handler.trackGpus(&pod)
+		// informers do not resync, ready pods listed after a restart may never be updated again
+		if sessionId != "" && pod.ObjectMeta.Labels["managed"] != "false" && pod.Status.Phase == v1.PodRunning &&
+			pod.ObjectMeta.DeletionTimestamp == nil {
+			m := funk.ToMap(pod.Status.Conditions, "Type").(map[v1.PodConditionType]v1.PodCondition)
+			if handler.containerRules.IsReady(&pod, m) {
+				handler.observeSession(&pod)
+			}
+		}
 		handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
 			domain.PodAddEvent,
 			&domain.PodEventPayload{
@@ -55,6 +87,7 @@ func (handler *PodEventHandler) OnAddObject(obj interface{}) {
 					Name:      name,
 					Namespace: namespace,
 					SessionId: sessionId,
+					Created:   podCreated(&pod),
 				},
 			},
 		))
@@ -68,6 +101,8 @@ func (handler *PodEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
 	pod, err := parsePod(newObj.(*unstructured.Unstructured))
 	if err != nil {
 		handler.logger.Sugar().Error("OnUpdateObject:", err)
+	} else {
+		handler.trackGpus(&pod)
 	}
 	// label managed allows dev's smoke test, which is living outside of session management backend
 	name, namespace, sessionId, isManaged, phase, nodeName, conditions, ip :=
@@ -78,7 +113,36 @@ func (handler *PodEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
 	m := funk.ToMap(conditions, "Type").(map[v1.PodConditionType]v1.PodCondition)
 	if sessionId != "" && isManaged != "false" {
 		handler.logger.Sugar().Infow("Pod is updated", "Name", name, "Namespace", namespace, "SessionId", sessionId, "Phase", pod.Status.Phase, "PodIP:", pod.Status.PodIP)
-		if phase == v1.PodFailed || phase == v1.PodSucceeded || phase == v1.PodUnknown {
+		handler.volumeTracker.TrackPod(&pod)
+		// unschedulable pods may sit in Pending without further updates
+		if phase == v1.PodPending {
+			reason, message := pendingReason(m[v1.PodScheduled])
+			handler.pendingTracker.Observe(&domain.Pod{
+				Name:      name,
+				Namespace: namespace,
+				SessionId: sessionId,
+			}, pod.ObjectMeta.CreationTimestamp.Time, reason, message)
+		} else {
+			handler.pendingTracker.Forget(sessionId)
+		}
+		handler.trackScaleUp(&pod, m[v1.PodScheduled])
+		// container failures are classified in every phase, so the broker can report a meaningful error
+		failure := handler.failureClassifier.Classify(&pod)
+		// evicted or preempted sessions are not user errors, containers killed by the cluster are not classified
+		if disruption := detectDisruption(&pod, m); disruption != nil {
+			handler.logger.Sugar().Infow("Pod is disrupted", "Name", name, "Namespace", namespace, "SessionId", sessionId,
+				"Cause", disruption.Cause, "Reason", disruption.Reason, "Message", disruption.Message)
+			eventName = disruptionEvents[disruption.Cause]
+			eventPlayload = &domain.PodEventPayload{
+				Pod: &domain.Pod{
+					Name:      name,
+					Namespace: namespace,
+					SessionId: sessionId,
+					NodeName:  nodeName,
+				},
+				Disruption: disruption,
+			}
+		} else if phase == v1.PodFailed || phase == v1.PodSucceeded || phase == v1.PodUnknown {
 			handler.logger.Sugar().Infow("Pod should be deleted", "Name", name, "Namespace", namespace, "SessionId", sessionId)
 			eventName = domain.PodDeleteEvent
 			eventPlayload = &domain.PodEventPayload{
@@ -87,34 +151,54 @@ func (handler *PodEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
 					Namespace: namespace,
 					SessionId: sessionId,
 				},
+				Failure: failure,
 			}
-		} else if phase == v1.PodPending && m[v1.PodScheduled].Status == v1.ConditionTrue {
-			eventName = domain.PodRecordPodScheduleEvent
+		} else if failure != nil {
+			// logic to catch crashed containers, image pull errors surface while pending
+			handler.logger.Sugar().Infow("Pod has crashed containers", "Name", name, "Namespace", namespace, "SessionId", sessionId,
+				"Reason", failure.Reason, "Container", failure.ContainerName, "RestartCount", failure.RestartCount)
+			handler.logger.Sugar().Infow("Pod should be deleted", "Name", name, "Namespace", namespace, "SessionId", sessionId)
+			eventName = domain.PodDeleteEvent
 			eventPlayload = &domain.PodEventPayload{
 				Pod: &domain.Pod{
 					Name:      name,
 					Namespace: namespace,
 					SessionId: sessionId,
 				},
+				Failure: failure,
+			}
+		} else if phase == v1.PodPending && m[v1.PodScheduled].Status == v1.ConditionTrue {
+			eventName = domain.PodRecordPodScheduleEvent
+			eventPlayload = &domain.PodEventPayload{
+				Pod: &domain.Pod{
+					Name:           name,
+					Namespace:      namespace,
+					SessionId:      sessionId,
+					ConditionTimes: podConditionTimes(m),
+				},
 			}
 		} else if phase == v1.PodRunning {
-			conditions := pod.Status.Conditions
-			m := funk.ToMap(conditions, "Type").(map[v1.PodConditionType]v1.PodCondition)
 			handler.logger.Sugar().Infow("Running Pod Status Update", "Name", name, "Namespace",
 				namespace, "SessionId", sessionId, "PodInitialized",
 				m[v1.PodInitialized].Status, "PodScheduled", m[v1.PodScheduled].Status, "ContainersReady",
 				m[v1.ContainersReady].Status, "PodReady", m[v1.PodReady].Status)
 
-			if m[v1.PodInitialized].Status == v1.ConditionTrue && m[v1.PodScheduled].Status == v1.ConditionTrue && m[v1.ContainersReady].Status == v1.ConditionTrue && m[v1.PodReady].Status == v1.ConditionTrue {
+			if handler.containerRules.IsReady(&pod, m) {
 				if pod.ObjectMeta.DeletionTimestamp == nil {
+					handler.observeSession(&pod)
 					eventName = domain.PodReadyEvent
 					eventPlayload = &domain.PodEventPayload{
 						Pod: &domain.Pod{
-							Name:      name,
-							Namespace: namespace,
-							SessionId: sessionId,
-							NodeName:  nodeName,
-							Ip:        ip,
+							Name:           name,
+							Namespace:      namespace,
+							SessionId:      sessionId,
+							NodeName:       nodeName,
+							Ip:             ip,
+							AgentPool:      handler.agentPoolOf(&pod),
+							ProbePort:      pod.ObjectMeta.Annotations[domain.ProbePortAnnotation],
+							Image:          pod.ObjectMeta.Labels[domain.ImageLabel],
+							Created:        podCreated(&pod),
+							ConditionTimes: podConditionTimes(m),
 						},
 					}
 				} else {
@@ -128,28 +212,26 @@ func (handler *PodEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
 						},
 					}
 				}
-			}
-			// logic to catch crashed containers
-			r := funk.Filter(pod.Status.ContainerStatuses, func(c v1.ContainerStatus) bool {
-				return c.State.Terminated != nil
-			}).([]v1.ContainerStatus)
-			if len(r) > 0 {
-				handler.logger.Sugar().Infow("Pod has crashed containers", "Name", name, "Namespace", namespace, "SessionId", sessionId)
-				handler.logger.Sugar().Infow("Pod should be deleted", "Name", name, "Namespace", namespace, "SessionId", sessionId)
-				out, _ := json.Marshal(r)
-				handler.logger.Sugar().Infow(string(out))
-
-				eventName = domain.PodDeleteEvent
+			} else if pod.ObjectMeta.DeletionTimestamp == nil {
+				// containers are starting or probes are not passing yet
+				eventName = domain.PodInitializingEvent
 				eventPlayload = &domain.PodEventPayload{
 					Pod: &domain.Pod{
 						Name:      name,
 						Namespace: namespace,
 						SessionId: sessionId,
+						NodeName:  nodeName,
+						Image:     pod.ObjectMeta.Labels[domain.ImageLabel],
 					},
 				}
 			}
 		}
 	}
+	// a session on its way out is not reaped again
+	switch eventName {
+	case domain.PodDeleteEvent, domain.PodEvictedEvent, domain.PodPreemptedEvent, domain.PodNodeShutdownEvent:
+		handler.sessionReaper.Forget(sessionId)
+	}
 	if eventName != domain.PodNilEvent {
 		handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
 			eventName,
@@ -163,8 +245,107 @@ func (handler *PodEventHandler) OnDeleteObject(obj interface{}) {
 	if err != nil {
 		handler.logger.Sugar().Error("OnAddObject:", err)
 	} else {
-		name, namespace := pod.ObjectMeta.Name, pod.ObjectMeta.Namespace
+		name, namespace, sessionId := pod.ObjectMeta.Name, pod.ObjectMeta.Namespace, pod.ObjectMeta.Labels["sessionId"]
 		handler.logger.Sugar().Infow("Pod is deleted", "Name", name, "Namespace", namespace)
+		handler.pendingTracker.Forget(sessionId)
+		handler.volumeTracker.Forget(sessionId)
+		handler.sessionReaper.Forget(sessionId)
+		handler.scaleUps.ForgetPod(namespace + "/" + name)
+		if err := handler.gpuInventory.RemovePod(handler.ctx, namespace+"/"+name); err != nil {
+			handler.logger.Sugar().Errorf("Remove pod %s/%s from gpu inventory has error: %s", namespace, name, err.Error())
+		}
+		if sessionId != "" {
+			handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
+				domain.PodRemovedEvent,
+				&domain.PodEventPayload{
+					Pod: &domain.Pod{
+						Name:      name,
+						Namespace: namespace,
+						SessionId: sessionId,
+					},
+				},
+			))
+		}
+	}
+}
+
+// the scheduler records FailedScheduling with the unschedulable condition, the session waits for a new node.
+// once bound the pod only waited for the node it is scheduled on
+func (handler *PodEventHandler) trackScaleUp(pod *v1.Pod, scheduled v1.PodCondition) {
+	key := pod.ObjectMeta.Namespace + "/" + pod.ObjectMeta.Name
+	if pod.Spec.NodeName != "" {
+		handler.scaleUps.PodScheduled(key, pod.Spec.NodeName)
+		return
+	}
+	if scheduled.Status != v1.ConditionFalse || scheduled.Reason != v1.PodReasonUnschedulable {
+		return
+	}
+	since := scheduled.LastTransitionTime.Time
+	if since.IsZero() {
+		since = time.Now()
+	}
+	handler.scaleUps.PodPending(key, handler.agentPoolOf(pod), since, scaleup.FailedSchedulingReason)
+}
+
+// ready sessions are reaped past their max lifetime or idle timeout
+func (handler *PodEventHandler) observeSession(pod *v1.Pod) {
+	handler.sessionReaper.Observe(&domain.Pod{
+		Name:      pod.ObjectMeta.Name,
+		Namespace: pod.ObjectMeta.Namespace,
+		SessionId: pod.ObjectMeta.Labels["sessionId"],
+		NodeName:  pod.Spec.NodeName,
+	}, pod.ObjectMeta.CreationTimestamp.Time, handler.sessionReaper.MaxLifetimeOf(pod))
+}
+
+// every scheduled pod of the namespace holds its gpus until it terminates, sessions or not
+func (handler *PodEventHandler) trackGpus(pod *v1.Pod) {
+	name, namespace := pod.ObjectMeta.Name, pod.ObjectMeta.Namespace
+	key := namespace + "/" + name
+	var err error
+	if phase := pod.Status.Phase; phase == v1.PodSucceeded || phase == v1.PodFailed {
+		err = handler.gpuInventory.RemovePod(handler.ctx, key)
+	} else {
+		err = handler.gpuInventory.SetPod(handler.ctx, key, pod.ObjectMeta.Labels["sessionId"], pod.Spec.NodeName, gpu.PodRequests(pod))
+	}
+	if err != nil {
+		handler.logger.Sugar().Errorf("Track gpus of pod %s/%s has error: %s", namespace, name, err.Error())
+	}
+}
+
+// the aks agentpool of the node the pod is scheduled on, as recorded by the node module.
+// pods not scheduled yet or on nodes not observed fall back to the agentpool node selector,
+// pods placed by node affinity carry none
+func (handler *PodEventHandler) agentPoolOf(pod *v1.Pod) string {
+	if pod.Spec.NodeName != "" {
+		if node, exist := handler.gpuInventory.Node(pod.Spec.NodeName); exist && node.AgentPool != "" {
+			return node.AgentPool
+		}
+	}
+	if agentPool := pod.Spec.NodeSelector["agentpool"]; agentPool != "" {
+		return agentPool
+	}
+	return "unknown"
+}
+
+func podCreated(pod *v1.Pod) int64 {
+	if pod.ObjectMeta.CreationTimestamp.IsZero() {
+		return 0
+	}
+	return pod.ObjectMeta.CreationTimestamp.Unix()
+}
+
+func podConditionTimes(conditions map[v1.PodConditionType]v1.PodCondition) domain.PodConditionTimes {
+	transitionTime := func(conditionType v1.PodConditionType) int64 {
+		condition := conditions[conditionType]
+		if condition.Status != v1.ConditionTrue || condition.LastTransitionTime.IsZero() {
+			return 0
+		}
+		return condition.LastTransitionTime.Unix()
+	}
+	return domain.PodConditionTimes{
+		Scheduled:   transitionTime(v1.PodScheduled),
+		Initialized: transitionTime(v1.PodInitialized),
+		Ready:       transitionTime(v1.PodReady),
 	}
 }
 
@@ -4,14 +4,38 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// accepts any pod, tests asserting the gpu tracking use their own mock
+func newGpuInventory() *gpu.MockIInventory {
+	inventory := &gpu.MockIInventory{}
+	inventory.On("SetPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	inventory.On("RemovePod", mock.Anything, mock.Anything).Return(nil).Maybe()
+	inventory.On("Node", mock.Anything).Return(gpu.NodeInventory{}, false).Maybe()
+	return inventory
+}
+
+// no session waits for a new node, tests asserting the scale up tracking use their own mock
+func newScaleUps() *scaleup.MockITracker {
+	scaleUps := &scaleup.MockITracker{}
+	scaleUps.On("PodPending", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+	scaleUps.On("PodScheduled", mock.Anything, mock.Anything).Return().Maybe()
+	scaleUps.On("ForgetPod", mock.Anything).Return().Maybe()
+	scaleUps.On("PodScaleUp", mock.Anything, mock.Anything).Return(scaleup.ScaleUp{}, false).Maybe()
+	return scaleUps
+}
 
 func TestNewPodEventHandler(t *testing.T) {
 	ctx := context.TODO()
@@ -21,7 +45,25 @@ func TestNewPodEventHandler(t *testing.T) {
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
 
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	assert.NotNil(t, h, "Pod Event Handler should not be nil")
 }
 
@@ -36,7 +78,25 @@ func TestCustomWatchErrorHandler(t *testing.T) {
 	err := errors.New("errors from k8s api server")
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	h.CustomWatchErrorHandler(nil, err)
 
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
@@ -54,7 +114,25 @@ func TestOnAddObject(t *testing.T) {
 	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -78,6 +156,72 @@ func TestOnAddObject(t *testing.T) {
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
 }
 
+// listed after a restart, the ready session may never be updated again
+func TestOnAddObject_Ready(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
+	pod := func(sessionId string, phase string, ready string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Pod",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name":      "pod-" + sessionId,
+					"namespace": "test_namespace",
+					"labels": map[string]interface{}{
+						"sessionId": sessionId,
+					},
+				},
+				"spec": map[string]interface{}{
+					"nodeName": "node-123",
+				},
+				"status": map[string]interface{}{
+					"phase": phase,
+					"conditions": []map[string]interface{}{
+						{"type": "PodScheduled", "status": "True"},
+						{"type": "Initialized", "status": "True"},
+						{"type": "ContainersReady", "status": ready},
+						{"type": "Ready", "status": ready},
+					},
+				},
+			},
+		}
+	}
+	h.OnAddObject(pod("session-ready", "Running", "True"))
+	h.OnAddObject(pod("session-starting", "Running", "False"))
+	h.OnAddObject(pod("session-pending", "Pending", "False"))
+	assert.Contains(t, reaper.sessions, "session-ready")
+	assert.NotContains(t, reaper.sessions, "session-starting")
+	assert.NotContains(t, reaper.sessions, "session-pending")
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 3)
+}
+
 func TestOnUpdateObject_Failed(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -88,7 +232,25 @@ func TestOnUpdateObject_Failed(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -122,7 +284,25 @@ func TestOnUpdateObject_Succeeded(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -156,7 +336,25 @@ func TestOnUpdateObject_Unknown(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -190,7 +388,25 @@ func TestOnUpdateObject_Running(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -201,9 +417,15 @@ func TestOnUpdateObject_Running(t *testing.T) {
 				"labels": map[string]interface{}{
 					"sessionId": "session-123",
 				},
+				"annotations": map[string]interface{}{
+					domain.ProbePortAnnotation: "8443",
+				},
 			},
 			"spec": map[string]interface{}{
 				"nodeName": "node-123",
+				"nodeSelector": map[string]interface{}{
+					"agentpool": "viz",
+				},
 			},
 			"status": map[string]interface{}{
 				"podIP": "1.2.3.4",
@@ -214,26 +436,116 @@ func TestOnUpdateObject_Running(t *testing.T) {
 						"status": "True",
 					},
 					{
-						"type":   "Ready",
-						"status": "True",
+						"type":               "Ready",
+						"status":             "True",
+						"lastTransitionTime": "2024-05-01T10:00:30Z",
 					},
 					{
 						"type":   "ContainersReady",
 						"status": "True",
 					},
+					{
+						"type":               "PodScheduled",
+						"status":             "True",
+						"lastTransitionTime": "2024-05-01T10:00:00Z",
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodReadyEvent, event.EventName())
+	assert.Equal(t, "viz", event.Payload().(*domain.PodEventPayload).Pod.AgentPool)
+	assert.Equal(t, "8443", event.Payload().(*domain.PodEventPayload).Pod.ProbePort)
+	assert.Contains(t, reaper.sessions, "session-123", "ready session is observed by the reaper")
+	// Initialized carries no transition time
+	assert.Equal(t, domain.PodConditionTimes{
+		Scheduled: 1714557600,
+		Ready:     1714557630,
+	}, event.Payload().(*domain.PodEventPayload).Pod.ConditionTimes)
+}
+
+// AKS pod in unstable state between deleted and running
+// pods placed by node affinity take the agent pool of their node
+func TestOnUpdateObject_Running_NodeAffinity(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	gpuInventory := &gpu.MockIInventory{}
+	gpuInventory.On("SetPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	gpuInventory.On("Node", "node-123").Return(gpu.NodeInventory{Name: "node-123", AgentPool: "viz3d"}, true)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, gpuInventory, newScaleUps())
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-123",
+			},
+			"status": map[string]interface{}{
+				"podIP": "1.2.3.4",
+				"phase": "Running",
+				"conditions": []map[string]interface{}{
+					{
+						"type":   "Initialized",
+						"status": "True",
+					},
 					{
 						"type":   "PodScheduled",
 						"status": "True",
 					},
+					{
+						"type":   "Ready",
+						"status": "True",
+					},
+					{
+						"type":   "ContainersReady",
+						"status": "True",
+					},
 				},
 			},
 		},
 	}
 	h.OnUpdateObject(nil, payload)
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodReadyEvent, event.EventName())
+	assert.Equal(t, "viz3d", event.Payload().(*domain.PodEventPayload).Pod.AgentPool)
 }
 
-// AKS pod in unstable state between deleted and running
 func TestOnUpdateObject_Running_Unstable(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -244,7 +556,25 @@ func TestOnUpdateObject_Running_Unstable(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -288,6 +618,88 @@ func TestOnUpdateObject_Running_Unstable(t *testing.T) {
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
 }
 
+func TestOnUpdateObject_Running_Initializing(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-123",
+			},
+			"status": map[string]interface{}{
+				"podIP": "1.2.3.4",
+				"phase": "Running",
+				"conditions": []map[string]interface{}{
+					{
+						"type":   "Initialized",
+						"status": "True",
+					},
+					{
+						"type":   "Ready",
+						"status": "False",
+					},
+					{
+						"type":   "ContainersReady",
+						"status": "False",
+					},
+					{
+						"type":   "PodScheduled",
+						"status": "True",
+					},
+				},
+				"containerStatuses": []map[string]interface{}{
+					{
+						"name":  "app",
+						"ready": false,
+						"state": map[string]interface{}{
+							"running": map[string]interface{}{},
+						},
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodInitializingEvent, event.EventName())
+}
+
 func TestOnUpdateObject_Pending(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -298,7 +710,25 @@ func TestOnUpdateObject_Pending(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -342,7 +772,25 @@ func TestOnUpdateObject_Crash(t *testing.T) {
 	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -382,6 +830,225 @@ func TestOnUpdateObject_Crash(t *testing.T) {
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
 }
 
+func TestOnUpdateObject_Pending_ImagePullBackOff(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-123",
+			},
+			"status": map[string]interface{}{
+				"phase": "Pending",
+				"conditions": []map[string]interface{}{
+					{
+						"type":   "PodScheduled",
+						"status": "True",
+					},
+				},
+				"containerStatuses": []map[string]interface{}{
+					{
+						"name": "container-0",
+						"state": map[string]interface{}{
+							"waiting": map[string]interface{}{
+								"reason":  "ImagePullBackOff",
+								"message": "Back-off pulling image",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodDeleteEvent, event.EventName())
+	failure := event.Payload().(*domain.PodEventPayload).Failure
+	assert.Equal(t, domain.ImagePullBackOff, failure.Reason)
+	assert.Equal(t, "container-0", failure.ContainerName)
+}
+
+// a pod deleted while its image pull is retried is reported with the pull error
+func TestOnUpdateObject_Deleted_ErrImagePull(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":              "test_name",
+				"namespace":         "test_namespace",
+				"deletionTimestamp": "2024-01-01T10:00:00Z",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-123",
+			},
+			"status": map[string]interface{}{
+				"phase": "Pending",
+				"conditions": []map[string]interface{}{
+					{
+						"type":   "PodScheduled",
+						"status": "True",
+					},
+				},
+				"containerStatuses": []map[string]interface{}{
+					{
+						"name": "container-0",
+						"state": map[string]interface{}{
+							"waiting": map[string]interface{}{
+								"reason":  "ErrImagePull",
+								"message": "manifest unknown",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodDeleteEvent, event.EventName())
+	failure := event.Payload().(*domain.PodEventPayload).Failure
+	assert.Equal(t, domain.ErrImagePull, failure.Reason)
+	assert.Equal(t, "container-0", failure.ContainerName)
+}
+
+func TestOnUpdateObject_Evicted(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-123",
+			},
+			"status": map[string]interface{}{
+				"phase":   "Failed",
+				"reason":  "Evicted",
+				"message": "The node was low on resource: ephemeral-storage.",
+				"containerStatuses": []map[string]interface{}{
+					{
+						"name": "container-0",
+						"state": map[string]interface{}{
+							"terminated": map[string]interface{}{
+								"exitCode": 137,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodEvictedEvent, event.EventName())
+	eventPayload := event.Payload().(*domain.PodEventPayload)
+	assert.Equal(t, domain.Eviction, eventPayload.Disruption.Cause)
+	assert.Nil(t, eventPayload.Failure)
+}
+
 func TestOnDeleteObject(t *testing.T) {
 	ctx := context.TODO()
 	logger := logger.NewZapLogger(logger.LogConfig{
@@ -389,10 +1056,28 @@ func TestOnDeleteObject(t *testing.T) {
 	})
 
 	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
-	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
 
 	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
-	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), newScaleUps())
 	payload := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"kind":       "Pod",
@@ -413,5 +1098,211 @@ func TestOnDeleteObject(t *testing.T) {
 		},
 	}
 	h.OnDeleteObject(payload)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodRemovedEvent, event.EventName())
+	assert.Equal(t, "test-app", event.Payload().(*domain.PodEventPayload).Pod.SessionId)
+}
+
+func TestOnUpdateObject_TrackGpus(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Maybe()
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	inventory := gpu.NewMockIInventory(t)
+	inventory.On("SetPod", ctx, "default/session-pod", "session-123", "aks-viz-0001", gpu.Resources{
+		"nvidia.com/gpu":         1,
+		"nvidia.com/mig-1g.10gb": 2,
+	}).Return(nil).Once()
+	inventory.On("RemovePod", ctx, "default/session-pod").Return(nil).Once()
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, inventory, newScaleUps())
+	pod := func(phase string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       "Pod",
+				"apiVersion": "v1",
+				"metadata": map[string]interface{}{
+					"name":      "session-pod",
+					"namespace": "default",
+					"labels": map[string]interface{}{
+						"sessionId": "session-123",
+						"managed":   "false",
+					},
+				},
+				"spec": map[string]interface{}{
+					"nodeName": "aks-viz-0001",
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "server",
+							"resources": map[string]interface{}{
+								"limits": map[string]interface{}{"nvidia.com/gpu": "1", "cpu": "4"},
+							},
+						},
+						map[string]interface{}{
+							"name": "encoder",
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"nvidia.com/mig-1g.10gb": "2"},
+							},
+						},
+					},
+				},
+				"status": map[string]interface{}{
+					"phase": phase,
+				},
+			},
+		}
+	}
+	h.OnUpdateObject(nil, pod("Running"))
+	// terminated pods free their gpus
+	h.OnUpdateObject(nil, pod("Succeeded"))
+}
+
+func TestOnUpdateObject_Pending_Unschedulable(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("PodPending", "test_namespace/test_name", "viz3d", mock.MatchedBy(func(since time.Time) bool {
+		return since.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	}), scaleup.FailedSchedulingReason).Return().Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), scaleUps)
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeSelector": map[string]interface{}{
+					"agentpool": "viz3d",
+				},
+			},
+			"status": map[string]interface{}{
+				"phase": "Pending",
+				"conditions": []map[string]interface{}{
+					{
+						"type":               "PodScheduled",
+						"status":             "False",
+						"reason":             "Unschedulable",
+						"message":            "0/3 nodes are available: 3 Insufficient nvidia.com/gpu.",
+						"lastTransitionTime": "2024-01-01T10:00:00Z",
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
 	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
 }
+
+func TestOnUpdateObject_Scheduled(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil)
+	eventHandler := ddd.MockIEventHandler[ddd.IEvent]{}
+	scaleUps := scaleup.NewMockITracker(t)
+	scaleUps.On("PodScheduled", "test_namespace/test_name", "node-1").Return().Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	mockConfig.On("Get", "app.container_restart_threshold").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	volumeTracker := NewVolumeTracker(ctx, logger, mockConfig, &eventDispatcher)
+	pendingTracker := NewPendingTracker(logger, mockConfig, &eventDispatcher, volumeTracker)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	classifier := NewFailureClassifier(mockConfig, containerRules)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	h := NewPodEventHandler(ctx, logger, &eventDispatcher, &eventHandler, pendingTracker, volumeTracker, classifier, containerRules, reaper, newGpuInventory(), scaleUps)
+	payload := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "test_name",
+				"namespace": "test_namespace",
+				"labels": map[string]interface{}{
+					"sessionId": "session-123",
+				},
+			},
+			"spec": map[string]interface{}{
+				"nodeName": "node-1",
+				"nodeSelector": map[string]interface{}{
+					"agentpool": "viz3d",
+				},
+			},
+			"status": map[string]interface{}{
+				"phase": "Pending",
+				"conditions": []map[string]interface{}{
+					{
+						"type":               "PodScheduled",
+						"status":             "True",
+						"lastTransitionTime": "2024-01-01T10:05:00Z",
+					},
+				},
+			},
+		},
+	}
+	h.OnUpdateObject(nil, payload)
+	scaleUps.AssertNotCalled(t, "PodPending", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
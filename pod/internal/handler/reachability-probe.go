@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultProbeTimeout       = 2 * time.Second
+	defaultProbeRetryInterval = 1 * time.Second
+	defaultProbeRetries       = 3
+	defaultProbeWorkers       = 4
+	defaultProbeQueueSize     = 256
+)
+
+// a ready pod does not guarantee the streaming server on the pod ip accepts connections.
+// ready sessions are queued and probed by a fixed number of workers, off the informer goroutine.
+// timeout and retries bound how long the enqueue is delayed, the result is published as a domain event
+type ReachabilityProbe struct {
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	enabled               bool
+	workers               int
+	queue                 chan *domain.Pod
+	mode                  domain.ProbeMode
+	httpPath              string
+	timeout               time.Duration
+	retries               int
+	retryInterval         time.Duration
+	dial                  func(ctx context.Context, network string, address string) (net.Conn, error)
+	client                *http.Client
+	mutex                 sync.Mutex
+	// sessions queued or being probed
+	pending map[string]bool
+}
+
+// app.reachability_probe_enabled is off by default, every ready session is enqueued right away.
+// at least one worker and queue slot, otherwise no session would ever be probed
+func NewReachabilityProbe(logger *zap.Logger, cfg config.IConfig,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]) (*ReachabilityProbe, error) {
+	mode := domain.ProbeMode(config.GetString(cfg, "app.reachability_probe_mode", string(domain.TCPProbe)))
+	if !mode.IsValid() {
+		return nil, domain.NewInvalidProbeModeErr(string(mode))
+	}
+	timeout := config.GetSeconds(cfg, "app.reachability_probe_timeout_seconds", defaultProbeTimeout)
+	dialer := &net.Dialer{Timeout: timeout}
+	return &ReachabilityProbe{
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		enabled:               config.GetBool(cfg, "app.reachability_probe_enabled", false),
+		workers:               max(config.GetInt(cfg, "app.reachability_probe_workers", defaultProbeWorkers), 1),
+		queue:                 make(chan *domain.Pod, max(config.GetInt(cfg, "app.reachability_probe_queue_size", defaultProbeQueueSize), 1)),
+		pending:               map[string]bool{},
+		mode:                  mode,
+		httpPath:              config.GetString(cfg, "app.reachability_probe_http_path", "/"),
+		timeout:               timeout,
+		retries:               config.GetInt(cfg, "app.reachability_probe_retries", defaultProbeRetries),
+		retryInterval:         config.GetSeconds(cfg, "app.reachability_probe_retry_interval_seconds", defaultProbeRetryInterval),
+		dial:                  dialer.DialContext,
+		client:                &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *ReachabilityProbe) Enabled() bool {
+	return p.enabled
+}
+
+// false when the session is queued already or the queue is full,
+// a session left out is probed with its next ready report or by the reconciler
+func (p *ReachabilityProbe) Submit(pod *domain.Pod) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.pending[pod.SessionId] {
+		return false
+	}
+	select {
+	case p.queue <- pod:
+		p.pending[pod.SessionId] = true
+		return true
+	default:
+		p.logger.Sugar().Warnw("Reachability probe queue is full", "SessionId", pod.SessionId)
+		return false
+	}
+}
+
+// worker.Worker, registered by the module. probes in flight finish before it returns,
+// a probe cut short by the shutdown reports nothing and the session is probed again after the restart
+func (p *ReachabilityProbe) Run(ctx context.Context) error {
+	if !p.enabled {
+		p.logger.Sugar().Info("Reachability probe is disabled")
+		return nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case pod := <-p.queue:
+					p.report(ctx, pod)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (p *ReachabilityProbe) report(ctx context.Context, pod *domain.Pod) {
+	defer func() {
+		p.mutex.Lock()
+		delete(p.pending, pod.SessionId)
+		p.mutex.Unlock()
+	}()
+	unreachable := p.Verify(ctx, pod)
+	if ctx.Err() != nil {
+		p.logger.Sugar().Infow("Reachability probe cancelled", "SessionId", pod.SessionId)
+		return
+	}
+	event := ddd.NewEvent(domain.PodReachableEvent, &domain.PodEventPayload{Pod: pod})
+	if unreachable != nil {
+		event = ddd.NewEvent(domain.PodUnreachableEvent, &domain.PodUnreachablePayload{Pod: pod, Err: unreachable})
+	}
+	if err := p.domainEventDispatcher.Publish(ctx, event); err != nil {
+		p.logger.Sugar().Errorf("Publish %s of session %s has error: %s", event.EventName(), pod.SessionId, err.Error())
+	}
+}
+
+// nil when the session is reachable, the probe is disabled or the pod has no probe port
+func (p *ReachabilityProbe) Verify(ctx context.Context, pod *domain.Pod) error {
+	if !p.enabled {
+		return nil
+	}
+	if pod.ProbePort == "" || pod.Ip == "" {
+		p.logger.Sugar().Infow("Reachability probe skipped", "SessionId", pod.SessionId, "Ip", pod.Ip, "Port", pod.ProbePort)
+		return nil
+	}
+	address := net.JoinHostPort(pod.Ip, pod.ProbePort)
+	attempts := p.retries + 1
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = p.probe(ctx, address); err == nil {
+			p.logger.Sugar().Infow("Session is reachable", "SessionId", pod.SessionId, "Address", address, "Attempt", attempt)
+			return nil
+		}
+		p.logger.Sugar().Warnw("Reachability probe failed", "SessionId", pod.SessionId, "Address", address,
+			"Attempt", attempt, "Error", err.Error())
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return domain.NewSessionUnreachableErr(pod.SessionId, address, attempt, ctx.Err())
+		case <-time.After(p.retryInterval):
+		}
+	}
+	return domain.NewSessionUnreachableErr(pod.SessionId, address, attempts, err)
+}
+
+func (p *ReachabilityProbe) probe(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	if p.mode == domain.HTTPProbe {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s", address, p.httpPath), nil)
+		if err != nil {
+			return err
+		}
+		response, err := p.client.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("http status %d", response.StatusCode)
+		}
+		return nil
+	}
+	conn, err := p.dial(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+)
+
+func probedPod(address string) *domain.Pod {
+	host, port, _ := net.SplitHostPort(address)
+	return &domain.Pod{
+		SessionId: "sessionId",
+		Ip:        host,
+		ProbePort: port,
+	}
+}
+
+func TestNewReachabilityProbe_InvalidMode(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return("udp")
+	_, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.True(t, domain.NewInvalidProbeModeErr("udp").Is(err))
+}
+
+func TestNewReachabilityProbe_AtLeastOneWorker(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(0)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(-1)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(nil)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, probe.workers)
+	assert.Equal(t, 1, cap(probe.queue))
+}
+
+func TestReachabilityProbe_Disabled(t *testing.T) {
+	// nothing listens on the port, the probe must not run
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(false)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(nil)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, probe.Verify(context.TODO(), probedPod("127.0.0.1:1")))
+}
+
+func TestReachabilityProbe_NoProbePort(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, probe.Verify(context.TODO(), &domain.Pod{SessionId: "sessionId", Ip: "127.0.0.1"}))
+}
+
+func TestReachabilityProbe_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, probe.Verify(context.TODO(), probedPod(listener.Addr().String())))
+}
+
+func TestReachabilityProbe_TCP_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	dials := 0
+	dial := probe.dial
+	probe.dial = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		dials++
+		return dial(ctx, network, address)
+	}
+	err = probe.Verify(context.TODO(), probedPod(address))
+	assert.True(t, domain.NewSessionUnreachableErr("sessionId", address, 3, nil).Is(err))
+	assert.Equal(t, 3, dials, "first attempt and 2 retries")
+}
+
+func TestReachabilityProbe_HTTP(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.HTTPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+	err = probe.Verify(context.TODO(), probedPod(serverUrl.Host))
+	assert.True(t, domain.NewSessionUnreachableErr("sessionId", serverUrl.Host, 3, nil).Is(err))
+	assert.Contains(t, err.Error(), "http status 503")
+
+	status = http.StatusOK
+	assert.Nil(t, probe.Verify(context.TODO(), probedPod(serverUrl.Host)))
+}
+
+func TestReachabilityProbe_Submit(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(1)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(nil)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, nil)
+	assert.Nil(t, err)
+
+	assert.True(t, probe.Submit(&domain.Pod{SessionId: "session-1"}))
+	assert.False(t, probe.Submit(&domain.Pod{SessionId: "session-1"}), "queued already")
+	assert.False(t, probe.Submit(&domain.Pod{SessionId: "session-2"}), "queue is full")
+}
+
+func TestReachabilityProbe_Run(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	published := make(chan ddd.IEvent, 1)
+	dispatcher := ddd.NewMockIEventDispatcher[ddd.IEvent](t)
+	dispatcher.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		published <- args.Get(1).(ddd.IEvent)
+	}).Return(nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, dispatcher)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- probe.Run(ctx)
+	}()
+
+	pod := probedPod(listener.Addr().String())
+	assert.True(t, probe.Submit(pod))
+	select {
+	case event := <-published:
+		assert.Equal(t, domain.PodReachableEvent, event.EventName())
+		assert.Equal(t, pod, event.Payload().(*domain.PodEventPayload).Pod)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "probe result was not published")
+	}
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+// a probe cut short by the shutdown does not fail the session
+func TestReachabilityProbe_Run_Cancelled(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.reachability_probe_mode").Return(string(domain.TCPProbe))
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(true)
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return(nil)
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(2)
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(0)
+	probe, err := NewReachabilityProbe(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, ddd.NewMockIEventDispatcher[ddd.IEvent](t))
+	assert.Nil(t, err)
+	probe.dial = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- probe.Run(ctx)
+	}()
+	assert.True(t, probe.Submit(probedPod("127.0.0.1:1")))
+	cancel()
+	assert.Nil(t, <-done)
+}
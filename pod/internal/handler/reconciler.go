@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const defaultReconcileInterval = 5 * time.Minute
+
+type DriftCause string
+
+const (
+	// ready pod whose enqueue task was never submitted, whatever its recorded state
+	PodReadyDrift DriftCause = "PodReady"
+	// terminated pod whose session was never marked deletable
+	PodTerminatedDrift DriftCause = "PodTerminated"
+	// recorded session whose pod is gone
+	PodGoneDrift DriftCause = "PodGone"
+)
+
+// task is empty when only the stale record is purged
+type SessionDrift struct {
+	SessionId string
+	Recorded  session.LifecycleState
+	Cause     DriftCause
+	Task      session.StreamTaskType
+}
+
+// pods that became ready or died while the monitor was down are never updated again.
+// the reconciler compares the informer cache with the recorded lifecycles
+// and replays the missing transitions, in dry run it only reports them.
+type Reconciler struct {
+	logger                *zap.Logger
+	informer              k8s.IK8sInformer
+	podEventHandler       k8s.IK8sEventHandler
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	sessionService        session.ISessionService
+	containerRules        *ContainerRules
+	namespace             string
+	interval              time.Duration
+	dryRun                bool
+	now                   func() time.Time
+}
+
+func NewReconciler(logger *zap.Logger, cfg config.IConfig,
+	informer k8s.IK8sInformer,
+	podEventHandler k8s.IK8sEventHandler,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent],
+	sessionService session.ISessionService,
+	containerRules *ContainerRules,
+) *Reconciler {
+	return &Reconciler{
+		logger:                logger,
+		informer:              informer,
+		podEventHandler:       podEventHandler,
+		domainEventDispatcher: domainEventDispatcher,
+		sessionService:        sessionService,
+		containerRules:        containerRules,
+		namespace:             config.GetString(cfg, "app.pod_namespace", ""),
+		interval:              config.GetSeconds(cfg, "app.reconcile_interval_seconds", defaultReconcileInterval),
+		dryRun:                config.GetBool(cfg, "app.reconcile_dry_run", false),
+		now:                   time.Now,
+	}
+}
+
+// nothing is compared before the informer cache is synced, an empty cache looks like every pod is gone
+func (r *Reconciler) Reconcile(ctx context.Context) ([]SessionDrift, error) {
+	if !r.informer.HasSynced() {
+		r.logger.Sugar().Info("Reconcile skipped, informer cache is not synced")
+		return nil, nil
+	}
+	recorded, err := r.sessionService.ListRecordedSessions()
+	if err != nil {
+		return nil, err
+	}
+	drifts := []SessionDrift{}
+	for _, obj := range r.informer.List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		pod, err := parsePod(u)
+		sessionId := pod.ObjectMeta.Labels["sessionId"]
+		if err != nil || sessionId == "" || pod.ObjectMeta.Labels["managed"] == "false" {
+			continue
+		}
+		rec := recorded[sessionId]
+		delete(recorded, sessionId)
+		drift := r.podDrift(&pod, sessionId, rec)
+		if drift == nil {
+			continue
+		}
+		drifts = append(drifts, *drift)
+		r.report(drift, pod.ObjectMeta.Name, pod.ObjectMeta.Namespace)
+		if !r.dryRun {
+			// the pod event handler emits the same events as for a live update
+			r.podEventHandler.OnUpdateObject(nil, u)
+		}
+	}
+	// sessions recorded by monitors of other namespaces or updated since the cache was listed are left alone
+	for sessionId, rec := range recorded {
+		if r.namespace != "" && rec.Namespace != r.namespace || r.now().Sub(time.Unix(rec.UpdatedAt, 0)) < r.interval {
+			continue
+		}
+		drift := &SessionDrift{
+			SessionId: sessionId,
+			Recorded:  rec.State,
+			Cause:     PodGoneDrift,
+		}
+		if !isTerminal(rec.State) {
+			drift.Task = session.DeleteSession
+		}
+		drifts = append(drifts, *drift)
+		r.report(drift, rec.PodName, rec.Namespace)
+		if !r.dryRun {
+			if err := r.onPodGone(ctx, drift, rec); err != nil {
+				r.logger.Sugar().Errorf("Reconcile session %s has error: %s", sessionId, err.Error())
+			}
+		}
+	}
+	r.logger.Sugar().Infow("Reconciled", "Drifts", len(drifts), "DryRun", r.dryRun)
+	return drifts, nil
+}
+
+func (r *Reconciler) podDrift(pod *v1.Pod, sessionId string, rec session.RecordedSession) *SessionDrift {
+	state := rec.State
+	phase := pod.Status.Phase
+	if phase == v1.PodFailed || phase == v1.PodSucceeded || pod.ObjectMeta.DeletionTimestamp != nil {
+		if isTerminal(state) {
+			return nil
+		}
+		return &SessionDrift{SessionId: sessionId, Recorded: state, Cause: PodTerminatedDrift, Task: session.DeleteSession}
+	}
+	conditions := funk.ToMap(pod.Status.Conditions, "Type").(map[v1.PodConditionType]v1.PodCondition)
+	if phase != v1.PodRunning || !r.containerRules.IsReady(pod, conditions) || isTerminal(state) {
+		return nil
+	}
+	// a session may have become ready without its enqueue task reaching the stream.
+	// a session updated since the cache was listed may still be probed or enqueued
+	if r.now().Sub(time.Unix(rec.UpdatedAt, 0)) < r.interval {
+		return nil
+	}
+	enqueued, err := r.sessionService.IsSessionEnqueued(sessionId)
+	if err != nil {
+		r.logger.Sugar().Errorf("IsSessionEnqueued %s has error: %s", sessionId, err.Error())
+		return nil
+	}
+	if enqueued {
+		return nil
+	}
+	return &SessionDrift{SessionId: sessionId, Recorded: state, Cause: PodReadyDrift, Task: session.EnqueueSession}
+}
+
+// the pod is gone, the removed event purges the record
+func (r *Reconciler) onPodGone(ctx context.Context, drift *SessionDrift, rec session.RecordedSession) error {
+	pod := &domain.Pod{
+		Name:      rec.PodName,
+		Namespace: rec.Namespace,
+		SessionId: drift.SessionId,
+	}
+	events := []ddd.IEvent{}
+	if drift.Task == session.DeleteSession {
+		events = append(events, ddd.NewEvent(domain.PodDeleteEvent, &domain.PodEventPayload{Pod: pod}))
+	}
+	events = append(events, ddd.NewEvent(domain.PodRemovedEvent, &domain.PodEventPayload{Pod: pod}))
+	return r.domainEventDispatcher.Publish(ctx, events...)
+}
+
+func (r *Reconciler) report(drift *SessionDrift, name string, namespace string) {
+	r.logger.Sugar().Warnw("Session drift", "Name", name, "Namespace", namespace, "SessionId", drift.SessionId,
+		"Recorded", drift.Recorded, "Cause", drift.Cause, "Task", drift.Task, "DryRun", r.dryRun)
+}
+
+// the session is going away, a failing session was reported already
+func isTerminal(state session.LifecycleState) bool {
+	return state == session.Failing || state == session.Terminating || state == session.Deleted
+}
+
+// worker.Worker, registered by the module
+func (r *Reconciler) Run(ctx context.Context) error {
+	if r.interval <= 0 {
+		r.logger.Sugar().Info("Reconciler is disabled")
+		return nil
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil {
+				r.logger.Sugar().Errorf("Reconcile has error: %s", err.Error())
+			}
+		}
+	}
+}
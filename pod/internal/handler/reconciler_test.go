@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func sessionPod(sessionId string, phase string, ready string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "Pod",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":      "pod-" + sessionId,
+				"namespace": "viz",
+				"labels": map[string]interface{}{
+					"sessionId": sessionId,
+				},
+			},
+			"status": map[string]interface{}{
+				"phase": phase,
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "PodScheduled", "status": "True"},
+					map[string]interface{}{"type": "Initialized", "status": "True"},
+					map[string]interface{}{"type": "ContainersReady", "status": ready},
+					map[string]interface{}{"type": "Ready", "status": ready},
+				},
+			},
+		},
+	}
+}
+
+func newTestReconciler(t *testing.T, dryRun bool, informer k8s.IK8sInformer, podEventHandler k8s.IK8sEventHandler,
+	eventDispatcher ddd.IEventDispatcher[ddd.IEvent], sessionService session.ISessionService) *Reconciler {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_namespace").Return("viz")
+	mockConfig.On("Get", "app.reconcile_dry_run").Return(dryRun)
+	mockConfig.On("Get", "app.reconcile_interval_seconds").Return(nil)
+	mockConfig.On("Get", "app.container_rules").Return(nil)
+	containerRules, err := NewContainerRules(mockConfig)
+	assert.Nil(t, err)
+	return NewReconciler(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig,
+		informer, podEventHandler, eventDispatcher, sessionService, containerRules)
+}
+
+func TestReconciler_NotSynced(t *testing.T) {
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("HasSynced").Return(false).Once()
+
+	reconciler := newTestReconciler(t, false, informer, k8s.NewMockIK8sEventHandler(t),
+		&ddd.MockIEventDispatcher[ddd.IEvent]{}, &session.MockISessionService{})
+	drifts, err := reconciler.Reconcile(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(drifts))
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	ctx := context.TODO()
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	readyPod, enqueuedPod, notEnqueuedPod, recentPod, failedPod, pendingPod :=
+		sessionPod("ready", "Running", "True"), sessionPod("enqueued", "Running", "True"),
+		sessionPod("not-enqueued", "Running", "True"), sessionPod("recent", "Running", "True"),
+		sessionPod("failed", "Failed", "False"), sessionPod("pending", "Pending", "False")
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("HasSynced").Return(true)
+	informer.On("List").Return([]interface{}{readyPod, enqueuedPod, notEnqueuedPod, recentPod, failedPod, pendingPod})
+	mockSessionService := &session.MockISessionService{}
+	mockSessionService.On("IsSessionEnqueued", "ready").Return(false, nil).Once()
+	mockSessionService.On("IsSessionEnqueued", "enqueued").Return(true, nil).Once()
+	mockSessionService.On("IsSessionEnqueued", "not-enqueued").Return(false, nil).Once()
+	mockSessionService.On("ListRecordedSessions").Return(map[string]session.RecordedSession{
+		"ready":    {State: session.Initializing, Namespace: "viz"},
+		"enqueued": {State: session.Ready, Namespace: "viz"},
+		// became ready, its enqueue failed
+		"not-enqueued": {State: session.Ready, Namespace: "viz"},
+		// became ready since the cache was listed, its enqueue may be in flight
+		"recent":  {State: session.Ready, Namespace: "viz", UpdatedAt: now.Unix()},
+		"pending": {State: session.Scheduled, Namespace: "viz"},
+		// pod deleted while the monitor was down
+		"gone": {State: session.Ready, PodName: "pod-gone", Namespace: "viz", UpdatedAt: now.Add(-time.Hour).Unix()},
+		// pod of another namespace
+		"other": {State: session.Ready, Namespace: "other", UpdatedAt: now.Add(-time.Hour).Unix()},
+		// recorded after the cache was listed
+		"new": {State: session.Requested, Namespace: "viz", UpdatedAt: now.Unix()},
+	}, nil)
+	podEventHandler := k8s.NewMockIK8sEventHandler(t)
+	podEventHandler.On("OnUpdateObject", nil, readyPod).Return().Once()
+	podEventHandler.On("OnUpdateObject", nil, notEnqueuedPod).Return().Once()
+	podEventHandler.On("OnUpdateObject", nil, failedPod).Return().Once()
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+
+	reconciler := newTestReconciler(t, false, informer, podEventHandler, eventDispatcher, mockSessionService)
+	reconciler.now = func() time.Time { return now }
+	drifts, err := reconciler.Reconcile(ctx)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []SessionDrift{
+		{SessionId: "ready", Recorded: session.Initializing, Cause: PodReadyDrift, Task: session.EnqueueSession},
+		{SessionId: "not-enqueued", Recorded: session.Ready, Cause: PodReadyDrift, Task: session.EnqueueSession},
+		{SessionId: "failed", Cause: PodTerminatedDrift, Task: session.DeleteSession},
+		{SessionId: "gone", Recorded: session.Ready, Cause: PodGoneDrift, Task: session.DeleteSession},
+	}, drifts)
+
+	// the gone session is marked deletable and purged
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	deleted, removed := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent), eventDispatcher.Calls[0].Arguments.Get(2).(ddd.IEvent)
+	assert.Equal(t, domain.PodDeleteEvent, deleted.EventName())
+	assert.Equal(t, domain.PodRemovedEvent, removed.EventName())
+	assert.Equal(t, &domain.Pod{Name: "pod-gone", Namespace: "viz", SessionId: "gone"}, removed.Payload().(*domain.PodEventPayload).Pod)
+}
+
+func TestReconciler_DryRun(t *testing.T) {
+	ctx := context.TODO()
+	informer := k8s.NewMockIK8sInformer(t)
+	informer.On("HasSynced").Return(true)
+	informer.On("List").Return([]interface{}{sessionPod("ready", "Running", "True")})
+	mockSessionService := &session.MockISessionService{}
+	mockSessionService.On("IsSessionEnqueued", "ready").Return(false, nil).Once()
+	mockSessionService.On("ListRecordedSessions").Return(map[string]session.RecordedSession{
+		"gone": {State: session.Terminating, Namespace: "viz"},
+	}, nil)
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+
+	reconciler := newTestReconciler(t, true, informer, k8s.NewMockIK8sEventHandler(t), eventDispatcher, mockSessionService)
+	drifts, err := reconciler.Reconcile(ctx)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []SessionDrift{
+		{SessionId: "ready", Cause: PodReadyDrift, Task: session.EnqueueSession},
+		// already deletable, only the record is stale
+		{SessionId: "gone", Recorded: session.Terminating, Cause: PodGoneDrift},
+	}, drifts)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+)
+
+const defaultReaperInterval = 1 * time.Minute
+
+// first matching rule wins, a rule selects by namespace or by pod label
+type maxLifetimeRule struct {
+	namespace  string
+	labelKey   string
+	labelValue string
+	maxLife    time.Duration
+}
+
+func (r maxLifetimeRule) matches(pod *v1.Pod) bool {
+	if r.namespace != "" {
+		return r.namespace == pod.ObjectMeta.Namespace
+	}
+	value, exist := pod.ObjectMeta.Labels[r.labelKey]
+	return exist && (r.labelValue == "" || r.labelValue == value)
+}
+
+type liveSession struct {
+	pod         *domain.Pod
+	since       time.Time
+	maxLifetime time.Duration
+	reaped      bool
+}
+
+// orphaned sessions keep their gpu node busy until someone deletes them.
+// the reaper remembers live session pods and a worker marks them deletable
+// once they outlive their max lifetime or stop sending heartbeats.
+type SessionReaper struct {
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	kvRepo                repository.IKVRepository
+	maxLifetime           time.Duration
+	rules                 []maxLifetimeRule
+	idleTimeout           time.Duration
+	heartbeatKeyPrefix    string
+	interval              time.Duration
+	now                   func() time.Time
+	mutex                 sync.Mutex
+	sessions              map[string]*liveSession
+}
+
+// app.session_max_lifetime_rules: [{namespace: trial, seconds: 3600}, {label: "tier=free", seconds: 1800}]
+func NewSessionReaper(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]) (*SessionReaper, error) {
+	rules := []maxLifetimeRule{}
+	for _, rule := range cast.ToSlice(cfg.Get("app.session_max_lifetime_rules")) {
+		r := cast.ToStringMap(rule)
+		namespace, label, seconds := cast.ToString(r["namespace"]), cast.ToString(r["label"]), cast.ToInt(r["seconds"])
+		if (namespace == "") == (label == "") || seconds <= 0 {
+			return nil, domain.NewInvalidMaxLifetimeRuleErr(namespace, label, seconds)
+		}
+		labelKey, labelValue, _ := strings.Cut(label, "=")
+		rules = append(rules, maxLifetimeRule{
+			namespace:  namespace,
+			labelKey:   labelKey,
+			labelValue: labelValue,
+			maxLife:    time.Duration(seconds) * time.Second,
+		})
+	}
+	return &SessionReaper{
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		kvRepo:                kvRepo,
+		maxLifetime:           config.GetSeconds(cfg, "app.session_max_lifetime_seconds", 0),
+		rules:                 rules,
+		idleTimeout:           config.GetSeconds(cfg, "app.session_idle_timeout_seconds", 0),
+		heartbeatKeyPrefix:    config.GetString(cfg, "app.session_heartbeat_key_prefix", "SessionMonitor.Heartbeat"),
+		interval:              config.GetSeconds(cfg, "app.session_reaper_interval_seconds", defaultReaperInterval),
+		now:                   time.Now,
+		sessions:              map[string]*liveSession{},
+	}, nil
+}
+
+// annotation first, then the configured rules, then the default. 0 is unlimited
+func (r *SessionReaper) MaxLifetimeOf(pod *v1.Pod) time.Duration {
+	if annotation, exist := pod.ObjectMeta.Annotations[domain.MaxLifetimeAnnotation]; exist {
+		seconds, err := strconv.Atoi(annotation)
+		if err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		r.logger.Sugar().Warnw("Invalid max lifetime annotation", "Name", pod.ObjectMeta.Name,
+			"Namespace", pod.ObjectMeta.Namespace, "Annotation", annotation)
+	}
+	for _, rule := range r.rules {
+		if rule.matches(pod) {
+			return rule.maxLife
+		}
+	}
+	return r.maxLifetime
+}
+
+// since is the pod creation time, so the lifetime also holds across monitor restarts
+func (r *SessionReaper) Observe(pod *domain.Pod, since time.Time, maxLifetime time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if since.IsZero() {
+		since = r.now()
+	}
+	entry, exist := r.sessions[pod.SessionId]
+	if !exist {
+		entry = &liveSession{
+			since: since,
+		}
+		r.sessions[pod.SessionId] = entry
+	}
+	entry.pod, entry.maxLifetime = pod, maxLifetime
+}
+
+func (r *SessionReaper) Forget(sessionId string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.sessions, sessionId)
+}
+
+// publish PodSessionExpiredEvent once for every expired session
+func (r *SessionReaper) Reap(ctx context.Context) error {
+	candidates := map[string]liveSession{}
+	r.mutex.Lock()
+	now := r.now()
+	for sessionId, entry := range r.sessions {
+		if !entry.reaped {
+			candidates[sessionId] = *entry
+		}
+	}
+	r.mutex.Unlock()
+
+	events := []ddd.IEvent{}
+	for sessionId, entry := range candidates {
+		reason, message := r.expiry(ctx, sessionId, entry, now)
+		if reason == "" {
+			continue
+		}
+		r.mutex.Lock()
+		// forgotten in the meantime
+		live, exist := r.sessions[sessionId]
+		if exist {
+			live.reaped = true
+		}
+		r.mutex.Unlock()
+		if !exist {
+			continue
+		}
+		r.logger.Sugar().Infow("Session expired", "Name", entry.pod.Name, "Namespace", entry.pod.Namespace,
+			"SessionId", sessionId, "Since", entry.since, "Reason", reason, "Message", message)
+		events = append(events, ddd.NewEvent(
+			domain.PodSessionExpiredEvent,
+			&domain.PodSessionExpiredPayload{
+				Pod:     entry.pod,
+				Since:   entry.since,
+				Reason:  reason,
+				Message: message,
+			},
+		))
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return r.domainEventDispatcher.Publish(ctx, events...)
+}
+
+// empty reason while the session is alive.
+// sessions which never sent a heartbeat are not idle, their server may not support it
+func (r *SessionReaper) expiry(ctx context.Context, sessionId string, entry liveSession, now time.Time) (string, string) {
+	if lifetime := now.Sub(entry.since); entry.maxLifetime > 0 && lifetime >= entry.maxLifetime {
+		return domain.MaxLifetimeExceededReason, fmt.Sprintf("session lived %s, max lifetime is %s",
+			lifetime.Truncate(time.Second), entry.maxLifetime)
+	}
+	if r.idleTimeout <= 0 {
+		return "", ""
+	}
+	heartbeat, err := r.kvRepo.Get(ctx, r.HeartbeatKey(sessionId))
+	if err != nil {
+		r.logger.Sugar().Errorf("Get heartbeat of session %s has error: %s", sessionId, err.Error())
+		return "", ""
+	}
+	if heartbeat == "" {
+		return "", ""
+	}
+	lastSeen, err := strconv.ParseInt(heartbeat, 10, 64)
+	if err != nil {
+		r.logger.Sugar().Warnw("Invalid heartbeat", "SessionId", sessionId, "Heartbeat", heartbeat)
+		return "", ""
+	}
+	if idle := now.Sub(time.Unix(lastSeen, 0)); idle >= r.idleTimeout {
+		return domain.IdleTimeoutReason, fmt.Sprintf("session idle for %s, idle timeout is %s",
+			idle.Truncate(time.Second), r.idleTimeout)
+	}
+	return "", ""
+}
+
+// the session writes the unix seconds of its last client activity to this key
+func (r *SessionReaper) HeartbeatKey(sessionId string) string {
+	return fmt.Sprintf("%s.%s", r.heartbeatKeyPrefix, sessionId)
+}
+
+// worker.Worker, registered by the module.
+// runs without configured limits too, the annotation may set a max lifetime per pod
+func (r *SessionReaper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Reap(ctx); err != nil {
+				r.logger.Sugar().Errorf("Reap expired sessions has error: %s", err.Error())
+			}
+		}
+	}
+}
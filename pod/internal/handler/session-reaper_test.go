@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewSessionReaper_InvalidRule(t *testing.T) {
+	for _, rule := range []map[string]interface{}{
+		{"namespace": "trial"},
+		{"seconds": 60},
+		{"namespace": "trial", "label": "tier=free", "seconds": 60},
+	} {
+		mockConfig := &config.MockIConfig{}
+		mockConfig.On("Get", "app.session_max_lifetime_rules").Return([]interface{}{rule})
+		_, err := NewSessionReaper(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig,
+			&repository.MockIKVRepository{}, &ddd.MockIEventDispatcher[ddd.IEvent]{})
+		assert.IsType(t, &domain.InvalidMaxLifetimeRuleErr{}, err, "rule %v", rule)
+	}
+}
+
+func TestSessionReaper_MaxLifetimeOf(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return([]interface{}{
+		map[string]interface{}{"label": "tier=free", "seconds": 1800},
+		map[string]interface{}{"namespace": "trial", "seconds": 3600},
+	})
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(28800)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	reaper, err := NewSessionReaper(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig,
+		&repository.MockIKVRepository{}, &ddd.MockIEventDispatcher[ddd.IEvent]{})
+	assert.Nil(t, err)
+
+	pod := func(namespace string, labels map[string]string, annotations map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Labels: labels, Annotations: annotations}}
+	}
+	assert.Equal(t, 8*time.Hour, reaper.MaxLifetimeOf(pod("default", nil, nil)))
+	assert.Equal(t, 1*time.Hour, reaper.MaxLifetimeOf(pod("trial", nil, nil)))
+	assert.Equal(t, 30*time.Minute, reaper.MaxLifetimeOf(pod("trial", map[string]string{"tier": "free"}, nil)))
+	assert.Equal(t, 8*time.Hour, reaper.MaxLifetimeOf(pod("default", map[string]string{"tier": "paid"}, nil)))
+	assert.Equal(t, 2*time.Minute, reaper.MaxLifetimeOf(pod("trial", map[string]string{"tier": "free"},
+		map[string]string{domain.MaxLifetimeAnnotation: "120"})))
+	// an invalid annotation is ignored
+	assert.Equal(t, 1*time.Hour, reaper.MaxLifetimeOf(pod("trial", nil,
+		map[string]string{domain.MaxLifetimeAnnotation: "forever"})))
+}
+
+func TestSessionReaper_Reap_MaxLifetime(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	reaper.now = func() time.Time { return now }
+	reaper.Observe(&domain.Pod{
+		Name:      "test_name",
+		Namespace: "test_namespace",
+		SessionId: "session-123",
+	}, now.Add(-30*time.Minute), 1*time.Hour)
+	// unlimited
+	reaper.Observe(&domain.Pod{
+		SessionId: "session-456",
+	}, now.Add(-30*time.Minute), 0)
+
+	// within max lifetime
+	assert.Nil(t, reaper.Reap(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+
+	// past max lifetime, published exactly once
+	now = now.Add(1 * time.Hour)
+	assert.Nil(t, reaper.Reap(ctx))
+	assert.Nil(t, reaper.Reap(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.PodSessionExpiredEvent, event.EventName())
+	payload := event.Payload().(*domain.PodSessionExpiredPayload)
+	assert.Equal(t, "session-123", payload.Pod.SessionId)
+	assert.Equal(t, domain.MaxLifetimeExceededReason, payload.Reason)
+	assert.Equal(t, "session lived 1h30m0s, max lifetime is 1h0m0s", payload.Message)
+}
+
+func TestSessionReaper_Reap_Idle(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(900)
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	mockKVRepository := &repository.MockIKVRepository{}
+	mockKVRepository.On("Get", ctx, "SessionMonitor.Heartbeat.session-idle").
+		Return("1699656300", nil) // 22:45
+	mockKVRepository.On("Get", ctx, "SessionMonitor.Heartbeat.session-active").
+		Return("1699657080", nil) // 22:58
+	mockKVRepository.On("Get", ctx, "SessionMonitor.Heartbeat.session-silent").Return("", nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", ctx, mock.Anything).Return(nil).Once()
+
+	reaper, err := NewSessionReaper(logger, mockConfig, mockKVRepository, &eventDispatcher)
+	assert.Nil(t, err)
+	reaper.now = func() time.Time { return now }
+	for _, sessionId := range []string{"session-idle", "session-active", "session-silent"} {
+		reaper.Observe(&domain.Pod{SessionId: sessionId}, now.Add(-2*time.Hour), 0)
+	}
+
+	assert.Nil(t, reaper.Reap(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	// ctx and a single event
+	assert.Len(t, eventDispatcher.Calls[0].Arguments, 2)
+	payload := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent).Payload().(*domain.PodSessionExpiredPayload)
+	assert.Equal(t, "session-idle", payload.Pod.SessionId)
+	assert.Equal(t, domain.IdleTimeoutReason, payload.Reason)
+	assert.Equal(t, "session idle for 15m0s, idle timeout is 15m0s", payload.Message)
+}
+
+func TestSessionReaper_Forget(t *testing.T) {
+	ctx := context.TODO()
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil)
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(nil)
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return(nil)
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(nil)
+	reaper, err := NewSessionReaper(logger, mockConfig, &repository.MockIKVRepository{}, &eventDispatcher)
+	assert.Nil(t, err)
+	now := time.Date(2023, 11, 10, 23, 0, 0, 0, time.UTC)
+	reaper.now = func() time.Time { return now }
+	reaper.Observe(&domain.Pod{
+		SessionId: "session-123",
+	}, now.Add(-2*time.Hour), 1*time.Hour)
+	reaper.Forget("session-123")
+
+	assert.Nil(t, reaper.Reap(ctx))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// the decisions of the monitor are written back to the session pod as annotations and kubernetes events.
+// failing writes are logged only, they must not hold back the session tasks
+type PodStatusWriter struct {
+	logger   *zap.Logger
+	enabled  bool
+	client   kubernetes.Interface
+	recorder record.EventRecorder
+	now      func() time.Time
+}
+
+// app.pod_status_writeback_enabled is off by default, it needs rbac to patch pods and create events
+func NewPodStatusWriter(ctx context.Context, logger *zap.Logger, cfg config.IConfig) (*PodStatusWriter, error) {
+	writer := &PodStatusWriter{
+		logger:  logger,
+		enabled: config.GetBool(cfg, "app.pod_status_writeback_enabled", false),
+		now:     time.Now,
+	}
+	if !writer.enabled {
+		return writer, nil
+	}
+	client, err := k8s.NewK8sClientset(cfg)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		broadcaster.Shutdown()
+	}()
+	writer.client = client
+	writer.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "session-monitor"})
+	return writer, nil
+}
+
+func (w *PodStatusWriter) Enqueued(ctx context.Context, pod *domain.Pod) {
+	w.write(ctx, pod, map[string]string{
+		domain.StateAnnotation:      string(session.Ready),
+		domain.EnqueuedAtAnnotation: w.now().UTC().Format(time.RFC3339),
+	}, v1.EventTypeNormal, domain.SessionEnqueuedEventReason, "session is ready and enqueued")
+}
+
+// state is Terminating or Failing, reason is empty unless the session failed or was terminated by the cluster
+func (w *PodStatusWriter) Deletable(ctx context.Context, pod *domain.Pod, state session.LifecycleState, reason string, message string) {
+	annotations := map[string]string{
+		domain.StateAnnotation: string(state),
+	}
+	eventType := v1.EventTypeNormal
+	if reason != "" {
+		annotations[domain.FailureReasonAnnotation] = reason
+		eventType = v1.EventTypeWarning
+	}
+	w.write(ctx, pod, annotations, eventType, domain.SessionDeletableEventReason, eventMessage("session is deletable", reason, message))
+}
+
+func (w *PodStatusWriter) Failed(ctx context.Context, pod *domain.Pod, reason string, message string) {
+	w.write(ctx, pod, map[string]string{
+		domain.StateAnnotation:         string(session.Failing),
+		domain.FailureReasonAnnotation: reason,
+	}, v1.EventTypeWarning, domain.SessionFailedEventReason, eventMessage("session failed", reason, message))
+}
+
+func (w *PodStatusWriter) write(ctx context.Context, pod *domain.Pod, annotations map[string]string,
+	eventType string, eventReason string, eventMessage string) {
+	if !w.enabled || pod.Name == "" || pod.Namespace == "" {
+		return
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	_, err := w.client.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	switch {
+	case err == nil:
+	case apierrors.IsNotFound(err):
+		w.logger.Sugar().Infow("Pod is gone, status not written", "Name", pod.Name, "Namespace", pod.Namespace)
+		return
+	case apierrors.IsForbidden(err):
+		w.logger.Sugar().Error(domain.NewPodStatusForbiddenErr(pod.Namespace, pod.Name, err).Error())
+	default:
+		w.logger.Sugar().Errorf("Patch annotations of pod %s/%s has error: %s", pod.Namespace, pod.Name, err.Error())
+	}
+	// recorded by the broadcaster in the background
+	w.recorder.Event(&v1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Name:       pod.Name,
+		Namespace:  pod.Namespace,
+	}, eventType, eventReason, eventMessage)
+}
+
+func eventMessage(summary string, reason string, message string) string {
+	if reason != "" {
+		summary += ": " + reason
+	}
+	if message != "" {
+		summary += ", " + message
+	}
+	return summary
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func newFakePodStatusWriter(objects ...runtime.Object) (*PodStatusWriter, *fake.Clientset, *record.FakeRecorder) {
+	client := fake.NewSimpleClientset(objects...)
+	recorder := record.NewFakeRecorder(10)
+	return &PodStatusWriter{
+		logger:   logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}),
+		enabled:  true,
+		client:   client,
+		recorder: recorder,
+		now: func() time.Time {
+			return time.Unix(1700000000, 0)
+		},
+	}, client, recorder
+}
+
+func statusPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "podName",
+			Namespace: "namespace",
+		},
+	}
+}
+
+var statusDomainPod = &domain.Pod{
+	Name:      "podName",
+	Namespace: "namespace",
+	SessionId: "sessionId",
+}
+
+func TestPodStatusWriter_Disabled(t *testing.T) {
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(nil)
+	writer, err := NewPodStatusWriter(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig)
+	assert.Nil(t, err)
+	assert.False(t, writer.enabled)
+	// nothing to write to, must not panic
+	writer.Enqueued(context.TODO(), statusDomainPod)
+}
+
+func TestPodStatusWriter_Enqueued(t *testing.T) {
+	ctx := context.TODO()
+	writer, client, recorder := newFakePodStatusWriter(statusPod())
+	writer.Enqueued(ctx, statusDomainPod)
+
+	pod, err := client.CoreV1().Pods("namespace").Get(ctx, "podName", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, string(session.Ready), pod.ObjectMeta.Annotations[domain.StateAnnotation])
+	assert.Equal(t, "2023-11-14T22:13:20Z", pod.ObjectMeta.Annotations[domain.EnqueuedAtAnnotation])
+	assert.Equal(t, "Normal SessionEnqueued session is ready and enqueued", <-recorder.Events)
+}
+
+func TestPodStatusWriter_Deletable(t *testing.T) {
+	ctx := context.TODO()
+	writer, client, recorder := newFakePodStatusWriter(statusPod())
+	writer.Deletable(ctx, statusDomainPod, session.Terminating, domain.MaxLifetimeExceededReason, "session lived 1h0m0s")
+
+	pod, err := client.CoreV1().Pods("namespace").Get(ctx, "podName", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, string(session.Terminating), pod.ObjectMeta.Annotations[domain.StateAnnotation])
+	assert.Equal(t, domain.MaxLifetimeExceededReason, pod.ObjectMeta.Annotations[domain.FailureReasonAnnotation])
+	assert.Equal(t, "Warning SessionDeletable session is deletable: MaxLifetimeExceeded, session lived 1h0m0s", <-recorder.Events)
+}
+
+func TestPodStatusWriter_Failed(t *testing.T) {
+	ctx := context.TODO()
+	writer, client, recorder := newFakePodStatusWriter(statusPod())
+	writer.Failed(ctx, statusDomainPod, domain.SessionUnreachableReason, "")
+
+	pod, err := client.CoreV1().Pods("namespace").Get(ctx, "podName", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, string(session.Failing), pod.ObjectMeta.Annotations[domain.StateAnnotation])
+	assert.Equal(t, domain.SessionUnreachableReason, pod.ObjectMeta.Annotations[domain.FailureReasonAnnotation])
+	assert.Equal(t, "Warning SessionFailed session failed: "+domain.SessionUnreachableReason, <-recorder.Events)
+}
+
+func TestPodStatusWriter_Forbidden(t *testing.T) {
+	writer, client, recorder := newFakePodStatusWriter(statusPod())
+	client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "podName", nil)
+	})
+	writer.Enqueued(context.TODO(), statusDomainPod)
+	// the event is still recorded, creating events may be allowed
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestPodStatusWriter_PodGone(t *testing.T) {
+	writer, _, recorder := newFakePodStatusWriter()
+	writer.Enqueued(context.TODO(), statusDomainPod)
+	assert.Len(t, recorder.Events, 0)
+}
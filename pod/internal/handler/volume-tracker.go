@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	persistentVolumeClaimKind = "PersistentVolumeClaim"
+	volumeAttachmentKind      = "VolumeAttachment"
+)
+
+type volumeClaim struct {
+	phase      v1.PersistentVolumeClaimPhase
+	volumeName string
+	created    time.Time
+	// zero when the claim was already bound once the informer saw it
+	bound time.Time
+}
+
+type volumeAttachment struct {
+	volumeName  string
+	nodeName    string
+	attached    bool
+	attachError string
+	// zero when the volume was already attached once the informer saw it
+	attachedAt time.Time
+}
+
+type volumePod struct {
+	pod      *domain.Pod
+	claims   []string
+	reported domain.VolumeTimes
+}
+
+// handles the events of both the persistentvolumeclaim and the volumeattachment informer.
+// pod updates tell which claims and which node a session uses
+type VolumeTracker struct {
+	ctx                   context.Context
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	enabled               bool
+	now                   func() time.Time
+	mutex                 sync.Mutex
+	// namespace/name of the claim
+	claims map[string]*volumeClaim
+	// name of the volume attachment
+	attachments map[string]*volumeAttachment
+	pods        map[string]*volumePod
+}
+
+func NewVolumeTracker(ctx context.Context, logger *zap.Logger, cfg config.IConfig,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]) *VolumeTracker {
+	return &VolumeTracker{
+		ctx:                   ctx,
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		enabled:               config.GetBool(cfg, "app.volume_tracking_enabled", false),
+		now:                   time.Now,
+		claims:                map[string]*volumeClaim{},
+		attachments:           map[string]*volumeAttachment{},
+		pods:                  map[string]*volumePod{},
+	}
+}
+
+func (t *VolumeTracker) Enabled() bool {
+	return t.enabled
+}
+
+// generic ephemeral volumes are claimed as <pod name>-<volume name>
+func ClaimNames(pod *v1.Pod) []string {
+	claims := []string{}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil {
+			claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+		} else if volume.Ephemeral != nil {
+			claims = append(claims, pod.ObjectMeta.Name+"-"+volume.Name)
+		}
+	}
+	return claims
+}
+
+// the session pod is known with its claims and node, sessions without claims are not tracked
+func (t *VolumeTracker) TrackPod(pod *v1.Pod) {
+	sessionId := pod.ObjectMeta.Labels["sessionId"]
+	if !t.enabled || sessionId == "" {
+		return
+	}
+	claims := ClaimNames(pod)
+	if len(claims) == 0 {
+		t.Forget(sessionId)
+		return
+	}
+	t.mutex.Lock()
+	entry, exist := t.pods[sessionId]
+	if !exist {
+		entry = &volumePod{}
+		t.pods[sessionId] = entry
+	}
+	entry.pod = &domain.Pod{
+		Name:      pod.ObjectMeta.Name,
+		Namespace: pod.ObjectMeta.Namespace,
+		SessionId: sessionId,
+		NodeName:  pod.Spec.NodeName,
+	}
+	entry.claims = claims
+	events := t.report([]*volumePod{entry})
+	t.mutex.Unlock()
+	t.publish(events)
+}
+
+func (t *VolumeTracker) Forget(sessionId string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.pods, sessionId)
+}
+
+// why the volumes of the session pod are not ready, false when they are or nothing is known about them.
+// claims the informer has not seen are left to the scheduler message
+func (t *VolumeTracker) Waiting(sessionId string) (string, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry, exist := t.pods[sessionId]
+	if !exist {
+		return "", false
+	}
+	reasons := []string{}
+	for _, name := range entry.claims {
+		claim, exist := t.claims[entry.pod.Namespace+"/"+name]
+		if !exist {
+			continue
+		}
+		if claim.phase != v1.ClaimBound {
+			reasons = append(reasons, fmt.Sprintf("persistentvolumeclaim %q is %s", name, claim.phase))
+			continue
+		}
+		for _, attachment := range t.attachmentsOf(claim.volumeName, entry.pod.NodeName) {
+			if attachment.attached {
+				continue
+			}
+			reason := fmt.Sprintf("volume %q of persistentvolumeclaim %q is not attached to node %s",
+				claim.volumeName, name, attachment.nodeName)
+			if attachment.attachError != "" {
+				reason += ": " + attachment.attachError
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+	return strings.Join(reasons, "; "), len(reasons) > 0
+}
+
+func (t *VolumeTracker) CustomWatchErrorHandler(r *cache.Reflector, err error) {
+	t.logger.Sugar().Errorw("Watch error", err)
+	t.domainEventDispatcher.Publish(t.ctx, ddd.NewEvent(
+		domain.PodInformerErrorEvent,
+		&domain.PodInformerErrorPayload{
+			Err: err,
+		},
+	))
+}
+
+func (t *VolumeTracker) OnAddObject(obj interface{}) {
+	if err := t.update(obj.(*unstructured.Unstructured)); err != nil {
+		t.logger.Sugar().Error("OnAddObject:", err)
+	}
+}
+
+func (t *VolumeTracker) OnUpdateObject(oldObj, newObj interface{}) {
+	if err := t.update(newObj.(*unstructured.Unstructured)); err != nil {
+		t.logger.Sugar().Error("OnUpdateObject:", err)
+	}
+}
+
+func (t *VolumeTracker) OnDeleteObject(obj interface{}) {
+	u := obj.(*unstructured.Unstructured)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch u.GetKind() {
+	case persistentVolumeClaimKind:
+		delete(t.claims, u.GetNamespace()+"/"+u.GetName())
+	case volumeAttachmentKind:
+		delete(t.attachments, u.GetName())
+	}
+}
+
+func (t *VolumeTracker) update(u *unstructured.Unstructured) error {
+	var events []ddd.IEvent
+	switch u.GetKind() {
+	case persistentVolumeClaimKind:
+		var pvc v1.PersistentVolumeClaim
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &pvc); err != nil {
+			return err
+		}
+		events = t.updateClaim(&pvc)
+	case volumeAttachmentKind:
+		var attachment storagev1.VolumeAttachment
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &attachment); err != nil {
+			return err
+		}
+		events = t.updateAttachment(&attachment)
+	default:
+		return domain.NewUnsupportedVolumeKindErr(u.GetKind())
+	}
+	t.publish(events)
+	return nil
+}
+
+func (t *VolumeTracker) updateClaim(pvc *v1.PersistentVolumeClaim) []ddd.IEvent {
+	namespace, name := pvc.ObjectMeta.Namespace, pvc.ObjectMeta.Name
+	claim := &volumeClaim{
+		phase:      pvc.Status.Phase,
+		volumeName: pvc.Spec.VolumeName,
+		created:    pvc.ObjectMeta.CreationTimestamp.Time,
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if known, exist := t.claims[namespace+"/"+name]; exist {
+		claim.bound = known.bound
+		if known.phase != v1.ClaimBound && claim.phase == v1.ClaimBound {
+			claim.bound = t.now()
+		}
+	}
+	t.claims[namespace+"/"+name] = claim
+	affected := []*volumePod{}
+	for _, entry := range t.pods {
+		if entry.pod.Namespace == namespace && funk.ContainsString(entry.claims, name) {
+			affected = append(affected, entry)
+		}
+	}
+	return t.report(affected)
+}
+
+// inline csi volumes have no persistent volume, they are not claimed
+func (t *VolumeTracker) updateAttachment(va *storagev1.VolumeAttachment) []ddd.IEvent {
+	volumeName := va.Spec.Source.PersistentVolumeName
+	if volumeName == nil {
+		return nil
+	}
+	attachment := &volumeAttachment{
+		volumeName: *volumeName,
+		nodeName:   va.Spec.NodeName,
+		attached:   va.Status.Attached,
+	}
+	if attachError := va.Status.AttachError; attachError != nil {
+		attachment.attachError = attachError.Message
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if known, exist := t.attachments[va.ObjectMeta.Name]; exist {
+		attachment.attachedAt = known.attachedAt
+		if !known.attached && attachment.attached {
+			attachment.attachedAt = t.now()
+		}
+	}
+	t.attachments[va.ObjectMeta.Name] = attachment
+	affected := []*volumePod{}
+	for _, entry := range t.pods {
+		if entry.pod.NodeName != attachment.nodeName {
+			continue
+		}
+		for _, name := range entry.claims {
+			if claim, exist := t.claims[entry.pod.Namespace+"/"+name]; exist && claim.volumeName == attachment.volumeName {
+				affected = append(affected, entry)
+				break
+			}
+		}
+	}
+	return t.report(affected)
+}
+
+// called with the lock held, one event for every pod whose volume times changed
+func (t *VolumeTracker) report(entries []*volumePod) []ddd.IEvent {
+	events := []ddd.IEvent{}
+	for _, entry := range entries {
+		times := t.volumeTimes(entry)
+		if times == entry.reported {
+			continue
+		}
+		entry.reported = times
+		t.logger.Sugar().Infow("Pod volumes are updated", "Name", entry.pod.Name, "Namespace", entry.pod.Namespace,
+			"SessionId", entry.pod.SessionId, "Claimed", times.Claimed, "Bound", times.Bound, "Attached", times.Attached)
+		pod := *entry.pod
+		events = append(events, ddd.NewEvent(
+			domain.PodVolumeUpdateEvent,
+			&domain.PodVolumePayload{
+				Pod:   &pod,
+				Times: times,
+			},
+		))
+	}
+	return events
+}
+
+// called with the lock held. a time is known once it is known for every claim,
+// volumes without attachment on the node of the pod need none, e.g. nfs or azure files
+func (t *VolumeTracker) volumeTimes(entry *volumePod) domain.VolumeTimes {
+	var times domain.VolumeTimes
+	var claimed, bound, attached time.Time
+	boundKnown, attachedKnown, attachments := true, true, 0
+	for _, name := range entry.claims {
+		claim, exist := t.claims[entry.pod.Namespace+"/"+name]
+		if !exist {
+			return times
+		}
+		claimed = latest(claimed, claim.created)
+		if claim.phase != v1.ClaimBound || claim.bound.IsZero() {
+			boundKnown = false
+		}
+		bound = latest(bound, claim.bound)
+		if claim.phase != v1.ClaimBound {
+			attachedKnown = false
+			continue
+		}
+		for _, attachment := range t.attachmentsOf(claim.volumeName, entry.pod.NodeName) {
+			attachments++
+			if !attachment.attached || attachment.attachedAt.IsZero() {
+				attachedKnown = false
+			}
+			attached = latest(attached, attachment.attachedAt)
+		}
+	}
+	times.Claimed = unix(claimed)
+	if boundKnown {
+		times.Bound = unix(bound)
+	}
+	if attachedKnown && attachments > 0 {
+		times.Attached = unix(attached)
+	}
+	return times
+}
+
+// called with the lock held, none before the pod is scheduled
+func (t *VolumeTracker) attachmentsOf(volumeName string, nodeName string) []*volumeAttachment {
+	attachments := []*volumeAttachment{}
+	if volumeName == "" || nodeName == "" {
+		return attachments
+	}
+	for _, attachment := range t.attachments {
+		if attachment.volumeName == volumeName && attachment.nodeName == nodeName {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments
+}
+
+func (t *VolumeTracker) publish(events []ddd.IEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if err := t.domainEventDispatcher.Publish(t.ctx, events...); err != nil {
+		t.logger.Sugar().Errorf("Publish volume events has error: %s", err.Error())
+	}
+}
+
+func latest(a time.Time, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/pod/internal/domain"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newVolumePod(nodeName string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test_name",
+			Namespace: "test_namespace",
+			Labels: map[string]string{
+				"sessionId": "session-123",
+			},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Volumes: []v1.Volume{
+				{Name: "data", VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+				}},
+				{Name: "config", VolumeSource: v1.VolumeSource{
+					ConfigMap: &v1.ConfigMapVolumeSource{},
+				}},
+			},
+		},
+	}
+}
+
+func newClaim(phase string, volumeName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "PersistentVolumeClaim",
+			"apiVersion": "v1",
+			"metadata": map[string]interface{}{
+				"name":              "data",
+				"namespace":         "test_namespace",
+				"creationTimestamp": "2024-01-01T10:00:00Z",
+			},
+			"spec": map[string]interface{}{
+				"volumeName": volumeName,
+			},
+			"status": map[string]interface{}{
+				"phase": phase,
+			},
+		},
+	}
+}
+
+func newVolumeAttachment(attached bool, attachError string) *unstructured.Unstructured {
+	status := map[string]interface{}{
+		"attached": attached,
+	}
+	if attachError != "" {
+		status["attachError"] = map[string]interface{}{
+			"message": attachError,
+		}
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       "VolumeAttachment",
+			"apiVersion": "storage.k8s.io/v1",
+			"metadata": map[string]interface{}{
+				"name": "csi-123",
+			},
+			"spec": map[string]interface{}{
+				"attacher": "disk.csi.azure.com",
+				"nodeName": "node-1",
+				"source": map[string]interface{}{
+					"persistentVolumeName": "pv-1",
+				},
+			},
+			"status": status,
+		},
+	}
+}
+
+func TestClaimNames(t *testing.T) {
+	pod := newVolumePod("")
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: "scratch", VolumeSource: v1.VolumeSource{
+		Ephemeral: &v1.EphemeralVolumeSource{},
+	}})
+	assert.Equal(t, []string{"data", "test_name-scratch"}, ClaimNames(pod))
+}
+
+func TestVolumeTracker_Disabled(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(nil)
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+
+	tracker := NewVolumeTracker(context.TODO(), logger, mockConfig, &eventDispatcher)
+	tracker.TrackPod(newVolumePod(""))
+	tracker.OnAddObject(newClaim("Pending", ""))
+	_, waiting := tracker.Waiting("session-123")
+	assert.False(t, waiting)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+}
+
+func TestVolumeTracker_BindAndAttach(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", context.TODO(), mock.Anything).Return(nil)
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(true)
+	tracker := NewVolumeTracker(context.TODO(), logger, mockConfig, &eventDispatcher)
+	now := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	timesOf := func(call int) domain.VolumeTimes {
+		event := eventDispatcher.Calls[call].Arguments.Get(1).(ddd.IEvent)
+		assert.Equal(t, domain.PodVolumeUpdateEvent, event.EventName())
+		payload := event.Payload().(*domain.PodVolumePayload)
+		assert.Equal(t, "session-123", payload.Pod.SessionId)
+		return payload.Times
+	}
+
+	// the claim is unknown yet
+	tracker.TrackPod(newVolumePod(""))
+	_, waiting := tracker.Waiting("session-123")
+	assert.False(t, waiting)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 0)
+
+	tracker.OnAddObject(newClaim("Pending", ""))
+	message, waiting := tracker.Waiting("session-123")
+	assert.True(t, waiting)
+	assert.Equal(t, `persistentvolumeclaim "data" is Pending`, message)
+	assert.Equal(t, domain.VolumeTimes{Claimed: 1704103200}, timesOf(0))
+
+	tracker.OnUpdateObject(nil, newClaim("Bound", "pv-1"))
+	assert.Equal(t, domain.VolumeTimes{Claimed: 1704103200, Bound: 1704103230}, timesOf(1))
+
+	// scheduled, the volume is attached to the node
+	now = now.Add(20 * time.Second)
+	tracker.TrackPod(newVolumePod("node-1"))
+	tracker.OnAddObject(newVolumeAttachment(false, "disk is busy"))
+	message, waiting = tracker.Waiting("session-123")
+	assert.True(t, waiting)
+	assert.Equal(t, `volume "pv-1" of persistentvolumeclaim "data" is not attached to node node-1: disk is busy`, message)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 2)
+
+	tracker.OnUpdateObject(nil, newVolumeAttachment(true, ""))
+	_, waiting = tracker.Waiting("session-123")
+	assert.False(t, waiting)
+	assert.Equal(t, domain.VolumeTimes{Claimed: 1704103200, Bound: 1704103230, Attached: 1704103250}, timesOf(2))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 3)
+
+	tracker.Forget("session-123")
+	tracker.OnDeleteObject(newClaim("Bound", "pv-1"))
+	_, waiting = tracker.Waiting("session-123")
+	assert.False(t, waiting)
+}
+
+// claims bound before the monitor started have no bind time
+func TestVolumeTracker_BoundBeforeObserved(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", context.TODO(), mock.Anything).Return(nil)
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(true)
+	tracker := NewVolumeTracker(context.TODO(), logger, mockConfig, &eventDispatcher)
+	tracker.OnAddObject(newClaim("Bound", "pv-1"))
+	tracker.TrackPod(newVolumePod("node-1"))
+	_, waiting := tracker.Waiting("session-123")
+	assert.False(t, waiting)
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.VolumeTimes{Claimed: 1704103200}, event.Payload().(*domain.PodVolumePayload).Times)
+}
+
+func TestVolumeTracker_UnsupportedKind(t *testing.T) {
+	logger := logger.NewZapLogger(logger.LogConfig{
+		LogLevel: logger.DEBUG,
+	})
+	eventDispatcher := ddd.MockIEventDispatcher[ddd.IEvent]{}
+
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(true)
+	tracker := NewVolumeTracker(context.TODO(), logger, mockConfig, &eventDispatcher)
+	err := tracker.update(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": "PersistentVolume",
+		},
+	})
+	assert.True(t, domain.NewUnsupportedVolumeKindErr("PersistentVolume").Is(err))
+}
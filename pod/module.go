@@ -6,10 +6,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
 	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/internal/worker"
 	"github.com/xcheng85/session-monitor-k8s/pod/internal/handler"
 	"go.uber.org/dig"
 	"go.uber.org/zap"
@@ -45,6 +48,91 @@ func (m PodMonitoringModule) Startup(ctx context.Context, mono module.IModuleCon
 	if err != nil {
 		return nil, err
 	}
+	err = container.Provide(handler.NewPendingTracker)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func(tracker *handler.PendingTracker) worker.Worker {
+		return tracker.Run
+	}, dig.Group("workers"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewVolumeTracker)
+	if err != nil {
+		return nil, err
+	}
+	// the tracker handles the events of both volume informers, volume attachments are cluster scoped
+	for _, filter := range []k8s.K8sInformerFilter{
+		{Resource: "persistentvolumeclaims"},
+		{Resource: "volumeattachments", Group: "storage.k8s.io", Version: "v1"},
+	} {
+		filter := filter
+		err = container.Provide(func(ctx context.Context, logger *zap.Logger, cfg config.IConfig,
+			tracker *handler.VolumeTracker) (worker.Worker, error) {
+			if !tracker.Enabled() {
+				return func(ctx context.Context) error {
+					return nil
+				}, nil
+			}
+			if filter.Resource == "persistentvolumeclaims" {
+				filter.Namespace = cfg.Get("app.pod_namespace").(string)
+			}
+			informer, err := k8s.NewK8sDynamicInformer(ctx, logger, cfg, tracker, filter)
+			if err != nil {
+				return nil, err
+			}
+			return func(ctx context.Context) error {
+				informer.Run()
+				return nil
+			}, nil
+		}, dig.Group("workers"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = container.Provide(handler.NewSessionReaper)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func(reaper *handler.SessionReaper) worker.Worker {
+		return reaper.Run
+	}, dig.Group("workers"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewReconciler)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func(reconciler *handler.Reconciler) worker.Worker {
+		return reconciler.Run
+	}, dig.Group("workers"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewContainerRules)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewFailureClassifier)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewPodStatusWriter)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewReachabilityProbe)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func(probe *handler.ReachabilityProbe) worker.Worker {
+		return probe.Run
+	}, dig.Group("workers"))
+	if err != nil {
+		return nil, err
+	}
 	err = container.Provide(handler.NewPodEventHandler)
 	if err != nil {
 		return nil, err
@@ -61,7 +149,27 @@ func (m PodMonitoringModule) Startup(ctx context.Context, mono module.IModuleCon
 	if err != nil {
 		return nil, err
 	}
-	err = container.Provide(session.NewSessionService)
+	err = container.Provide(func() session.ISessionService {
+		return mono.SessionService()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() scaleup.ITracker {
+		return mono.ScaleUpTracker()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() gpu.IInventory {
+		return mono.GpuInventory()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() session.ISessionMetrics {
+		return mono.SessionMetrics()
+	})
 	if err != nil {
 		return nil, err
 	}
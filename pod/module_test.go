@@ -6,9 +6,12 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/xcheng85/session-monitor-k8s/internal/config"
 	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/gpu"
 	"github.com/xcheng85/session-monitor-k8s/internal/logger"
 	"github.com/xcheng85/session-monitor-k8s/internal/module"
 	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/scaleup"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
 	"testing"
 	"time"
 )
@@ -27,10 +30,34 @@ func Test_ModuleStartup(t *testing.T) {
 	mockModuleCtx.On("Config").Return(mockConfig).Once()
 	mockModuleCtx.On("EventDispatcher").Return(mockEventDispatcher).Once()
 	mockModuleCtx.On("KvRepository").Return(mockKVRepository).Once()
+	mockModuleCtx.On("SessionService").Return(&session.MockISessionService{}).Once()
+	mockModuleCtx.On("SessionMetrics").Return(&session.MockISessionMetrics{}).Once()
+	mockModuleCtx.On("GpuInventory").Return(&gpu.MockIInventory{}).Once()
+	mockModuleCtx.On("ScaleUpTracker").Return(&scaleup.MockITracker{}).Once()
 	mockEventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockConfig.On("Get", "app.kube_config").Return("", nil).Once()
 	mockConfig.On("Get", "app.pod_namespace").Return("namespace", nil).Once()
+	mockConfig.On("Get", "app.pod_pending_timeout_seconds").Return(600).Once()
+	mockConfig.On("Get", "app.pod_pending_check_interval_seconds").Return(30).Once()
+	mockConfig.On("Get", "app.volume_tracking_enabled").Return(false).Once()
+	mockConfig.On("Get", "app.container_restart_threshold").Return(3).Once()
+	mockConfig.On("Get", "app.container_rules").Return(nil).Once()
+	mockConfig.On("Get", "app.reachability_probe_mode").Return("tcp").Once()
+	mockConfig.On("Get", "app.reachability_probe_timeout_seconds").Return(2).Once()
+	mockConfig.On("Get", "app.reachability_probe_enabled").Return(false).Once()
+	mockConfig.On("Get", "app.reachability_probe_workers").Return(4).Once()
+	mockConfig.On("Get", "app.reachability_probe_queue_size").Return(256).Once()
+	mockConfig.On("Get", "app.reachability_probe_http_path").Return("/").Once()
+	mockConfig.On("Get", "app.reachability_probe_retries").Return(3).Once()
+	mockConfig.On("Get", "app.reachability_probe_retry_interval_seconds").Return(1).Once()
+	mockConfig.On("Get", "app.pod_status_writeback_enabled").Return(false).Once()
+	mockConfig.On("Get", "app.session_max_lifetime_rules").Return(nil).Once()
+	mockConfig.On("Get", "app.session_max_lifetime_seconds").Return(0).Once()
+	mockConfig.On("Get", "app.session_idle_timeout_seconds").Return(0).Once()
+	mockConfig.On("Get", "app.session_heartbeat_key_prefix").Return("SessionMonitor.Heartbeat").Once()
+	mockConfig.On("Get", "app.session_reaper_interval_seconds").Return(60).Once()
 
 	module := NewPodMonitoringModule()
 	// define context and therefore test timeout
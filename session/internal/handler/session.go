@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	http_utils "github.com/xcheng85/session-monitor-k8s/internal/http"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+//go:generate mockery --name ISessionHandler
+type ISessionHandler interface {
+	GetSession(w http.ResponseWriter, r *http.Request)
+	ListSessions(w http.ResponseWriter, r *http.Request)
+}
+
+type sessionHandler struct {
+	logger         *zap.Logger
+	sessionService session.ISessionService
+}
+
+func NewSessionHandler(logger *zap.Logger, sessionService session.ISessionService) ISessionHandler {
+	return &sessionHandler{
+		logger:         logger,
+		sessionService: sessionService,
+	}
+}
+
+func (handler sessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	sessionId := chi.URLParam(r, "sessionId")
+	view, err := handler.sessionService.GetSession(sessionId)
+	if _, notFound := err.(*session.InvalidStoreKeyErr); notFound {
+		render.Render(w, r, http_utils.ErrNotFound)
+		return
+	}
+	if err != nil {
+		handler.logger.Sugar().Errorf("GetSession %s has error: %s", sessionId, err.Error())
+		render.Render(w, r, http_utils.ErrServerInternal(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, view)
+}
+
+// ?limit=<n> pages through the sessions, ?after=<sessionId> continues after the last session of the previous page
+func (handler sessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultListLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxListLimit {
+			render.Render(w, r, http_utils.ErrBadRequest(fmt.Errorf("limit must be between 1 and %d", maxListLimit)))
+			return
+		}
+		limit = parsed
+	}
+	views, err := handler.sessionService.ListSessions(r.URL.Query().Get("after"), limit)
+	if err != nil {
+		handler.logger.Sugar().Errorf("ListSessions has error: %s", err.Error())
+		render.Render(w, r, http_utils.ErrServerInternal(err))
+		return
+	}
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, views)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/zap"
+)
+
+func newGetSessionRequest(t *testing.T, sessionId string) *http.Request {
+	request, err := http.NewRequest("GET", "/sessions/"+sessionId, nil)
+	require.NoError(t, err)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("sessionId", sessionId)
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func TestSessionHandlerGetSession(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("GetSession", "sessionId").Return(&session.SessionView{
+		SessionId: "sessionId",
+		State:     session.Ready,
+	}, nil).Once()
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	response := httptest.NewRecorder()
+	sessionHandler.GetSession(response, newGetSessionRequest(t, "sessionId"))
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"sessionId\":\"sessionId\"")
+	assert.Contains(t, response.Body.String(), "\"state\":\"Ready\"")
+}
+
+func TestSessionHandlerGetSession_NotFound(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("GetSession", "unknown").Return(nil, session.NewInvalidStoreKeyErr("SessionLifecycle.unknown")).Once()
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	response := httptest.NewRecorder()
+	sessionHandler.GetSession(response, newGetSessionRequest(t, "unknown"))
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestSessionHandlerListSessions(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessions", "", defaultListLimit).Return([]*session.SessionView{
+		{SessionId: "a", State: session.Ready},
+		{SessionId: "b", State: session.Scheduled},
+	}, nil).Once()
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	request, err := http.NewRequest("GET", "/sessions", nil)
+	require.NoError(t, err)
+	response := httptest.NewRecorder()
+	sessionHandler.ListSessions(response, request)
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"sessionId\":\"a\"")
+	assert.Contains(t, response.Body.String(), "\"sessionId\":\"b\"")
+}
+
+func TestSessionHandlerListSessions_Error(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessions", "", defaultListLimit).Return(nil, errors.New("boom")).Once()
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	request, err := http.NewRequest("GET", "/sessions", nil)
+	require.NoError(t, err)
+	response := httptest.NewRecorder()
+	sessionHandler.ListSessions(response, request)
+	assert.Equal(t, 500, response.Code)
+}
+
+func TestSessionHandlerListSessions_Page(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	mockSessionService.On("ListSessions", "a", 1).Return([]*session.SessionView{
+		{SessionId: "b", State: session.Scheduled},
+	}, nil).Once()
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	request, err := http.NewRequest("GET", "/sessions?after=a&limit=1", nil)
+	require.NoError(t, err)
+	response := httptest.NewRecorder()
+	sessionHandler.ListSessions(response, request)
+	require.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "\"sessionId\":\"b\"")
+}
+
+func TestSessionHandlerListSessions_InvalidLimit(t *testing.T) {
+	mockSessionService := session.NewMockISessionService(t)
+	sessionHandler := NewSessionHandler(zap.NewNop(), mockSessionService)
+	for _, limit := range []string{"0", "-1", "1001", "all"} {
+		request, err := http.NewRequest("GET", "/sessions?limit="+limit, nil)
+		require.NoError(t, err)
+		response := httptest.NewRecorder()
+		sessionHandler.ListSessions(response, request)
+		assert.Equal(t, 400, response.Code, limit)
+	}
+}
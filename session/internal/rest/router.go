@@ -0,0 +1,31 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xcheng85/session-monitor-k8s/session/internal/handler"
+)
+
+type SessionRouter struct {
+	handler handler.ISessionHandler
+	mux     *chi.Mux
+	ctx     context.Context
+}
+
+func NewSessionRouter(handler handler.ISessionHandler, ctx context.Context, mux *chi.Mux) *SessionRouter {
+	return &SessionRouter{
+		handler: handler,
+		mux:     mux,
+		ctx:     ctx,
+	}
+}
+
+func (router *SessionRouter) Register() error {
+	r := chi.NewRouter()
+	r.Get("/", router.handler.ListSessions)
+	r.Get("/{sessionId}", router.handler.GetSession)
+	// mounting path must be unique
+	router.mux.Mount("/sessions", r)
+	return nil
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/test"
+	"github.com/xcheng85/session-monitor-k8s/session/internal/handler"
+)
+
+func TestNewSessionRouter_RegisterListSessions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	mux := chi.NewRouter()
+	mockSessionHandler := handler.NewMockISessionHandler(t)
+	mockSessionHandler.On("ListSessions", mock.Anything, mock.Anything).Return().Once()
+	sessionRouter := NewSessionRouter(mockSessionHandler, ctx, mux)
+	sessionRouter.Register()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	if _, body := test.TestRequest(t, ts, "GET", "/sessions", nil); body != "" {
+		t.Fatalf(body)
+	}
+}
+
+func TestNewSessionRouter_RegisterGetSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	mux := chi.NewRouter()
+	mockSessionHandler := handler.NewMockISessionHandler(t)
+	mockSessionHandler.On("GetSession", mock.Anything, mock.Anything).Return().Once()
+	sessionRouter := NewSessionRouter(mockSessionHandler, ctx, mux)
+	sessionRouter.Register()
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	if _, body := test.TestRequest(t, ts, "GET", "/sessions/sessionId", nil); body != "" {
+		t.Fatalf(body)
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xcheng85/session-monitor-k8s/internal/module"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"github.com/xcheng85/session-monitor-k8s/session/internal/handler"
+	"github.com/xcheng85/session-monitor-k8s/session/internal/rest"
+	"go.uber.org/dig"
+	"go.uber.org/zap"
+)
+
+// read-only rest api over the session service
+type SessionModule struct{}
+
+func (m SessionModule) Startup(ctx context.Context, mono module.IModuleContext) (*dig.Container, error) {
+	container := dig.New()
+	err := container.Provide(handler.NewSessionHandler)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(rest.NewSessionRouter)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() *chi.Mux {
+		return mono.Mux()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() *zap.Logger {
+		return mono.Logger()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() session.ISessionService {
+		return mono.SessionService()
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() context.Context {
+		return ctx
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = container.Invoke(func(r *rest.SessionRouter) error {
+		return r.Register()
+	})
+	return container, err
+}
+
+func NewSessionModule() module.Module {
+	return &SessionModule{}
+}
@@ -0,0 +1,27 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/internal/module"
+	"github.com/xcheng85/session-monitor-k8s/internal/session"
+	"go.uber.org/zap"
+)
+
+func Test_ModuleStartup(t *testing.T) {
+	mux := chi.NewRouter()
+	mockModuleCtx := module.NewMockIModuleContext(t)
+	mockModuleCtx.On("Mux").Return(mux).Once()
+	mockModuleCtx.On("Logger").Return(zap.NewNop()).Once()
+	mockModuleCtx.On("SessionService").Return(&session.MockISessionService{}).Once()
+	module := NewSessionModule()
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, error := module.Startup(ctx, mockModuleCtx)
+	assert.Nil(t, error, "session module can start up")
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "fmt"
+
+type UnsupportedWorkloadKindErr struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func (r *UnsupportedWorkloadKindErr) Error() string {
+	return fmt.Sprintf("workload %s/%s has unsupported kind %s", r.namespace, r.name, r.kind)
+}
+
+func (s *UnsupportedWorkloadKindErr) Is(target error) bool {
+	targetErr, ok := target.(*UnsupportedWorkloadKindErr)
+	if !ok {
+		return false
+	}
+	return *s == *targetErr
+}
+
+func NewUnsupportedWorkloadKindErr(kind string, namespace string, name string) *UnsupportedWorkloadKindErr {
+	return &UnsupportedWorkloadKindErr{kind, namespace, name}
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedWorkloadKindErr(t *testing.T) {
+	err := NewUnsupportedWorkloadKindErr("DaemonSet", "viz", "gateway")
+	assert.Equal(t, "workload viz/gateway has unsupported kind DaemonSet", err.Error())
+	assert.ErrorIs(t, err, NewUnsupportedWorkloadKindErr("DaemonSet", "viz", "gateway"))
+	assert.NotErrorIs(t, err, NewUnsupportedWorkloadKindErr("ReplicaSet", "viz", "gateway"))
+}
@@ -0,0 +1,22 @@
+package domain
+
+const (
+	WorkloadInformerErrorEvent        = "WorkloadInformerErrorEvent"
+	WorkloadUpdateEvent               = "WorkloadUpdateEvent"
+	WorkloadDeleteEvent               = "WorkloadDeleteEvent"
+	WorkloadRolloutStatusChangedEvent = "WorkloadRolloutStatusChangedEvent"
+)
+
+type WorkloadInformerErrorPayload struct {
+	Err error
+}
+
+type WorkloadEventPayload struct {
+	Workload *Workload
+}
+
+// the previous status is empty when the workload was added
+type WorkloadRolloutStatusChangedPayload struct {
+	Workload              *Workload
+	PreviousRolloutStatus RolloutStatus
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "sort"
+
+// capacity of the workloads sharing a warm pool label, the broker places sessions on available replicas only
+type WarmPool struct {
+	Name              string   `json:"name"`
+	Workloads         []string `json:"workloads"`
+	DesiredReplicas   int32    `json:"desiredReplicas"`
+	ReadyReplicas     int32    `json:"readyReplicas"`
+	AvailableReplicas int32    `json:"availableReplicas"`
+	// true while one of the workloads is rolling out
+	RollingOut bool `json:"rollingOut"`
+}
+
+func NewWarmPool(name string, workloads []*Workload) WarmPool {
+	pool := WarmPool{
+		Name:      name,
+		Workloads: []string{},
+	}
+	for _, workload := range workloads {
+		if workload.WarmPool != name {
+			continue
+		}
+		pool.Workloads = append(pool.Workloads, workload.Key())
+		pool.DesiredReplicas += workload.DesiredReplicas
+		pool.ReadyReplicas += workload.ReadyReplicas
+		pool.AvailableReplicas += workload.AvailableReplicas
+		pool.RollingOut = pool.RollingOut || workload.RolloutStatus != RolloutComplete
+	}
+	sort.Strings(pool.Workloads)
+	return pool
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWarmPool(t *testing.T) {
+	workloads := []*Workload{
+		{Kind: DeploymentKind, Namespace: "viz", Name: "warm-viz3d", WarmPool: "viz3d",
+			DesiredReplicas: 4, ReadyReplicas: 3, AvailableReplicas: 3, RolloutStatus: RolloutComplete},
+		{Kind: StatefulSetKind, Namespace: "viz", Name: "warm-viz3d-large", WarmPool: "viz3d",
+			DesiredReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 1, RolloutStatus: RolloutProgressing},
+		{Kind: DeploymentKind, Namespace: "viz", Name: "streaming-gateway",
+			DesiredReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2, RolloutStatus: RolloutComplete},
+	}
+	pool := NewWarmPool("viz3d", workloads)
+	assert.Equal(t, []string{"Deployment/viz/warm-viz3d", "StatefulSet/viz/warm-viz3d-large"}, pool.Workloads)
+	assert.Equal(t, int32(6), pool.DesiredReplicas)
+	assert.Equal(t, int32(5), pool.ReadyReplicas)
+	assert.Equal(t, int32(4), pool.AvailableReplicas)
+	assert.True(t, pool.RollingOut)
+	assert.Equal(t, int32(1), workloads[1].UnavailableReplicas())
+
+	pool = NewWarmPool("viz2d", workloads)
+	assert.Empty(t, pool.Workloads)
+	assert.False(t, pool.RollingOut)
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "fmt"
+
+const (
+	DeploymentKind  = "Deployment"
+	StatefulSetKind = "StatefulSet"
+)
+
+// rollout status as reported by kubectl rollout status
+type RolloutStatus string
+
+const (
+	RolloutComplete    RolloutStatus = "Complete"
+	RolloutProgressing RolloutStatus = "Progressing"
+	RolloutPaused      RolloutStatus = "Paused"
+	RolloutFailed      RolloutStatus = "Failed"
+)
+
+// a deployment or statefulset selected by app.workload_label_selector.
+// WarmPool is the value of the app.warm_pool_label label, empty for other workloads
+type Workload struct {
+	Kind              string        `json:"kind"`
+	Namespace         string        `json:"namespace"`
+	Name              string        `json:"name"`
+	WarmPool          string        `json:"warmPool,omitempty"`
+	DesiredReplicas   int32         `json:"desiredReplicas"`
+	ReadyReplicas     int32         `json:"readyReplicas"`
+	AvailableReplicas int32         `json:"availableReplicas"`
+	UpdatedReplicas   int32         `json:"updatedReplicas"`
+	RolloutStatus     RolloutStatus `json:"rolloutStatus"`
+	RolloutMessage    string        `json:"rolloutMessage,omitempty"`
+}
+
+// kind/namespace/name, unique across deployments and statefulsets
+func (w *Workload) Key() string {
+	return WorkloadKey(w.Kind, w.Namespace, w.Name)
+}
+
+func WorkloadKey(kind string, namespace string, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// replicas desired but not available yet
+func (w *Workload) UnavailableReplicas() int32 {
+	return max(w.DesiredReplicas-w.AvailableReplicas, 0)
+}
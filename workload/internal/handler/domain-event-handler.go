@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	"go.uber.org/zap"
+)
+
+type domainEventHandlers[T ddd.IEvent] struct {
+	logger    *zap.Logger
+	workloads IWorkloadStore
+}
+
+var _ ddd.IEventHandler[ddd.IEvent] = (*domainEventHandlers[ddd.IEvent])(nil)
+
+func NewDomainEventHandlers(
+	logger *zap.Logger,
+	subscriber ddd.IEventDispatcher[ddd.IEvent],
+	workloads IWorkloadStore,
+) ddd.IEventHandler[ddd.IEvent] {
+	handler := &domainEventHandlers[ddd.IEvent]{
+		logger,
+		workloads,
+	}
+	subscriber.Subscribe(handler,
+		domain.WorkloadUpdateEvent,
+		domain.WorkloadDeleteEvent,
+		domain.WorkloadRolloutStatusChangedEvent,
+		domain.WorkloadInformerErrorEvent,
+	)
+	return handler
+}
+
+func (d domainEventHandlers[T]) HandleEvent(ctx context.Context, event T) (err error) {
+	switch event.EventName() {
+	case domain.WorkloadUpdateEvent:
+		return d.onWorkloadUpdated(ctx, event)
+	case domain.WorkloadDeleteEvent:
+		return d.onWorkloadDeleted(ctx, event)
+	case domain.WorkloadRolloutStatusChangedEvent:
+		return d.onRolloutStatusChanged(ctx, event)
+	case domain.WorkloadInformerErrorEvent:
+		d.logger.Sugar().Errorf("Workload informer has error: %s", event.Payload().(*domain.WorkloadInformerErrorPayload).Err)
+	}
+	return nil
+}
+
+func (d domainEventHandlers[T]) onWorkloadUpdated(ctx context.Context, event ddd.IEvent) error {
+	workload := event.Payload().(*domain.WorkloadEventPayload).Workload
+	d.logger.Sugar().Infow("Workload is updated", "Workload", workload.Key(), "WarmPool", workload.WarmPool,
+		"DesiredReplicas", workload.DesiredReplicas, "AvailableReplicas", workload.AvailableReplicas)
+	return d.workloads.Set(ctx, workload)
+}
+
+func (d domainEventHandlers[T]) onWorkloadDeleted(ctx context.Context, event ddd.IEvent) error {
+	workload := event.Payload().(*domain.WorkloadEventPayload).Workload
+	d.logger.Sugar().Infow("Workload is deleted", "Workload", workload.Key(), "WarmPool", workload.WarmPool)
+	return d.workloads.Remove(ctx, workload.Key())
+}
+
+// a failed rollout of a warm pool leaves it without the capacity the broker expects
+func (d domainEventHandlers[T]) onRolloutStatusChanged(ctx context.Context, event ddd.IEvent) error {
+	payload := event.Payload().(*domain.WorkloadRolloutStatusChangedPayload)
+	workload := payload.Workload
+	if workload.RolloutStatus == domain.RolloutFailed {
+		d.logger.Sugar().Warnw("Workload rollout failed", "Workload", workload.Key(), "WarmPool", workload.WarmPool,
+			"Message", workload.RolloutMessage, "UnavailableReplicas", workload.UnavailableReplicas())
+		return nil
+	}
+	d.logger.Sugar().Infow("Workload rollout status changed", "Workload", workload.Key(),
+		"PreviousRolloutStatus", payload.PreviousRolloutStatus, "RolloutStatus", workload.RolloutStatus, "Message", workload.RolloutMessage)
+	return nil
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+)
+
+func TestHandleEvent(t *testing.T) {
+	ctx := context.TODO()
+	workload := &domain.Workload{Kind: domain.DeploymentKind, Namespace: "viz", Name: "warm-viz3d", WarmPool: "viz3d",
+		DesiredReplicas: 3, AvailableReplicas: 1, RolloutStatus: domain.RolloutFailed}
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Subscribe", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	workloads := NewMockIWorkloadStore(t)
+	workloads.On("Set", ctx, workload).Return(nil).Once()
+	workloads.On("Remove", ctx, "Deployment/viz/warm-viz3d").Return(nil).Once()
+
+	h := NewDomainEventHandlers(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), eventDispatcher, workloads)
+	assert.Nil(t, h.HandleEvent(ctx, ddd.NewEvent(domain.WorkloadUpdateEvent, &domain.WorkloadEventPayload{
+		Workload: workload,
+	})))
+	assert.Nil(t, h.HandleEvent(ctx, ddd.NewEvent(domain.WorkloadRolloutStatusChangedEvent, &domain.WorkloadRolloutStatusChangedPayload{
+		Workload:              workload,
+		PreviousRolloutStatus: domain.RolloutProgressing,
+	})))
+	assert.Nil(t, h.HandleEvent(ctx, ddd.NewEvent(domain.WorkloadDeleteEvent, &domain.WorkloadEventPayload{
+		Workload: workload,
+	})))
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// the deployment controller sets this reason once the progress deadline passed
+const progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+func deploymentWorkload(deployment *appsv1.Deployment, warmPoolLabel string) *domain.Workload {
+	workload := &domain.Workload{
+		Kind:              domain.DeploymentKind,
+		Namespace:         deployment.Namespace,
+		Name:              deployment.Name,
+		WarmPool:          deployment.Labels[warmPoolLabel],
+		DesiredReplicas:   desiredReplicas(deployment.Spec.Replicas),
+		ReadyReplicas:     deployment.Status.ReadyReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+		UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+	}
+	workload.RolloutStatus, workload.RolloutMessage = deploymentRolloutStatus(deployment, workload.DesiredReplicas)
+	return workload
+}
+
+func statefulSetWorkload(statefulSet *appsv1.StatefulSet, warmPoolLabel string) *domain.Workload {
+	workload := &domain.Workload{
+		Kind:              domain.StatefulSetKind,
+		Namespace:         statefulSet.Namespace,
+		Name:              statefulSet.Name,
+		WarmPool:          statefulSet.Labels[warmPoolLabel],
+		DesiredReplicas:   desiredReplicas(statefulSet.Spec.Replicas),
+		ReadyReplicas:     statefulSet.Status.ReadyReplicas,
+		AvailableReplicas: statefulSet.Status.AvailableReplicas,
+		UpdatedReplicas:   statefulSet.Status.UpdatedReplicas,
+	}
+	workload.RolloutStatus, workload.RolloutMessage = statefulSetRolloutStatus(statefulSet, workload.DesiredReplicas)
+	return workload
+}
+
+// replicas defaults to 1
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// same checks as kubectl rollout status
+func deploymentRolloutStatus(deployment *appsv1.Deployment, desired int32) (domain.RolloutStatus, string) {
+	status := deployment.Status
+	if deployment.Spec.Paused {
+		return domain.RolloutPaused, "rollout is paused"
+	}
+	if deployment.Generation > status.ObservedGeneration {
+		return domain.RolloutProgressing, "waiting for the rollout to be observed"
+	}
+	for _, condition := range status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceededReason {
+			return domain.RolloutFailed, condition.Message
+		}
+	}
+	if status.UpdatedReplicas < desired {
+		return domain.RolloutProgressing, fmt.Sprintf("%d out of %d new replicas have been updated", status.UpdatedReplicas, desired)
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return domain.RolloutProgressing, fmt.Sprintf("%d old replicas are pending termination", status.Replicas-status.UpdatedReplicas)
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return domain.RolloutProgressing, fmt.Sprintf("%d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas)
+	}
+	return domain.RolloutComplete, ""
+}
+
+// pods of an OnDelete statefulset are only replaced when deleted, the rollout is complete once they are ready
+func statefulSetRolloutStatus(statefulSet *appsv1.StatefulSet, desired int32) (domain.RolloutStatus, string) {
+	status := statefulSet.Status
+	if statefulSet.Generation > status.ObservedGeneration {
+		return domain.RolloutProgressing, "waiting for the rollout to be observed"
+	}
+	if status.ReadyReplicas < desired {
+		return domain.RolloutProgressing, fmt.Sprintf("%d of %d replicas are ready", status.ReadyReplicas, desired)
+	}
+	strategy := statefulSet.Spec.UpdateStrategy
+	if strategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return domain.RolloutComplete, ""
+	}
+	if strategy.RollingUpdate != nil && strategy.RollingUpdate.Partition != nil {
+		partitioned := max(desired-*strategy.RollingUpdate.Partition, 0)
+		if status.UpdatedReplicas < partitioned {
+			return domain.RolloutProgressing, fmt.Sprintf("%d of %d partitioned replicas have been updated", status.UpdatedReplicas, partitioned)
+		}
+		return domain.RolloutComplete, ""
+	}
+	if status.UpdateRevision != status.CurrentRevision {
+		return domain.RolloutProgressing, fmt.Sprintf("%d of %d replicas have been updated", status.UpdatedReplicas, desired)
+	}
+	return domain.RolloutComplete, ""
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func replicas(n int32) *int32 {
+	return &n
+}
+
+func TestDeploymentWorkload(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "warm-viz3d",
+			Namespace:  "viz",
+			Generation: 2,
+			Labels:     map[string]string{"session-monitor/warm-pool": "viz3d"},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: replicas(3)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           3,
+			UpdatedReplicas:    3,
+			ReadyReplicas:      3,
+			AvailableReplicas:  3,
+		},
+	}
+	workload := deploymentWorkload(deployment, "session-monitor/warm-pool")
+	assert.Equal(t, &domain.Workload{
+		Kind:              domain.DeploymentKind,
+		Namespace:         "viz",
+		Name:              "warm-viz3d",
+		WarmPool:          "viz3d",
+		DesiredReplicas:   3,
+		ReadyReplicas:     3,
+		AvailableReplicas: 3,
+		UpdatedReplicas:   3,
+		RolloutStatus:     domain.RolloutComplete,
+	}, workload)
+
+	tests := []struct {
+		name   string
+		update func(d *appsv1.Deployment)
+		status domain.RolloutStatus
+	}{
+		{"paused", func(d *appsv1.Deployment) { d.Spec.Paused = true }, domain.RolloutPaused},
+		{"not observed", func(d *appsv1.Deployment) { d.Generation = 3 }, domain.RolloutProgressing},
+		{"updating", func(d *appsv1.Deployment) { d.Status.UpdatedReplicas = 1 }, domain.RolloutProgressing},
+		{"old replicas", func(d *appsv1.Deployment) { d.Status.Replicas = 4 }, domain.RolloutProgressing},
+		{"unavailable", func(d *appsv1.Deployment) { d.Status.AvailableReplicas = 2 }, domain.RolloutProgressing},
+		{"deadline exceeded", func(d *appsv1.Deployment) {
+			d.Status.Conditions = []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Reason:  progressDeadlineExceededReason,
+				Message: `ReplicaSet "warm-viz3d-5d8f" has timed out progressing.`,
+			}}
+		}, domain.RolloutFailed},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			updated := deployment.DeepCopy()
+			test.update(updated)
+			workload := deploymentWorkload(updated, "session-monitor/warm-pool")
+			assert.Equal(t, test.status, workload.RolloutStatus)
+		})
+	}
+}
+
+func TestStatefulSetWorkload(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "streaming-gateway", Namespace: "viz"},
+		Spec: appsv1.StatefulSetSpec{
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+		},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:     1,
+			AvailableReplicas: 1,
+			UpdatedReplicas:   1,
+			CurrentRevision:   "streaming-gateway-6c9f",
+			UpdateRevision:    "streaming-gateway-6c9f",
+		},
+	}
+	workload := statefulSetWorkload(statefulSet, "session-monitor/warm-pool")
+	assert.Equal(t, domain.StatefulSetKind, workload.Kind)
+	assert.Equal(t, "", workload.WarmPool)
+	assert.Equal(t, int32(1), workload.DesiredReplicas, "replicas defaults to 1")
+	assert.Equal(t, domain.RolloutComplete, workload.RolloutStatus)
+
+	updating := statefulSet.DeepCopy()
+	updating.Status.UpdateRevision = "streaming-gateway-7a1b"
+	assert.Equal(t, domain.RolloutProgressing, statefulSetWorkload(updating, "").RolloutStatus)
+
+	// replicas below the partition keep the current revision
+	partitioned := updating.DeepCopy()
+	partitioned.Spec.Replicas = replicas(3)
+	partitioned.Status.ReadyReplicas = 3
+	partitioned.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: replicas(2)}
+	assert.Equal(t, domain.RolloutComplete, statefulSetWorkload(partitioned, "").RolloutStatus)
+
+	notReady := statefulSet.DeepCopy()
+	notReady.Spec.Replicas = replicas(2)
+	assert.Equal(t, domain.RolloutProgressing, statefulSetWorkload(notReady, "").RolloutStatus)
+
+	onDelete := updating.DeepCopy()
+	onDelete.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	assert.Equal(t, domain.RolloutComplete, statefulSetWorkload(onDelete, "").RolloutStatus)
+}
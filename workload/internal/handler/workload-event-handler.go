@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// handles the events of both the deployment and the statefulset informer
+type WorkloadEventHandler struct {
+	ctx                   context.Context
+	logger                *zap.Logger
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent]
+	warmPoolLabel         string
+}
+
+func NewWorkloadEventHandler(ctx context.Context, logger *zap.Logger, cfg config.IConfig,
+	domainEventDispatcher ddd.IEventDispatcher[ddd.IEvent],
+	domainEventHandler ddd.IEventHandler[ddd.IEvent],
+) k8s.IK8sEventHandler {
+	return &WorkloadEventHandler{
+		ctx:                   ctx,
+		logger:                logger,
+		domainEventDispatcher: domainEventDispatcher,
+		warmPoolLabel:         config.GetString(cfg, "app.warm_pool_label", "session-monitor/warm-pool"),
+	}
+}
+
+func (handler *WorkloadEventHandler) CustomWatchErrorHandler(r *cache.Reflector, err error) {
+	handler.logger.Sugar().Errorw("Watch error", err)
+	handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(
+		domain.WorkloadInformerErrorEvent,
+		&domain.WorkloadInformerErrorPayload{
+			Err: err,
+		},
+	))
+}
+
+func (handler *WorkloadEventHandler) OnAddObject(obj interface{}) {
+	workload, err := handler.parseWorkload(obj.(*unstructured.Unstructured))
+	if err != nil {
+		handler.logger.Sugar().Error("OnAddObject:", err)
+		return
+	}
+	handler.domainEventDispatcher.Publish(handler.ctx,
+		ddd.NewEvent(domain.WorkloadUpdateEvent, &domain.WorkloadEventPayload{
+			Workload: workload,
+		}),
+		ddd.NewEvent(domain.WorkloadRolloutStatusChangedEvent, &domain.WorkloadRolloutStatusChangedPayload{
+			Workload: workload,
+		}),
+	)
+}
+
+func (handler *WorkloadEventHandler) OnUpdateObject(oldObj, newObj interface{}) {
+	workload, err := handler.parseWorkload(newObj.(*unstructured.Unstructured))
+	if err != nil {
+		handler.logger.Sugar().Error("OnUpdateObject:", err)
+		return
+	}
+	events := []ddd.IEvent{
+		ddd.NewEvent(domain.WorkloadUpdateEvent, &domain.WorkloadEventPayload{
+			Workload: workload,
+		}),
+	}
+	if oldObj != nil {
+		if oldWorkload, err := handler.parseWorkload(oldObj.(*unstructured.Unstructured)); err == nil &&
+			oldWorkload.RolloutStatus != workload.RolloutStatus {
+			events = append(events, ddd.NewEvent(domain.WorkloadRolloutStatusChangedEvent, &domain.WorkloadRolloutStatusChangedPayload{
+				Workload:              workload,
+				PreviousRolloutStatus: oldWorkload.RolloutStatus,
+			}))
+		}
+	}
+	handler.domainEventDispatcher.Publish(handler.ctx, events...)
+}
+
+func (handler *WorkloadEventHandler) OnDeleteObject(obj interface{}) {
+	workload, err := handler.parseWorkload(obj.(*unstructured.Unstructured))
+	if err != nil {
+		handler.logger.Sugar().Error("OnDeleteObject:", err)
+		return
+	}
+	handler.domainEventDispatcher.Publish(handler.ctx, ddd.NewEvent(domain.WorkloadDeleteEvent, &domain.WorkloadEventPayload{
+		Workload: workload,
+	}))
+}
+
+func (handler *WorkloadEventHandler) parseWorkload(u *unstructured.Unstructured) (*domain.Workload, error) {
+	return parseWorkload(u, handler.warmPoolLabel)
+}
+
+func parseWorkload(u *unstructured.Unstructured, warmPoolLabel string) (*domain.Workload, error) {
+	switch u.GetKind() {
+	case domain.StatefulSetKind:
+		statefulSet := appsv1.StatefulSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &statefulSet); err != nil {
+			return nil, err
+		}
+		return statefulSetWorkload(&statefulSet, warmPoolLabel), nil
+	case domain.DeploymentKind:
+		deployment := appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &deployment); err != nil {
+			return nil, err
+		}
+		return deploymentWorkload(&deployment, warmPoolLabel), nil
+	}
+	return nil, domain.NewUnsupportedWorkloadKindErr(u.GetKind(), u.GetNamespace(), u.GetName())
+}
+
+// deletes missed while the monitor was down never reach the handler, once the informers
+// synced the store keeps only the workloads in their caches
+func PruneWorkloads(ctx context.Context, logger *zap.Logger, cfg config.IConfig,
+	store IWorkloadStore, informers ...k8s.IK8sInformer) error {
+	warmPoolLabel := config.GetString(cfg, "app.warm_pool_label", "session-monitor/warm-pool")
+	listed := []*domain.Workload{}
+	for _, informer := range informers {
+		for _, obj := range informer.List() {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			workload, err := parseWorkload(u, warmPoolLabel)
+			if err != nil {
+				logger.Sugar().Error("PruneWorkloads:", err)
+				continue
+			}
+			listed = append(listed, workload)
+		}
+	}
+	return store.Prune(ctx, listed)
+}
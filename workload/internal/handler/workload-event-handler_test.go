@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deploymentObject(kind string, availableReplicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       kind,
+			"apiVersion": "apps/v1",
+			"metadata": map[string]interface{}{
+				"name":      "warm-viz3d",
+				"namespace": "viz",
+				"labels": map[string]interface{}{
+					"session-monitor/workload":  "true",
+					"session-monitor/warm-pool": "viz3d",
+				},
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(2),
+			},
+			"status": map[string]interface{}{
+				"replicas":          int64(2),
+				"updatedReplicas":   int64(2),
+				"readyReplicas":     availableReplicas,
+				"availableReplicas": availableReplicas,
+			},
+		},
+	}
+}
+
+func TestWorkloadEventHandler_CustomWatchErrorHandler(t *testing.T) {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.warm_pool_label").Return(nil)
+	h := NewWorkloadEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}).(*WorkloadEventHandler)
+	h.CustomWatchErrorHandler(nil, errors.New("errors from k8s api server"))
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestWorkloadEventHandler_OnAddObject(t *testing.T) {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.warm_pool_label").Return(nil)
+	h := NewWorkloadEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}).(*WorkloadEventHandler)
+	h.OnAddObject(deploymentObject("Deployment", 1))
+	// not a deployment or statefulset
+	h.OnAddObject(deploymentObject("DaemonSet", 1))
+
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 1)
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.WorkloadUpdateEvent, event.EventName())
+	workload := event.Payload().(*domain.WorkloadEventPayload).Workload
+	assert.Equal(t, "Deployment/viz/warm-viz3d", workload.Key())
+	assert.Equal(t, "viz3d", workload.WarmPool)
+	assert.Equal(t, int32(1), workload.AvailableReplicas)
+	assert.Equal(t, domain.RolloutProgressing, workload.RolloutStatus)
+}
+
+func TestWorkloadEventHandler_OnUpdateObject(t *testing.T) {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.warm_pool_label").Return(nil)
+	h := NewWorkloadEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}).(*WorkloadEventHandler)
+	h.OnUpdateObject(deploymentObject("StatefulSet", 1), deploymentObject("StatefulSet", 2))
+	// same rollout status
+	h.OnUpdateObject(deploymentObject("StatefulSet", 2), deploymentObject("StatefulSet", 2))
+
+	eventDispatcher.AssertNumberOfCalls(t, "Publish", 2)
+	event := eventDispatcher.Calls[0].Arguments.Get(2).(ddd.IEvent)
+	assert.Equal(t, domain.WorkloadRolloutStatusChangedEvent, event.EventName())
+	payload := event.Payload().(*domain.WorkloadRolloutStatusChangedPayload)
+	assert.Equal(t, domain.RolloutProgressing, payload.PreviousRolloutStatus)
+	assert.Equal(t, domain.RolloutComplete, payload.Workload.RolloutStatus)
+	assert.Equal(t, domain.StatefulSetKind, payload.Workload.Kind)
+}
+
+func TestWorkloadEventHandler_OnDeleteObject(t *testing.T) {
+	eventDispatcher := &ddd.MockIEventDispatcher[ddd.IEvent]{}
+	eventDispatcher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+	cfg := &config.MockIConfig{}
+	cfg.On("Get", "app.warm_pool_label").Return(nil)
+	h := NewWorkloadEventHandler(context.TODO(), logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), cfg,
+		eventDispatcher, &ddd.MockIEventHandler[ddd.IEvent]{}).(*WorkloadEventHandler)
+	h.OnDeleteObject(deploymentObject("Deployment", 2))
+	event := eventDispatcher.Calls[0].Arguments.Get(1).(ddd.IEvent)
+	assert.Equal(t, domain.WorkloadDeleteEvent, event.EventName())
+}
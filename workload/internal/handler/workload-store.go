@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+	"go.uber.org/zap"
+)
+
+// workloads built from the deployment and statefulset events, the informers list them again after a restart
+//
+//go:generate mockery --name IWorkloadStore
+type IWorkloadStore interface {
+	// a relabelled workload leaves its previous warm pool
+	Set(ctx context.Context, workload *domain.Workload) error
+	Remove(ctx context.Context, key string) error
+	Get(key string) (domain.Workload, bool)
+	WarmPool(name string) (domain.WarmPool, bool)
+	// listed are the workloads of the synced informer caches, the fields of
+	// workloads and warm pools deleted while the monitor was down are removed
+	Prune(ctx context.Context, listed []*domain.Workload) error
+}
+
+// the workloads are written to the app.workload_status_key hash, fields are the workload keys.
+// the warm pools to the app.warm_pool_capacity_key hash read by the broker, fields are the warm pool names.
+// the lock is held while writing so the hashes never go back to an older view
+type workloadStore struct {
+	logger      *zap.Logger
+	kvRepo      repository.IKVRepository
+	statusKey   string
+	warmPoolKey string
+	mutex       sync.Mutex
+	workloads   map[string]*domain.Workload
+}
+
+var _ IWorkloadStore = (*workloadStore)(nil)
+
+func NewWorkloadStore(logger *zap.Logger, cfg config.IConfig, kvRepo repository.IKVRepository) IWorkloadStore {
+	return &workloadStore{
+		logger:      logger,
+		kvRepo:      kvRepo,
+		statusKey:   config.GetString(cfg, "app.workload_status_key", "SessionMonitor.Workloads"),
+		warmPoolKey: config.GetString(cfg, "app.warm_pool_capacity_key", "SessionMonitor.WarmPoolCapacity"),
+		workloads:   map[string]*domain.Workload{},
+	}
+}
+
+func (s *workloadStore) Set(ctx context.Context, workload *domain.Workload) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := workload.Key()
+	previous, exist := s.workloads[key]
+	s.workloads[key] = workload
+	view, _ := json.Marshal(workload)
+	if _, err := s.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     s.statusKey,
+		Payload: map[string]interface{}{key: string(view)},
+	}); err != nil {
+		s.logger.Sugar().Errorf("Write workload %s has error: %s", key, err.Error())
+		return err
+	}
+	if exist && previous.WarmPool != workload.WarmPool {
+		if err := s.writeWarmPool(ctx, previous.WarmPool); err != nil {
+			return err
+		}
+	}
+	return s.writeWarmPool(ctx, workload.WarmPool)
+}
+
+func (s *workloadStore) Remove(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	previous, exist := s.workloads[key]
+	if !exist {
+		return nil
+	}
+	delete(s.workloads, key)
+	if _, err := s.kvRepo.RemoveFromHash(ctx, s.statusKey, key); err != nil {
+		s.logger.Sugar().Errorf("Remove workload %s has error: %s", key, err.Error())
+		return err
+	}
+	return s.writeWarmPool(ctx, previous.WarmPool)
+}
+
+func (s *workloadStore) Get(key string) (domain.Workload, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	workload, exist := s.workloads[key]
+	if !exist {
+		return domain.Workload{}, false
+	}
+	return *workload, true
+}
+
+func (s *workloadStore) WarmPool(name string) (domain.WarmPool, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pool := s.warmPool(name)
+	return pool, name != "" && len(pool.Workloads) > 0
+}
+
+func (s *workloadStore) Prune(ctx context.Context, listed []*domain.Workload) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys := map[string]bool{}
+	warmPools := map[string]bool{}
+	for _, workload := range listed {
+		keys[workload.Key()] = true
+		warmPools[workload.WarmPool] = true
+	}
+	// the delete events of workloads gone since the list may still be queued
+	changed := map[string]bool{}
+	for key, workload := range s.workloads {
+		if !keys[key] {
+			delete(s.workloads, key)
+			changed[workload.WarmPool] = true
+			s.logger.Sugar().Infow("Pruned workload", "Key", key, "WarmPool", workload.WarmPool)
+		}
+	}
+	if err := s.pruneHash(ctx, s.statusKey, keys); err != nil {
+		return err
+	}
+	if err := s.pruneHash(ctx, s.warmPoolKey, warmPools); err != nil {
+		return err
+	}
+	// the warm pools still listed drop the pruned workloads from their capacity
+	for name := range changed {
+		if warmPools[name] {
+			if err := s.writeWarmPool(ctx, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// caller holds the lock
+func (s *workloadStore) pruneHash(ctx context.Context, key string, keep map[string]bool) error {
+	values, err := s.kvRepo.GetHash(ctx, key)
+	if err != nil {
+		s.logger.Sugar().Errorf("Read %s has error: %s", key, err.Error())
+		return err
+	}
+	stale := []string{}
+	for field := range values {
+		if !keep[field] {
+			stale = append(stale, field)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	sort.Strings(stale)
+	if _, err := s.kvRepo.RemoveFromHash(ctx, key, stale...); err != nil {
+		s.logger.Sugar().Errorf("Prune %s has error: %s", key, err.Error())
+		return err
+	}
+	s.logger.Sugar().Infow("Pruned hash", "Key", key, "Fields", stale)
+	return nil
+}
+
+// caller holds the lock
+func (s *workloadStore) warmPool(name string) domain.WarmPool {
+	workloads := make([]*domain.Workload, 0, len(s.workloads))
+	for _, workload := range s.workloads {
+		workloads = append(workloads, workload)
+	}
+	return domain.NewWarmPool(name, workloads)
+}
+
+// caller holds the lock. workloads outside of warm pools have no capacity to publish,
+// the warm pool field is removed with its last workload
+func (s *workloadStore) writeWarmPool(ctx context.Context, name string) error {
+	if name == "" {
+		return nil
+	}
+	pool := s.warmPool(name)
+	if len(pool.Workloads) == 0 {
+		if _, err := s.kvRepo.RemoveFromHash(ctx, s.warmPoolKey, name); err != nil {
+			s.logger.Sugar().Errorf("Remove warm pool %s has error: %s", name, err.Error())
+			return err
+		}
+		return nil
+	}
+	view, _ := json.Marshal(pool)
+	if _, err := s.kvRepo.SetHash(ctx, &repository.Object{
+		Key:     s.warmPoolKey,
+		Payload: map[string]interface{}{name: string(view)},
+	}); err != nil {
+		s.logger.Sugar().Errorf("Write warm pool %s has error: %s", name, err.Error())
+		return err
+	}
+	return nil
+}
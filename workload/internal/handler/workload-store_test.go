@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/logger"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/domain"
+)
+
+func workloadField(key string) interface{} {
+	return mock.MatchedBy(func(object *repository.Object) bool {
+		_, exist := object.Payload.(map[string]interface{})[key]
+		return object.Key == "SessionMonitor.Workloads" && exist
+	})
+}
+
+func warmPoolField(name string, available int32) interface{} {
+	return mock.MatchedBy(func(object *repository.Object) bool {
+		value, exist := object.Payload.(map[string]interface{})[name]
+		pool := domain.WarmPool{}
+		return object.Key == "SessionMonitor.WarmPoolCapacity" && exist &&
+			json.Unmarshal([]byte(value.(string)), &pool) == nil && pool.AvailableReplicas == available
+	})
+}
+
+func TestWorkloadStore(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, workloadField("Deployment/viz/warm-viz3d")).Return(int64(1), nil).Times(3)
+	mockKVRepository.On("SetHash", ctx, workloadField("StatefulSet/viz/warm-viz3d-large")).Return(int64(1), nil).Once()
+	mockKVRepository.On("SetHash", ctx, workloadField("Deployment/viz/streaming-gateway")).Return(int64(1), nil).Once()
+	// the relabelled workload leaves the warm-viz3d-large replicas behind
+	mockKVRepository.On("SetHash", ctx, warmPoolField("viz3d", 2)).Return(int64(1), nil).Twice()
+	mockKVRepository.On("SetHash", ctx, warmPoolField("viz3d", 4)).Return(int64(0), nil).Once()
+	mockKVRepository.On("SetHash", ctx, warmPoolField("viz3d", 5)).Return(int64(0), nil).Once()
+	mockKVRepository.On("SetHash", ctx, warmPoolField("viz3d-canary", 3)).Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.Workloads", "StatefulSet/viz/warm-viz3d-large").Return(int64(1), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.WarmPoolCapacity", "viz3d").Return(int64(1), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.workload_status_key").Return(nil)
+	mockConfig.On("Get", "app.warm_pool_capacity_key").Return(nil)
+	store := NewWorkloadStore(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	warmViz3d := &domain.Workload{Kind: domain.DeploymentKind, Namespace: "viz", Name: "warm-viz3d", WarmPool: "viz3d",
+		DesiredReplicas: 3, AvailableReplicas: 2, RolloutStatus: domain.RolloutProgressing}
+	assert.Nil(t, store.Set(ctx, warmViz3d))
+	assert.Nil(t, store.Set(ctx, &domain.Workload{Kind: domain.StatefulSetKind, Namespace: "viz", Name: "warm-viz3d-large",
+		WarmPool: "viz3d", DesiredReplicas: 2, AvailableReplicas: 2, RolloutStatus: domain.RolloutComplete}))
+	// outside of warm pools
+	assert.Nil(t, store.Set(ctx, &domain.Workload{Kind: domain.DeploymentKind, Namespace: "viz", Name: "streaming-gateway",
+		DesiredReplicas: 2, AvailableReplicas: 2, RolloutStatus: domain.RolloutComplete}))
+
+	pool, exist := store.WarmPool("viz3d")
+	assert.True(t, exist)
+	assert.Equal(t, int32(5), pool.DesiredReplicas)
+	assert.Equal(t, int32(4), pool.AvailableReplicas)
+	assert.True(t, pool.RollingOut)
+	_, exist = store.WarmPool("")
+	assert.False(t, exist)
+
+	workload, exist := store.Get("Deployment/viz/streaming-gateway")
+	assert.True(t, exist)
+	assert.Equal(t, int32(2), workload.AvailableReplicas)
+
+	// rollout completed
+	completed := *warmViz3d
+	completed.AvailableReplicas, completed.RolloutStatus = 3, domain.RolloutComplete
+	assert.Nil(t, store.Set(ctx, &completed))
+	pool, _ = store.WarmPool("viz3d")
+	assert.False(t, pool.RollingOut)
+
+	// relabelled into another warm pool
+	canary := completed
+	canary.WarmPool = "viz3d-canary"
+	assert.Nil(t, store.Set(ctx, &canary))
+
+	assert.Nil(t, store.Remove(ctx, "StatefulSet/viz/warm-viz3d-large"))
+	// unknown workload
+	assert.Nil(t, store.Remove(ctx, "StatefulSet/viz/warm-viz3d-large"))
+	_, exist = store.WarmPool("viz3d")
+	assert.False(t, exist)
+}
+
+func TestWorkloadStorePrune(t *testing.T) {
+	ctx := context.TODO()
+	mockKVRepository := repository.NewMockIKVRepository(t)
+	mockKVRepository.On("SetHash", ctx, mock.Anything).Return(int64(1), nil).Times(4)
+	mockKVRepository.On("GetHash", ctx, "SessionMonitor.Workloads").Return(map[string]string{
+		"Deployment/viz/warm-viz3d":        "{}",
+		"StatefulSet/viz/warm-viz3d-large": "{}",
+		// deleted before the restart
+		"Deployment/viz/warm-viz2d": "{}",
+	}, nil).Once()
+	mockKVRepository.On("GetHash", ctx, "SessionMonitor.WarmPoolCapacity").Return(map[string]string{
+		"viz3d": "{}",
+		"viz2d": "{}",
+	}, nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.Workloads",
+		"Deployment/viz/warm-viz2d", "StatefulSet/viz/warm-viz3d-large").Return(int64(2), nil).Once()
+	mockKVRepository.On("RemoveFromHash", ctx, "SessionMonitor.WarmPoolCapacity", "viz2d").Return(int64(1), nil).Once()
+	mockKVRepository.On("SetHash", ctx, warmPoolField("viz3d", 3)).Return(int64(0), nil).Once()
+	mockConfig := &config.MockIConfig{}
+	mockConfig.On("Get", "app.workload_status_key").Return(nil)
+	mockConfig.On("Get", "app.warm_pool_capacity_key").Return(nil)
+	store := NewWorkloadStore(logger.NewZapLogger(logger.LogConfig{LogLevel: logger.DEBUG}), mockConfig, mockKVRepository)
+
+	warmViz3d := &domain.Workload{Kind: domain.DeploymentKind, Namespace: "viz", Name: "warm-viz3d", WarmPool: "viz3d",
+		DesiredReplicas: 3, AvailableReplicas: 3, RolloutStatus: domain.RolloutComplete}
+	assert.Nil(t, store.Set(ctx, warmViz3d))
+	// deleted since the informer listed it, the delete event is still queued
+	assert.Nil(t, store.Set(ctx, &domain.Workload{Kind: domain.StatefulSetKind, Namespace: "viz", Name: "warm-viz3d-large",
+		WarmPool: "viz3d", DesiredReplicas: 2, AvailableReplicas: 2, RolloutStatus: domain.RolloutComplete}))
+
+	assert.Nil(t, store.Prune(ctx, []*domain.Workload{warmViz3d}))
+	_, exist := store.Get("StatefulSet/viz/warm-viz3d-large")
+	assert.False(t, exist)
+	pool, exist := store.WarmPool("viz3d")
+	assert.True(t, exist)
+	assert.Equal(t, int32(3), pool.AvailableReplicas)
+}
@@ -0,0 +1,119 @@
+package workload
+
+import (
+	"context"
+
+	"github.com/xcheng85/session-monitor-k8s/internal/config"
+	"github.com/xcheng85/session-monitor-k8s/internal/ddd"
+	"github.com/xcheng85/session-monitor-k8s/internal/k8s"
+	"github.com/xcheng85/session-monitor-k8s/internal/module"
+	"github.com/xcheng85/session-monitor-k8s/internal/repository"
+	"github.com/xcheng85/session-monitor-k8s/internal/worker"
+	"github.com/xcheng85/session-monitor-k8s/workload/internal/handler"
+	"go.uber.org/dig"
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watches the deployments and statefulsets of the warm pools and streaming gateways
+type WorkloadMonitoringModule struct{}
+
+func (m WorkloadMonitoringModule) Startup(ctx context.Context, mono module.IModuleContext) (*dig.Container, error) {
+	container := dig.New()
+	err := container.Provide(func() context.Context {
+		return ctx
+	})
+	err = container.Provide(func() *zap.Logger {
+		return mono.Logger()
+	})
+	err = container.Provide(func() config.IConfig {
+		return mono.Config()
+	})
+	err = container.Provide(func() repository.IKVRepository {
+		return mono.KvRepository()
+	})
+	err = container.Provide(func() ddd.IEventDispatcher[ddd.IEvent] {
+		return mono.EventDispatcher()
+	})
+	err = container.Provide(k8s.NewK8sDynamicInformer)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewDomainEventHandlers)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewWorkloadStore)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(handler.NewWorkloadEventHandler)
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() string {
+		return "deployments"
+	}, dig.Name("k8s_resource"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() string {
+		return "apps"
+	}, dig.Name("k8s_resource_group"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func() string {
+		return "v1"
+	}, dig.Name("k8s_resource_version"))
+	if err != nil {
+		return nil, err
+	}
+	// empty namespace watches all namespaces
+	err = container.Provide(func(cfg config.IConfig) string {
+		return config.GetString(cfg, "app.workload_namespace", "")
+	}, dig.Name("k8s_resource_namespace"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Provide(func(cfg config.IConfig) string {
+		return config.GetString(cfg, "app.workload_label_selector", "session-monitor/workload")
+	}, dig.Name("k8s_resource_label_selector"))
+	if err != nil {
+		return nil, err
+	}
+	// the app runs the deployment informer, the statefulset informer shares its filter and handler.
+	// the stale workloads are pruned once both caches are synced
+	err = container.Provide(func(ctx context.Context, logger *zap.Logger, cfg config.IConfig,
+		eventHandler k8s.IK8sEventHandler, filter k8s.K8sInformerFilter,
+		deployments k8s.IK8sInformer, store handler.IWorkloadStore) (worker.Worker, error) {
+		filter.Resource = "statefulsets"
+		statefulSets, err := k8s.NewK8sDynamicInformer(ctx, logger, cfg, eventHandler, filter)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error {
+			go func() {
+				if !cache.WaitForCacheSync(ctx.Done(), deployments.HasSynced, statefulSets.HasSynced) {
+					return
+				}
+				if err := handler.PruneWorkloads(ctx, logger, cfg, store, deployments, statefulSets); err != nil {
+					logger.Sugar().Errorf("Prune workloads has error: %s", err.Error())
+				}
+			}()
+			statefulSets.Run()
+			return nil
+		}, nil
+	}, dig.Group("workers"))
+	if err != nil {
+		return nil, err
+	}
+	err = container.Invoke(func(informer k8s.IK8sInformer) error {
+		return nil
+	})
+	return container, err
+}
+
+func NewWorkloadMonitoringModule() module.Module {
+	return &WorkloadMonitoringModule{}
+}